@@ -0,0 +1,53 @@
+// Command migrate-thumbnails moves the stored album thumbnails to the backend selected by THUMBNAIL_STORE. it reads the
+// same environment as the service, so rows in every configured backend can be moved. an interrupted run is resumed by
+// passing the last id it logged to -after
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/waifuvault/WaifuVault/shared/utils"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/store"
+)
+
+func main() {
+	afterId := flag.Int("after", 0, "only move rows with an id above this one")
+	batchSize := flag.Int("batch", 100, "rows moved per page")
+	flag.Parse()
+
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	utils.LoadEnvs()
+
+	thumbnailStore, err := store.New(store.ConfigFromEnv())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to open thumbnail store")
+	}
+
+	// moving a row doesn't change its thumbnail, so nothing is cached
+	mainDao, err := dao.NewDao(cache.NewLRUCache(0), thumbnailStore)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to connect to the database")
+	}
+
+	log.Info().Str("store", thumbnailStore.Backend()).Int("after", *afterId).Msg("moving thumbnails")
+	cursor, moved, failed := *afterId, 0, 0
+	for {
+		result, err := mainDao.MoveThumbnails(cursor, *batchSize)
+		if err != nil {
+			log.Fatal().Err(err).Int("after", cursor).Msg("failed to move thumbnails, resume with -after")
+		}
+		if result.LastId == 0 {
+			break
+		}
+		cursor = result.LastId
+		moved += result.Moved
+		failed += result.Failed
+		log.Info().Int("lastId", cursor).Int("moved", moved).Int("failed", failed).Msg("moved page")
+	}
+	log.Info().Int("moved", moved).Int("failed", failed).Msg("finished moving thumbnails")
+}
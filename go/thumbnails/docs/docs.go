@@ -31,6 +31,8 @@ const docTemplate = `{
                     "multipart/form-data"
                 ],
                 "produces": [
+                    "image/webp",
+                    "image/jpeg",
                     "application/json"
                 ],
                 "tags": [
@@ -50,6 +52,48 @@ const docTemplate = `{
                         "description": "set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)",
                         "name": "animate",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "attention",
+                            "entropy",
+                            "centre"
+                        ],
+                        "type": "string",
+                        "description": "crop the thumbnail to a square using this strategy",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "embed the original ICC profile instead of converting to sRGB",
+                        "name": "keepProfile",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1024,
+                        "type": "integer",
+                        "description": "byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality",
+                        "name": "maxBytes",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 40,
+                        "description": "lowest quality allowed when fitting maxBytes",
+                        "name": "minQuality",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 2048,
+                        "minimum": 16,
+                        "type": "integer",
+                        "description": "width of the thumbnail in pixels, defaults to the Sec-CH-Width / Sec-CH-DPR client hints or 400",
+                        "name": "width",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -57,6 +101,20 @@ const docTemplate = `{
                         "description": "File uploaded successfully",
                         "schema": {
                             "$ref": "#/definitions/wapimod.ApiResult"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "private, no-store"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the thumbnail bytes"
+                            },
+                            "X-Thumbnail-Quality": {
+                                "type": "string",
+                                "description": "quality chosen to fit maxBytes, e.g. 62, near-lossless or 40; alpha=lossy"
+                            }
                         }
                     },
                     "400": {
@@ -72,7 +130,117 @@ const docTemplate = `{
             "get": {
                 "description": "Generates a thumbnail from a file URL",
                 "produces": [
-                    "image/webp"
+                    "image/webp",
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Generate thumbnail from URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL of the file to generate thumbnail for",
+                        "name": "url",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)",
+                        "name": "animate",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "attention",
+                            "entropy",
+                            "centre"
+                        ],
+                        "type": "string",
+                        "description": "crop the thumbnail to a square using this strategy",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "embed the original ICC profile instead of converting to sRGB",
+                        "name": "keepProfile",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1024,
+                        "type": "integer",
+                        "description": "byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality",
+                        "name": "maxBytes",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 40,
+                        "description": "lowest quality allowed when fitting maxBytes",
+                        "name": "minQuality",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 2048,
+                        "minimum": 16,
+                        "type": "integer",
+                        "description": "width of the thumbnail in pixels, defaults to the Sec-CH-Width / Sec-CH-DPR client hints or 400",
+                        "name": "width",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thumbnail image, WebP or a JPEG frame for videos",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public, max-age=600"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the thumbnail bytes"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "time the thumbnail was rendered"
+                            },
+                            "X-Thumbnail-Quality": {
+                                "type": "string",
+                                "description": "quality chosen to fit maxBytes, e.g. 62, near-lossless or 40; alpha=lossy"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "the cached copy matching If-None-Match or If-Modified-Since is current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid URL or unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Generates a thumbnail from a file URL",
+                "produces": [
+                    "image/webp",
+                    "image/jpeg"
                 ],
                 "tags": [
                     "thumbnails"
@@ -91,15 +259,78 @@ const docTemplate = `{
                         "description": "set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)",
                         "name": "animate",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "attention",
+                            "entropy",
+                            "centre"
+                        ],
+                        "type": "string",
+                        "description": "crop the thumbnail to a square using this strategy",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "embed the original ICC profile instead of converting to sRGB",
+                        "name": "keepProfile",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1024,
+                        "type": "integer",
+                        "description": "byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality",
+                        "name": "maxBytes",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 40,
+                        "description": "lowest quality allowed when fitting maxBytes",
+                        "name": "minQuality",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 2048,
+                        "minimum": 16,
+                        "type": "integer",
+                        "description": "width of the thumbnail in pixels, defaults to the Sec-CH-Width / Sec-CH-DPR client hints or 400",
+                        "name": "width",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Thumbnail image in WebP format",
+                        "description": "Thumbnail image, WebP or a JPEG frame for videos",
                         "schema": {
                             "type": "string"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public, max-age=600"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the thumbnail bytes"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "time the thumbnail was rendered"
+                            },
+                            "X-Thumbnail-Quality": {
+                                "type": "string",
+                                "description": "quality chosen to fit maxBytes, e.g. 62, near-lossless or 40; alpha=lossy"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "the cached copy matching If-None-Match or If-Modified-Since is current"
+                    },
                     "400": {
                         "description": "Bad request - invalid URL or unsupported file type",
                         "schema": {
@@ -119,7 +350,8 @@ const docTemplate = `{
             "get": {
                 "description": "Generates a thumbnail from an existing file using its token",
                 "produces": [
-                    "image/webp"
+                    "image/webp",
+                    "image/jpeg"
                 ],
                 "tags": [
                     "thumbnails"
@@ -138,15 +370,78 @@ const docTemplate = `{
                         "description": "set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)",
                         "name": "animate",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "attention",
+                            "entropy",
+                            "centre"
+                        ],
+                        "type": "string",
+                        "description": "crop the thumbnail to a square using this strategy, the file's focal point takes precedence when set",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "embed the original ICC profile instead of converting to sRGB",
+                        "name": "keepProfile",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1024,
+                        "type": "integer",
+                        "description": "byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality",
+                        "name": "maxBytes",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 40,
+                        "description": "lowest quality allowed when fitting maxBytes",
+                        "name": "minQuality",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 2048,
+                        "minimum": 16,
+                        "type": "integer",
+                        "description": "width of the thumbnail in pixels, defaults to the Sec-CH-Width / Sec-CH-DPR client hints or 400",
+                        "name": "width",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Thumbnail image in WebP format",
+                        "description": "Thumbnail image, WebP or a JPEG frame for videos",
                         "schema": {
                             "type": "string"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public, max-age=0, must-revalidate"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the thumbnail bytes"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "time the thumbnail was rendered"
+                            },
+                            "X-Thumbnail-Quality": {
+                                "type": "string",
+                                "description": "quality chosen to fit maxBytes, e.g. 62, near-lossless or 40; alpha=lossy"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "the cached copy matching If-None-Match or If-Modified-Since is current"
+                    },
                     "400": {
                         "description": "Bad request - invalid file token or unsupported file type",
                         "schema": {
@@ -160,37 +455,120 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/generateThumbnails/supported": {
-            "get": {
-                "description": "Returns a list of all file extensions supported by the thumbnail service for both images and videos",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "head": {
+                "description": "Generates a thumbnail from an existing file using its token",
                 "produces": [
-                    "application/json"
+                    "image/webp",
+                    "image/jpeg"
                 ],
                 "tags": [
                     "thumbnails"
                 ],
-                "summary": "Get supported file extensions",
+                "summary": "Generate thumbnail from file token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File token to generate thumbnail for",
+                        "name": "fileToken",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)",
+                        "name": "animate",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "attention",
+                            "entropy",
+                            "centre"
+                        ],
+                        "type": "string",
+                        "description": "crop the thumbnail to a square using this strategy, the file's focal point takes precedence when set",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "embed the original ICC profile instead of converting to sRGB",
+                        "name": "keepProfile",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1024,
+                        "type": "integer",
+                        "description": "byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality",
+                        "name": "maxBytes",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 40,
+                        "description": "lowest quality allowed when fitting maxBytes",
+                        "name": "minQuality",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 2048,
+                        "minimum": 16,
+                        "type": "integer",
+                        "description": "width of the thumbnail in pixels, defaults to the Sec-CH-Width / Sec-CH-DPR client hints or 400",
+                        "name": "width",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "List of supported file extensions",
+                        "description": "Thumbnail image, WebP or a JPEG frame for videos",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "string"
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public, max-age=0, must-revalidate"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the thumbnail bytes"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "time the thumbnail was rendered"
+                            },
+                            "X-Thumbnail-Quality": {
+                                "type": "string",
+                                "description": "quality chosen to fit maxBytes, e.g. 62, near-lossless or 40; alpha=lossy"
                             }
                         }
+                    },
+                    "304": {
+                        "description": "the cached copy matching If-None-Match or If-Modified-Since is current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid file token or unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
                     }
                 }
             }
         },
-        "/health": {
-            "get": {
-                "description": "Returns the health status of the thumbnail service",
+        "/generateThumbnail/{fileToken}/focalPoint": {
+            "put": {
+                "description": "Stores the point of interest used when cropping thumbnails of this file. Coordinates are normalised from 0 to 1, starting at the top left",
                 "consumes": [
                     "application/json"
                 ],
@@ -198,42 +576,1582 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "thumbnails"
+                ],
+                "summary": "Set the focal point of a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File token to set the focal point for",
+                        "name": "fileToken",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Focal point",
+                        "name": "focalPoint",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.FocalPointDto"
+                        }
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
-                        "description": "Service health status",
+                        "description": "Focal point saved",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid file token or focal point",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "wapimod.ApiResult": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "Operation completed successfully"
-                },
-                "success": {
-                    "type": "boolean",
-                    "example": true
-                }
-            }
-        }
-    },
-    "tags": [
-        {
-            "description": "Operations for generating and managing thumbnails",
-            "name": "thumbnails"
+        },
+        "/generateThumbnail/{fileToken}/set": {
+            "get": {
+                "description": "Decodes the file once and returns a thumbnail for every width multiplied by every device pixel ratio, ready to build a srcset. Each width is cached on its own",
+                "produces": [
+                    "application/json",
+                    "multipart/mixed"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Generate a responsive thumbnail set from a file token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File token to generate thumbnails for",
+                        "name": "fileToken",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "200,400,800",
+                        "description": "comma separated widths in pixels",
+                        "name": "widths",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "1",
+                        "description": "comma separated device pixel ratios applied to every width",
+                        "name": "dpr",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "multipart"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "json manifest with base64 data or a multipart/mixed body with one part per width",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)",
+                        "name": "animate",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "attention",
+                            "entropy",
+                            "centre"
+                        ],
+                        "type": "string",
+                        "description": "crop the thumbnail to a square using this strategy, the file's focal point takes precedence when set",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "embed the original ICC profile instead of converting to sRGB",
+                        "name": "keepProfile",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1024,
+                        "type": "integer",
+                        "description": "byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality",
+                        "name": "maxBytes",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 40,
+                        "description": "lowest quality allowed when fitting maxBytes",
+                        "name": "minQuality",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thumbnail set",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ThumbnailSetDto"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public, max-age=0, must-revalidate"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the response body"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "time the newest variant was rendered"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "the cached copy matching If-None-Match or If-Modified-Since is current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid file token, widths or unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Decodes the file once and returns a thumbnail for every width multiplied by every device pixel ratio, ready to build a srcset. Each width is cached on its own",
+                "produces": [
+                    "application/json",
+                    "multipart/mixed"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Generate a responsive thumbnail set from a file token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File token to generate thumbnails for",
+                        "name": "fileToken",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "200,400,800",
+                        "description": "comma separated widths in pixels",
+                        "name": "widths",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "1",
+                        "description": "comma separated device pixel ratios applied to every width",
+                        "name": "dpr",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "multipart"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "json manifest with base64 data or a multipart/mixed body with one part per width",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)",
+                        "name": "animate",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "attention",
+                            "entropy",
+                            "centre"
+                        ],
+                        "type": "string",
+                        "description": "crop the thumbnail to a square using this strategy, the file's focal point takes precedence when set",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "embed the original ICC profile instead of converting to sRGB",
+                        "name": "keepProfile",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1024,
+                        "type": "integer",
+                        "description": "byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality",
+                        "name": "maxBytes",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 40,
+                        "description": "lowest quality allowed when fitting maxBytes",
+                        "name": "minQuality",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thumbnail set",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ThumbnailSetDto"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public, max-age=0, must-revalidate"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the response body"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "time the newest variant was rendered"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "the cached copy matching If-None-Match or If-Modified-Since is current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid file token, widths or unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/generateThumbnails": {
+            "post": {
+                "description": "Looks up the files of the album and queues a job rendering their thumbnails. Files of other albums, expired files and encrypted or password protected files are left out, and files that already have a thumbnail are skipped unless force is set",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Queue a job generating the thumbnails of album files",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id of the album",
+                        "name": "albumId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Files of the album to render, every file of the album when omitted",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GenerateThumbnailsRequestDto"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "set when files were added to an album that may still be loading",
+                        "name": "addingAdditionalFiles",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "attention",
+                            "entropy",
+                            "centre"
+                        ],
+                        "type": "string",
+                        "description": "crop the thumbnails to a square using this strategy",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "render the files that already have a thumbnail again",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL that receives the signed completion event of the job",
+                        "name": "callbackUrl",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The job was queued",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid album, file ids, crop or callback",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "The album does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/generateThumbnails/supported": {
+            "get": {
+                "description": "Returns a list of all file extensions supported by the thumbnail service for both images and videos",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Get supported file extensions",
+                "responses": {
+                    "200": {
+                        "description": "List of supported file extensions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/generateThumbnails/{albumId}": {
+            "delete": {
+                "description": "Cancels the queued and running thumbnail jobs of an album. A running job stops taking files and kills its ffmpeg processes, a job running on another instance is stopped within a few seconds. The thumbnails it rendered but hasn't stored yet are stored, or dropped with ` + "`" + `discard=true` + "`" + `",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Cancel the thumbnail jobs of an album",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Album id",
+                        "name": "albumId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "drop the thumbnails rendered but not stored yet",
+                        "name": "discard",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Jobs cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid album id",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "The album has no queued or running job",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/generateThumbnails/{albumId}/callbacks": {
+            "get": {
+                "description": "Returns the latest 50 attempts at delivering the completion events of the thumbnail jobs of an album, newest first, for debugging callbacks",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Get the completion event deliveries of an album",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Album id",
+                        "name": "albumId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delivery attempts",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.CallbackDeliveryDto"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid album id",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/generateThumbnails/{albumId}/status": {
+            "get": {
+                "description": "Returns the state and file counts of the latest thumbnail job of an album, with an estimate of the time left while it runs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Get the thumbnail job progress of an album",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Album id",
+                        "name": "albumId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Job progress",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AlbumJobStatusDto"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid album id",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "The album has no thumbnail job",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/generateThumbnails/{albumId}/status/stream": {
+            "get": {
+                "description": "Server-sent events with a ` + "`" + `progress` + "`" + ` event carrying the job status whenever it changes. The stream ends after the job is done or failed",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Stream the thumbnail job progress of an album",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Album id",
+                        "name": "albumId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of progress events",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AlbumJobStatusDto"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid album id",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "The album has no thumbnail job",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Returns the health status of the thumbnail service",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "Service health status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/queue": {
+            "get": {
+                "description": "Returns the queue depth and wait times of the interactive and batch thumbnail work of this instance",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Thumbnail queue stats",
+                "responses": {
+                    "200": {
+                        "description": "Worker pool stats",
+                        "schema": {
+                            "$ref": "#/definitions/dto.QueueStatsDto"
+                        }
+                    }
+                }
+            }
+        },
+        "/scrubMetadata": {
+            "post": {
+                "description": "Returns a copy of the upload with EXIF, XMP, IPTC, comments and video metadata atoms removed. Only the orientation and colour profile are kept and the image or video data is not re-encoded. Supports JPEG, PNG, WebP, HEIC, MP4 and MOV",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "metadata"
+                ],
+                "summary": "Remove metadata from an uploaded file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "File to scrub",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Scrubbed file",
+                        "schema": {
+                            "type": "file"
+                        },
+                        "headers": {
+                            "X-Metadata-Removed": {
+                                "type": "string",
+                                "description": "comma separated list of the metadata that was removed"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - no file uploaded or unsupported format",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/scrubMetadata/{fileToken}": {
+            "post": {
+                "description": "Rewrites the stored original without EXIF, XMP, IPTC, comments and video metadata atoms, keeping only the orientation and colour profile. The image or video data is not re-encoded. Encrypted files are rejected",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "metadata"
+                ],
+                "summary": "Remove metadata from a stored file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token of the file to scrub",
+                        "name": "fileToken",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Metadata removed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ScrubResultDto"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid token, encrypted file or unsupported format",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/thumbnails/album/{albumId}": {
+            "delete": {
+                "description": "Removes the cached and stored thumbnails of every file in an album",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Purge the thumbnails of an album",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id of the album",
+                        "name": "albumId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "What was purged",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PurgeResultDto"
+                        }
+                    },
+                    "404": {
+                        "description": "The album does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/thumbnails/backfill": {
+            "get": {
+                "description": "Returns the progress of the current or last backfill run of this instance",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Get the thumbnail backfill progress",
+                "responses": {
+                    "200": {
+                        "description": "Backfill progress",
+                        "schema": {
+                            "$ref": "#/definitions/dto.BackfillStatusDto"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Generates the missing thumbnails of album files in the background, at the rate set by THUMBNAIL_BACKFILL_RATE. A run carries on from the cursor of an interrupted run unless restart is set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Start the thumbnail backfill",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Discard the stored cursor and start from the first file",
+                        "name": "restart",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "The backfill was started",
+                        "schema": {
+                            "$ref": "#/definitions/dto.BackfillStatusDto"
+                        }
+                    },
+                    "409": {
+                        "description": "A backfill is already running",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/thumbnails/batch": {
+            "post": {
+                "description": "Returns the thumbnails the album batch generated for up to 500 files in one response, so an album page needs a single round trip. Files without a thumbnail are listed in missing",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Get the stored thumbnails of many files",
+                "parameters": [
+                    {
+                        "description": "Files to return thumbnails for",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ThumbnailBatchRequestDto"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stored thumbnails",
+                        "schema": {
+                            "$ref": "#/definitions/dto.StoredThumbnailsDto"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - no file ids, too many or invalid ones",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/thumbnails/failures": {
+            "get": {
+                "description": "Returns the latest 500 album files the thumbnail jobs skip after they kept failing to render, most recently failed first. A file of an unsupported type or missing from disk is quarantined after its first failure, any other file after THUMBNAIL_FILE_MAX_ATTEMPTS failures",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "List the quarantined album files",
+                "responses": {
+                    "200": {
+                        "description": "Quarantined files",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.QuarantinedFileDto"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Clears the failures of every quarantined file so the next thumbnail job of their album renders them again. Files waiting for a retry keep their failures",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Reset every quarantined album file",
+                "responses": {
+                    "200": {
+                        "description": "Files reset",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/thumbnails/failures/{fileId}": {
+            "delete": {
+                "description": "Clears the failures of a file, quarantined or waiting for a retry, so the next thumbnail job of its album renders it again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Reset the failures of an album file",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id of the file",
+                        "name": "fileId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File reset",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file id",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "The file has no recorded failure",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/thumbnails/token/{fileToken}": {
+            "delete": {
+                "description": "Removes every cached thumbnail rendered for a file token, and the stored album thumbnail when the file still exists. Use this after a file was replaced, protected or re-encrypted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Purge the thumbnails of a file token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File token to purge thumbnails for",
+                        "name": "fileToken",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "What was purged",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PurgeResultDto"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid file token",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/thumbnails/{fileId}": {
+            "get": {
+                "description": "Returns the thumbnail the album batch generated for a file, read from the cache first and then from the database",
+                "produces": [
+                    "image/webp",
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Get the stored thumbnail of a file",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id of the file",
+                        "name": "fileId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thumbnail image, WebP or a JPEG frame for videos",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public, max-age=0, must-revalidate"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the thumbnail bytes"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "the cached copy matching If-None-Match is current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid file id",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "The file has no stored thumbnail",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the stored album thumbnail of a file, and its cached thumbnails when the file still exists",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Purge the thumbnails of a file",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id of the file",
+                        "name": "fileId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "What was purged",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PurgeResultDto"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid file id",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Returns the thumbnail the album batch generated for a file, read from the cache first and then from the database",
+                "produces": [
+                    "image/webp",
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "thumbnails"
+                ],
+                "summary": "Get the stored thumbnail of a file",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Id of the file",
+                        "name": "fileId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thumbnail image, WebP or a JPEG frame for videos",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "public, max-age=0, must-revalidate"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "strong validator of the thumbnail bytes"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "the cached copy matching If-None-Match is current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid file id",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "404": {
+                        "description": "The file has no stored thumbnail",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/wapimod.ApiResult"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.AlbumJobStatusDto": {
+            "type": "object",
+            "properties": {
+                "albumId": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "attempt": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "etaSeconds": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "failures": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FileFailureDto"
+                    }
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "integer",
+                    "example": 40
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer",
+                    "example": 80
+                },
+                "skipped": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string",
+                    "enum": [
+                        "queued",
+                        "running",
+                        "done",
+                        "failed",
+                        "cancelled"
+                    ],
+                    "example": "running"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 120
+                }
+            }
+        },
+        "dto.BackfillStatusDto": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "integer",
+                    "example": 1250
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer",
+                    "example": 350
+                },
+                "remaining": {
+                    "type": "integer",
+                    "example": 4000
+                },
+                "running": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "skipped": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "startedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CallbackDeliveryDto": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "delivered": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "deliveryId": {
+                    "type": "string",
+                    "example": "0b6c6f3e-2f1d-4a4e-9a51-3d8f0f1c2b7a"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "integer",
+                    "example": 40
+                },
+                "statusCode": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "target": {
+                    "type": "string",
+                    "example": "https://waifuvault.moe/rest/thumbnails/callback"
+                }
+            }
+        },
+        "dto.FileFailureDto": {
+            "type": "object",
+            "properties": {
+                "fileId": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "unsupported file type"
+                }
+            }
+        },
+        "dto.FocalPointDto": {
+            "type": "object",
+            "required": [
+                "x",
+                "y"
+            ],
+            "properties": {
+                "x": {
+                    "type": "number",
+                    "example": 0.5
+                },
+                "y": {
+                    "type": "number",
+                    "example": 0.25
+                }
+            }
+        },
+        "dto.GenerateThumbnailsRequestDto": {
+            "type": "object",
+            "properties": {
+                "fileIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3
+                    ]
+                }
+            }
+        },
+        "dto.PurgeResultDto": {
+            "type": "object",
+            "properties": {
+                "files": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "storedThumbnails": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "dto.QuarantinedFileDto": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "errorClass": {
+                    "type": "string",
+                    "enum": [
+                        "transient",
+                        "unsupported",
+                        "missing"
+                    ],
+                    "example": "transient"
+                },
+                "failedAt": {
+                    "type": "string"
+                },
+                "fileId": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "lastError": {
+                    "type": "string",
+                    "example": "VipsJpeg: premature end of JPEG file"
+                }
+            }
+        },
+        "dto.QueueClassDto": {
+            "type": "object",
+            "properties": {
+                "averageWaitMs": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "oldestWaitMs": {
+                    "type": "integer",
+                    "example": 850
+                },
+                "queued": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "running": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "started": {
+                    "type": "integer",
+                    "example": 4200
+                }
+            }
+        },
+        "dto.QueueStatsDto": {
+            "type": "object",
+            "properties": {
+                "batch": {
+                    "$ref": "#/definitions/dto.QueueClassDto"
+                },
+                "inUseBytes": {
+                    "type": "integer",
+                    "example": 268435456
+                },
+                "interactive": {
+                    "$ref": "#/definitions/dto.QueueClassDto"
+                },
+                "maxBytes": {
+                    "type": "integer",
+                    "example": 1073741824
+                },
+                "workers": {
+                    "type": "integer",
+                    "example": 4
+                }
+            }
+        },
+        "dto.ScrubResultDto": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "format": {
+                    "type": "string",
+                    "example": "jpeg"
+                },
+                "removed": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "EXIF",
+                        "XMP"
+                    ]
+                }
+            }
+        },
+        "dto.StoredThumbnailDto": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string",
+                    "example": "image/webp"
+                },
+                "data": {
+                    "type": "string",
+                    "format": "base64"
+                },
+                "fileId": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "dto.StoredThumbnailsDto": {
+            "type": "object",
+            "properties": {
+                "missing": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "thumbnails": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.StoredThumbnailDto"
+                    }
+                }
+            }
+        },
+        "dto.ThumbnailBatchRequestDto": {
+            "type": "object",
+            "required": [
+                "fileIds"
+            ],
+            "properties": {
+                "fileIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3
+                    ]
+                }
+            }
+        },
+        "dto.ThumbnailSetDto": {
+            "type": "object",
+            "properties": {
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ThumbnailVariantDto"
+                    }
+                }
+            }
+        },
+        "dto.ThumbnailVariantDto": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string",
+                    "example": "image/webp"
+                },
+                "data": {
+                    "type": "string",
+                    "format": "base64"
+                },
+                "descriptor": {
+                    "type": "string",
+                    "example": "800w"
+                },
+                "quality": {
+                    "type": "string",
+                    "example": "62"
+                },
+                "width": {
+                    "type": "integer",
+                    "example": 800
+                }
+            }
+        },
+        "wapimod.ApiResult": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Operation completed successfully"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        }
+    },
+    "tags": [
+        {
+            "description": "Operations for generating and managing thumbnails",
+            "name": "thumbnails"
+        },
+        {
+            "description": "Operations for removing privacy sensitive metadata from files",
+            "name": "metadata"
         }
     ]
 }`
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"strings"
 
@@ -13,11 +14,14 @@ import (
 	"github.com/samber/lo"
 	"github.com/waifuvault/WaifuVault/shared/middleware"
 	"github.com/waifuvault/WaifuVault/shared/utils"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/controllers"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/lifecycle"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/routes"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/store"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
-	"golang.org/x/net/context"
 
 	"github.com/waifuvault/WaifuVault/thumbnails/docs"
 	_ "github.com/waifuvault/WaifuVault/thumbnails/docs"
@@ -41,6 +45,9 @@ import (
 
 // @tag.name thumbnails
 // @tag.description Operations for generating and managing thumbnails
+
+// @tag.name metadata
+// @tag.description Operations for removing privacy sensitive metadata from files
 func main() {
 	configureLog()
 
@@ -48,18 +55,27 @@ func main() {
 
 	configureSwaggerServers()
 
-	rdb := initRedis()
+	thumbnailCache, rdb := cache.New(cache.ConfigFromEnv(redisAddr()))
 
 	app := fiber.New(fiber.Config{
 		BodyLimit: thumbnail.BodyLimit,
 	})
 
-	mainDao, err := dao.NewDao(rdb)
+	thumbnailStore, err := store.New(store.ConfigFromEnv())
 	if err != nil {
 		panic(err)
 	}
 
-	service := controllers.NewService(mainDao, rdb)
+	mainDao, err := dao.NewDao(thumbnailCache, thumbnailStore)
+	if err != nil {
+		panic(err)
+	}
+
+	notifier := callback.NewNotifier(callback.ConfigFromEnv(), rdb, mainDao)
+
+	service := controllers.NewService(mainDao, thumbnailCache, cache.LockerFromEnv(rdb), notifier)
+
+	startLifecycleConsumer(rdb, service.ThumbnailService)
 
 	middleware.SetupCommonMiddleware(app)
 
@@ -84,23 +100,23 @@ func configureSwaggerServers() {
 	log.Info().Msgf("Configured Swagger for %s mode", baseUrl)
 }
 
-func initRedis() *redis.Client {
+func redisAddr() string {
 	rawRedisUri := lo.Ternary(utils.DockerMode, os.Getenv("REDIS_URI"), "redis://redis:6379")
-	redisAddr := strings.TrimPrefix(rawRedisUri, "redis://")
-
-	client := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
-	})
+	return strings.TrimPrefix(rawRedisUri, "redis://")
+}
 
-	pong, err := client.Ping(context.Background()).Result()
-	if err != nil {
-		log.Error().Err(err).Msg("failed to connect to Redis")
-		panic(err)
-	} else {
-		log.Info().Str("response", pong).Msg("successfully connected to Redis")
+// startLifecycleConsumer purges thumbnails for the file lifecycle events of the Node service, which are only
+// available when the cache is backed by Redis
+func startLifecycleConsumer(rdb *redis.Client, purger lifecycle.Purger) {
+	if rdb == nil {
+		log.Warn().Msg("redis is unavailable, thumbnails won't be purged for file lifecycle events")
+		return
 	}
-
-	return client
+	go func() {
+		if err := lifecycle.NewConsumer(rdb, purger).Run(context.Background()); err != nil {
+			log.Error().Err(err).Msg("file lifecycle consumer stopped")
+		}
+	}()
 }
 
 func configureLog() {
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCacheMiss is returned by Get when the key is not cached or has expired
+var ErrCacheMiss = errors.New("cache miss")
+
+// ThumbnailCache stores rendered thumbnails by key. a ttl of 0 keeps the entry until it is evicted or deleted
+type ThumbnailCache interface {
+	Get(key string) ([]byte, error)
+	// GetMany returns the values in the order of keys, missing entries are nil
+	GetMany(keys []string) ([][]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	SetMany(entries map[string][]byte, ttl time.Duration) error
+	Delete(keys ...string) error
+	// DeleteMatching removes every key matching a glob pattern, only `*` and `?` are supported
+	DeleteMatching(pattern string) error
+}
+
+// matchPattern reports whether key matches a glob pattern where `*` matches any run of characters and `?` matches one
+func matchPattern(pattern, key string) bool {
+	starPattern, starKey := -1, 0
+	p, k := 0, 0
+	for k < len(key) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == key[k]):
+			p++
+			k++
+		case p < len(pattern) && pattern[p] == '*':
+			starPattern, starKey = p, k
+			p++
+		case starPattern != -1:
+			starKey++
+			p, k = starPattern+1, starKey
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
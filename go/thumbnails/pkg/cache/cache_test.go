@@ -0,0 +1,18 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPattern(t *testing.T) {
+	// then
+	assert.True(t, matchPattern("token:*:attention*", "token:static:attention"))
+	assert.True(t, matchPattern("token:*:attention*", "token:animated:attention:w400"))
+	assert.True(t, matchPattern("thumbnail:?", "thumbnail:7"))
+	assert.True(t, matchPattern("url:*", "url:https://example.com/a.png:static"))
+	assert.False(t, matchPattern("token:*:attention*", "token:static"))
+	assert.False(t, matchPattern("token:*:attention*", "other:static:attention"))
+	assert.False(t, matchPattern("thumbnail:?", "thumbnail:12"))
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+	BackendDisk   = "disk"
+
+	DefaultMaxBytes     = 256 * 1024 * 1024
+	DefaultDiskMaxBytes = 4 * 1024 * 1024 * 1024
+	redisPingTimeout    = 5 * time.Second
+)
+
+// Config selects and sizes the thumbnail cache
+type Config struct {
+	Backend   string
+	RedisAddr string
+	MaxBytes  int64
+	Dir       string
+	// HotMaxBytes sizes the memory tier in front of Redis, 0 disables it
+	HotMaxBytes int64
+	HotTTL      time.Duration
+}
+
+// ConfigFromEnv reads THUMBNAIL_CACHE (redis, memory or disk), THUMBNAIL_CACHE_MAX_BYTES, THUMBNAIL_CACHE_DIR,
+// THUMBNAIL_HOT_CACHE_MAX_BYTES and THUMBNAIL_HOT_CACHE_TTL
+func ConfigFromEnv(redisAddr string) Config {
+	config := Config{
+		Backend:     strings.ToLower(strings.TrimSpace(os.Getenv("THUMBNAIL_CACHE"))),
+		RedisAddr:   redisAddr,
+		Dir:         os.Getenv("THUMBNAIL_CACHE_DIR"),
+		HotMaxBytes: DefaultHotMaxBytes,
+		HotTTL:      DefaultHotTTL,
+	}
+	if config.Backend == "" {
+		config.Backend = BackendRedis
+	}
+	if config.Dir == "" {
+		config.Dir = filepath.Join(os.TempDir(), "waifuvault-thumbnails")
+	}
+	if raw := os.Getenv("THUMBNAIL_CACHE_MAX_BYTES"); raw != "" {
+		maxBytes, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || maxBytes <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_CACHE_MAX_BYTES")
+		} else {
+			config.MaxBytes = maxBytes
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_HOT_CACHE_MAX_BYTES"); raw != "" {
+		maxBytes, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || maxBytes < 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_HOT_CACHE_MAX_BYTES")
+		} else {
+			config.HotMaxBytes = maxBytes
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_HOT_CACHE_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_HOT_CACHE_TTL")
+		} else {
+			config.HotTTL = ttl
+		}
+	}
+	return config
+}
+
+// New builds the configured cache. when Redis can't be reached, or the disk directory can't be used, the service
+// falls back to an in memory cache instead of refusing to start. the Redis client is nil unless Redis is in use
+func New(config Config) (ThumbnailCache, *redis.Client) {
+	switch config.Backend {
+	case BackendMemory:
+		return newMemoryCache(config), nil
+	case BackendDisk:
+		maxBytes := config.MaxBytes
+		if maxBytes == 0 {
+			maxBytes = DefaultDiskMaxBytes
+		}
+		diskCache, err := NewDiskCache(config.Dir, maxBytes)
+		if err != nil {
+			log.Error().Err(err).Str("dir", config.Dir).Msg("failed to open the disk cache, falling back to memory")
+			return newMemoryCache(config), nil
+		}
+		log.Info().Str("dir", config.Dir).Msg("caching thumbnails on disk")
+		return diskCache, nil
+	case BackendRedis:
+	default:
+		log.Warn().Str("backend", config.Backend).Msg("unknown thumbnail cache backend, using redis")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: config.RedisAddr,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisPingTimeout)
+	defer cancel()
+	pong, err := client.Ping(ctx).Result()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to connect to Redis, falling back to memory")
+		_ = client.Close()
+		return newMemoryCache(config), nil
+	}
+	log.Info().Str("response", pong).Msg("successfully connected to Redis")
+	if config.HotMaxBytes > 0 {
+		return NewTieredCache(client, config.HotMaxBytes, config.HotTTL), client
+	}
+	return NewRedisCache(client), client
+}
+
+func newMemoryCache(config Config) ThumbnailCache {
+	maxBytes := config.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	log.Info().Int64("maxBytes", maxBytes).Msg("caching thumbnails in memory")
+	return NewLRUCache(maxBytes)
+}
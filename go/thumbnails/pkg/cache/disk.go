@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// diskHeaderSize is the expiry (unix nanoseconds, 0 for none) and the key length that prefix every entry
+	diskHeaderSize   = 12
+	maxDiskKeyLength = 64 * 1024
+)
+
+type diskFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+type diskCache struct {
+	dir      string
+	maxBytes int64
+	mu       sync.Mutex
+	size     int64
+	now      func() time.Time
+}
+
+// NewDiskCache stores each thumbnail as a file under dir. reads refresh the modification time, and the least recently
+// read files are removed once the directory holds more than maxBytes, 0 disables the limit
+func NewDiskCache(dir string, maxBytes int64) (ThumbnailCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &diskCache{dir: dir, maxBytes: maxBytes, now: time.Now}
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		c.size += file.size
+	}
+	return c, nil
+}
+
+func (c *diskCache) Get(key string) ([]byte, error) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	storedKey, expiresAt, value, ok := decodeDiskEntry(data)
+	if !ok || storedKey != key {
+		return nil, ErrCacheMiss
+	}
+	if !expiresAt.IsZero() && !c.now().Before(expiresAt) {
+		c.removeFile(path)
+		return nil, ErrCacheMiss
+	}
+
+	now := c.now()
+	_ = os.Chtimes(path, now, now)
+	return value, nil
+}
+
+func (c *diskCache) GetMany(keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := c.Get(key)
+		if err != nil && !errors.Is(err, ErrCacheMiss) {
+			return values, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (c *diskCache) Set(key string, value []byte, ttl time.Duration) error {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+	data := encodeDiskEntry(key, expiresAt, value)
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info, err := os.Stat(path); err == nil {
+		c.size -= info.Size()
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	c.size += int64(len(data))
+	if c.maxBytes > 0 && c.size > c.maxBytes {
+		c.prune()
+	}
+	return nil
+}
+
+func (c *diskCache) SetMany(entries map[string][]byte, ttl time.Duration) error {
+	var errs []error
+	for key, value := range entries {
+		errs = append(errs, c.Set(key, value, ttl))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *diskCache) Delete(keys ...string) error {
+	for _, key := range keys {
+		c.removeFile(c.path(key))
+	}
+	return nil
+}
+
+func (c *diskCache) DeleteMatching(pattern string) error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		key, err := readDiskKey(file.path)
+		if err != nil || matchPattern(pattern, key) {
+			c.removeFile(file.path)
+		}
+	}
+	return nil
+}
+
+// path spreads the entries over 256 directories so no single directory grows too large
+func (c *diskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+func (c *diskCache) removeFile(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeFileLocked(path)
+}
+
+func (c *diskCache) removeFileLocked(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		log.Error().Err(err).Str("path", path).Msg("failed to remove cached thumbnail")
+		return
+	}
+	c.size -= info.Size()
+}
+
+// prune removes the least recently read files until the cache is back under 90% of its limit, leaving room for new
+// entries before the next prune
+func (c *diskCache) prune() {
+	files, err := c.files()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list cached thumbnails")
+		return
+	}
+	slices.SortFunc(files, func(a, b diskFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	target := c.maxBytes / 10 * 9
+	for _, file := range files {
+		if c.size <= target {
+			return
+		}
+		c.removeFileLocked(file.path)
+	}
+}
+
+func (c *diskCache) files() ([]diskFile, error) {
+	var files []diskFile
+	err := filepath.WalkDir(c.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Base(path)[0] == '.' {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, diskFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+func encodeDiskEntry(key string, expiresAt time.Time, value []byte) []byte {
+	data := make([]byte, 0, diskHeaderSize+len(key)+len(value))
+	var expiry int64
+	if !expiresAt.IsZero() {
+		expiry = expiresAt.UnixNano()
+	}
+	data = binary.BigEndian.AppendUint64(data, uint64(expiry))
+	data = binary.BigEndian.AppendUint32(data, uint32(len(key)))
+	data = append(data, key...)
+	return append(data, value...)
+}
+
+func decodeDiskEntry(data []byte) (string, time.Time, []byte, bool) {
+	if len(data) < diskHeaderSize {
+		return "", time.Time{}, nil, false
+	}
+	keyLength := int(binary.BigEndian.Uint32(data[8:12]))
+	if keyLength > maxDiskKeyLength || len(data) < diskHeaderSize+keyLength {
+		return "", time.Time{}, nil, false
+	}
+	var expiresAt time.Time
+	if expiry := int64(binary.BigEndian.Uint64(data[:8])); expiry != 0 {
+		expiresAt = time.Unix(0, expiry)
+	}
+	return string(data[diskHeaderSize : diskHeaderSize+keyLength]), expiresAt, data[diskHeaderSize+keyLength:], true
+}
+
+// readDiskKey reads only the key of an entry, so pattern deletes don't load every thumbnail
+func readDiskKey(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, diskHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return "", err
+	}
+	keyLength := binary.BigEndian.Uint32(header[8:12])
+	if keyLength > maxDiskKeyLength {
+		return "", errors.New("invalid cache entry")
+	}
+	key := make([]byte, keyLength)
+	if _, err := io.ReadFull(file, key); err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
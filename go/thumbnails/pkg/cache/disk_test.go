@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskCache_SetAndGet(t *testing.T) {
+	// given
+	c, err := NewDiskCache(t.TempDir(), 0)
+	assert.NoError(t, err)
+
+	// when
+	err = c.Set("token:static", []byte("thumbnail"), 0)
+
+	// then
+	assert.NoError(t, err)
+	value, err := c.Get("token:static")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("thumbnail"), value)
+	_, err = c.Get("token:animated")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestDiskCache_PersistsAcrossInstances(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	first, _ := NewDiskCache(dir, 0)
+	_ = first.Set("thumbnail:1", []byte("thumbnail"), time.Hour)
+
+	// when
+	second, err := NewDiskCache(dir, 0)
+
+	// then
+	assert.NoError(t, err)
+	value, err := second.Get("thumbnail:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("thumbnail"), value)
+	assert.Equal(t, first.(*diskCache).size, second.(*diskCache).size)
+}
+
+func TestDiskCache_Expiry(t *testing.T) {
+	// given
+	now := time.Unix(1000, 0)
+	created, _ := NewDiskCache(t.TempDir(), 0)
+	c := created.(*diskCache)
+	c.now = func() time.Time { return now }
+	_ = c.Set("a", []byte("value"), time.Minute)
+
+	// when
+	now = now.Add(time.Minute)
+
+	// then
+	_, err := c.Get("a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = os.Stat(c.path("a"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Zero(t, c.size)
+}
+
+func TestDiskCache_PrunesLeastRecentlyRead(t *testing.T) {
+	// given
+	created, _ := NewDiskCache(t.TempDir(), 100)
+	c := created.(*diskCache)
+	_ = c.Set("a", make([]byte, 30), 0)
+	_ = c.Set("b", make([]byte, 30), 0)
+	_ = os.Chtimes(c.path("a"), time.Unix(1, 0), time.Unix(1, 0))
+
+	// when
+	_ = c.Set("c", make([]byte, 30), 0)
+
+	// then
+	_, err := c.Get("a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = c.Get("b")
+	assert.NoError(t, err)
+	_, err = c.Get("c")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, c.size, int64(90))
+}
+
+func TestDiskCache_DeleteMatching(t *testing.T) {
+	// given
+	c, _ := NewDiskCache(t.TempDir(), 0)
+	_ = c.SetMany(map[string][]byte{
+		"token:static:attention": []byte("a"),
+		"token:static":           []byte("b"),
+	}, 0)
+
+	// when
+	err := c.DeleteMatching("token:*:attention*")
+
+	// then
+	assert.NoError(t, err)
+	values, _ := c.GetMany([]string{"token:static:attention", "token:static"})
+	assert.Equal(t, [][]byte{nil, []byte("b")}, values)
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const lockPrefix = "lock:"
+
+// unlockScript only deletes the lock while it still holds our token, so an expired lock taken over by another
+// instance is left alone
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Locker is a lock shared by every instance of the service
+type Locker interface {
+	// TryLock takes the lock for key without waiting, held is false while another owner has it. the lock is released
+	// by unlock or once ttl passes
+	TryLock(key string, ttl time.Duration) (unlock func(), held bool, err error)
+}
+
+type redisLocker struct {
+	client *redis.Client
+}
+
+func NewRedisLocker(client *redis.Client) Locker {
+	return &redisLocker{client: client}
+}
+
+// LockerFromEnv returns a Redis lock when THUMBNAIL_GENERATION_LOCK is true, nil when it is disabled or Redis is not in use
+func LockerFromEnv(client *redis.Client) Locker {
+	if os.Getenv("THUMBNAIL_GENERATION_LOCK") != "true" {
+		return nil
+	}
+	if client == nil {
+		log.Warn().Msg("THUMBNAIL_GENERATION_LOCK needs the redis cache, generations are only coalesced within this instance")
+		return nil
+	}
+	return NewRedisLocker(client)
+}
+
+func (l *redisLocker) TryLock(key string, ttl time.Duration) (func(), bool, error) {
+	lockKey := lockPrefix + key
+	token := uuid.NewString()
+	held, err := l.client.SetNX(context.Background(), lockKey, token, ttl).Result()
+	if err != nil || !held {
+		return nil, false, err
+	}
+
+	unlock := func() {
+		if err := unlockScript.Run(context.Background(), l.client, []string{lockKey}, token).Err(); err != nil {
+			log.Error().Err(err).Str("key", lockKey).Msg("failed to release lock")
+		}
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisLocker_TryLock(t *testing.T) {
+	// given
+	mr := miniredis.RunT(t)
+	locker := NewRedisLocker(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	unlock, held, err := locker.TryLock("token:static", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, held)
+
+	// when
+	_, heldTwice, err := locker.TryLock("token:static", time.Minute)
+
+	// then
+	assert.NoError(t, err)
+	assert.False(t, heldTwice)
+	unlock()
+	_, heldAfterUnlock, _ := locker.TryLock("token:static", time.Minute)
+	assert.True(t, heldAfterUnlock)
+}
+
+func TestRedisLocker_UnlockLeavesExpiredLockTakenByAnother(t *testing.T) {
+	// given
+	mr := miniredis.RunT(t)
+	locker := NewRedisLocker(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	unlock, _, _ := locker.TryLock("token:static", time.Second)
+	mr.FastForward(2 * time.Second)
+	_, heldByOther, _ := locker.TryLock("token:static", time.Minute)
+
+	// when
+	unlock()
+
+	// then
+	assert.True(t, heldByOther)
+	assert.True(t, mr.Exists(lockPrefix+"token:static"))
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e *lruEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+type lruCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+	now      func() time.Time
+}
+
+// NewLRUCache keeps thumbnails in memory, evicting the least recently used entries once maxBytes of keys and values are held
+func NewLRUCache(maxBytes int64) ThumbnailCache {
+	return &lruCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (c *lruCache) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+func (c *lruCache) GetMany(keys []string) ([][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i], _ = c.get(key)
+	}
+	return values, nil
+}
+
+func (c *lruCache) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl)
+	return nil
+}
+
+func (c *lruCache) SetMany(entries map[string][]byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, value := range entries {
+		c.set(key, value, ttl)
+	}
+	return nil
+}
+
+func (c *lruCache) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if element, found := c.entries[key]; found {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+func (c *lruCache) DeleteMatching(pattern string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, element := range c.entries {
+		if matchPattern(pattern, key) {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+func (c *lruCache) get(key string) ([]byte, error) {
+	element, found := c.entries[key]
+	if !found {
+		return nil, ErrCacheMiss
+	}
+	entry := element.Value.(*lruEntry)
+	if c.expired(entry) {
+		c.remove(element)
+		return nil, ErrCacheMiss
+	}
+	c.order.MoveToFront(element)
+	return entry.value, nil
+}
+
+func (c *lruCache) set(key string, value []byte, ttl time.Duration) {
+	if element, found := c.entries[key]; found {
+		c.remove(element)
+	}
+
+	entry := &lruEntry{key: key, value: bytes.Clone(value)}
+	if ttl > 0 {
+		entry.expiresAt = c.now().Add(ttl)
+	}
+	// an entry that can never fit would flush the whole cache for nothing
+	if entry.size() > c.maxBytes {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	c.size += entry.size()
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *lruCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*lruEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size()
+}
+
+func (c *lruCache) expired(entry *lruEntry) bool {
+	return !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt)
+}
+
+func (c *lruCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.entries)
+	c.size = 0
+}
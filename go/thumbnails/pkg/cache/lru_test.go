@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	// given
+	c := NewLRUCache(30)
+	_ = c.Set("a", []byte("0123456789"), 0)
+	_ = c.Set("b", []byte("0123456789"), 0)
+	_, _ = c.Get("a")
+
+	// when
+	_ = c.Set("c", []byte("0123456789"), 0)
+
+	// then
+	values, err := c.GetMany([]string{"a", "b", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("0123456789"), values[0])
+	assert.Nil(t, values[1])
+	assert.Equal(t, []byte("0123456789"), values[2])
+}
+
+func TestLRUCache_SkipsEntriesLargerThanTheCache(t *testing.T) {
+	// given
+	c := NewLRUCache(16)
+	_ = c.Set("a", []byte("small"), 0)
+
+	// when
+	_ = c.Set("b", make([]byte, 32), 0)
+
+	// then
+	_, err := c.Get("b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	value, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("small"), value)
+}
+
+func TestLRUCache_Expiry(t *testing.T) {
+	// given
+	now := time.Unix(1000, 0)
+	c := NewLRUCache(1024).(*lruCache)
+	c.now = func() time.Time { return now }
+	_ = c.Set("a", []byte("value"), time.Minute)
+
+	// when
+	now = now.Add(time.Minute)
+
+	// then
+	_, err := c.Get("a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Zero(t, c.size)
+}
+
+func TestLRUCache_DeleteMatching(t *testing.T) {
+	// given
+	c := NewLRUCache(1024)
+	_ = c.SetMany(map[string][]byte{
+		"token:static:attention":      []byte("a"),
+		"token:static:attention:w400": []byte("b"),
+		"token:static":                []byte("c"),
+	}, 0)
+
+	// when
+	err := c.DeleteMatching("token:*:attention*")
+
+	// then
+	assert.NoError(t, err)
+	values, _ := c.GetMany([]string{"token:static:attention", "token:static:attention:w400", "token:static"})
+	assert.Equal(t, [][]byte{nil, nil, []byte("c")}, values)
+}
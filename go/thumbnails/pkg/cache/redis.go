@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type redisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache stores thumbnails in Redis, where the Node service can read the album thumbnails directly
+func NewRedisCache(client *redis.Client) ThumbnailCache {
+	return &redisCache{client: client}
+}
+
+func (c *redisCache) Get(key string) ([]byte, error) {
+	value, err := c.client.Get(context.Background(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return value, err
+}
+
+func (c *redisCache) GetMany(keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	results, err := c.client.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return values, err
+	}
+	for i, result := range results {
+		if str, ok := result.(string); ok {
+			values[i] = []byte(str)
+		}
+	}
+	return values, nil
+}
+
+func (c *redisCache) Set(key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(context.Background(), key, value, ttl).Err()
+}
+
+func (c *redisCache) SetMany(entries map[string][]byte, ttl time.Duration) error {
+	_, err := c.client.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for key, value := range entries {
+			pipe.Set(context.Background(), key, value, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (c *redisCache) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(context.Background(), keys...).Err()
+}
+
+func (c *redisCache) DeleteMatching(pattern string) error {
+	ctx := context.Background()
+	iter := c.client.Scan(ctx, 0, pattern, 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return c.Delete(keys...)
+}
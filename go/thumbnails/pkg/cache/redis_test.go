@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTestRedis(t *testing.T) (*miniredis.Miniredis, ThumbnailCache) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	return mr, NewRedisCache(redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	}))
+}
+
+func TestRedisCache_SetManyStoresRawBytesWithTTL(t *testing.T) {
+	// given
+	mr, c := setupTestRedis(t)
+
+	// when
+	err := c.SetMany(map[string][]byte{"thumbnail:1": []byte("one"), "thumbnail:2": []byte("two")}, time.Hour)
+
+	// then
+	assert.NoError(t, err)
+	value, _ := mr.Get("thumbnail:1")
+	assert.Equal(t, "one", value)
+	assert.Equal(t, time.Hour, mr.TTL("thumbnail:2"))
+	values, err := c.GetMany([]string{"thumbnail:1", "thumbnail:3", "thumbnail:2"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("one"), nil, []byte("two")}, values)
+}
+
+func TestRedisCache_Miss(t *testing.T) {
+	// given
+	_, c := setupTestRedis(t)
+
+	// when
+	_, err := c.Get("missing")
+
+	// then
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestNew_FallsBackToMemoryWithoutRedis(t *testing.T) {
+	// given
+	mr, err := miniredis.Run()
+	assert.NoError(t, err)
+	addr := mr.Addr()
+	mr.Close()
+
+	// when
+	c, client := New(Config{Backend: BackendRedis, RedisAddr: addr})
+
+	// then
+	assert.IsType(t, &lruCache{}, c)
+	assert.Nil(t, client)
+}
@@ -0,0 +1,264 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// InvalidationChannel carries the keys and patterns removed or replaced by any instance
+	InvalidationChannel = "thumbnail-cache:invalidate"
+	// keyEventPattern receives the Redis keyspace events when `notify-keyspace-events` is enabled, which also covers
+	// keys written by the Node service
+	keyEventPattern      = "__keyevent@*__:*"
+	resubscribeBackoff   = time.Second
+	DefaultHotMaxBytes   = 64 * 1024 * 1024
+	DefaultHotTTL        = 10 * time.Minute
+	invalidationKeyBatch = 500
+)
+
+// invalidation is published whenever an instance changes Redis, the origin lets the publisher skip its own messages
+type invalidation struct {
+	Origin  string   `json:"origin"`
+	Keys    []string `json:"keys,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+}
+
+type tieredCache struct {
+	hot    *lruCache
+	remote ThumbnailCache
+	client *redis.Client
+	hotTTL time.Duration
+	origin string
+	stop   context.CancelFunc
+}
+
+// NewTieredCache keeps recently read thumbnails in a byte bounded memory tier in front of Redis. hot entries never
+// outlive their Redis TTL or hotTTL, and are dropped when any instance changes the key in Redis
+func NewTieredCache(client *redis.Client, hotMaxBytes int64, hotTTL time.Duration) ThumbnailCache {
+	if hotTTL <= 0 {
+		hotTTL = DefaultHotTTL
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &tieredCache{
+		hot:    NewLRUCache(hotMaxBytes).(*lruCache),
+		remote: NewRedisCache(client),
+		client: client,
+		hotTTL: hotTTL,
+		origin: uuid.NewString(),
+		stop:   cancel,
+	}
+
+	pubSub := client.Subscribe(ctx, InvalidationChannel)
+	if err := pubSub.PSubscribe(ctx, keyEventPattern); err != nil {
+		log.Error().Err(err).Msg("failed to subscribe to Redis keyspace events")
+	}
+	// the first confirmations are read here, so only a re-subscription clears the hot tier
+	for range 2 {
+		if _, err := pubSub.ReceiveTimeout(ctx, redisPingTimeout); err != nil {
+			log.Error().Err(err).Msg("failed to subscribe to thumbnail cache invalidations")
+			break
+		}
+	}
+	go c.listen(ctx, pubSub)
+	return c
+}
+
+func (c *tieredCache) Get(key string) ([]byte, error) {
+	if value, err := c.hot.Get(key); err == nil {
+		return value, nil
+	}
+	values, err := c.fetch([]string{key})
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == nil {
+		return nil, ErrCacheMiss
+	}
+	return values[0], nil
+}
+
+func (c *tieredCache) GetMany(keys []string) ([][]byte, error) {
+	values, _ := c.hot.GetMany(keys)
+	var missing []string
+	var missingIndexes []int
+	for i, value := range values {
+		if value == nil {
+			missing = append(missing, keys[i])
+			missingIndexes = append(missingIndexes, i)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	fetched, err := c.fetch(missing)
+	if err != nil {
+		return values, err
+	}
+	for i, value := range fetched {
+		values[missingIndexes[i]] = value
+	}
+	return values, nil
+}
+
+func (c *tieredCache) Set(key string, value []byte, ttl time.Duration) error {
+	if err := c.remote.Set(key, value, ttl); err != nil {
+		return err
+	}
+	_ = c.hot.Set(key, value, c.hotTTLFor(ttl))
+	c.publish(invalidation{Keys: []string{key}})
+	return nil
+}
+
+func (c *tieredCache) SetMany(entries map[string][]byte, ttl time.Duration) error {
+	if err := c.remote.SetMany(entries, ttl); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(entries))
+	for key, value := range entries {
+		_ = c.hot.Set(key, value, c.hotTTLFor(ttl))
+		keys = append(keys, key)
+	}
+	c.publish(invalidation{Keys: keys})
+	return nil
+}
+
+func (c *tieredCache) Delete(keys ...string) error {
+	_ = c.hot.Delete(keys...)
+	if err := c.remote.Delete(keys...); err != nil {
+		return err
+	}
+	c.publish(invalidation{Keys: keys})
+	return nil
+}
+
+func (c *tieredCache) DeleteMatching(pattern string) error {
+	_ = c.hot.DeleteMatching(pattern)
+	if err := c.remote.DeleteMatching(pattern); err != nil {
+		return err
+	}
+	c.publish(invalidation{Pattern: pattern})
+	return nil
+}
+
+// fetch reads the keys and their remaining TTLs from Redis in one round trip, and keeps the hits in the hot tier
+func (c *tieredCache) fetch(keys []string) ([][]byte, error) {
+	ctx := context.Background()
+	gets := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			gets[i] = pipe.Get(ctx, key)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return make([][]byte, len(keys)), err
+	}
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := gets[i].Bytes()
+		if err != nil {
+			continue
+		}
+		values[i] = value
+
+		// PTTL is negative for keys without an expiry
+		_ = c.hot.Set(key, value, c.hotTTLFor(max(ttls[i].Val(), 0)))
+	}
+	return values, nil
+}
+
+// hotTTLFor caps the hot tier TTL, so a missed invalidation can only serve a stale thumbnail for hotTTL
+func (c *tieredCache) hotTTLFor(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > c.hotTTL {
+		return c.hotTTL
+	}
+	return ttl
+}
+
+func (c *tieredCache) publish(message invalidation) {
+	message.Origin = c.origin
+	for len(message.Keys) > invalidationKeyBatch {
+		batch := message
+		batch.Keys = message.Keys[:invalidationKeyBatch]
+		c.send(batch)
+		message.Keys = message.Keys[invalidationKeyBatch:]
+	}
+	c.send(message)
+}
+
+func (c *tieredCache) send(message invalidation) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	if err := c.client.Publish(context.Background(), InvalidationChannel, payload).Err(); err != nil {
+		log.Error().Err(err).Msg("failed to publish thumbnail cache invalidation")
+	}
+}
+
+// listen applies the invalidations of other instances and Redis keyspace events to the hot tier. messages sent while
+// the connection was down are lost, so the hot tier is cleared every time the subscription is re-established
+func (c *tieredCache) listen(ctx context.Context, pubSub *redis.PubSub) {
+	defer pubSub.Close()
+	for {
+		received, err := pubSub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Error().Err(err).Msg("lost the thumbnail cache invalidation subscription")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(resubscribeBackoff):
+			}
+			continue
+		}
+
+		switch message := received.(type) {
+		case *redis.Subscription:
+			c.hot.clear()
+		case *redis.Message:
+			c.apply(message)
+		}
+	}
+}
+
+func (c *tieredCache) apply(message *redis.Message) {
+	if message.Channel != InvalidationChannel {
+		// keyspace events carry the key as the payload
+		if strings.HasPrefix(message.Channel, "__keyevent@") {
+			_ = c.hot.Delete(message.Payload)
+		}
+		return
+	}
+
+	var received invalidation
+	if err := json.Unmarshal([]byte(message.Payload), &received); err != nil {
+		log.Error().Err(err).Msg("invalid thumbnail cache invalidation")
+		return
+	}
+	if received.Origin == c.origin {
+		return
+	}
+	_ = c.hot.Delete(received.Keys...)
+	if received.Pattern != "" {
+		_ = c.hot.DeleteMatching(received.Pattern)
+	}
+}
+
+func (c *tieredCache) close() {
+	c.stop()
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTieredCache(t *testing.T, mr *miniredis.Miniredis) *tieredCache {
+	c := NewTieredCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}), 1024, time.Minute).(*tieredCache)
+	t.Cleanup(c.close)
+	return c
+}
+
+func TestTieredCache_ServesHitsFromMemory(t *testing.T) {
+	// given
+	mr := miniredis.RunT(t)
+	_ = mr.Set("token:static", "thumbnail")
+	c := newTestTieredCache(t, mr)
+	_, _ = c.Get("token:static")
+
+	// when
+	mr.Close()
+	value, err := c.Get("token:static")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("thumbnail"), value)
+}
+
+func TestTieredCache_HotTTLFollowsRedis(t *testing.T) {
+	// given
+	mr := miniredis.RunT(t)
+	_ = mr.Set("short", "a")
+	mr.SetTTL("short", 5*time.Second)
+	_ = mr.Set("long", "b")
+	mr.SetTTL("long", time.Hour)
+	_ = mr.Set("forever", "c")
+	c := newTestTieredCache(t, mr)
+	now := time.Now()
+	c.hot.now = func() time.Time { return now }
+
+	// when
+	values, err := c.GetMany([]string{"short", "long", "forever", "missing"})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b"), []byte("c"), nil}, values)
+	assert.Equal(t, now.Add(5*time.Second), c.hot.entries["short"].Value.(*lruEntry).expiresAt)
+	assert.Equal(t, now.Add(time.Minute), c.hot.entries["long"].Value.(*lruEntry).expiresAt)
+	assert.Equal(t, now.Add(time.Minute), c.hot.entries["forever"].Value.(*lruEntry).expiresAt)
+}
+
+func TestTieredCache_InvalidatesOtherInstances(t *testing.T) {
+	// given
+	mr := miniredis.RunT(t)
+	writer := newTestTieredCache(t, mr)
+	reader := newTestTieredCache(t, mr)
+	_ = writer.Set("token:static:attention", []byte("old"), time.Hour)
+	_ = writer.Set("token:static", []byte("old"), time.Hour)
+	_, _ = reader.GetMany([]string{"token:static:attention", "token:static"})
+
+	// when
+	_ = writer.DeleteMatching("token:*:attention*")
+	_ = writer.Set("token:static", []byte("new"), time.Hour)
+
+	// then
+	assert.Eventually(t, func() bool {
+		_, missing := reader.hot.Get("token:static:attention")
+		_, replaced := reader.hot.Get("token:static")
+		return missing != nil && replaced != nil
+	}, time.Second, 10*time.Millisecond)
+	value, err := reader.Get("token:static")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), value)
+	_, err = reader.Get("token:static:attention")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestTieredCache_IgnoresOwnInvalidations(t *testing.T) {
+	// given
+	mr := miniredis.RunT(t)
+	c := newTestTieredCache(t, mr)
+
+	// when
+	_ = c.Set("token:static", []byte("thumbnail"), time.Hour)
+	time.Sleep(50 * time.Millisecond)
+
+	// then
+	value, err := c.hot.Get("token:static")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("thumbnail"), value)
+}
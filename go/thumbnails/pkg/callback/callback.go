@@ -0,0 +1,375 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+const (
+	// EventAlbumFinished is sent once an album job is done, cancelled or has failed for good
+	EventAlbumFinished = "album.thumbnails.finished"
+
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">"
+	SignatureHeader = "X-Thumbnail-Signature"
+	EventHeader     = "X-Thumbnail-Event"
+	// DeliveryHeader is the same for every attempt of an event, so receivers can drop repeats
+	DeliveryHeader = "X-Thumbnail-Delivery"
+
+	DefaultMaxAttempts = 5
+	retryDelay         = 2 * time.Second
+	maxRetryDelay      = 5 * time.Minute
+	requestTimeout     = 10 * time.Second
+)
+
+var (
+	ErrDisabled       = errors.New("callbacks are disabled, THUMBNAIL_CALLBACK_SECRET is not set")
+	ErrInvalidURL     = errors.New("invalid callback URL")
+	ErrNoSubscribers  = errors.New("no subscribers on the callback channel")
+	ErrDeliveryFailed = errors.New("callback delivery failed")
+)
+
+// Outcome of a file of an album job
+const (
+	OutcomeProcessed = "processed"
+	OutcomeSkipped   = "skipped"
+	OutcomeFailed    = "failed"
+)
+
+// FileOutcome is what happened to a file of an album job
+type FileOutcome struct {
+	FileId  int    `json:"fileId"`
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Event reports the end of an album job
+type Event struct {
+	Type      string        `json:"type"`
+	JobId     int           `json:"jobId"`
+	AlbumId   int           `json:"albumId"`
+	State     string        `json:"state"`
+	Attempt   int           `json:"attempt"`
+	Total     int           `json:"total"`
+	Processed int           `json:"processed"`
+	Skipped   int           `json:"skipped"`
+	Failed    int           `json:"failed"`
+	Error     string        `json:"error,omitempty"`
+	Files     []FileOutcome `json:"files"`
+	// FinishedAt is when the job ended
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Config controls where completion events are sent
+type Config struct {
+	// Secret signs every event, callbacks are disabled without it
+	Secret string
+	// Channel is a Redis channel every event is published to, empty publishes nothing
+	Channel string
+	// Hosts limits the hosts callback URLs may point at, empty allows any public host. loopback, link-local and private
+	// addresses are only reached when they are listed
+	Hosts       []string
+	MaxAttempts int
+}
+
+// ConfigFromEnv reads THUMBNAIL_CALLBACK_SECRET, THUMBNAIL_CALLBACK_CHANNEL, THUMBNAIL_CALLBACK_HOSTS and
+// THUMBNAIL_CALLBACK_MAX_ATTEMPTS
+func ConfigFromEnv() Config {
+	config := Config{
+		Secret:      os.Getenv("THUMBNAIL_CALLBACK_SECRET"),
+		Channel:     strings.TrimSpace(os.Getenv("THUMBNAIL_CALLBACK_CHANNEL")),
+		MaxAttempts: DefaultMaxAttempts,
+	}
+	if raw := os.Getenv("THUMBNAIL_CALLBACK_HOSTS"); raw != "" {
+		config.Hosts = lo.FilterMap(strings.Split(raw, ","), func(host string, _ int) (string, bool) {
+			host = strings.ToLower(strings.TrimSpace(host))
+			return host, host != ""
+		})
+	}
+	if raw := os.Getenv("THUMBNAIL_CALLBACK_MAX_ATTEMPTS"); raw != "" {
+		attempts, err := strconv.Atoi(raw)
+		if err != nil || attempts <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_CALLBACK_MAX_ATTEMPTS")
+		} else {
+			config.MaxAttempts = attempts
+		}
+	}
+	return config
+}
+
+// Notifier delivers the completion events of album jobs
+type Notifier interface {
+	// ValidateURL checks a callback URL before a job is queued with it
+	ValidateURL(callbackUrl string) error
+	// Notify sends an event in the background to the callback URL, if any, and to the configured channel. failed
+	// deliveries are retried with backoff and every attempt is logged
+	Notify(event Event, callbackUrl string)
+}
+
+type notifier struct {
+	config Config
+	client *redis.Client
+	dao    dao.Dao
+	http   *http.Client
+	sleep  func(time.Duration)
+}
+
+// NewNotifier creates the notifier, client is optional and only needed to publish to the channel
+func NewNotifier(config Config, client *redis.Client, daoService dao.Dao) Notifier {
+	if config.Secret == "" {
+		log.Warn().Msg("THUMBNAIL_CALLBACK_SECRET is not set, album job completion callbacks are disabled")
+	} else if config.Channel != "" && client == nil {
+		log.Warn().Str("channel", config.Channel).Msg("redis is unavailable, completion events won't be published")
+	}
+	return &notifier{
+		config: config,
+		client: client,
+		dao:    daoService,
+		http:   newHttpClient(config),
+		sleep:  time.Sleep,
+	}
+}
+
+// newHttpClient creates the client callbacks are posted with. redirects aren't followed, they could lead anywhere, and
+// without an allowlist the address a host resolves to is checked when it is dialled, so a public name can't resolve to
+// an internal address. no proxy is used, the check would see the proxy instead of the callback host
+func newHttpClient(config Config) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if len(config.Hosts) == 0 {
+		dialer.Control = func(_ string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || internal(ip) {
+				return fmt.Errorf("%w: address %s is not allowed", ErrInvalidURL, host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// internal reports whether an address is not reachable from the internet, callbacks to it need an allowlist
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+func (n *notifier) ValidateURL(callbackUrl string) error {
+	if n.config.Secret == "" {
+		return ErrDisabled
+	}
+	parsed, err := url.Parse(callbackUrl)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrInvalidURL, parsed.Scheme)
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("%w: missing host", ErrInvalidURL)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if len(n.config.Hosts) > 0 {
+		if !slices.Contains(n.config.Hosts, host) {
+			return fmt.Errorf("%w: host %s is not allowed", ErrInvalidURL, parsed.Hostname())
+		}
+		return nil
+	}
+	// names are checked again once they are resolved, when the callback is posted
+	if ip := net.ParseIP(host); host == "localhost" || strings.HasSuffix(host, ".localhost") || (ip != nil && internal(ip)) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrInvalidURL, parsed.Hostname())
+	}
+	return nil
+}
+
+func (n *notifier) Notify(event Event, callbackUrl string) {
+	if n.config.Secret == "" {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Int("jobId", event.JobId).Msg("failed to encode completion event")
+		return
+	}
+
+	deliveryId := uuid.NewString()
+	if callbackUrl != "" {
+		go n.deliver(event, deliveryId, callbackUrl, func() (*int, error) {
+			return n.post(callbackUrl, deliveryId, event.Type, body)
+		})
+	}
+	if n.config.Channel != "" && n.client != nil {
+		go n.deliver(event, deliveryId, "redis:"+n.config.Channel, func() (*int, error) {
+			return nil, n.publish(deliveryId, event.Type, body)
+		})
+	}
+}
+
+// deliver attempts a delivery until it succeeds or runs out of attempts, logging every attempt
+func (n *notifier) deliver(event Event, deliveryId string, target string, attempt func() (*int, error)) {
+	for i := 1; ; i++ {
+		statusCode, err := attempt()
+		delivery := mod.CallbackDelivery{
+			JobId:      event.JobId,
+			AlbumId:    event.AlbumId,
+			DeliveryId: deliveryId,
+			Target:     target,
+			Attempt:    i,
+			StatusCode: statusCode,
+			Delivered:  err == nil,
+		}
+		if err != nil {
+			message := err.Error()
+			delivery.Error = &message
+		}
+		n.record(delivery)
+		if err == nil {
+			return
+		}
+
+		logger := log.Warn().Err(err).Int("jobId", event.JobId).Str("target", target).Int("attempt", i)
+		if i >= n.config.MaxAttempts || !retryable(statusCode) {
+			logger.Msg("giving up on completion event delivery")
+			return
+		}
+		delay := backoff(i)
+		logger.Dur("retryIn", delay).Msg("completion event delivery failed, retrying")
+		n.sleep(delay)
+	}
+}
+
+func (n *notifier) record(delivery mod.CallbackDelivery) {
+	if err := n.dao.SaveCallbackDelivery(&delivery); err != nil {
+		log.Error().Err(err).Int("jobId", delivery.JobId).Msg("failed to log completion event delivery")
+	}
+}
+
+func (n *notifier) post(callbackUrl string, deliveryId string, eventType string, body []byte) (*int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackUrl, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, Sign([]byte(n.config.Secret), time.Now(), body))
+	request.Header.Set(EventHeader, eventType)
+	request.Header.Set(DeliveryHeader, deliveryId)
+
+	response, err := n.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &response.StatusCode, fmt.Errorf("%w: status %d", ErrDeliveryFailed, response.StatusCode)
+	}
+	return &response.StatusCode, nil
+}
+
+// publish sends the event with its signature on the channel, the payload is kept as the signed string
+func (n *notifier) publish(deliveryId string, eventType string, body []byte) error {
+	message, err := json.Marshal(map[string]string{
+		"type":       eventType,
+		"deliveryId": deliveryId,
+		"signature":  Sign([]byte(n.config.Secret), time.Now(), body),
+		"payload":    string(body),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	receivers, err := n.client.Publish(ctx, n.config.Channel, message).Result()
+	if err != nil {
+		return err
+	}
+	if receivers == 0 {
+		return ErrNoSubscribers
+	}
+	return nil
+}
+
+// Sign returns the signature header value of a body sent at the given time
+func Sign(secret []byte, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Verify checks a signature header against a body, rejecting signatures older than tolerance
+func Verify(secret []byte, signature string, body []byte, tolerance time.Duration, now time.Time) bool {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || now.Sub(time.Unix(seconds, 0)).Abs() > tolerance {
+		return false
+	}
+
+	_, expected, _ := strings.Cut(Sign(secret, time.Unix(seconds, 0), body), ",v1=")
+	return lo.ContainsBy(signatures, func(candidate string) bool {
+		return hmac.Equal([]byte(candidate), []byte(expected))
+	})
+}
+
+// retryable reports whether a delivery may succeed later, client errors other than timeouts and rate limits won't
+func retryable(statusCode *int) bool {
+	if statusCode == nil {
+		return true
+	}
+	code := *statusCode
+	return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+// backoff doubles the wait for every failed attempt
+func backoff(attempt int) time.Duration {
+	delay := retryDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
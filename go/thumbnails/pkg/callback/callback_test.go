@@ -0,0 +1,307 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+)
+
+var testSecret = []byte("secret")
+
+func setupTestRedis(t *testing.T) *redis.Client {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	return redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+}
+
+func newTestNotifier(daoService dao.Dao, client *redis.Client, config Config) (*notifier, *[]time.Duration) {
+	var slept []time.Duration
+	return &notifier{
+		config: config,
+		client: client,
+		dao:    daoService,
+		http:   &http.Client{Timeout: time.Second},
+		sleep: func(delay time.Duration) {
+			slept = append(slept, delay)
+		},
+	}, &slept
+}
+
+// expectDeliveries captures every logged delivery attempt
+func expectDeliveries(daoService *dao.MockDao) *[]mod.CallbackDelivery {
+	var mu sync.Mutex
+	var deliveries []mod.CallbackDelivery
+	daoService.EXPECT().SaveCallbackDelivery(mock.Anything).RunAndReturn(func(delivery *mod.CallbackDelivery, _ ...*gorm.DB) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deliveries = append(deliveries, *delivery)
+		return nil
+	})
+	return &deliveries
+}
+
+func testEvent() Event {
+	return Event{
+		Type:    EventAlbumFinished,
+		JobId:   7,
+		AlbumId: 3,
+		State:   "done",
+		Total:   1,
+		Files:   []FileOutcome{{FileId: 1, Outcome: OutcomeProcessed}},
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	// given
+	body := []byte(`{"jobId":7}`)
+	now := time.Unix(1700000000, 0)
+
+	// when
+	signature := Sign(testSecret, now, body)
+
+	// then
+	assert.True(t, Verify(testSecret, signature, body, time.Minute, now.Add(30*time.Second)))
+	assert.False(t, Verify(testSecret, signature, []byte(`{"jobId":8}`), time.Minute, now))
+	assert.False(t, Verify([]byte("other"), signature, body, time.Minute, now))
+	assert.False(t, Verify(testSecret, signature, body, time.Minute, now.Add(2*time.Minute)))
+	assert.False(t, Verify(testSecret, "v1=abc", body, time.Minute, now))
+}
+
+func TestNotifier_ValidateURL(t *testing.T) {
+	n, _ := newTestNotifier(nil, nil, Config{Secret: "secret", Hosts: []string{"waifuvault.moe"}})
+
+	assert.NoError(t, n.ValidateURL("https://waifuvault.moe/callback"))
+	assert.NoError(t, n.ValidateURL("http://WAIFUVAULT.moe:8080/callback"))
+	assert.ErrorIs(t, n.ValidateURL("https://example.com/callback"), ErrInvalidURL)
+	assert.ErrorIs(t, n.ValidateURL("ftp://waifuvault.moe/callback"), ErrInvalidURL)
+	assert.ErrorIs(t, n.ValidateURL("https:///callback"), ErrInvalidURL)
+	assert.ErrorIs(t, n.ValidateURL("://"), ErrInvalidURL)
+}
+
+func TestNotifier_ValidateURL_InternalHostsNeedAllowlist(t *testing.T) {
+	n, _ := newTestNotifier(nil, nil, Config{Secret: "secret"})
+
+	assert.NoError(t, n.ValidateURL("https://waifuvault.moe/callback"))
+	assert.ErrorIs(t, n.ValidateURL("http://localhost:8080/callback"), ErrInvalidURL)
+	assert.ErrorIs(t, n.ValidateURL("http://127.0.0.1/callback"), ErrInvalidURL)
+	assert.ErrorIs(t, n.ValidateURL("http://169.254.169.254/latest/meta-data"), ErrInvalidURL)
+	assert.ErrorIs(t, n.ValidateURL("http://10.0.0.5/callback"), ErrInvalidURL)
+	assert.ErrorIs(t, n.ValidateURL("http://[::1]/callback"), ErrInvalidURL)
+}
+
+func TestNewHttpClient_RefusesInternalAddresses(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// when
+	_, err := newHttpClient(Config{}).Post(server.URL, "application/json", nil)
+
+	// then the loopback address the server listens on is only dialled when allowed
+	assert.ErrorIs(t, err, ErrInvalidURL)
+	response, err := newHttpClient(Config{Hosts: []string{"127.0.0.1"}}).Post(server.URL, "application/json", nil)
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+}
+
+func TestNewHttpClient_DoesNotFollowRedirects(t *testing.T) {
+	// given
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// when
+	response, err := newHttpClient(Config{Hosts: []string{"127.0.0.1"}}).Post(server.URL, "application/json", nil)
+
+	// then
+	assert.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, response.StatusCode)
+	assert.False(t, redirected)
+}
+
+func TestNotifier_ValidateURL_Disabled(t *testing.T) {
+	n, _ := newTestNotifier(nil, nil, Config{})
+
+	assert.ErrorIs(t, n.ValidateURL("https://waifuvault.moe/callback"), ErrDisabled)
+}
+
+func TestNotifier_Deliver_PostsSignedEvent(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	deliveries := expectDeliveries(daoService)
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	n, slept := newTestNotifier(daoService, nil, Config{Secret: "secret", MaxAttempts: 3})
+	body, _ := json.Marshal(testEvent())
+
+	// when
+	n.deliver(testEvent(), "delivery", server.URL, func() (*int, error) {
+		return n.post(server.URL, "delivery", EventAlbumFinished, body)
+	})
+
+	// then
+	assert.Equal(t, body, receivedBody)
+	assert.Equal(t, EventAlbumFinished, received.Header.Get(EventHeader))
+	assert.Equal(t, "delivery", received.Header.Get(DeliveryHeader))
+	assert.True(t, Verify(testSecret, received.Header.Get(SignatureHeader), receivedBody, time.Minute, time.Now()))
+	assert.Empty(t, *slept)
+	assert.Len(t, *deliveries, 1)
+	assert.True(t, (*deliveries)[0].Delivered)
+	assert.Equal(t, http.StatusNoContent, *(*deliveries)[0].StatusCode)
+	assert.Equal(t, 7, (*deliveries)[0].JobId)
+	assert.Equal(t, 3, (*deliveries)[0].AlbumId)
+}
+
+func TestNotifier_Deliver_RetriesWithBackoff(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	deliveries := expectDeliveries(daoService)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	n, slept := newTestNotifier(daoService, nil, Config{Secret: "secret", MaxAttempts: 3})
+
+	// when
+	n.deliver(testEvent(), "delivery", server.URL, func() (*int, error) {
+		return n.post(server.URL, "delivery", EventAlbumFinished, []byte(`{}`))
+	})
+
+	// then
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{retryDelay, 2 * retryDelay}, *slept)
+	assert.Len(t, *deliveries, 3)
+	for i, delivery := range *deliveries {
+		assert.Equal(t, i+1, delivery.Attempt)
+		assert.False(t, delivery.Delivered)
+		assert.Equal(t, "callback delivery failed: status 500", *delivery.Error)
+		assert.Equal(t, "delivery", delivery.DeliveryId)
+	}
+}
+
+func TestNotifier_Deliver_StopsOnClientError(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	deliveries := expectDeliveries(daoService)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	n, slept := newTestNotifier(daoService, nil, Config{Secret: "secret", MaxAttempts: 3})
+
+	// when
+	n.deliver(testEvent(), "delivery", server.URL, func() (*int, error) {
+		return n.post(server.URL, "delivery", EventAlbumFinished, []byte(`{}`))
+	})
+
+	// then
+	assert.Empty(t, *slept)
+	assert.Len(t, *deliveries, 1)
+	assert.Equal(t, http.StatusBadRequest, *(*deliveries)[0].StatusCode)
+}
+
+func TestNotifier_Publish(t *testing.T) {
+	// given
+	client := setupTestRedis(t)
+	subscription := client.Subscribe(context.Background(), "thumbnails")
+	defer subscription.Close()
+	_, err := subscription.Receive(context.Background())
+	assert.NoError(t, err)
+	n, _ := newTestNotifier(nil, client, Config{Secret: "secret", Channel: "thumbnails"})
+	body, _ := json.Marshal(testEvent())
+
+	// when
+	err = n.publish("delivery", EventAlbumFinished, body)
+
+	// then
+	assert.NoError(t, err)
+	message, err := subscription.ReceiveMessage(context.Background())
+	assert.NoError(t, err)
+	var envelope map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(message.Payload), &envelope))
+	assert.Equal(t, EventAlbumFinished, envelope["type"])
+	assert.Equal(t, "delivery", envelope["deliveryId"])
+	assert.Equal(t, string(body), envelope["payload"])
+	assert.True(t, Verify(testSecret, envelope["signature"], []byte(envelope["payload"]), time.Minute, time.Now()))
+}
+
+func TestNotifier_Publish_NoSubscribers(t *testing.T) {
+	// given
+	n, _ := newTestNotifier(nil, setupTestRedis(t), Config{Secret: "secret", Channel: "thumbnails"})
+
+	// when
+	err := n.publish("delivery", EventAlbumFinished, []byte(`{}`))
+
+	// then
+	assert.ErrorIs(t, err, ErrNoSubscribers)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, retryDelay, backoff(1))
+	assert.Equal(t, 4*retryDelay, backoff(3))
+	assert.Equal(t, maxRetryDelay, backoff(100))
+}
+
+func TestRetryable(t *testing.T) {
+	code := func(statusCode int) *int {
+		return &statusCode
+	}
+
+	assert.True(t, retryable(nil))
+	assert.True(t, retryable(code(http.StatusBadGateway)))
+	assert.True(t, retryable(code(http.StatusTooManyRequests)))
+	assert.False(t, retryable(code(http.StatusNotFound)))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_CALLBACK_SECRET", "secret")
+	t.Setenv("THUMBNAIL_CALLBACK_CHANNEL", " thumbnails ")
+	t.Setenv("THUMBNAIL_CALLBACK_HOSTS", "WaifuVault.moe, ,localhost")
+	t.Setenv("THUMBNAIL_CALLBACK_MAX_ATTEMPTS", "-1")
+
+	// when
+	config := ConfigFromEnv()
+
+	// then
+	assert.Equal(t, "secret", config.Secret)
+	assert.Equal(t, "thumbnails", config.Channel)
+	assert.Equal(t, []string{"waifuvault.moe", "localhost"}, config.Hosts)
+	assert.Equal(t, DefaultMaxAttempts, config.MaxAttempts)
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	thumbnailPkg "github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/wapimod"
+)
+
+// albumStatusPollInterval is how often a progress stream checks for progress made on other instances, it doubles as a
+// keep alive that notices closed connections
+const albumStatusPollInterval = 2 * time.Second
+
+func (s *Service) getAllAlbumJobRoutes() []FSetupRoute {
+	return []FSetupRoute{
+		s.setupGetAlbumStatusRoute,
+		s.setupStreamAlbumStatusRoute,
+		s.setupGetAlbumCallbacksRoute,
+		s.setupCancelAlbumJobRoute,
+	}
+}
+
+// Get album job status godoc
+//
+//	@Summary	Get the thumbnail job progress of an album
+//	@Description	Returns the state and file counts of the latest thumbnail job of an album, with an estimate of the time left while it runs
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Param	albumId	path	int	true	"Album id"
+//	@Success	200	{object}	dto.AlbumJobStatusDto	"Job progress"
+//	@Failure	400	{object}	wapimod.ApiResult	"Invalid album id"
+//	@Failure	404	{object}	wapimod.ApiResult	"The album has no thumbnail job"
+//	@Router	/generateThumbnails/{albumId}/status [get]
+func (s *Service) setupGetAlbumStatusRoute(routeGroup fiber.Router) {
+	routeGroup.Get("/generateThumbnails/:albumId/status", s.getAlbumStatus)
+}
+
+func (s *Service) getAlbumStatus(ctx fiber.Ctx) error {
+	albumId := fiber.Params[int](ctx, "albumId")
+	if albumId <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid albumId", errors.New("invalid albumId")))
+	}
+
+	progress, err := s.ThumbnailService.GetAlbumStatus(albumId)
+	if err != nil {
+		return albumStatusError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(albumJobStatusDto(progress, time.Now()))
+}
+
+// Stream album job status godoc
+//
+//	@Summary	Stream the thumbnail job progress of an album
+//	@Description	Server-sent events with a `progress` event carrying the job status whenever it changes. The stream ends after the job is done or failed
+//	@Tags	thumbnails
+//	@Produce	text/event-stream
+//	@Param	albumId	path	int	true	"Album id"
+//	@Success	200	{object}	dto.AlbumJobStatusDto	"Stream of progress events"
+//	@Failure	400	{object}	wapimod.ApiResult	"Invalid album id"
+//	@Failure	404	{object}	wapimod.ApiResult	"The album has no thumbnail job"
+//	@Router	/generateThumbnails/{albumId}/status/stream [get]
+func (s *Service) setupStreamAlbumStatusRoute(routeGroup fiber.Router) {
+	routeGroup.Get("/generateThumbnails/:albumId/status/stream", s.streamAlbumStatus)
+}
+
+func (s *Service) streamAlbumStatus(ctx fiber.Ctx) error {
+	albumId := fiber.Params[int](ctx, "albumId")
+	if albumId <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid albumId", errors.New("invalid albumId")))
+	}
+
+	// subscribed before the first status is read, so no change is missed in between
+	updates, unsubscribe := s.ThumbnailService.SubscribeAlbumProgress(albumId)
+	progress, err := s.ThumbnailService.GetAlbumStatus(albumId)
+	if err != nil {
+		unsubscribe()
+		return albumStatusError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no")
+
+	return ctx.SendStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		ticker := time.NewTicker(albumStatusPollInterval)
+		defer ticker.Stop()
+
+		var last []byte
+		send := func(progress thumbnailPkg.AlbumProgress) bool {
+			event, _ := json.Marshal(albumJobStatusDto(progress, time.Now()))
+			if string(event) != string(last) {
+				last = event
+				_, _ = fmt.Fprintf(w, "event: progress\ndata: %s\n\n", event)
+			}
+			return w.Flush() == nil && !progress.Finished()
+		}
+
+		if !send(progress) {
+			return
+		}
+		for {
+			select {
+			case progress = <-updates:
+			case <-ticker.C:
+				// progress made on another instance only shows up here
+				polled, err := s.ThumbnailService.GetAlbumStatus(albumId)
+				if err != nil {
+					log.Error().Err(err).Int("albumId", albumId).Msg("failed to get album job status")
+					continue
+				}
+				progress = polled
+				_, _ = w.WriteString(": keep-alive\n\n")
+			}
+			if !send(progress) {
+				return
+			}
+		}
+	})
+}
+
+// Get album job callbacks godoc
+//
+//	@Summary	Get the completion event deliveries of an album
+//	@Description	Returns the latest 50 attempts at delivering the completion events of the thumbnail jobs of an album, newest first, for debugging callbacks
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Param	albumId	path	int	true	"Album id"
+//	@Success	200	{array}	dto.CallbackDeliveryDto	"Delivery attempts"
+//	@Failure	400	{object}	wapimod.ApiResult	"Invalid album id"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/generateThumbnails/{albumId}/callbacks [get]
+func (s *Service) setupGetAlbumCallbacksRoute(routeGroup fiber.Router) {
+	routeGroup.Get("/generateThumbnails/:albumId/callbacks", s.getAlbumCallbacks)
+}
+
+func (s *Service) getAlbumCallbacks(ctx fiber.Ctx) error {
+	albumId := fiber.Params[int](ctx, "albumId")
+	if albumId <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid albumId", errors.New("invalid albumId")))
+	}
+
+	deliveries, err := s.ThumbnailService.GetCallbackDeliveries(albumId)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	return ctx.Status(fiber.StatusOK).JSON(lo.Map(deliveries, func(delivery mod.CallbackDelivery, _ int) dto.CallbackDeliveryDto {
+		return dto.CallbackDeliveryDto{
+			JobId:      delivery.JobId,
+			DeliveryId: delivery.DeliveryId,
+			Target:     delivery.Target,
+			Attempt:    delivery.Attempt,
+			StatusCode: delivery.StatusCode,
+			Error:      lo.FromPtr(delivery.Error),
+			Delivered:  delivery.Delivered,
+			CreatedAt:  delivery.CreatedAt,
+		}
+	}))
+}
+
+// Cancel album job godoc
+//
+//	@Summary	Cancel the thumbnail jobs of an album
+//	@Description	Cancels the queued and running thumbnail jobs of an album. A running job stops taking files and kills its ffmpeg processes, a job running on another instance is stopped within a few seconds. The thumbnails it rendered but hasn't stored yet are stored, or dropped with `discard=true`
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Param	albumId	path	int	true	"Album id"
+//	@Param	discard	query	bool	false	"drop the thumbnails rendered but not stored yet"	default(false)
+//	@Success	200	{object}	wapimod.ApiResult	"Jobs cancelled"
+//	@Failure	400	{object}	wapimod.ApiResult	"Invalid album id"
+//	@Failure	404	{object}	wapimod.ApiResult	"The album has no queued or running job"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/generateThumbnails/{albumId} [delete]
+func (s *Service) setupCancelAlbumJobRoute(routeGroup fiber.Router) {
+	routeGroup.Delete("/generateThumbnails/:albumId", s.cancelAlbumJob)
+}
+
+func (s *Service) cancelAlbumJob(ctx fiber.Ctx) error {
+	albumId := fiber.Params[int](ctx, "albumId")
+	if albumId <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid albumId", errors.New("invalid albumId")))
+	}
+
+	cancelled, err := s.ThumbnailService.CancelAlbumJobs(albumId, fiber.Query[bool](ctx, "discard", false))
+	if err != nil {
+		return albumStatusError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(wapimod.NewApiResult(fmt.Sprintf("cancelled %d thumbnail jobs", cancelled), true))
+}
+
+func albumStatusError(ctx fiber.Ctx, err error) error {
+	if errors.Is(err, thumbnailPkg.ErrJobNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+}
+
+func albumJobStatusDto(progress thumbnailPkg.AlbumProgress, now time.Time) dto.AlbumJobStatusDto {
+	optionalTime := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	return dto.AlbumJobStatusDto{
+		AlbumId:   progress.AlbumId,
+		JobId:     progress.JobId,
+		State:     string(progress.State),
+		Attempt:   progress.Attempt,
+		Total:     progress.Total,
+		Processed: progress.Processed,
+		Skipped:   progress.Skipped,
+		Failed:    progress.Failed,
+		Failures: lo.Map(progress.Failures, func(failure thumbnailPkg.FileFailure, _ int) dto.FileFailureDto {
+			return dto.FileFailureDto{FileId: failure.FileId, Reason: failure.Reason}
+		}),
+		LastError:  progress.LastError,
+		StartedAt:  optionalTime(progress.StartedAt),
+		FinishedAt: optionalTime(progress.FinishedAt),
+		EtaSeconds: int(progress.ETA(now).Round(time.Second).Seconds()),
+	}
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	thumbnailPkg "github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/wapimod"
+)
+
+func (s *Service) getAllBackfillRoutes() []FSetupRoute {
+	return []FSetupRoute{
+		s.setupStartBackfillRoute,
+		s.setupGetBackfillStatusRoute,
+	}
+}
+
+// Start backfill godoc
+//
+//	@Summary	Start the thumbnail backfill
+//	@Description	Generates the missing thumbnails of album files in the background, at the rate set by THUMBNAIL_BACKFILL_RATE. A run carries on from the cursor of an interrupted run unless restart is set
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Param	restart	query	bool	false	"Discard the stored cursor and start from the first file"
+//	@Success	202	{object}	dto.BackfillStatusDto	"The backfill was started"
+//	@Failure	409	{object}	wapimod.ApiResult	"A backfill is already running"
+//	@Router	/thumbnails/backfill [post]
+func (s *Service) setupStartBackfillRoute(routeGroup fiber.Router) {
+	routeGroup.Post("/thumbnails/backfill", s.startBackfill)
+}
+
+func (s *Service) startBackfill(ctx fiber.Ctx) error {
+	status, err := s.ThumbnailService.StartBackfill(fiber.Query[bool](ctx, "restart", false))
+	if err != nil {
+		if errors.Is(err, thumbnailPkg.ErrBackfillRunning) {
+			return ctx.Status(fiber.StatusConflict).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	return ctx.Status(fiber.StatusAccepted).JSON(backfillStatusDto(status))
+}
+
+// Get backfill status godoc
+//
+//	@Summary	Get the thumbnail backfill progress
+//	@Description	Returns the progress of the current or last backfill run of this instance
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Success	200	{object}	dto.BackfillStatusDto	"Backfill progress"
+//	@Router	/thumbnails/backfill [get]
+func (s *Service) setupGetBackfillStatusRoute(routeGroup fiber.Router) {
+	routeGroup.Get("/thumbnails/backfill", s.getBackfillStatus)
+}
+
+func (s *Service) getBackfillStatus(ctx fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(backfillStatusDto(s.ThumbnailService.GetBackfillStatus()))
+}
+
+func backfillStatusDto(status thumbnailPkg.BackfillStatus) dto.BackfillStatusDto {
+	optionalTime := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	return dto.BackfillStatusDto{
+		Running:    status.Running,
+		Cursor:     status.Cursor,
+		Remaining:  status.Remaining,
+		Processed:  status.Processed,
+		Skipped:    status.Skipped,
+		StartedAt:  optionalTime(status.StartedAt),
+		FinishedAt: optionalTime(status.FinishedAt),
+		LastError:  status.LastError,
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	thumbnailPkg "github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/wapimod"
+)
+
+func (s *Service) getAllFileFailureRoutes() []FSetupRoute {
+	return []FSetupRoute{
+		s.setupGetQuarantinedFilesRoute,
+		s.setupResetQuarantinedFilesRoute,
+		s.setupResetFileFailureRoute,
+	}
+}
+
+// Get quarantined files godoc
+//
+//	@Summary	List the quarantined album files
+//	@Description	Returns the latest 500 album files the thumbnail jobs skip after they kept failing to render, most recently failed first. A file of an unsupported type or missing from disk is quarantined after its first failure, any other file after THUMBNAIL_FILE_MAX_ATTEMPTS failures
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Success	200	{array}	dto.QuarantinedFileDto	"Quarantined files"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/thumbnails/failures [get]
+func (s *Service) setupGetQuarantinedFilesRoute(routeGroup fiber.Router) {
+	routeGroup.Get("/thumbnails/failures", s.getQuarantinedFiles)
+}
+
+func (s *Service) getQuarantinedFiles(ctx fiber.Ctx) error {
+	failures, err := s.ThumbnailService.GetQuarantinedFiles()
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	return ctx.Status(fiber.StatusOK).JSON(lo.Map(failures, func(failure mod.FileFailure, _ int) dto.QuarantinedFileDto {
+		return dto.QuarantinedFileDto{
+			FileId:     failure.FileId,
+			ErrorClass: string(failure.ErrorClass),
+			Attempts:   failure.Attempts,
+			LastError:  failure.LastError,
+			FailedAt:   failure.UpdatedAt,
+		}
+	}))
+}
+
+// Reset quarantined files godoc
+//
+//	@Summary	Reset every quarantined album file
+//	@Description	Clears the failures of every quarantined file so the next thumbnail job of their album renders them again. Files waiting for a retry keep their failures
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Success	200	{object}	wapimod.ApiResult	"Files reset"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/thumbnails/failures [delete]
+func (s *Service) setupResetQuarantinedFilesRoute(routeGroup fiber.Router) {
+	routeGroup.Delete("/thumbnails/failures", s.resetQuarantinedFiles)
+}
+
+func (s *Service) resetQuarantinedFiles(ctx fiber.Ctx) error {
+	reset, err := s.ThumbnailService.ResetQuarantinedFiles()
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	return ctx.Status(fiber.StatusOK).JSON(wapimod.NewApiResult(fmt.Sprintf("reset %d quarantined files", reset), true))
+}
+
+// Reset file failure godoc
+//
+//	@Summary	Reset the failures of an album file
+//	@Description	Clears the failures of a file, quarantined or waiting for a retry, so the next thumbnail job of its album renders it again
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Param	fileId	path	int	true	"Id of the file"
+//	@Success	200	{object}	wapimod.ApiResult	"File reset"
+//	@Failure	400	{object}	wapimod.ApiResult	"Invalid file id"
+//	@Failure	404	{object}	wapimod.ApiResult	"The file has no recorded failure"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/thumbnails/failures/{fileId} [delete]
+func (s *Service) setupResetFileFailureRoute(routeGroup fiber.Router) {
+	routeGroup.Delete("/thumbnails/failures/:fileId", s.resetFileFailure)
+}
+
+func (s *Service) resetFileFailure(ctx fiber.Ctx) error {
+	fileId := fiber.Params[int](ctx, "fileId")
+	if fileId <= 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid fileId", errors.New("invalid fileId")))
+	}
+
+	if err := s.ThumbnailService.ResetFileFailure(fileId); err != nil {
+		if errors.Is(err, thumbnailPkg.ErrFileFailureNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	return ctx.Status(fiber.StatusOK).JSON(wapimod.NewApiResult(fmt.Sprintf("reset the failures of file %d", fileId), true))
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/gofiber/fiber/v3"
+	thumbnailPkg "github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+)
+
+// Cache-Control policies of the thumbnail responses
+const (
+	// token thumbnails are purged when their file is removed or protected, so every use is revalidated with the ETag
+	// and a purged thumbnail isn't served from a cache
+	tokenCacheControl = "public, max-age=0, must-revalidate"
+	// URL thumbnails follow a remote file that can change at any time, matching the server side cache of 10 minutes
+	urlCacheControl = "public, max-age=600"
+	// thumbnails of uploads belong to the uploader and have no URL to revalidate against
+	uploadCacheControl = "private, no-store"
+)
+
+// sendThumbnail writes a thumbnail with its validators and cache policy, uncropped video thumbnails are a JPEG frame
+func sendThumbnail(ctx fiber.Ctx, thumbnail []byte, cacheControl string) error {
+	setEncodingHeader(ctx, thumbnail)
+	lastModified, _ := thumbnailPkg.ReadRenderTime(thumbnail)
+	return sendCacheable(ctx, thumbnail, thumbnailPkg.ContentType(thumbnail), cacheControl, lastModified)
+}
+
+// sendCacheable sets a strong ETag of the body, Last-Modified when known and Cache-Control, and answers GET and HEAD
+// requests whose cached copy is still current with 304
+func sendCacheable(ctx fiber.Ctx, body []byte, contentType, cacheControl string, lastModified time.Time) error {
+	etag := `"` + strconv.FormatUint(xxhash.Sum64(body), 16) + `"`
+	ctx.Set(fiber.HeaderETag, etag)
+	ctx.Set(fiber.HeaderCacheControl, cacheControl)
+	if !lastModified.IsZero() {
+		ctx.Set(fiber.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(ctx, etag, lastModified) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	ctx.Set(fiber.HeaderContentLength, strconv.Itoa(len(body)))
+	ctx.Set(fiber.HeaderContentType, contentType)
+	return ctx.Status(fiber.StatusOK).Send(body)
+}
+
+// isNotModified evaluates If-None-Match, or If-Modified-Since when no ETags are sent, as RFC 9110 section 13.2.2 orders them
+func isNotModified(ctx fiber.Ctx, etag string, lastModified time.Time) bool {
+	if ctx.Method() != fiber.MethodGet && ctx.Method() != fiber.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := ctx.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince := ctx.Get(fiber.HeaderIfModifiedSince)
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches uses the weak comparison If-None-Match calls for, so tags a proxy marked weak still match
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	thumbnailPkg "github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/wapimod"
+)
+
+func (s *Service) getAllPurgeRoutes() []FSetupRoute {
+	return []FSetupRoute{
+		s.setupPurgeByTokenRoute,
+		s.setupPurgeByAlbumRoute,
+		s.setupPurgeByFileIdRoute,
+	}
+}
+
+// Purge thumbnails by token godoc
+//
+//	@Summary	Purge the thumbnails of a file token
+//	@Description	Removes every cached thumbnail rendered for a file token, and the stored album thumbnail when the file still exists. Use this after a file was replaced, protected or re-encrypted
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Param	fileToken	path	string	true	"File token to purge thumbnails for"
+//	@Success	200	{object}	dto.PurgeResultDto	"What was purged"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid file token"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/thumbnails/token/{fileToken} [delete]
+func (s *Service) setupPurgeByTokenRoute(routeGroup fiber.Router) {
+	routeGroup.Delete("/thumbnails/token/:fileToken", s.purgeByToken)
+}
+
+func (s *Service) purgeByToken(ctx fiber.Ctx) error {
+	tokenUUid, err := uuid.Parse(ctx.Params("fileToken"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid file token", err))
+	}
+
+	result, err := s.ThumbnailService.PurgeByToken(tokenUUid)
+	return sendPurgeResult(ctx, result, err)
+}
+
+// Purge thumbnails by file id godoc
+//
+//	@Summary	Purge the thumbnails of a file
+//	@Description	Removes the stored album thumbnail of a file, and its cached thumbnails when the file still exists
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Param	fileId	path	int	true	"Id of the file"
+//	@Success	200	{object}	dto.PurgeResultDto	"What was purged"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid file id"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/thumbnails/{fileId} [delete]
+func (s *Service) setupPurgeByFileIdRoute(routeGroup fiber.Router) {
+	routeGroup.Delete("/thumbnails/:fileId", s.purgeByFileId)
+}
+
+func (s *Service) purgeByFileId(ctx fiber.Ctx) error {
+	result, err := s.ThumbnailService.PurgeByFileId(fiber.Params[int](ctx, "fileId"))
+	return sendPurgeResult(ctx, result, err)
+}
+
+// Purge thumbnails by album godoc
+//
+//	@Summary	Purge the thumbnails of an album
+//	@Description	Removes the cached and stored thumbnails of every file in an album
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Param	albumId	path	int	true	"Id of the album"
+//	@Success	200	{object}	dto.PurgeResultDto	"What was purged"
+//	@Failure	404	{object}	wapimod.ApiResult	"The album does not exist"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/thumbnails/album/{albumId} [delete]
+func (s *Service) setupPurgeByAlbumRoute(routeGroup fiber.Router) {
+	routeGroup.Delete("/thumbnails/album/:albumId", s.purgeByAlbum)
+}
+
+func (s *Service) purgeByAlbum(ctx fiber.Ctx) error {
+	result, err := s.ThumbnailService.PurgeByAlbum(fiber.Params[int](ctx, "albumId"))
+	return sendPurgeResult(ctx, result, err)
+}
+
+func sendPurgeResult(ctx fiber.Ctx, result thumbnailPkg.PurgeResult, err error) error {
+	if err != nil {
+		if errors.Is(err, thumbnailPkg.ErrInvalidFileIds) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		if errors.Is(err, thumbnailPkg.ErrAlbumNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(dto.PurgeResultDto{
+		Files:            result.Files,
+		StoredThumbnails: result.StoredThumbnails,
+	})
+}
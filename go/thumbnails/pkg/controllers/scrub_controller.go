@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/scrub"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/wapimod"
+)
+
+func (s *Service) getAllScrubRoutes() []FSetupRoute {
+	return []FSetupRoute{
+		s.setupScrubUploadRoute,
+		s.setupScrubByTokenRoute,
+	}
+}
+
+// scrubErrorStatus maps scrub errors to an HTTP status
+func scrubErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, scrub.ErrFileNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, scrub.ErrUnsupportedFormat),
+		errors.Is(err, scrub.ErrMalformedFile),
+		errors.Is(err, scrub.ErrEncryptedFile):
+		return fiber.StatusBadRequest
+	}
+	return fiber.StatusInternalServerError
+}
+
+// Scrub uploaded file godoc
+//
+//	@Summary	Remove metadata from an uploaded file
+//	@Description	Returns a copy of the upload with EXIF, XMP, IPTC, comments and video metadata atoms removed. Only the orientation and colour profile are kept and the image or video data is not re-encoded. Supports JPEG, PNG, WebP, HEIC, MP4 and MOV
+//	@Tags	metadata
+//	@Accept	multipart/form-data
+//	@Produce	octet-stream
+//	@Param	file	formData	file	true	"File to scrub"
+//	@Success	200	{file}	binary	"Scrubbed file"
+//	@Header	200	{string}	X-Metadata-Removed	"comma separated list of the metadata that was removed"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - no file uploaded or unsupported format"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/scrubMetadata [post]
+func (s *Service) setupScrubUploadRoute(routeGroup fiber.Router) {
+	routeGroup.Post("/scrubMetadata", s.scrubUpload)
+}
+
+func (s *Service) scrubUpload(ctx fiber.Ctx) error {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("No file uploaded", err))
+	}
+
+	scrubbed, result, err := s.ScrubService.ScrubUpload(fileHeader)
+	if err != nil {
+		return ctx.Status(scrubErrorStatus(err)).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	ctx.Set("Content-Length", fmt.Sprintf("%d", len(scrubbed)))
+	ctx.Set("X-Metadata-Removed", strings.Join(result.Removed, ","))
+	ctx.Set(fiber.HeaderContentType, result.ContentType())
+	ctx.Status(fiber.StatusOK)
+
+	return ctx.Send(scrubbed)
+}
+
+// Scrub stored file godoc
+//
+//	@Summary	Remove metadata from a stored file
+//	@Description	Rewrites the stored original without EXIF, XMP, IPTC, comments and video metadata atoms, keeping only the orientation and colour profile. The image or video data is not re-encoded. Encrypted files are rejected
+//	@Tags	metadata
+//	@Produce	json
+//	@Param	fileToken	path	string	true	"Token of the file to scrub"
+//	@Success	200	{object}	dto.ScrubResultDto	"Metadata removed"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid token, encrypted file or unsupported format"
+//	@Failure	404	{object}	wapimod.ApiResult	"File not found"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/scrubMetadata/{fileToken} [post]
+func (s *Service) setupScrubByTokenRoute(routeGroup fiber.Router) {
+	routeGroup.Post("/scrubMetadata/:fileToken", s.scrubByToken)
+}
+
+func (s *Service) scrubByToken(ctx fiber.Ctx) error {
+	tokenUUid, err := uuid.Parse(ctx.Params("fileToken"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid file token", err))
+	}
+
+	result, err := s.ScrubService.ScrubByToken(tokenUUid)
+	if err != nil {
+		return ctx.Status(scrubErrorStatus(err)).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(dto.ScrubResultFromResult(result))
+}
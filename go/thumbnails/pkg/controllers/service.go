@@ -1,26 +1,38 @@
 package controllers
 
 import (
-	"github.com/redis/go-redis/v9"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/scrub"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
 )
 
 type Service struct {
 	ThumbnailService thumbnail.Service
+	ScrubService     scrub.Service
 }
 
-func NewService(dao dao.Dao, rdb *redis.Client) *Service {
-	thumbnailService := thumbnail.NewService(dao, rdb)
+func NewService(dao dao.Dao, thumbnailCache cache.ThumbnailCache, locker cache.Locker, notifier callback.Notifier) *Service {
+	thumbnailService := thumbnail.NewService(dao, thumbnailCache, locker, notifier)
 
 	return &Service{
 		ThumbnailService: thumbnailService,
+		ScrubService:     scrub.NewService(dao),
 	}
 }
 
 func (s *Service) GetAllRoutes() []FSetupRoute {
 	all := []FSetupRoute{}
 	all = append(all, s.getAllThumbnailRoutes()...)
+	all = append(all, s.getAllAlbumJobRoutes()...)
+	// registered before the stored thumbnail routes, which would match /thumbnails/backfill and /thumbnails/failures as
+	// a file id
+	all = append(all, s.getAllBackfillRoutes()...)
+	all = append(all, s.getAllFileFailureRoutes()...)
+	all = append(all, s.getAllStoredThumbnailRoutes()...)
+	all = append(all, s.getAllPurgeRoutes()...)
+	all = append(all, s.getAllScrubRoutes()...)
 	all = append(all, s.getAllSystemRoutes()...)
 
 	return all
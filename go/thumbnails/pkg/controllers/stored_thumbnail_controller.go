@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	thumbnailPkg "github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/wapimod"
+)
+
+func (s *Service) getAllStoredThumbnailRoutes() []FSetupRoute {
+	return []FSetupRoute{
+		s.setupGetStoredThumbnailsRoute,
+		s.setupGetStoredThumbnailRoute,
+	}
+}
+
+// Get stored thumbnail godoc
+//
+//	@Summary	Get the stored thumbnail of a file
+//	@Description	Returns the thumbnail the album batch generated for a file, read from the cache first and then from the database
+//	@Tags	thumbnails
+//	@Produce	image/webp
+//	@Produce	image/jpeg
+//	@Param	fileId	path	int	true	"Id of the file"
+//	@Success	200	{string}	map[string]interface{}	"Thumbnail image, WebP or a JPEG frame for videos"
+//	@Header	200,304	{string}	ETag	"strong validator of the thumbnail bytes"
+//	@Header	200,304	{string}	Cache-Control	"public, max-age=0, must-revalidate"
+//	@Success	304	"the cached copy matching If-None-Match is current"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid file id"
+//	@Failure	404	{object}	wapimod.ApiResult	"The file has no stored thumbnail"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/thumbnails/{fileId} [get]
+//	@Router	/thumbnails/{fileId} [head]
+func (s *Service) setupGetStoredThumbnailRoute(routeGroup fiber.Router) {
+	routeGroup.Get("/thumbnails/:fileId", s.getStoredThumbnail)
+}
+
+func (s *Service) getStoredThumbnail(ctx fiber.Ctx) error {
+	fileId := fiber.Params[int](ctx, "fileId")
+	thumbnails, err := s.ThumbnailService.GetStoredThumbnails([]int{fileId})
+	if err != nil {
+		return storedThumbnailError(ctx, err)
+	}
+
+	thumbnail, found := thumbnails[fileId]
+	if !found {
+		errMsg := fmt.Sprintf("file %d has no stored thumbnail", fileId)
+		return ctx.Status(fiber.StatusNotFound).JSON(wapimod.NewApiError(errMsg, errors.New(errMsg)))
+	}
+	return sendCacheable(ctx, thumbnail, thumbnailPkg.ContentType(thumbnail), tokenCacheControl, time.Time{})
+}
+
+// Get stored thumbnails godoc
+//
+//	@Summary	Get the stored thumbnails of many files
+//	@Description	Returns the thumbnails the album batch generated for up to 500 files in one response, so an album page needs a single round trip. Files without a thumbnail are listed in missing
+//	@Tags	thumbnails
+//	@Accept	json
+//	@Produce	json
+//	@Param	request	body	dto.ThumbnailBatchRequestDto	true	"Files to return thumbnails for"
+//	@Success	200	{object}	dto.StoredThumbnailsDto	"Stored thumbnails"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - no file ids, too many or invalid ones"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/thumbnails/batch [post]
+func (s *Service) setupGetStoredThumbnailsRoute(routeGroup fiber.Router) {
+	routeGroup.Post("/thumbnails/batch", s.getStoredThumbnails)
+}
+
+func (s *Service) getStoredThumbnails(ctx fiber.Ctx) error {
+	var request dto.ThumbnailBatchRequestDto
+	if err := ctx.Bind().Body(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid payload", err))
+	}
+
+	thumbnails, err := s.ThumbnailService.GetStoredThumbnails(request.FileIds)
+	if err != nil {
+		return storedThumbnailError(ctx, err)
+	}
+
+	response := dto.StoredThumbnailsDto{
+		Thumbnails: make([]dto.StoredThumbnailDto, 0, len(thumbnails)),
+		Missing:    []int{},
+	}
+	seen := make(map[int]bool, len(request.FileIds))
+	for _, fileId := range request.FileIds {
+		if seen[fileId] {
+			continue
+		}
+		seen[fileId] = true
+
+		thumbnail, found := thumbnails[fileId]
+		if !found {
+			response.Missing = append(response.Missing, fileId)
+			continue
+		}
+		response.Thumbnails = append(response.Thumbnails, dto.StoredThumbnailDto{
+			FileId:      fileId,
+			ContentType: thumbnailPkg.ContentType(thumbnail),
+			Data:        thumbnail,
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func storedThumbnailError(ctx fiber.Ctx, err error) error {
+	if errors.Is(err, thumbnailPkg.ErrInvalidFileIds) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+}
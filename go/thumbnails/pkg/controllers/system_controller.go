@@ -2,11 +2,14 @@ package controllers
 
 import (
 	"github.com/gofiber/fiber/v3"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	thumbnailPkg "github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
 )
 
 func (s *Service) getAllSystemRoutes() []FSetupRoute {
 	return []FSetupRoute{
 		s.setupHealthRoute,
+		s.setupQueueStatsRoute,
 	}
 }
 
@@ -28,3 +31,35 @@ func (s *Service) healthCheck(ctx fiber.Ctx) error {
 		"service": "thumbnail-service",
 	})
 }
+
+func (s *Service) setupQueueStatsRoute(routeGroup fiber.Router) {
+	routeGroup.Get("/queue", s.getQueueStats)
+}
+
+// GetQueueStats godoc
+// @Summary      Thumbnail queue stats
+// @Description  Returns the queue depth and wait times of the interactive and batch thumbnail work of this instance
+// @Tags         system
+// @Produce      json
+// @Success      200  {object}  dto.QueueStatsDto  "Worker pool stats"
+// @Router       /queue [get]
+func (s *Service) getQueueStats(ctx fiber.Ctx) error {
+	stats := s.ThumbnailService.GetQueueStats()
+	return ctx.JSON(dto.QueueStatsDto{
+		Workers:     stats.Workers,
+		InUseBytes:  stats.InUseBytes,
+		MaxBytes:    stats.MaxBytes,
+		Interactive: queueClassDto(stats.Interactive),
+		Batch:       queueClassDto(stats.Batch),
+	})
+}
+
+func queueClassDto(stats thumbnailPkg.ClassStats) dto.QueueClassDto {
+	return dto.QueueClassDto{
+		Queued:        stats.Queued,
+		Running:       stats.Running,
+		Started:       stats.Started,
+		OldestWaitMs:  stats.OldestWait.Milliseconds(),
+		AverageWaitMs: stats.AverageWait.Milliseconds(),
+	}
+}
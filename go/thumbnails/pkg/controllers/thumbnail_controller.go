@@ -1,15 +1,23 @@
 package controllers
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
-	"github.com/waifuvault/WaifuVault/shared/utils"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
 	thumbnailPkg "github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/wapimod"
 )
@@ -21,9 +29,73 @@ func (s *Service) getAllThumbnailRoutes() []FSetupRoute {
 		s.setupUploadFileRoute,
 		s.setupGenerateThumbnailByTokenRoute,
 		s.setupGenerateThumbnailFromURLRoute,
+		s.setupGenerateThumbnailSetRoute,
+		s.setupSetFocalPointRoute,
 	}
 }
 
+// getRenderOptions reads the render options shared by the single thumbnail endpoints from the query string
+func getRenderOptions(ctx fiber.Ctx) (thumbnailPkg.Options, error) {
+	crop, err := thumbnailPkg.ParseCropMode(ctx.Query("crop"))
+	if err != nil {
+		return thumbnailPkg.Options{}, err
+	}
+
+	width, err := getRequestedWidth(ctx)
+	if err != nil {
+		return thumbnailPkg.Options{}, err
+	}
+
+	maxBytes, minQuality, err := getByteBudget(ctx)
+	if err != nil {
+		return thumbnailPkg.Options{}, err
+	}
+
+	return thumbnailPkg.Options{
+		Animate:     fiber.Query[bool](ctx, "animate", true),
+		Crop:        crop,
+		Width:       width,
+		KeepProfile: fiber.Query[bool](ctx, "keepProfile", false),
+		MaxBytes:    maxBytes,
+		MinQuality:  minQuality,
+	}, nil
+}
+
+// getByteBudget reads the optional maxBytes and minQuality query parameters
+func getByteBudget(ctx fiber.Ctx) (maxBytes, minQuality int, err error) {
+	maxBytes = fiber.Query[int](ctx, "maxBytes")
+	minQuality = fiber.Query[int](ctx, "minQuality")
+	if err := thumbnailPkg.ValidateBudget(maxBytes, minQuality); err != nil {
+		return 0, 0, err
+	}
+	return maxBytes, minQuality, nil
+}
+
+// setEncodingHeader reports the quality the encoder settled on for thumbnails made with a byte budget
+func setEncodingHeader(ctx fiber.Ctx, thumbnail []byte) {
+	if encoding, ok := thumbnailPkg.ReadEncoding(thumbnail); ok {
+		ctx.Set("X-Thumbnail-Quality", encoding.String())
+	}
+}
+
+// getRequestedWidth reads the width query parameter, falling back to the Sec-CH-Width and Sec-CH-DPR client hints
+func getRequestedWidth(ctx fiber.Ctx) (int, error) {
+	ctx.Set(fiber.HeaderAcceptCH, "Sec-CH-Width, Sec-CH-DPR")
+	ctx.Vary("Sec-CH-Width", "Sec-CH-DPR")
+
+	if ctx.Query("width") != "" {
+		width := fiber.Query[int](ctx, "width")
+		if err := thumbnailPkg.ValidateWidth(width); err != nil {
+			return 0, err
+		}
+		return width, nil
+	}
+
+	hintWidth, _ := strconv.Atoi(ctx.Get("Sec-CH-Width"))
+	hintDpr, _ := strconv.ParseFloat(ctx.Get("Sec-CH-DPR"), 64)
+	return thumbnailPkg.ClientHintWidth(hintWidth, hintDpr), nil
+}
+
 // GetAllSupportedExtensions godoc
 //
 //	@Summary		Get supported file extensions
@@ -43,14 +115,35 @@ func (s *Service) getAllSupportedExtensionsRoute(ctx fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(fileTypes)
 }
 
+// Generate album thumbnails godoc
+//
+//	@Summary	Queue a job generating the thumbnails of album files
+//	@Description	Looks up the files of the album and queues a job rendering their thumbnails. Files of other albums, expired files and encrypted or password protected files are left out, and files that already have a thumbnail are skipped unless force is set
+//	@Tags	thumbnails
+//	@Accept	json
+//	@Produce	json
+//	@Param	albumId	query	int	true	"Id of the album"
+//	@Param	request	body	dto.GenerateThumbnailsRequestDto	false	"Files of the album to render, every file of the album when omitted"
+//	@Param	addingAdditionalFiles	query	bool	false	"set when files were added to an album that may still be loading"	default(false)
+//	@Param	crop	query	string	false	"crop the thumbnails to a square using this strategy"	Enums(attention, entropy, centre)
+//	@Param	force	query	bool	false	"render the files that already have a thumbnail again"	default(false)
+//	@Param	callbackUrl	query	string	false	"URL that receives the signed completion event of the job"
+//	@Success	200	{object}	wapimod.ApiResult	"The job was queued"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid album, file ids, crop or callback"
+//	@Failure	404	{object}	wapimod.ApiResult	"The album does not exist"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/generateThumbnails [post]
 func (s *Service) setupGenerateThumbnailsRoute(routeGroup fiber.Router) {
 	routeGroup.Post("/generateThumbnails", s.generateThumbnails)
 }
 
 func (s *Service) generateThumbnails(ctx fiber.Ctx) error {
-	var thumbnailEntries []dto.FileEntryDto
-	if err := ctx.Bind().Body(&thumbnailEntries); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid payload", err))
+	// the body is optional, without it every file of the album is rendered
+	var request dto.GenerateThumbnailsRequestDto
+	if len(ctx.Body()) > 0 {
+		if err := ctx.Bind().Body(&request); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid payload", err))
+		}
 	}
 
 	var albumId int
@@ -60,24 +153,26 @@ func (s *Service) generateThumbnails(ctx fiber.Ctx) error {
 
 	addingAdditionalFiles := fiber.Query[bool](ctx, "addingAdditionalFiles", false)
 
+	crop, err := thumbnailPkg.ParseCropMode(ctx.Query("crop"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
 	if !addingAdditionalFiles && s.ThumbnailService.IsAlbumLoading(albumId) {
 		errMsg := fmt.Sprintf("albumId %d is currently loading", albumId)
 		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(errMsg, errors.New(errMsg)))
 	}
 
-	go func() {
-		var start time.Time
-		if utils.DockerMode {
-			start = time.Now()
-		}
-		err := s.ThumbnailService.GenerateThumbnails(thumbnailEntries, albumId)
-		if utils.DockerMode {
-			log.Debug().Msgf("time taken to generate thumbnails: %s", time.Since(start))
+	if err := s.ThumbnailService.QueueThumbnails(albumId, request.FileIds, crop, fiber.Query[bool](ctx, "force", false), ctx.Query("callbackUrl")); err != nil {
+		if errors.Is(err, callback.ErrInvalidURL) || errors.Is(err, callback.ErrDisabled) || errors.Is(err, thumbnailPkg.ErrInvalidFileIds) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
 		}
-		if err != nil {
-			log.Error().Err(err).Msg("error generating thumbnails")
+		if errors.Is(err, thumbnailPkg.ErrAlbumNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(wapimod.NewApiError(err.Error(), err))
 		}
-	}()
+		log.Error().Err(err).Int("albumId", albumId).Msg("failed to queue thumbnail job")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError("failed to queue thumbnails", err))
+	}
 	return ctx.Status(fiber.StatusOK).JSON(wapimod.NewApiResult("", true))
 }
 
@@ -87,10 +182,20 @@ func (s *Service) generateThumbnails(ctx fiber.Ctx) error {
 //	@Description	Accepts a file upload via multipart form data
 //	@Tags	thumbnails
 //	@Accept	multipart/form-data
+//	@Produce	image/webp
+//	@Produce	image/jpeg
 //	@Produce	json
 //	@Param	file	formData	file	true	"File to upload"
 //	@Param	animate	query	bool	false	"set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)"
+//	@Param	crop	query	string	false	"crop the thumbnail to a square using this strategy"	Enums(attention, entropy, centre)
+//	@Param	keepProfile	query	bool	false	"embed the original ICC profile instead of converting to sRGB"	default(false)
+//	@Param	maxBytes	query	int	false	"byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality"	minimum(1024)
+//	@Param	minQuality	query	int	false	"lowest quality allowed when fitting maxBytes"	minimum(1)	maximum(100)	default(40)
+//	@Param	width	query	int	false	"width of the thumbnail in pixels, defaults to the Sec-CH-Width / Sec-CH-DPR client hints or 400"	minimum(16)	maximum(2048)
 //	@Success	200	{object}	wapimod.ApiResult	"File uploaded successfully"
+//	@Header	200	{string}	X-Thumbnail-Quality	"quality chosen to fit maxBytes, e.g. 62, near-lossless or 40; alpha=lossy"
+//	@Header	200	{string}	ETag	"strong validator of the thumbnail bytes"
+//	@Header	200	{string}	Cache-Control	"private, no-store"
 //	@Failure	400	{object}	wapimod.ApiResult	"Bad request - no file uploaded"
 //	@Router	/generateThumbnail [post]
 func (s *Service) setupUploadFileRoute(routeGroup fiber.Router) {
@@ -105,9 +210,12 @@ func (s *Service) generateThumbnail(ctx fiber.Ctx) error {
 		})
 	}
 
-	animate := fiber.Query[bool](ctx, "animate", true)
+	options, err := getRenderOptions(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
 
-	thumbnail, err := s.ThumbnailService.GenerateThumbnail(fileHeader, animate)
+	thumbnail, err := s.ThumbnailService.GenerateThumbnail(fileHeader, options)
 	if err != nil {
 		if errors.Is(err, thumbnailPkg.ErrUnsupportedFileType) {
 			return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
@@ -115,11 +223,7 @@ func (s *Service) generateThumbnail(ctx fiber.Ctx) error {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
 	}
 
-	ctx.Set("Content-Length", fmt.Sprintf("%d", len(thumbnail)))
-	ctx.Status(fiber.StatusOK)
-	ctx.Set(fiber.HeaderContentType, "image/webp")
-
-	return ctx.Send(thumbnail)
+	return sendThumbnail(ctx, thumbnail, uploadCacheControl)
 }
 
 // Generate thumbnail by token godoc
@@ -128,12 +232,24 @@ func (s *Service) generateThumbnail(ctx fiber.Ctx) error {
 //	@Description	Generates a thumbnail from an existing file using its token
 //	@Tags	thumbnails
 //	@Produce	image/webp
+//	@Produce	image/jpeg
 //	@Param	fileToken	path	string	true	"File token to generate thumbnail for"
 //	@Param	animate	query	bool	false	"set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)"
-//	@Success	200	{string}	map[string]interface{}	"Thumbnail image in WebP format"
+//	@Param	crop	query	string	false	"crop the thumbnail to a square using this strategy, the file's focal point takes precedence when set"	Enums(attention, entropy, centre)
+//	@Param	keepProfile	query	bool	false	"embed the original ICC profile instead of converting to sRGB"	default(false)
+//	@Param	maxBytes	query	int	false	"byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality"	minimum(1024)
+//	@Param	minQuality	query	int	false	"lowest quality allowed when fitting maxBytes"	minimum(1)	maximum(100)	default(40)
+//	@Param	width	query	int	false	"width of the thumbnail in pixels, defaults to the Sec-CH-Width / Sec-CH-DPR client hints or 400"	minimum(16)	maximum(2048)
+//	@Success	200	{string}	map[string]interface{}	"Thumbnail image, WebP or a JPEG frame for videos"
+//	@Header	200	{string}	X-Thumbnail-Quality	"quality chosen to fit maxBytes, e.g. 62, near-lossless or 40; alpha=lossy"
+//	@Header	200,304	{string}	ETag	"strong validator of the thumbnail bytes"
+//	@Header	200,304	{string}	Last-Modified	"time the thumbnail was rendered"
+//	@Header	200,304	{string}	Cache-Control	"public, max-age=0, must-revalidate"
+//	@Success	304	"the cached copy matching If-None-Match or If-Modified-Since is current"
 //	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid file token or unsupported file type"
 //	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
 //	@Router	/generateThumbnail/{fileToken} [get]
+//	@Router	/generateThumbnail/{fileToken} [head]
 func (s *Service) setupGenerateThumbnailByTokenRoute(routeGroup fiber.Router) {
 	routeGroup.Get("/generateThumbnail/:fileToken", s.generateThumbnailByToken)
 }
@@ -151,9 +267,12 @@ func (s *Service) generateThumbnailByToken(ctx fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid file token", err))
 	}
 
-	animate := fiber.Query[bool](ctx, "animate", true)
+	options, err := getRenderOptions(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
 
-	thumbnail, err := s.ThumbnailService.GenerateThumbnailByToken(tokenUUid, animate)
+	thumbnail, err := s.ThumbnailService.GenerateThumbnailByToken(tokenUUid, options)
 	if err != nil {
 		if errors.Is(err, thumbnailPkg.ErrUnsupportedFileType) {
 			return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
@@ -164,11 +283,7 @@ func (s *Service) generateThumbnailByToken(ctx fiber.Ctx) error {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
 	}
 
-	ctx.Set("Content-Length", fmt.Sprintf("%d", len(thumbnail)))
-	ctx.Status(fiber.StatusOK)
-	ctx.Set(fiber.HeaderContentType, "image/webp")
-
-	return ctx.Send(thumbnail)
+	return sendThumbnail(ctx, thumbnail, tokenCacheControl)
 }
 
 // Generate thumbnail from URL godoc
@@ -177,12 +292,24 @@ func (s *Service) generateThumbnailByToken(ctx fiber.Ctx) error {
 //	@Description	Generates a thumbnail from a file URL
 //	@Tags	thumbnails
 //	@Produce	image/webp
+//	@Produce	image/jpeg
 //	@Param	url	query	string	true	"URL of the file to generate thumbnail for"
 //	@Param	animate	query	bool	false	"set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)"
-//	@Success	200	{string}	map[string]interface{}	"Thumbnail image in WebP format"
+//	@Param	crop	query	string	false	"crop the thumbnail to a square using this strategy"	Enums(attention, entropy, centre)
+//	@Param	keepProfile	query	bool	false	"embed the original ICC profile instead of converting to sRGB"	default(false)
+//	@Param	maxBytes	query	int	false	"byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality"	minimum(1024)
+//	@Param	minQuality	query	int	false	"lowest quality allowed when fitting maxBytes"	minimum(1)	maximum(100)	default(40)
+//	@Param	width	query	int	false	"width of the thumbnail in pixels, defaults to the Sec-CH-Width / Sec-CH-DPR client hints or 400"	minimum(16)	maximum(2048)
+//	@Success	200	{string}	map[string]interface{}	"Thumbnail image, WebP or a JPEG frame for videos"
+//	@Header	200	{string}	X-Thumbnail-Quality	"quality chosen to fit maxBytes, e.g. 62, near-lossless or 40; alpha=lossy"
+//	@Header	200,304	{string}	ETag	"strong validator of the thumbnail bytes"
+//	@Header	200,304	{string}	Last-Modified	"time the thumbnail was rendered"
+//	@Header	200,304	{string}	Cache-Control	"public, max-age=600"
+//	@Success	304	"the cached copy matching If-None-Match or If-Modified-Since is current"
 //	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid URL or unsupported file type"
 //	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
 //	@Router	/generateThumbnail/ext/fromURL [get]
+//	@Router	/generateThumbnail/ext/fromURL [head]
 func (s *Service) setupGenerateThumbnailFromURLRoute(routeGroup fiber.Router) {
 	routeGroup.Get("/generateThumbnail/ext/fromURL", s.generateThumbnailFromURL)
 }
@@ -195,9 +322,12 @@ func (s *Service) generateThumbnailFromURL(ctx fiber.Ctx) error {
 		})
 	}
 
-	animate := fiber.Query[bool](ctx, "animate", true)
+	options, err := getRenderOptions(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
 
-	thumbnail, err := s.ThumbnailService.GenerateThumbnailFromURL(url, animate)
+	thumbnail, err := s.ThumbnailService.GenerateThumbnailFromURL(url, options)
 	if err != nil {
 		if errors.Is(err, thumbnailPkg.ErrInvalidURL) ||
 			errors.Is(err, thumbnailPkg.ErrFileTooLarge) ||
@@ -209,9 +339,204 @@ func (s *Service) generateThumbnailFromURL(ctx fiber.Ctx) error {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
 	}
 
-	ctx.Set("Content-Length", fmt.Sprintf("%d", len(thumbnail)))
-	ctx.Status(fiber.StatusOK)
-	ctx.Set(fiber.HeaderContentType, "image/webp")
+	return sendThumbnail(ctx, thumbnail, urlCacheControl)
+}
+
+// Generate thumbnail set by token godoc
+//
+//	@Summary	Generate a responsive thumbnail set from a file token
+//	@Description	Decodes the file once and returns a thumbnail for every width multiplied by every device pixel ratio, ready to build a srcset. Each width is cached on its own
+//	@Tags	thumbnails
+//	@Produce	json
+//	@Produce	multipart/mixed
+//	@Param	fileToken	path	string	true	"File token to generate thumbnails for"
+//	@Param	widths	query	string	false	"comma separated widths in pixels"	default(200,400,800)
+//	@Param	dpr	query	string	false	"comma separated device pixel ratios applied to every width"	default(1)
+//	@Param	format	query	string	false	"json manifest with base64 data or a multipart/mixed body with one part per width"	Enums(json, multipart)	default(json)
+//	@Param	animate	query	bool	false	"set to true if you want to animate the thumbnail (only works with animated gif, webp or heif)"
+//	@Param	crop	query	string	false	"crop the thumbnail to a square using this strategy, the file's focal point takes precedence when set"	Enums(attention, entropy, centre)
+//	@Param	keepProfile	query	bool	false	"embed the original ICC profile instead of converting to sRGB"	default(false)
+//	@Param	maxBytes	query	int	false	"byte budget, the quality is lowered until the thumbnail fits and reported in X-Thumbnail-Quality"	minimum(1024)
+//	@Param	minQuality	query	int	false	"lowest quality allowed when fitting maxBytes"	minimum(1)	maximum(100)	default(40)
+//	@Success	200	{object}	dto.ThumbnailSetDto	"Thumbnail set"
+//	@Header	200,304	{string}	ETag	"strong validator of the response body"
+//	@Header	200,304	{string}	Last-Modified	"time the newest variant was rendered"
+//	@Header	200,304	{string}	Cache-Control	"public, max-age=0, must-revalidate"
+//	@Success	304	"the cached copy matching If-None-Match or If-Modified-Since is current"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid file token, widths or unsupported file type"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/generateThumbnail/{fileToken}/set [get]
+//	@Router	/generateThumbnail/{fileToken}/set [head]
+func (s *Service) setupGenerateThumbnailSetRoute(routeGroup fiber.Router) {
+	routeGroup.Get("/generateThumbnail/:fileToken/set", s.generateThumbnailSet)
+}
+
+func (s *Service) generateThumbnailSet(ctx fiber.Ctx) error {
+	tokenUUid, err := uuid.Parse(ctx.Params("fileToken"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid file token", err))
+	}
+
+	crop, err := thumbnailPkg.ParseCropMode(ctx.Query("crop"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	widths, err := thumbnailPkg.ParseWidths(ctx.Query("widths"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	dprs, err := thumbnailPkg.ParseDPRs(ctx.Query("dpr"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	variantWidths, err := thumbnailPkg.VariantWidths(widths, dprs)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	format := ctx.Query("format", "json")
+	if format != "json" && format != "multipart" {
+		errMsg := fmt.Sprintf("unknown format %s", format)
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(errMsg, errors.New(errMsg)))
+	}
+
+	maxBytes, minQuality, err := getByteBudget(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	options := thumbnailPkg.Options{
+		Animate:     fiber.Query[bool](ctx, "animate", true),
+		Crop:        crop,
+		KeepProfile: fiber.Query[bool](ctx, "keepProfile", false),
+		MaxBytes:    maxBytes,
+		MinQuality:  minQuality,
+	}
+
+	variants, err := s.ThumbnailService.GenerateThumbnailSetByToken(tokenUUid, options, variantWidths)
+	if err != nil {
+		if errors.Is(err, thumbnailPkg.ErrUnsupportedFileType) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		if errors.Is(err, thumbnailPkg.ErrFileNotFound) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	var lastModified time.Time
+	for _, variant := range variants {
+		if renderedAt, ok := thumbnailPkg.ReadRenderTime(variant.Thumbnail); ok && renderedAt.After(lastModified) {
+			lastModified = renderedAt
+		}
+	}
+
+	if format == "multipart" {
+		return sendMultipartVariants(ctx, variants, lastModified)
+	}
+
+	manifest := dto.ThumbnailSetDto{Variants: make([]dto.ThumbnailVariantDto, 0, len(variants))}
+	for _, variant := range variants {
+		variantDto := dto.ThumbnailVariantDto{
+			Width:       variant.Width,
+			Descriptor:  fmt.Sprintf("%dw", variant.Width),
+			ContentType: thumbnailPkg.ContentType(variant.Thumbnail),
+			Data:        variant.Thumbnail,
+		}
+		if encoding, ok := thumbnailPkg.ReadEncoding(variant.Thumbnail); ok {
+			variantDto.Quality = encoding.String()
+		}
+		manifest.Variants = append(manifest.Variants, variantDto)
+	}
+
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	return sendCacheable(ctx, body, fiber.MIMEApplicationJSON, tokenCacheControl, lastModified)
+}
+
+// sendMultipartVariants writes every variant as its own part of a multipart/mixed body
+func sendMultipartVariants(ctx fiber.Ctx, variants []thumbnailPkg.Variant, lastModified time.Time) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	// the boundary follows the content, so the body and its ETag are the same for every request
+	hasher := xxhash.New()
+	for _, variant := range variants {
+		_, _ = hasher.Write(variant.Thumbnail)
+	}
+	if err := writer.SetBoundary(fmt.Sprintf("thumbnail-set-%016x", hasher.Sum64())); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+	for _, variant := range variants {
+		contentType := thumbnailPkg.ContentType(variant.Thumbnail)
+		header := textproto.MIMEHeader{}
+		header.Set(fiber.HeaderContentType, contentType)
+		header.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%dw.%s"`, variant.Width, strings.TrimPrefix(contentType, "image/")))
+		header.Set("X-Thumbnail-Width", strconv.Itoa(variant.Width))
+		if encoding, ok := thumbnailPkg.ReadEncoding(variant.Thumbnail); ok {
+			header.Set("X-Thumbnail-Quality", encoding.String())
+		}
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		if _, err := part.Write(variant.Thumbnail); err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	return sendCacheable(ctx, body.Bytes(), "multipart/mixed; boundary="+writer.Boundary(), tokenCacheControl, lastModified)
+}
+
+// Set focal point godoc
+//
+//	@Summary	Set the focal point of a file
+//	@Description	Stores the point of interest used when cropping thumbnails of this file. Coordinates are normalised from 0 to 1, starting at the top left
+//	@Tags	thumbnails
+//	@Accept	json
+//	@Produce	json
+//	@Param	fileToken	path	string	true	"File token to set the focal point for"
+//	@Param	focalPoint	body	dto.FocalPointDto	true	"Focal point"
+//	@Success	200	{object}	wapimod.ApiResult	"Focal point saved"
+//	@Failure	400	{object}	wapimod.ApiResult	"Bad request - invalid file token or focal point"
+//	@Failure	404	{object}	wapimod.ApiResult	"File not found"
+//	@Failure	500	{object}	wapimod.ApiResult	"Internal server error"
+//	@Router	/generateThumbnail/{fileToken}/focalPoint [put]
+func (s *Service) setupSetFocalPointRoute(routeGroup fiber.Router) {
+	routeGroup.Put("/generateThumbnail/:fileToken/focalPoint", s.setFocalPoint)
+}
+
+func (s *Service) setFocalPoint(ctx fiber.Ctx) error {
+	tokenUUid, err := uuid.Parse(ctx.Params("fileToken"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid file token", err))
+	}
+
+	var focalPoint dto.FocalPointDto
+	if err := ctx.Bind().Body(&focalPoint); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError("invalid payload", err))
+	}
 
-	return ctx.Send(thumbnail)
+	err = s.ThumbnailService.SetFocalPoint(tokenUUid, mod.FocalPoint{
+		X: focalPoint.X,
+		Y: focalPoint.Y,
+	})
+	if err != nil {
+		if errors.Is(err, thumbnailPkg.ErrInvalidFocalPoint) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		if errors.Is(err, thumbnailPkg.ErrFileNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(wapimod.NewApiError(err.Error(), err))
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(wapimod.NewApiError(err.Error(), err))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(wapimod.NewApiResult("", true))
 }
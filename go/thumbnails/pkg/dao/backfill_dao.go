@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BackfillDao interface {
+	GetBackfillCursor(name string, tx ...*gorm.DB) (int, error)
+	SaveBackfillCursor(name string, cursor int, tx ...*gorm.DB) error
+}
+
+// GetBackfillCursor returns the cursor a job saved, 0 when it has none
+func (d dao) GetBackfillCursor(name string, tx ...*gorm.DB) (int, error) {
+	var states []mod.BackfillState
+	err := d.getDb(tx...).
+		Model(&mod.BackfillState{}).
+		Where(`"name" = ?`, name).
+		Limit(1).
+		Find(&states).
+		Error
+	if err != nil || len(states) == 0 {
+		return 0, err
+	}
+	return states[0].Cursor, nil
+}
+
+// SaveBackfillCursor upserts the cursor of a job on its name, a cursor of 0 removes it
+func (d dao) SaveBackfillCursor(name string, cursor int, tx ...*gorm.DB) error {
+	if cursor == 0 {
+		return d.getDb(tx...).
+			Where(`"name" = ?`, name).
+			Delete(&mod.BackfillState{}).
+			Error
+	}
+	return d.getDb(tx...).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"cursor", "updatedAt"}),
+		}).
+		Create(&mod.BackfillState{Name: name, Cursor: cursor}).
+		Error
+}
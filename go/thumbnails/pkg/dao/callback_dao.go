@@ -0,0 +1,31 @@
+package dao
+
+import (
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+)
+
+type CallbackDao interface {
+	SaveCallbackDelivery(delivery *mod.CallbackDelivery, tx ...*gorm.DB) error
+	GetCallbackDeliveries(albumId int, limit int, tx ...*gorm.DB) ([]mod.CallbackDelivery, error)
+}
+
+// SaveCallbackDelivery logs a delivery attempt, the log is removed with its job
+func (d dao) SaveCallbackDelivery(delivery *mod.CallbackDelivery, tx ...*gorm.DB) error {
+	return d.getDb(tx...).
+		Create(delivery).
+		Error
+}
+
+// GetCallbackDeliveries returns the latest delivery attempts of the jobs of an album, newest first
+func (d dao) GetCallbackDeliveries(albumId int, limit int, tx ...*gorm.DB) ([]mod.CallbackDelivery, error) {
+	var deliveries []mod.CallbackDelivery
+	err := d.getDb(tx...).
+		Model(&mod.CallbackDelivery{}).
+		Where(`"albumId" = ?`, albumId).
+		Order(`"id" DESC`).
+		Limit(limit).
+		Find(&deliveries).
+		Error
+	return deliveries, err
+}
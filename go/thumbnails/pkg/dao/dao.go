@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 	"github.com/waifuvault/WaifuVault/shared/utils"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/store"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -18,21 +19,27 @@ import (
 type Dao interface {
 	ThumbnailDao
 	FileEntryDao
+	JobDao
+	CallbackDao
+	FailureDao
+	BackfillDao
 }
 type dao struct {
-	db          *gorm.DB
-	redisClient *redis.Client
+	db    *gorm.DB
+	cache cache.ThumbnailCache
+	store store.ThumbnailStore
 }
 
-func NewDao(rdb *redis.Client) (Dao, error) {
+func NewDao(thumbnailCache cache.ThumbnailCache, thumbnailStore store.ThumbnailStore) (Dao, error) {
 	connection, err := getConnection()
 	if err != nil {
 		return nil, err
 	}
 
 	return &dao{
-		db:          connection,
-		redisClient: rdb,
+		db:    connection,
+		cache: thumbnailCache,
+		store: thumbnailStore,
 	}, nil
 }
 
@@ -5,6 +5,8 @@
 package dao
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	mock "github.com/stretchr/testify/mock"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
@@ -38,58 +40,220 @@ func (_m *MockDao) EXPECT() *MockDao_Expecter {
 	return &MockDao_Expecter{mock: &_m.Mock}
 }
 
-// GetFileEntry provides a mock function for the type MockDao
-func (_mock *MockDao) GetFileEntry(token uuid.UUID, tx ...*gorm.DB) (*mod.FileEntry, error) {
+// CancelJobs provides a mock function for the type MockDao
+func (_mock *MockDao) CancelJobs(albumId int, discardPartial bool, tx ...*gorm.DB) (int64, error) {
 	var tmpRet mock.Arguments
 	if len(tx) > 0 {
-		tmpRet = _mock.Called(token, tx)
+		tmpRet = _mock.Called(albumId, discardPartial, tx)
 	} else {
-		tmpRet = _mock.Called(token)
+		tmpRet = _mock.Called(albumId, discardPartial)
 	}
 	ret := tmpRet
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetFileEntry")
+		panic("no return value specified for CancelJobs")
 	}
 
-	var r0 *mod.FileEntry
+	var r0 int64
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, ...*gorm.DB) (*mod.FileEntry, error)); ok {
-		return returnFunc(token, tx...)
+	if returnFunc, ok := ret.Get(0).(func(int, bool, ...*gorm.DB) (int64, error)); ok {
+		return returnFunc(albumId, discardPartial, tx...)
 	}
-	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, ...*gorm.DB) *mod.FileEntry); ok {
-		r0 = returnFunc(token, tx...)
+	if returnFunc, ok := ret.Get(0).(func(int, bool, ...*gorm.DB) int64); ok {
+		r0 = returnFunc(albumId, discardPartial, tx...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, bool, ...*gorm.DB) error); ok {
+		r1 = returnFunc(albumId, discardPartial, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_CancelJobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CancelJobs'
+type MockDao_CancelJobs_Call struct {
+	*mock.Call
+}
+
+// CancelJobs is a helper method to define mock.On call
+//   - albumId int
+//   - discardPartial bool
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) CancelJobs(albumId interface{}, discardPartial interface{}, tx ...interface{}) *MockDao_CancelJobs_Call {
+	return &MockDao_CancelJobs_Call{Call: _e.mock.On("CancelJobs",
+		append([]interface{}{albumId, discardPartial}, tx...)...)}
+}
+
+func (_c *MockDao_CancelJobs_Call) Run(run func(albumId int, discardPartial bool, tx ...*gorm.DB)) *MockDao_CancelJobs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 bool
+		if args[1] != nil {
+			arg1 = args[1].(bool)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_CancelJobs_Call) Return(n int64, err error) *MockDao_CancelJobs_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockDao_CancelJobs_Call) RunAndReturn(run func(albumId int, discardPartial bool, tx ...*gorm.DB) (int64, error)) *MockDao_CancelJobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ClaimJob provides a mock function for the type MockDao
+func (_mock *MockDao) ClaimJob(owner string, lease time.Duration, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(owner, lease, tx)
+	} else {
+		tmpRet = _mock.Called(owner, lease)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimJob")
+	}
+
+	var r0 *mod.ThumbnailJob
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string, time.Duration, ...*gorm.DB) (*mod.ThumbnailJob, error)); ok {
+		return returnFunc(owner, lease, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string, time.Duration, ...*gorm.DB) *mod.ThumbnailJob); ok {
+		r0 = returnFunc(owner, lease, tx...)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*mod.FileEntry)
+			r0 = ret.Get(0).(*mod.ThumbnailJob)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(uuid.UUID, ...*gorm.DB) error); ok {
-		r1 = returnFunc(token, tx...)
+	if returnFunc, ok := ret.Get(1).(func(string, time.Duration, ...*gorm.DB) error); ok {
+		r1 = returnFunc(owner, lease, tx...)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockDao_GetFileEntry_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFileEntry'
-type MockDao_GetFileEntry_Call struct {
+// MockDao_ClaimJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClaimJob'
+type MockDao_ClaimJob_Call struct {
 	*mock.Call
 }
 
-// GetFileEntry is a helper method to define mock.On call
-//   - token uuid.UUID
+// ClaimJob is a helper method to define mock.On call
+//   - owner string
+//   - lease time.Duration
 //   - tx ...*gorm.DB
-func (_e *MockDao_Expecter) GetFileEntry(token interface{}, tx ...interface{}) *MockDao_GetFileEntry_Call {
-	return &MockDao_GetFileEntry_Call{Call: _e.mock.On("GetFileEntry",
-		append([]interface{}{token}, tx...)...)}
+func (_e *MockDao_Expecter) ClaimJob(owner interface{}, lease interface{}, tx ...interface{}) *MockDao_ClaimJob_Call {
+	return &MockDao_ClaimJob_Call{Call: _e.mock.On("ClaimJob",
+		append([]interface{}{owner, lease}, tx...)...)}
 }
 
-func (_c *MockDao_GetFileEntry_Call) Run(run func(token uuid.UUID, tx ...*gorm.DB)) *MockDao_GetFileEntry_Call {
+func (_c *MockDao_ClaimJob_Call) Run(run func(owner string, lease time.Duration, tx ...*gorm.DB)) *MockDao_ClaimJob_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 uuid.UUID
+		var arg0 string
 		if args[0] != nil {
-			arg0 = args[0].(uuid.UUID)
+			arg0 = args[0].(string)
+		}
+		var arg1 time.Duration
+		if args[1] != nil {
+			arg1 = args[1].(time.Duration)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_ClaimJob_Call) Return(thumbnailJob *mod.ThumbnailJob, err error) *MockDao_ClaimJob_Call {
+	_c.Call.Return(thumbnailJob, err)
+	return _c
+}
+
+func (_c *MockDao_ClaimJob_Call) RunAndReturn(run func(owner string, lease time.Duration, tx ...*gorm.DB) (*mod.ThumbnailJob, error)) *MockDao_ClaimJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CountFilesMissingThumbnails provides a mock function for the type MockDao
+func (_mock *MockDao) CountFilesMissingThumbnails(afterId int, tx ...*gorm.DB) (int64, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(afterId, tx)
+	} else {
+		tmpRet = _mock.Called(afterId)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountFilesMissingThumbnails")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) (int64, error)); ok {
+		return returnFunc(afterId, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) int64); ok {
+		r0 = returnFunc(afterId, tx...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(afterId, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_CountFilesMissingThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CountFilesMissingThumbnails'
+type MockDao_CountFilesMissingThumbnails_Call struct {
+	*mock.Call
+}
+
+// CountFilesMissingThumbnails is a helper method to define mock.On call
+//   - afterId int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) CountFilesMissingThumbnails(afterId interface{}, tx ...interface{}) *MockDao_CountFilesMissingThumbnails_Call {
+	return &MockDao_CountFilesMissingThumbnails_Call{Call: _e.mock.On("CountFilesMissingThumbnails",
+		append([]interface{}{afterId}, tx...)...)}
+}
+
+func (_c *MockDao_CountFilesMissingThumbnails_Call) Run(run func(afterId int, tx ...*gorm.DB)) *MockDao_CountFilesMissingThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
 		}
 		var arg1 []*gorm.DB
 		var variadicArgs []*gorm.DB
@@ -105,68 +269,207 @@ func (_c *MockDao_GetFileEntry_Call) Run(run func(token uuid.UUID, tx ...*gorm.D
 	return _c
 }
 
-func (_c *MockDao_GetFileEntry_Call) Return(fileEntry *mod.FileEntry, err error) *MockDao_GetFileEntry_Call {
-	_c.Call.Return(fileEntry, err)
+func (_c *MockDao_CountFilesMissingThumbnails_Call) Return(n int64, err error) *MockDao_CountFilesMissingThumbnails_Call {
+	_c.Call.Return(n, err)
 	return _c
 }
 
-func (_c *MockDao_GetFileEntry_Call) RunAndReturn(run func(token uuid.UUID, tx ...*gorm.DB) (*mod.FileEntry, error)) *MockDao_GetFileEntry_Call {
+func (_c *MockDao_CountFilesMissingThumbnails_Call) RunAndReturn(run func(afterId int, tx ...*gorm.DB) (int64, error)) *MockDao_CountFilesMissingThumbnails_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// SaveThumbnails provides a mock function for the type MockDao
-func (_mock *MockDao) SaveThumbnails(thumbnails []mod.Thumbnail, tx ...*gorm.DB) ([]mod.Thumbnail, error) {
+// CreateJob provides a mock function for the type MockDao
+func (_mock *MockDao) CreateJob(job *mod.ThumbnailJob, tx ...*gorm.DB) error {
 	var tmpRet mock.Arguments
 	if len(tx) > 0 {
-		tmpRet = _mock.Called(thumbnails, tx)
+		tmpRet = _mock.Called(job, tx)
 	} else {
-		tmpRet = _mock.Called(thumbnails)
+		tmpRet = _mock.Called(job)
 	}
 	ret := tmpRet
 
 	if len(ret) == 0 {
-		panic("no return value specified for SaveThumbnails")
+		panic("no return value specified for CreateJob")
 	}
 
-	var r0 []mod.Thumbnail
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(*mod.ThumbnailJob, ...*gorm.DB) error); ok {
+		r0 = returnFunc(job, tx...)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockDao_CreateJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateJob'
+type MockDao_CreateJob_Call struct {
+	*mock.Call
+}
+
+// CreateJob is a helper method to define mock.On call
+//   - job *mod.ThumbnailJob
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) CreateJob(job interface{}, tx ...interface{}) *MockDao_CreateJob_Call {
+	return &MockDao_CreateJob_Call{Call: _e.mock.On("CreateJob",
+		append([]interface{}{job}, tx...)...)}
+}
+
+func (_c *MockDao_CreateJob_Call) Run(run func(job *mod.ThumbnailJob, tx ...*gorm.DB)) *MockDao_CreateJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *mod.ThumbnailJob
+		if args[0] != nil {
+			arg0 = args[0].(*mod.ThumbnailJob)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_CreateJob_Call) Return(err error) *MockDao_CreateJob_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockDao_CreateJob_Call) RunAndReturn(run func(job *mod.ThumbnailJob, tx ...*gorm.DB) error) *MockDao_CreateJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteFileFailures provides a mock function for the type MockDao
+func (_mock *MockDao) DeleteFileFailures(fileIds []int, tx ...*gorm.DB) (int64, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(fileIds, tx)
+	} else {
+		tmpRet = _mock.Called(fileIds)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFileFailures")
+	}
+
+	var r0 int64
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func([]mod.Thumbnail, ...*gorm.DB) ([]mod.Thumbnail, error)); ok {
-		return returnFunc(thumbnails, tx...)
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) (int64, error)); ok {
+		return returnFunc(fileIds, tx...)
 	}
-	if returnFunc, ok := ret.Get(0).(func([]mod.Thumbnail, ...*gorm.DB) []mod.Thumbnail); ok {
-		r0 = returnFunc(thumbnails, tx...)
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) int64); ok {
+		r0 = returnFunc(fileIds, tx...)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]mod.Thumbnail)
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func([]int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(fileIds, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_DeleteFileFailures_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteFileFailures'
+type MockDao_DeleteFileFailures_Call struct {
+	*mock.Call
+}
+
+// DeleteFileFailures is a helper method to define mock.On call
+//   - fileIds []int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) DeleteFileFailures(fileIds interface{}, tx ...interface{}) *MockDao_DeleteFileFailures_Call {
+	return &MockDao_DeleteFileFailures_Call{Call: _e.mock.On("DeleteFileFailures",
+		append([]interface{}{fileIds}, tx...)...)}
+}
+
+func (_c *MockDao_DeleteFileFailures_Call) Run(run func(fileIds []int, tx ...*gorm.DB)) *MockDao_DeleteFileFailures_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []int
+		if args[0] != nil {
+			arg0 = args[0].([]int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
 		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_DeleteFileFailures_Call) Return(n int64, err error) *MockDao_DeleteFileFailures_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockDao_DeleteFileFailures_Call) RunAndReturn(run func(fileIds []int, tx ...*gorm.DB) (int64, error)) *MockDao_DeleteFileFailures_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteFinishedJobs provides a mock function for the type MockDao
+func (_mock *MockDao) DeleteFinishedJobs(before time.Time, tx ...*gorm.DB) (int64, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(before, tx)
+	} else {
+		tmpRet = _mock.Called(before)
 	}
-	if returnFunc, ok := ret.Get(1).(func([]mod.Thumbnail, ...*gorm.DB) error); ok {
-		r1 = returnFunc(thumbnails, tx...)
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFinishedJobs")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(time.Time, ...*gorm.DB) (int64, error)); ok {
+		return returnFunc(before, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(time.Time, ...*gorm.DB) int64); ok {
+		r0 = returnFunc(before, tx...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(time.Time, ...*gorm.DB) error); ok {
+		r1 = returnFunc(before, tx...)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockDao_SaveThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveThumbnails'
-type MockDao_SaveThumbnails_Call struct {
+// MockDao_DeleteFinishedJobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteFinishedJobs'
+type MockDao_DeleteFinishedJobs_Call struct {
 	*mock.Call
 }
 
-// SaveThumbnails is a helper method to define mock.On call
-//   - thumbnails []mod.Thumbnail
+// DeleteFinishedJobs is a helper method to define mock.On call
+//   - before time.Time
 //   - tx ...*gorm.DB
-func (_e *MockDao_Expecter) SaveThumbnails(thumbnails interface{}, tx ...interface{}) *MockDao_SaveThumbnails_Call {
-	return &MockDao_SaveThumbnails_Call{Call: _e.mock.On("SaveThumbnails",
-		append([]interface{}{thumbnails}, tx...)...)}
+func (_e *MockDao_Expecter) DeleteFinishedJobs(before interface{}, tx ...interface{}) *MockDao_DeleteFinishedJobs_Call {
+	return &MockDao_DeleteFinishedJobs_Call{Call: _e.mock.On("DeleteFinishedJobs",
+		append([]interface{}{before}, tx...)...)}
 }
 
-func (_c *MockDao_SaveThumbnails_Call) Run(run func(thumbnails []mod.Thumbnail, tx ...*gorm.DB)) *MockDao_SaveThumbnails_Call {
+func (_c *MockDao_DeleteFinishedJobs_Call) Run(run func(before time.Time, tx ...*gorm.DB)) *MockDao_DeleteFinishedJobs_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 []mod.Thumbnail
+		var arg0 time.Time
 		if args[0] != nil {
-			arg0 = args[0].([]mod.Thumbnail)
+			arg0 = args[0].(time.Time)
 		}
 		var arg1 []*gorm.DB
 		var variadicArgs []*gorm.DB
@@ -182,12 +485,2633 @@ func (_c *MockDao_SaveThumbnails_Call) Run(run func(thumbnails []mod.Thumbnail,
 	return _c
 }
 
-func (_c *MockDao_SaveThumbnails_Call) Return(thumbnails1 []mod.Thumbnail, err error) *MockDao_SaveThumbnails_Call {
-	_c.Call.Return(thumbnails1, err)
+func (_c *MockDao_DeleteFinishedJobs_Call) Return(n int64, err error) *MockDao_DeleteFinishedJobs_Call {
+	_c.Call.Return(n, err)
 	return _c
 }
 
-func (_c *MockDao_SaveThumbnails_Call) RunAndReturn(run func(thumbnails []mod.Thumbnail, tx ...*gorm.DB) ([]mod.Thumbnail, error)) *MockDao_SaveThumbnails_Call {
+func (_c *MockDao_DeleteFinishedJobs_Call) RunAndReturn(run func(before time.Time, tx ...*gorm.DB) (int64, error)) *MockDao_DeleteFinishedJobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteQuarantinedFiles provides a mock function for the type MockDao
+func (_mock *MockDao) DeleteQuarantinedFiles(tx ...*gorm.DB) (int64, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(tx)
+	} else {
+		tmpRet = _mock.Called()
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteQuarantinedFiles")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(...*gorm.DB) (int64, error)); ok {
+		return returnFunc(tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(...*gorm.DB) int64); ok {
+		r0 = returnFunc(tx...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(...*gorm.DB) error); ok {
+		r1 = returnFunc(tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_DeleteQuarantinedFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteQuarantinedFiles'
+type MockDao_DeleteQuarantinedFiles_Call struct {
+	*mock.Call
+}
+
+// DeleteQuarantinedFiles is a helper method to define mock.On call
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) DeleteQuarantinedFiles(tx ...interface{}) *MockDao_DeleteQuarantinedFiles_Call {
+	return &MockDao_DeleteQuarantinedFiles_Call{Call: _e.mock.On("DeleteQuarantinedFiles",
+		append([]interface{}{}, tx...)...)}
+}
+
+func (_c *MockDao_DeleteQuarantinedFiles_Call) Run(run func(tx ...*gorm.DB)) *MockDao_DeleteQuarantinedFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 0 {
+			variadicArgs = args[0].([]*gorm.DB)
+		}
+		arg0 = variadicArgs
+		run(
+			arg0...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_DeleteQuarantinedFiles_Call) Return(n int64, err error) *MockDao_DeleteQuarantinedFiles_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockDao_DeleteQuarantinedFiles_Call) RunAndReturn(run func(tx ...*gorm.DB) (int64, error)) *MockDao_DeleteQuarantinedFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteThumbnails provides a mock function for the type MockDao
+func (_mock *MockDao) DeleteThumbnails(fileIds []int, tx ...*gorm.DB) (int64, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(fileIds, tx)
+	} else {
+		tmpRet = _mock.Called(fileIds)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteThumbnails")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) (int64, error)); ok {
+		return returnFunc(fileIds, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) int64); ok {
+		r0 = returnFunc(fileIds, tx...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func([]int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(fileIds, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_DeleteThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteThumbnails'
+type MockDao_DeleteThumbnails_Call struct {
+	*mock.Call
+}
+
+// DeleteThumbnails is a helper method to define mock.On call
+//   - fileIds []int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) DeleteThumbnails(fileIds interface{}, tx ...interface{}) *MockDao_DeleteThumbnails_Call {
+	return &MockDao_DeleteThumbnails_Call{Call: _e.mock.On("DeleteThumbnails",
+		append([]interface{}{fileIds}, tx...)...)}
+}
+
+func (_c *MockDao_DeleteThumbnails_Call) Run(run func(fileIds []int, tx ...*gorm.DB)) *MockDao_DeleteThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []int
+		if args[0] != nil {
+			arg0 = args[0].([]int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_DeleteThumbnails_Call) Return(n int64, err error) *MockDao_DeleteThumbnails_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockDao_DeleteThumbnails_Call) RunAndReturn(run func(fileIds []int, tx ...*gorm.DB) (int64, error)) *MockDao_DeleteThumbnails_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAlbumFileEntries provides a mock function for the type MockDao
+func (_mock *MockDao) GetAlbumFileEntries(albumId int, tx ...*gorm.DB) ([]mod.FileEntry, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(albumId, tx)
+	} else {
+		tmpRet = _mock.Called(albumId)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAlbumFileEntries")
+	}
+
+	var r0 []mod.FileEntry
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) ([]mod.FileEntry, error)); ok {
+		return returnFunc(albumId, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) []mod.FileEntry); ok {
+		r0 = returnFunc(albumId, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.FileEntry)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(albumId, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetAlbumFileEntries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAlbumFileEntries'
+type MockDao_GetAlbumFileEntries_Call struct {
+	*mock.Call
+}
+
+// GetAlbumFileEntries is a helper method to define mock.On call
+//   - albumId int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetAlbumFileEntries(albumId interface{}, tx ...interface{}) *MockDao_GetAlbumFileEntries_Call {
+	return &MockDao_GetAlbumFileEntries_Call{Call: _e.mock.On("GetAlbumFileEntries",
+		append([]interface{}{albumId}, tx...)...)}
+}
+
+func (_c *MockDao_GetAlbumFileEntries_Call) Run(run func(albumId int, tx ...*gorm.DB)) *MockDao_GetAlbumFileEntries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetAlbumFileEntries_Call) Return(fileEntrys []mod.FileEntry, err error) *MockDao_GetAlbumFileEntries_Call {
+	_c.Call.Return(fileEntrys, err)
+	return _c
+}
+
+func (_c *MockDao_GetAlbumFileEntries_Call) RunAndReturn(run func(albumId int, tx ...*gorm.DB) ([]mod.FileEntry, error)) *MockDao_GetAlbumFileEntries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAlbumThumbnailFiles provides a mock function for the type MockDao
+func (_mock *MockDao) GetAlbumThumbnailFiles(albumId int, fileIds []int, tx ...*gorm.DB) ([]mod.FileEntry, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(albumId, fileIds, tx)
+	} else {
+		tmpRet = _mock.Called(albumId, fileIds)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAlbumThumbnailFiles")
+	}
+
+	var r0 []mod.FileEntry
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, []int, ...*gorm.DB) ([]mod.FileEntry, error)); ok {
+		return returnFunc(albumId, fileIds, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, []int, ...*gorm.DB) []mod.FileEntry); ok {
+		r0 = returnFunc(albumId, fileIds, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.FileEntry)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, []int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(albumId, fileIds, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetAlbumThumbnailFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAlbumThumbnailFiles'
+type MockDao_GetAlbumThumbnailFiles_Call struct {
+	*mock.Call
+}
+
+// GetAlbumThumbnailFiles is a helper method to define mock.On call
+//   - albumId int
+//   - fileIds []int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetAlbumThumbnailFiles(albumId interface{}, fileIds interface{}, tx ...interface{}) *MockDao_GetAlbumThumbnailFiles_Call {
+	return &MockDao_GetAlbumThumbnailFiles_Call{Call: _e.mock.On("GetAlbumThumbnailFiles",
+		append([]interface{}{albumId, fileIds}, tx...)...)}
+}
+
+func (_c *MockDao_GetAlbumThumbnailFiles_Call) Run(run func(albumId int, fileIds []int, tx ...*gorm.DB)) *MockDao_GetAlbumThumbnailFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []int
+		if args[1] != nil {
+			arg1 = args[1].([]int)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetAlbumThumbnailFiles_Call) Return(fileEntrys []mod.FileEntry, err error) *MockDao_GetAlbumThumbnailFiles_Call {
+	_c.Call.Return(fileEntrys, err)
+	return _c
+}
+
+func (_c *MockDao_GetAlbumThumbnailFiles_Call) RunAndReturn(run func(albumId int, fileIds []int, tx ...*gorm.DB) ([]mod.FileEntry, error)) *MockDao_GetAlbumThumbnailFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetBackfillCursor provides a mock function for the type MockDao
+func (_mock *MockDao) GetBackfillCursor(name string, tx ...*gorm.DB) (int, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(name, tx)
+	} else {
+		tmpRet = _mock.Called(name)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBackfillCursor")
+	}
+
+	var r0 int
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string, ...*gorm.DB) (int, error)); ok {
+		return returnFunc(name, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string, ...*gorm.DB) int); ok {
+		r0 = returnFunc(name, tx...)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string, ...*gorm.DB) error); ok {
+		r1 = returnFunc(name, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetBackfillCursor_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetBackfillCursor'
+type MockDao_GetBackfillCursor_Call struct {
+	*mock.Call
+}
+
+// GetBackfillCursor is a helper method to define mock.On call
+//   - name string
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetBackfillCursor(name interface{}, tx ...interface{}) *MockDao_GetBackfillCursor_Call {
+	return &MockDao_GetBackfillCursor_Call{Call: _e.mock.On("GetBackfillCursor",
+		append([]interface{}{name}, tx...)...)}
+}
+
+func (_c *MockDao_GetBackfillCursor_Call) Run(run func(name string, tx ...*gorm.DB)) *MockDao_GetBackfillCursor_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetBackfillCursor_Call) Return(n int, err error) *MockDao_GetBackfillCursor_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockDao_GetBackfillCursor_Call) RunAndReturn(run func(name string, tx ...*gorm.DB) (int, error)) *MockDao_GetBackfillCursor_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetCallbackDeliveries provides a mock function for the type MockDao
+func (_mock *MockDao) GetCallbackDeliveries(albumId int, limit int, tx ...*gorm.DB) ([]mod.CallbackDelivery, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(albumId, limit, tx)
+	} else {
+		tmpRet = _mock.Called(albumId, limit)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCallbackDeliveries")
+	}
+
+	var r0 []mod.CallbackDelivery
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, int, ...*gorm.DB) ([]mod.CallbackDelivery, error)); ok {
+		return returnFunc(albumId, limit, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, int, ...*gorm.DB) []mod.CallbackDelivery); ok {
+		r0 = returnFunc(albumId, limit, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.CallbackDelivery)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(albumId, limit, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetCallbackDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCallbackDeliveries'
+type MockDao_GetCallbackDeliveries_Call struct {
+	*mock.Call
+}
+
+// GetCallbackDeliveries is a helper method to define mock.On call
+//   - albumId int
+//   - limit int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetCallbackDeliveries(albumId interface{}, limit interface{}, tx ...interface{}) *MockDao_GetCallbackDeliveries_Call {
+	return &MockDao_GetCallbackDeliveries_Call{Call: _e.mock.On("GetCallbackDeliveries",
+		append([]interface{}{albumId, limit}, tx...)...)}
+}
+
+func (_c *MockDao_GetCallbackDeliveries_Call) Run(run func(albumId int, limit int, tx ...*gorm.DB)) *MockDao_GetCallbackDeliveries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetCallbackDeliveries_Call) Return(callbackDeliverys []mod.CallbackDelivery, err error) *MockDao_GetCallbackDeliveries_Call {
+	_c.Call.Return(callbackDeliverys, err)
+	return _c
+}
+
+func (_c *MockDao_GetCallbackDeliveries_Call) RunAndReturn(run func(albumId int, limit int, tx ...*gorm.DB) ([]mod.CallbackDelivery, error)) *MockDao_GetCallbackDeliveries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFileEntry provides a mock function for the type MockDao
+func (_mock *MockDao) GetFileEntry(token uuid.UUID, tx ...*gorm.DB) (*mod.FileEntry, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(token, tx)
+	} else {
+		tmpRet = _mock.Called(token)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFileEntry")
+	}
+
+	var r0 *mod.FileEntry
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, ...*gorm.DB) (*mod.FileEntry, error)); ok {
+		return returnFunc(token, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, ...*gorm.DB) *mod.FileEntry); ok {
+		r0 = returnFunc(token, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mod.FileEntry)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(uuid.UUID, ...*gorm.DB) error); ok {
+		r1 = returnFunc(token, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetFileEntry_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFileEntry'
+type MockDao_GetFileEntry_Call struct {
+	*mock.Call
+}
+
+// GetFileEntry is a helper method to define mock.On call
+//   - token uuid.UUID
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetFileEntry(token interface{}, tx ...interface{}) *MockDao_GetFileEntry_Call {
+	return &MockDao_GetFileEntry_Call{Call: _e.mock.On("GetFileEntry",
+		append([]interface{}{token}, tx...)...)}
+}
+
+func (_c *MockDao_GetFileEntry_Call) Run(run func(token uuid.UUID, tx ...*gorm.DB)) *MockDao_GetFileEntry_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 uuid.UUID
+		if args[0] != nil {
+			arg0 = args[0].(uuid.UUID)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetFileEntry_Call) Return(fileEntry *mod.FileEntry, err error) *MockDao_GetFileEntry_Call {
+	_c.Call.Return(fileEntry, err)
+	return _c
+}
+
+func (_c *MockDao_GetFileEntry_Call) RunAndReturn(run func(token uuid.UUID, tx ...*gorm.DB) (*mod.FileEntry, error)) *MockDao_GetFileEntry_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFileEntryById provides a mock function for the type MockDao
+func (_mock *MockDao) GetFileEntryById(fileId int, tx ...*gorm.DB) (*mod.FileEntry, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(fileId, tx)
+	} else {
+		tmpRet = _mock.Called(fileId)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFileEntryById")
+	}
+
+	var r0 *mod.FileEntry
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) (*mod.FileEntry, error)); ok {
+		return returnFunc(fileId, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) *mod.FileEntry); ok {
+		r0 = returnFunc(fileId, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mod.FileEntry)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(fileId, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetFileEntryById_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFileEntryById'
+type MockDao_GetFileEntryById_Call struct {
+	*mock.Call
+}
+
+// GetFileEntryById is a helper method to define mock.On call
+//   - fileId int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetFileEntryById(fileId interface{}, tx ...interface{}) *MockDao_GetFileEntryById_Call {
+	return &MockDao_GetFileEntryById_Call{Call: _e.mock.On("GetFileEntryById",
+		append([]interface{}{fileId}, tx...)...)}
+}
+
+func (_c *MockDao_GetFileEntryById_Call) Run(run func(fileId int, tx ...*gorm.DB)) *MockDao_GetFileEntryById_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetFileEntryById_Call) Return(fileEntry *mod.FileEntry, err error) *MockDao_GetFileEntryById_Call {
+	_c.Call.Return(fileEntry, err)
+	return _c
+}
+
+func (_c *MockDao_GetFileEntryById_Call) RunAndReturn(run func(fileId int, tx ...*gorm.DB) (*mod.FileEntry, error)) *MockDao_GetFileEntryById_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFileFailures provides a mock function for the type MockDao
+func (_mock *MockDao) GetFileFailures(fileIds []int, tx ...*gorm.DB) (map[int]mod.FileFailure, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(fileIds, tx)
+	} else {
+		tmpRet = _mock.Called(fileIds)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFileFailures")
+	}
+
+	var r0 map[int]mod.FileFailure
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) (map[int]mod.FileFailure, error)); ok {
+		return returnFunc(fileIds, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) map[int]mod.FileFailure); ok {
+		r0 = returnFunc(fileIds, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int]mod.FileFailure)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func([]int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(fileIds, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetFileFailures_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFileFailures'
+type MockDao_GetFileFailures_Call struct {
+	*mock.Call
+}
+
+// GetFileFailures is a helper method to define mock.On call
+//   - fileIds []int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetFileFailures(fileIds interface{}, tx ...interface{}) *MockDao_GetFileFailures_Call {
+	return &MockDao_GetFileFailures_Call{Call: _e.mock.On("GetFileFailures",
+		append([]interface{}{fileIds}, tx...)...)}
+}
+
+func (_c *MockDao_GetFileFailures_Call) Run(run func(fileIds []int, tx ...*gorm.DB)) *MockDao_GetFileFailures_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []int
+		if args[0] != nil {
+			arg0 = args[0].([]int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetFileFailures_Call) Return(intToFileFailure map[int]mod.FileFailure, err error) *MockDao_GetFileFailures_Call {
+	_c.Call.Return(intToFileFailure, err)
+	return _c
+}
+
+func (_c *MockDao_GetFileFailures_Call) RunAndReturn(run func(fileIds []int, tx ...*gorm.DB) (map[int]mod.FileFailure, error)) *MockDao_GetFileFailures_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFilesMissingThumbnails provides a mock function for the type MockDao
+func (_mock *MockDao) GetFilesMissingThumbnails(afterId int, limit int, tx ...*gorm.DB) ([]mod.AlbumFile, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(afterId, limit, tx)
+	} else {
+		tmpRet = _mock.Called(afterId, limit)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFilesMissingThumbnails")
+	}
+
+	var r0 []mod.AlbumFile
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, int, ...*gorm.DB) ([]mod.AlbumFile, error)); ok {
+		return returnFunc(afterId, limit, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, int, ...*gorm.DB) []mod.AlbumFile); ok {
+		r0 = returnFunc(afterId, limit, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.AlbumFile)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(afterId, limit, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetFilesMissingThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFilesMissingThumbnails'
+type MockDao_GetFilesMissingThumbnails_Call struct {
+	*mock.Call
+}
+
+// GetFilesMissingThumbnails is a helper method to define mock.On call
+//   - afterId int
+//   - limit int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetFilesMissingThumbnails(afterId interface{}, limit interface{}, tx ...interface{}) *MockDao_GetFilesMissingThumbnails_Call {
+	return &MockDao_GetFilesMissingThumbnails_Call{Call: _e.mock.On("GetFilesMissingThumbnails",
+		append([]interface{}{afterId, limit}, tx...)...)}
+}
+
+func (_c *MockDao_GetFilesMissingThumbnails_Call) Run(run func(afterId int, limit int, tx ...*gorm.DB)) *MockDao_GetFilesMissingThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetFilesMissingThumbnails_Call) Return(albumFiles []mod.AlbumFile, err error) *MockDao_GetFilesMissingThumbnails_Call {
+	_c.Call.Return(albumFiles, err)
+	return _c
+}
+
+func (_c *MockDao_GetFilesMissingThumbnails_Call) RunAndReturn(run func(afterId int, limit int, tx ...*gorm.DB) ([]mod.AlbumFile, error)) *MockDao_GetFilesMissingThumbnails_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetFocalPoints provides a mock function for the type MockDao
+func (_mock *MockDao) GetFocalPoints(fileIds []int, tx ...*gorm.DB) (map[int]mod.FocalPoint, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(fileIds, tx)
+	} else {
+		tmpRet = _mock.Called(fileIds)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFocalPoints")
+	}
+
+	var r0 map[int]mod.FocalPoint
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) (map[int]mod.FocalPoint, error)); ok {
+		return returnFunc(fileIds, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) map[int]mod.FocalPoint); ok {
+		r0 = returnFunc(fileIds, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int]mod.FocalPoint)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func([]int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(fileIds, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetFocalPoints_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFocalPoints'
+type MockDao_GetFocalPoints_Call struct {
+	*mock.Call
+}
+
+// GetFocalPoints is a helper method to define mock.On call
+//   - fileIds []int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetFocalPoints(fileIds interface{}, tx ...interface{}) *MockDao_GetFocalPoints_Call {
+	return &MockDao_GetFocalPoints_Call{Call: _e.mock.On("GetFocalPoints",
+		append([]interface{}{fileIds}, tx...)...)}
+}
+
+func (_c *MockDao_GetFocalPoints_Call) Run(run func(fileIds []int, tx ...*gorm.DB)) *MockDao_GetFocalPoints_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []int
+		if args[0] != nil {
+			arg0 = args[0].([]int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetFocalPoints_Call) Return(intToFocalPoint map[int]mod.FocalPoint, err error) *MockDao_GetFocalPoints_Call {
+	_c.Call.Return(intToFocalPoint, err)
+	return _c
+}
+
+func (_c *MockDao_GetFocalPoints_Call) RunAndReturn(run func(fileIds []int, tx ...*gorm.DB) (map[int]mod.FocalPoint, error)) *MockDao_GetFocalPoints_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetJob provides a mock function for the type MockDao
+func (_mock *MockDao) GetJob(jobId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(jobId, tx)
+	} else {
+		tmpRet = _mock.Called(jobId)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetJob")
+	}
+
+	var r0 *mod.ThumbnailJob
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) (*mod.ThumbnailJob, error)); ok {
+		return returnFunc(jobId, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) *mod.ThumbnailJob); ok {
+		r0 = returnFunc(jobId, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mod.ThumbnailJob)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(jobId, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetJob'
+type MockDao_GetJob_Call struct {
+	*mock.Call
+}
+
+// GetJob is a helper method to define mock.On call
+//   - jobId int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetJob(jobId interface{}, tx ...interface{}) *MockDao_GetJob_Call {
+	return &MockDao_GetJob_Call{Call: _e.mock.On("GetJob",
+		append([]interface{}{jobId}, tx...)...)}
+}
+
+func (_c *MockDao_GetJob_Call) Run(run func(jobId int, tx ...*gorm.DB)) *MockDao_GetJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetJob_Call) Return(thumbnailJob *mod.ThumbnailJob, err error) *MockDao_GetJob_Call {
+	_c.Call.Return(thumbnailJob, err)
+	return _c
+}
+
+func (_c *MockDao_GetJob_Call) RunAndReturn(run func(jobId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error)) *MockDao_GetJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetLatestJob provides a mock function for the type MockDao
+func (_mock *MockDao) GetLatestJob(albumId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(albumId, tx)
+	} else {
+		tmpRet = _mock.Called(albumId)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLatestJob")
+	}
+
+	var r0 *mod.ThumbnailJob
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) (*mod.ThumbnailJob, error)); ok {
+		return returnFunc(albumId, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) *mod.ThumbnailJob); ok {
+		r0 = returnFunc(albumId, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mod.ThumbnailJob)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(albumId, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetLatestJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetLatestJob'
+type MockDao_GetLatestJob_Call struct {
+	*mock.Call
+}
+
+// GetLatestJob is a helper method to define mock.On call
+//   - albumId int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetLatestJob(albumId interface{}, tx ...interface{}) *MockDao_GetLatestJob_Call {
+	return &MockDao_GetLatestJob_Call{Call: _e.mock.On("GetLatestJob",
+		append([]interface{}{albumId}, tx...)...)}
+}
+
+func (_c *MockDao_GetLatestJob_Call) Run(run func(albumId int, tx ...*gorm.DB)) *MockDao_GetLatestJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetLatestJob_Call) Return(thumbnailJob *mod.ThumbnailJob, err error) *MockDao_GetLatestJob_Call {
+	_c.Call.Return(thumbnailJob, err)
+	return _c
+}
+
+func (_c *MockDao_GetLatestJob_Call) RunAndReturn(run func(albumId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error)) *MockDao_GetLatestJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetQuarantinedFiles provides a mock function for the type MockDao
+func (_mock *MockDao) GetQuarantinedFiles(limit int, tx ...*gorm.DB) ([]mod.FileFailure, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(limit, tx)
+	} else {
+		tmpRet = _mock.Called(limit)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetQuarantinedFiles")
+	}
+
+	var r0 []mod.FileFailure
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) ([]mod.FileFailure, error)); ok {
+		return returnFunc(limit, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) []mod.FileFailure); ok {
+		r0 = returnFunc(limit, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.FileFailure)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(limit, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetQuarantinedFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetQuarantinedFiles'
+type MockDao_GetQuarantinedFiles_Call struct {
+	*mock.Call
+}
+
+// GetQuarantinedFiles is a helper method to define mock.On call
+//   - limit int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetQuarantinedFiles(limit interface{}, tx ...interface{}) *MockDao_GetQuarantinedFiles_Call {
+	return &MockDao_GetQuarantinedFiles_Call{Call: _e.mock.On("GetQuarantinedFiles",
+		append([]interface{}{limit}, tx...)...)}
+}
+
+func (_c *MockDao_GetQuarantinedFiles_Call) Run(run func(limit int, tx ...*gorm.DB)) *MockDao_GetQuarantinedFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetQuarantinedFiles_Call) Return(fileFailures []mod.FileFailure, err error) *MockDao_GetQuarantinedFiles_Call {
+	_c.Call.Return(fileFailures, err)
+	return _c
+}
+
+func (_c *MockDao_GetQuarantinedFiles_Call) RunAndReturn(run func(limit int, tx ...*gorm.DB) ([]mod.FileFailure, error)) *MockDao_GetQuarantinedFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetRevisedJob provides a mock function for the type MockDao
+func (_mock *MockDao) GetRevisedJob(jobId int, revision int, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(jobId, revision, tx)
+	} else {
+		tmpRet = _mock.Called(jobId, revision)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRevisedJob")
+	}
+
+	var r0 *mod.ThumbnailJob
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, int, ...*gorm.DB) (*mod.ThumbnailJob, error)); ok {
+		return returnFunc(jobId, revision, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, int, ...*gorm.DB) *mod.ThumbnailJob); ok {
+		r0 = returnFunc(jobId, revision, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mod.ThumbnailJob)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(jobId, revision, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetRevisedJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRevisedJob'
+type MockDao_GetRevisedJob_Call struct {
+	*mock.Call
+}
+
+// GetRevisedJob is a helper method to define mock.On call
+//   - jobId int
+//   - revision int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetRevisedJob(jobId interface{}, revision interface{}, tx ...interface{}) *MockDao_GetRevisedJob_Call {
+	return &MockDao_GetRevisedJob_Call{Call: _e.mock.On("GetRevisedJob",
+		append([]interface{}{jobId, revision}, tx...)...)}
+}
+
+func (_c *MockDao_GetRevisedJob_Call) Run(run func(jobId int, revision int, tx ...*gorm.DB)) *MockDao_GetRevisedJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetRevisedJob_Call) Return(thumbnailJob *mod.ThumbnailJob, err error) *MockDao_GetRevisedJob_Call {
+	_c.Call.Return(thumbnailJob, err)
+	return _c
+}
+
+func (_c *MockDao_GetRevisedJob_Call) RunAndReturn(run func(jobId int, revision int, tx ...*gorm.DB) (*mod.ThumbnailJob, error)) *MockDao_GetRevisedJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetStaleThumbnails provides a mock function for the type MockDao
+func (_mock *MockDao) GetStaleThumbnails(version int, afterId int, limit int, tx ...*gorm.DB) ([]mod.StaleThumbnail, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(version, afterId, limit, tx)
+	} else {
+		tmpRet = _mock.Called(version, afterId, limit)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStaleThumbnails")
+	}
+
+	var r0 []mod.StaleThumbnail
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, int, int, ...*gorm.DB) ([]mod.StaleThumbnail, error)); ok {
+		return returnFunc(version, afterId, limit, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, int, int, ...*gorm.DB) []mod.StaleThumbnail); ok {
+		r0 = returnFunc(version, afterId, limit, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.StaleThumbnail)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, int, int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(version, afterId, limit, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetStaleThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetStaleThumbnails'
+type MockDao_GetStaleThumbnails_Call struct {
+	*mock.Call
+}
+
+// GetStaleThumbnails is a helper method to define mock.On call
+//   - version int
+//   - afterId int
+//   - limit int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetStaleThumbnails(version interface{}, afterId interface{}, limit interface{}, tx ...interface{}) *MockDao_GetStaleThumbnails_Call {
+	return &MockDao_GetStaleThumbnails_Call{Call: _e.mock.On("GetStaleThumbnails",
+		append([]interface{}{version, afterId, limit}, tx...)...)}
+}
+
+func (_c *MockDao_GetStaleThumbnails_Call) Run(run func(version int, afterId int, limit int, tx ...*gorm.DB)) *MockDao_GetStaleThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		var arg3 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 3 {
+			variadicArgs = args[3].([]*gorm.DB)
+		}
+		arg3 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetStaleThumbnails_Call) Return(staleThumbnails []mod.StaleThumbnail, err error) *MockDao_GetStaleThumbnails_Call {
+	_c.Call.Return(staleThumbnails, err)
+	return _c
+}
+
+func (_c *MockDao_GetStaleThumbnails_Call) RunAndReturn(run func(version int, afterId int, limit int, tx ...*gorm.DB) ([]mod.StaleThumbnail, error)) *MockDao_GetStaleThumbnails_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetThumbnailFileIds provides a mock function for the type MockDao
+func (_mock *MockDao) GetThumbnailFileIds(fileIds []int, tx ...*gorm.DB) ([]int, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(fileIds, tx)
+	} else {
+		tmpRet = _mock.Called(fileIds)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetThumbnailFileIds")
+	}
+
+	var r0 []int
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) ([]int, error)); ok {
+		return returnFunc(fileIds, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) []int); ok {
+		r0 = returnFunc(fileIds, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func([]int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(fileIds, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetThumbnailFileIds_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetThumbnailFileIds'
+type MockDao_GetThumbnailFileIds_Call struct {
+	*mock.Call
+}
+
+// GetThumbnailFileIds is a helper method to define mock.On call
+//   - fileIds []int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetThumbnailFileIds(fileIds interface{}, tx ...interface{}) *MockDao_GetThumbnailFileIds_Call {
+	return &MockDao_GetThumbnailFileIds_Call{Call: _e.mock.On("GetThumbnailFileIds",
+		append([]interface{}{fileIds}, tx...)...)}
+}
+
+func (_c *MockDao_GetThumbnailFileIds_Call) Run(run func(fileIds []int, tx ...*gorm.DB)) *MockDao_GetThumbnailFileIds_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []int
+		if args[0] != nil {
+			arg0 = args[0].([]int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetThumbnailFileIds_Call) Return(ints []int, err error) *MockDao_GetThumbnailFileIds_Call {
+	_c.Call.Return(ints, err)
+	return _c
+}
+
+func (_c *MockDao_GetThumbnailFileIds_Call) RunAndReturn(run func(fileIds []int, tx ...*gorm.DB) ([]int, error)) *MockDao_GetThumbnailFileIds_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetThumbnailRowsByRenderKey provides a mock function for the type MockDao
+func (_mock *MockDao) GetThumbnailRowsByRenderKey(renderKeys []string, tx ...*gorm.DB) (map[string]mod.Thumbnail, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(renderKeys, tx)
+	} else {
+		tmpRet = _mock.Called(renderKeys)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetThumbnailRowsByRenderKey")
+	}
+
+	var r0 map[string]mod.Thumbnail
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]string, ...*gorm.DB) (map[string]mod.Thumbnail, error)); ok {
+		return returnFunc(renderKeys, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]string, ...*gorm.DB) map[string]mod.Thumbnail); ok {
+		r0 = returnFunc(renderKeys, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]mod.Thumbnail)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func([]string, ...*gorm.DB) error); ok {
+		r1 = returnFunc(renderKeys, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetThumbnailRowsByRenderKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetThumbnailRowsByRenderKey'
+type MockDao_GetThumbnailRowsByRenderKey_Call struct {
+	*mock.Call
+}
+
+// GetThumbnailRowsByRenderKey is a helper method to define mock.On call
+//   - renderKeys []string
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetThumbnailRowsByRenderKey(renderKeys interface{}, tx ...interface{}) *MockDao_GetThumbnailRowsByRenderKey_Call {
+	return &MockDao_GetThumbnailRowsByRenderKey_Call{Call: _e.mock.On("GetThumbnailRowsByRenderKey",
+		append([]interface{}{renderKeys}, tx...)...)}
+}
+
+func (_c *MockDao_GetThumbnailRowsByRenderKey_Call) Run(run func(renderKeys []string, tx ...*gorm.DB)) *MockDao_GetThumbnailRowsByRenderKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []string
+		if args[0] != nil {
+			arg0 = args[0].([]string)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetThumbnailRowsByRenderKey_Call) Return(stringToThumbnail map[string]mod.Thumbnail, err error) *MockDao_GetThumbnailRowsByRenderKey_Call {
+	_c.Call.Return(stringToThumbnail, err)
+	return _c
+}
+
+func (_c *MockDao_GetThumbnailRowsByRenderKey_Call) RunAndReturn(run func(renderKeys []string, tx ...*gorm.DB) (map[string]mod.Thumbnail, error)) *MockDao_GetThumbnailRowsByRenderKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetThumbnails provides a mock function for the type MockDao
+func (_mock *MockDao) GetThumbnails(fileIds []int, tx ...*gorm.DB) (map[int][]byte, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(fileIds, tx)
+	} else {
+		tmpRet = _mock.Called(fileIds)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetThumbnails")
+	}
+
+	var r0 map[int][]byte
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) (map[int][]byte, error)); ok {
+		return returnFunc(fileIds, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]int, ...*gorm.DB) map[int][]byte); ok {
+		r0 = returnFunc(fileIds, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int][]byte)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func([]int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(fileIds, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetThumbnails'
+type MockDao_GetThumbnails_Call struct {
+	*mock.Call
+}
+
+// GetThumbnails is a helper method to define mock.On call
+//   - fileIds []int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetThumbnails(fileIds interface{}, tx ...interface{}) *MockDao_GetThumbnails_Call {
+	return &MockDao_GetThumbnails_Call{Call: _e.mock.On("GetThumbnails",
+		append([]interface{}{fileIds}, tx...)...)}
+}
+
+func (_c *MockDao_GetThumbnails_Call) Run(run func(fileIds []int, tx ...*gorm.DB)) *MockDao_GetThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []int
+		if args[0] != nil {
+			arg0 = args[0].([]int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetThumbnails_Call) Return(intToBytes map[int][]byte, err error) *MockDao_GetThumbnails_Call {
+	_c.Call.Return(intToBytes, err)
+	return _c
+}
+
+func (_c *MockDao_GetThumbnails_Call) RunAndReturn(run func(fileIds []int, tx ...*gorm.DB) (map[int][]byte, error)) *MockDao_GetThumbnails_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetThumbnailsByRenderKey provides a mock function for the type MockDao
+func (_mock *MockDao) GetThumbnailsByRenderKey(renderKeys []string, tx ...*gorm.DB) (map[string][]byte, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(renderKeys, tx)
+	} else {
+		tmpRet = _mock.Called(renderKeys)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetThumbnailsByRenderKey")
+	}
+
+	var r0 map[string][]byte
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]string, ...*gorm.DB) (map[string][]byte, error)); ok {
+		return returnFunc(renderKeys, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]string, ...*gorm.DB) map[string][]byte); ok {
+		r0 = returnFunc(renderKeys, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]byte)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func([]string, ...*gorm.DB) error); ok {
+		r1 = returnFunc(renderKeys, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetThumbnailsByRenderKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetThumbnailsByRenderKey'
+type MockDao_GetThumbnailsByRenderKey_Call struct {
+	*mock.Call
+}
+
+// GetThumbnailsByRenderKey is a helper method to define mock.On call
+//   - renderKeys []string
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetThumbnailsByRenderKey(renderKeys interface{}, tx ...interface{}) *MockDao_GetThumbnailsByRenderKey_Call {
+	return &MockDao_GetThumbnailsByRenderKey_Call{Call: _e.mock.On("GetThumbnailsByRenderKey",
+		append([]interface{}{renderKeys}, tx...)...)}
+}
+
+func (_c *MockDao_GetThumbnailsByRenderKey_Call) Run(run func(renderKeys []string, tx ...*gorm.DB)) *MockDao_GetThumbnailsByRenderKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []string
+		if args[0] != nil {
+			arg0 = args[0].([]string)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetThumbnailsByRenderKey_Call) Return(stringToBytes map[string][]byte, err error) *MockDao_GetThumbnailsByRenderKey_Call {
+	_c.Call.Return(stringToBytes, err)
+	return _c
+}
+
+func (_c *MockDao_GetThumbnailsByRenderKey_Call) RunAndReturn(run func(renderKeys []string, tx ...*gorm.DB) (map[string][]byte, error)) *MockDao_GetThumbnailsByRenderKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUnfinishedJob provides a mock function for the type MockDao
+func (_mock *MockDao) GetUnfinishedJob(albumId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(albumId, tx)
+	} else {
+		tmpRet = _mock.Called(albumId)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUnfinishedJob")
+	}
+
+	var r0 *mod.ThumbnailJob
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) (*mod.ThumbnailJob, error)); ok {
+		return returnFunc(albumId, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) *mod.ThumbnailJob); ok {
+		r0 = returnFunc(albumId, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mod.ThumbnailJob)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(albumId, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_GetUnfinishedJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUnfinishedJob'
+type MockDao_GetUnfinishedJob_Call struct {
+	*mock.Call
+}
+
+// GetUnfinishedJob is a helper method to define mock.On call
+//   - albumId int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) GetUnfinishedJob(albumId interface{}, tx ...interface{}) *MockDao_GetUnfinishedJob_Call {
+	return &MockDao_GetUnfinishedJob_Call{Call: _e.mock.On("GetUnfinishedJob",
+		append([]interface{}{albumId}, tx...)...)}
+}
+
+func (_c *MockDao_GetUnfinishedJob_Call) Run(run func(albumId int, tx ...*gorm.DB)) *MockDao_GetUnfinishedJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_GetUnfinishedJob_Call) Return(thumbnailJob *mod.ThumbnailJob, err error) *MockDao_GetUnfinishedJob_Call {
+	_c.Call.Return(thumbnailJob, err)
+	return _c
+}
+
+func (_c *MockDao_GetUnfinishedJob_Call) RunAndReturn(run func(albumId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error)) *MockDao_GetUnfinishedJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// HasUnfinishedJob provides a mock function for the type MockDao
+func (_mock *MockDao) HasUnfinishedJob(albumId int, tx ...*gorm.DB) (bool, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(albumId, tx)
+	} else {
+		tmpRet = _mock.Called(albumId)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for HasUnfinishedJob")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) (bool, error)); ok {
+		return returnFunc(albumId, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, ...*gorm.DB) bool); ok {
+		r0 = returnFunc(albumId, tx...)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(albumId, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_HasUnfinishedJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HasUnfinishedJob'
+type MockDao_HasUnfinishedJob_Call struct {
+	*mock.Call
+}
+
+// HasUnfinishedJob is a helper method to define mock.On call
+//   - albumId int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) HasUnfinishedJob(albumId interface{}, tx ...interface{}) *MockDao_HasUnfinishedJob_Call {
+	return &MockDao_HasUnfinishedJob_Call{Call: _e.mock.On("HasUnfinishedJob",
+		append([]interface{}{albumId}, tx...)...)}
+}
+
+func (_c *MockDao_HasUnfinishedJob_Call) Run(run func(albumId int, tx ...*gorm.DB)) *MockDao_HasUnfinishedJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_HasUnfinishedJob_Call) Return(b bool, err error) *MockDao_HasUnfinishedJob_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockDao_HasUnfinishedJob_Call) RunAndReturn(run func(albumId int, tx ...*gorm.DB) (bool, error)) *MockDao_HasUnfinishedJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MoveThumbnails provides a mock function for the type MockDao
+func (_mock *MockDao) MoveThumbnails(afterId int, limit int, tx ...*gorm.DB) (MoveResult, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(afterId, limit, tx)
+	} else {
+		tmpRet = _mock.Called(afterId, limit)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveThumbnails")
+	}
+
+	var r0 MoveResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, int, ...*gorm.DB) (MoveResult, error)); ok {
+		return returnFunc(afterId, limit, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, int, ...*gorm.DB) MoveResult); ok {
+		r0 = returnFunc(afterId, limit, tx...)
+	} else {
+		r0 = ret.Get(0).(MoveResult)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, int, ...*gorm.DB) error); ok {
+		r1 = returnFunc(afterId, limit, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_MoveThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MoveThumbnails'
+type MockDao_MoveThumbnails_Call struct {
+	*mock.Call
+}
+
+// MoveThumbnails is a helper method to define mock.On call
+//   - afterId int
+//   - limit int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) MoveThumbnails(afterId interface{}, limit interface{}, tx ...interface{}) *MockDao_MoveThumbnails_Call {
+	return &MockDao_MoveThumbnails_Call{Call: _e.mock.On("MoveThumbnails",
+		append([]interface{}{afterId, limit}, tx...)...)}
+}
+
+func (_c *MockDao_MoveThumbnails_Call) Run(run func(afterId int, limit int, tx ...*gorm.DB)) *MockDao_MoveThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_MoveThumbnails_Call) Return(moveResult MoveResult, err error) *MockDao_MoveThumbnails_Call {
+	_c.Call.Return(moveResult, err)
+	return _c
+}
+
+func (_c *MockDao_MoveThumbnails_Call) RunAndReturn(run func(afterId int, limit int, tx ...*gorm.DB) (MoveResult, error)) *MockDao_MoveThumbnails_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReleaseJob provides a mock function for the type MockDao
+func (_mock *MockDao) ReleaseJob(job mod.ThumbnailJob, tx ...*gorm.DB) (bool, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(job, tx)
+	} else {
+		tmpRet = _mock.Called(job)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseJob")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(mod.ThumbnailJob, ...*gorm.DB) (bool, error)); ok {
+		return returnFunc(job, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(mod.ThumbnailJob, ...*gorm.DB) bool); ok {
+		r0 = returnFunc(job, tx...)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(mod.ThumbnailJob, ...*gorm.DB) error); ok {
+		r1 = returnFunc(job, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_ReleaseJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReleaseJob'
+type MockDao_ReleaseJob_Call struct {
+	*mock.Call
+}
+
+// ReleaseJob is a helper method to define mock.On call
+//   - job mod.ThumbnailJob
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) ReleaseJob(job interface{}, tx ...interface{}) *MockDao_ReleaseJob_Call {
+	return &MockDao_ReleaseJob_Call{Call: _e.mock.On("ReleaseJob",
+		append([]interface{}{job}, tx...)...)}
+}
+
+func (_c *MockDao_ReleaseJob_Call) Run(run func(job mod.ThumbnailJob, tx ...*gorm.DB)) *MockDao_ReleaseJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 mod.ThumbnailJob
+		if args[0] != nil {
+			arg0 = args[0].(mod.ThumbnailJob)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_ReleaseJob_Call) Return(b bool, err error) *MockDao_ReleaseJob_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockDao_ReleaseJob_Call) RunAndReturn(run func(job mod.ThumbnailJob, tx ...*gorm.DB) (bool, error)) *MockDao_ReleaseJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReleaseJobLeases provides a mock function for the type MockDao
+func (_mock *MockDao) ReleaseJobLeases(ownerPrefix string, tx ...*gorm.DB) (int64, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(ownerPrefix, tx)
+	} else {
+		tmpRet = _mock.Called(ownerPrefix)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseJobLeases")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string, ...*gorm.DB) (int64, error)); ok {
+		return returnFunc(ownerPrefix, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string, ...*gorm.DB) int64); ok {
+		r0 = returnFunc(ownerPrefix, tx...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string, ...*gorm.DB) error); ok {
+		r1 = returnFunc(ownerPrefix, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_ReleaseJobLeases_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReleaseJobLeases'
+type MockDao_ReleaseJobLeases_Call struct {
+	*mock.Call
+}
+
+// ReleaseJobLeases is a helper method to define mock.On call
+//   - ownerPrefix string
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) ReleaseJobLeases(ownerPrefix interface{}, tx ...interface{}) *MockDao_ReleaseJobLeases_Call {
+	return &MockDao_ReleaseJobLeases_Call{Call: _e.mock.On("ReleaseJobLeases",
+		append([]interface{}{ownerPrefix}, tx...)...)}
+}
+
+func (_c *MockDao_ReleaseJobLeases_Call) Run(run func(ownerPrefix string, tx ...*gorm.DB)) *MockDao_ReleaseJobLeases_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_ReleaseJobLeases_Call) Return(n int64, err error) *MockDao_ReleaseJobLeases_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockDao_ReleaseJobLeases_Call) RunAndReturn(run func(ownerPrefix string, tx ...*gorm.DB) (int64, error)) *MockDao_ReleaseJobLeases_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RenewJobLease provides a mock function for the type MockDao
+func (_mock *MockDao) RenewJobLease(jobId int, owner string, lease time.Duration, tx ...*gorm.DB) (bool, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(jobId, owner, lease, tx)
+	} else {
+		tmpRet = _mock.Called(jobId, owner, lease)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenewJobLease")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, string, time.Duration, ...*gorm.DB) (bool, error)); ok {
+		return returnFunc(jobId, owner, lease, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, string, time.Duration, ...*gorm.DB) bool); ok {
+		r0 = returnFunc(jobId, owner, lease, tx...)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, string, time.Duration, ...*gorm.DB) error); ok {
+		r1 = returnFunc(jobId, owner, lease, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_RenewJobLease_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RenewJobLease'
+type MockDao_RenewJobLease_Call struct {
+	*mock.Call
+}
+
+// RenewJobLease is a helper method to define mock.On call
+//   - jobId int
+//   - owner string
+//   - lease time.Duration
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) RenewJobLease(jobId interface{}, owner interface{}, lease interface{}, tx ...interface{}) *MockDao_RenewJobLease_Call {
+	return &MockDao_RenewJobLease_Call{Call: _e.mock.On("RenewJobLease",
+		append([]interface{}{jobId, owner, lease}, tx...)...)}
+}
+
+func (_c *MockDao_RenewJobLease_Call) Run(run func(jobId int, owner string, lease time.Duration, tx ...*gorm.DB)) *MockDao_RenewJobLease_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 time.Duration
+		if args[2] != nil {
+			arg2 = args[2].(time.Duration)
+		}
+		var arg3 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 3 {
+			variadicArgs = args[3].([]*gorm.DB)
+		}
+		arg3 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_RenewJobLease_Call) Return(b bool, err error) *MockDao_RenewJobLease_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockDao_RenewJobLease_Call) RunAndReturn(run func(jobId int, owner string, lease time.Duration, tx ...*gorm.DB) (bool, error)) *MockDao_RenewJobLease_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveBackfillCursor provides a mock function for the type MockDao
+func (_mock *MockDao) SaveBackfillCursor(name string, cursor int, tx ...*gorm.DB) error {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(name, cursor, tx)
+	} else {
+		tmpRet = _mock.Called(name, cursor)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveBackfillCursor")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, int, ...*gorm.DB) error); ok {
+		r0 = returnFunc(name, cursor, tx...)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockDao_SaveBackfillCursor_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveBackfillCursor'
+type MockDao_SaveBackfillCursor_Call struct {
+	*mock.Call
+}
+
+// SaveBackfillCursor is a helper method to define mock.On call
+//   - name string
+//   - cursor int
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) SaveBackfillCursor(name interface{}, cursor interface{}, tx ...interface{}) *MockDao_SaveBackfillCursor_Call {
+	return &MockDao_SaveBackfillCursor_Call{Call: _e.mock.On("SaveBackfillCursor",
+		append([]interface{}{name, cursor}, tx...)...)}
+}
+
+func (_c *MockDao_SaveBackfillCursor_Call) Run(run func(name string, cursor int, tx ...*gorm.DB)) *MockDao_SaveBackfillCursor_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_SaveBackfillCursor_Call) Return(err error) *MockDao_SaveBackfillCursor_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockDao_SaveBackfillCursor_Call) RunAndReturn(run func(name string, cursor int, tx ...*gorm.DB) error) *MockDao_SaveBackfillCursor_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveCallbackDelivery provides a mock function for the type MockDao
+func (_mock *MockDao) SaveCallbackDelivery(delivery *mod.CallbackDelivery, tx ...*gorm.DB) error {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(delivery, tx)
+	} else {
+		tmpRet = _mock.Called(delivery)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveCallbackDelivery")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(*mod.CallbackDelivery, ...*gorm.DB) error); ok {
+		r0 = returnFunc(delivery, tx...)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockDao_SaveCallbackDelivery_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveCallbackDelivery'
+type MockDao_SaveCallbackDelivery_Call struct {
+	*mock.Call
+}
+
+// SaveCallbackDelivery is a helper method to define mock.On call
+//   - delivery *mod.CallbackDelivery
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) SaveCallbackDelivery(delivery interface{}, tx ...interface{}) *MockDao_SaveCallbackDelivery_Call {
+	return &MockDao_SaveCallbackDelivery_Call{Call: _e.mock.On("SaveCallbackDelivery",
+		append([]interface{}{delivery}, tx...)...)}
+}
+
+func (_c *MockDao_SaveCallbackDelivery_Call) Run(run func(delivery *mod.CallbackDelivery, tx ...*gorm.DB)) *MockDao_SaveCallbackDelivery_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *mod.CallbackDelivery
+		if args[0] != nil {
+			arg0 = args[0].(*mod.CallbackDelivery)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_SaveCallbackDelivery_Call) Return(err error) *MockDao_SaveCallbackDelivery_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockDao_SaveCallbackDelivery_Call) RunAndReturn(run func(delivery *mod.CallbackDelivery, tx ...*gorm.DB) error) *MockDao_SaveCallbackDelivery_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveFileFailure provides a mock function for the type MockDao
+func (_mock *MockDao) SaveFileFailure(failure *mod.FileFailure, tx ...*gorm.DB) error {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(failure, tx)
+	} else {
+		tmpRet = _mock.Called(failure)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveFileFailure")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(*mod.FileFailure, ...*gorm.DB) error); ok {
+		r0 = returnFunc(failure, tx...)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockDao_SaveFileFailure_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveFileFailure'
+type MockDao_SaveFileFailure_Call struct {
+	*mock.Call
+}
+
+// SaveFileFailure is a helper method to define mock.On call
+//   - failure *mod.FileFailure
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) SaveFileFailure(failure interface{}, tx ...interface{}) *MockDao_SaveFileFailure_Call {
+	return &MockDao_SaveFileFailure_Call{Call: _e.mock.On("SaveFileFailure",
+		append([]interface{}{failure}, tx...)...)}
+}
+
+func (_c *MockDao_SaveFileFailure_Call) Run(run func(failure *mod.FileFailure, tx ...*gorm.DB)) *MockDao_SaveFileFailure_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *mod.FileFailure
+		if args[0] != nil {
+			arg0 = args[0].(*mod.FileFailure)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_SaveFileFailure_Call) Return(err error) *MockDao_SaveFileFailure_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockDao_SaveFileFailure_Call) RunAndReturn(run func(failure *mod.FileFailure, tx ...*gorm.DB) error) *MockDao_SaveFileFailure_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveThumbnails provides a mock function for the type MockDao
+func (_mock *MockDao) SaveThumbnails(thumbnails []mod.Thumbnail, tx ...*gorm.DB) ([]mod.Thumbnail, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(thumbnails, tx)
+	} else {
+		tmpRet = _mock.Called(thumbnails)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveThumbnails")
+	}
+
+	var r0 []mod.Thumbnail
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]mod.Thumbnail, ...*gorm.DB) ([]mod.Thumbnail, error)); ok {
+		return returnFunc(thumbnails, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]mod.Thumbnail, ...*gorm.DB) []mod.Thumbnail); ok {
+		r0 = returnFunc(thumbnails, tx...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.Thumbnail)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func([]mod.Thumbnail, ...*gorm.DB) error); ok {
+		r1 = returnFunc(thumbnails, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_SaveThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveThumbnails'
+type MockDao_SaveThumbnails_Call struct {
+	*mock.Call
+}
+
+// SaveThumbnails is a helper method to define mock.On call
+//   - thumbnails []mod.Thumbnail
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) SaveThumbnails(thumbnails interface{}, tx ...interface{}) *MockDao_SaveThumbnails_Call {
+	return &MockDao_SaveThumbnails_Call{Call: _e.mock.On("SaveThumbnails",
+		append([]interface{}{thumbnails}, tx...)...)}
+}
+
+func (_c *MockDao_SaveThumbnails_Call) Run(run func(thumbnails []mod.Thumbnail, tx ...*gorm.DB)) *MockDao_SaveThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []mod.Thumbnail
+		if args[0] != nil {
+			arg0 = args[0].([]mod.Thumbnail)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_SaveThumbnails_Call) Return(thumbnails1 []mod.Thumbnail, err error) *MockDao_SaveThumbnails_Call {
+	_c.Call.Return(thumbnails1, err)
+	return _c
+}
+
+func (_c *MockDao_SaveThumbnails_Call) RunAndReturn(run func(thumbnails []mod.Thumbnail, tx ...*gorm.DB) ([]mod.Thumbnail, error)) *MockDao_SaveThumbnails_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFileSize provides a mock function for the type MockDao
+func (_mock *MockDao) SetFileSize(token uuid.UUID, size int64, tx ...*gorm.DB) error {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(token, size, tx)
+	} else {
+		tmpRet = _mock.Called(token, size)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFileSize")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, int64, ...*gorm.DB) error); ok {
+		r0 = returnFunc(token, size, tx...)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockDao_SetFileSize_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFileSize'
+type MockDao_SetFileSize_Call struct {
+	*mock.Call
+}
+
+// SetFileSize is a helper method to define mock.On call
+//   - token uuid.UUID
+//   - size int64
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) SetFileSize(token interface{}, size interface{}, tx ...interface{}) *MockDao_SetFileSize_Call {
+	return &MockDao_SetFileSize_Call{Call: _e.mock.On("SetFileSize",
+		append([]interface{}{token, size}, tx...)...)}
+}
+
+func (_c *MockDao_SetFileSize_Call) Run(run func(token uuid.UUID, size int64, tx ...*gorm.DB)) *MockDao_SetFileSize_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 uuid.UUID
+		if args[0] != nil {
+			arg0 = args[0].(uuid.UUID)
+		}
+		var arg1 int64
+		if args[1] != nil {
+			arg1 = args[1].(int64)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_SetFileSize_Call) Return(err error) *MockDao_SetFileSize_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockDao_SetFileSize_Call) RunAndReturn(run func(token uuid.UUID, size int64, tx ...*gorm.DB) error) *MockDao_SetFileSize_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFocalPoint provides a mock function for the type MockDao
+func (_mock *MockDao) SetFocalPoint(token uuid.UUID, focalPoint mod.FocalPoint, tx ...*gorm.DB) (bool, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(token, focalPoint, tx)
+	} else {
+		tmpRet = _mock.Called(token, focalPoint)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFocalPoint")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, mod.FocalPoint, ...*gorm.DB) (bool, error)); ok {
+		return returnFunc(token, focalPoint, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, mod.FocalPoint, ...*gorm.DB) bool); ok {
+		r0 = returnFunc(token, focalPoint, tx...)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(uuid.UUID, mod.FocalPoint, ...*gorm.DB) error); ok {
+		r1 = returnFunc(token, focalPoint, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_SetFocalPoint_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFocalPoint'
+type MockDao_SetFocalPoint_Call struct {
+	*mock.Call
+}
+
+// SetFocalPoint is a helper method to define mock.On call
+//   - token uuid.UUID
+//   - focalPoint mod.FocalPoint
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) SetFocalPoint(token interface{}, focalPoint interface{}, tx ...interface{}) *MockDao_SetFocalPoint_Call {
+	return &MockDao_SetFocalPoint_Call{Call: _e.mock.On("SetFocalPoint",
+		append([]interface{}{token, focalPoint}, tx...)...)}
+}
+
+func (_c *MockDao_SetFocalPoint_Call) Run(run func(token uuid.UUID, focalPoint mod.FocalPoint, tx ...*gorm.DB)) *MockDao_SetFocalPoint_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 uuid.UUID
+		if args[0] != nil {
+			arg0 = args[0].(uuid.UUID)
+		}
+		var arg1 mod.FocalPoint
+		if args[1] != nil {
+			arg1 = args[1].(mod.FocalPoint)
+		}
+		var arg2 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 2 {
+			variadicArgs = args[2].([]*gorm.DB)
+		}
+		arg2 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_SetFocalPoint_Call) Return(b bool, err error) *MockDao_SetFocalPoint_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockDao_SetFocalPoint_Call) RunAndReturn(run func(token uuid.UUID, focalPoint mod.FocalPoint, tx ...*gorm.DB) (bool, error)) *MockDao_SetFocalPoint_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateJobFiles provides a mock function for the type MockDao
+func (_mock *MockDao) UpdateJobFiles(jobId int, revision int, files string, tx ...*gorm.DB) (bool, error) {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(jobId, revision, files, tx)
+	} else {
+		tmpRet = _mock.Called(jobId, revision, files)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateJobFiles")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, int, string, ...*gorm.DB) (bool, error)); ok {
+		return returnFunc(jobId, revision, files, tx...)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, int, string, ...*gorm.DB) bool); ok {
+		r0 = returnFunc(jobId, revision, files, tx...)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, int, string, ...*gorm.DB) error); ok {
+		r1 = returnFunc(jobId, revision, files, tx...)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockDao_UpdateJobFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateJobFiles'
+type MockDao_UpdateJobFiles_Call struct {
+	*mock.Call
+}
+
+// UpdateJobFiles is a helper method to define mock.On call
+//   - jobId int
+//   - revision int
+//   - files string
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) UpdateJobFiles(jobId interface{}, revision interface{}, files interface{}, tx ...interface{}) *MockDao_UpdateJobFiles_Call {
+	return &MockDao_UpdateJobFiles_Call{Call: _e.mock.On("UpdateJobFiles",
+		append([]interface{}{jobId, revision, files}, tx...)...)}
+}
+
+func (_c *MockDao_UpdateJobFiles_Call) Run(run func(jobId int, revision int, files string, tx ...*gorm.DB)) *MockDao_UpdateJobFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 3 {
+			variadicArgs = args[3].([]*gorm.DB)
+		}
+		arg3 = variadicArgs
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_UpdateJobFiles_Call) Return(b bool, err error) *MockDao_UpdateJobFiles_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockDao_UpdateJobFiles_Call) RunAndReturn(run func(jobId int, revision int, files string, tx ...*gorm.DB) (bool, error)) *MockDao_UpdateJobFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateThumbnails provides a mock function for the type MockDao
+func (_mock *MockDao) UpdateThumbnails(thumbnails []mod.Thumbnail, tx ...*gorm.DB) error {
+	var tmpRet mock.Arguments
+	if len(tx) > 0 {
+		tmpRet = _mock.Called(thumbnails, tx)
+	} else {
+		tmpRet = _mock.Called(thumbnails)
+	}
+	ret := tmpRet
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateThumbnails")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func([]mod.Thumbnail, ...*gorm.DB) error); ok {
+		r0 = returnFunc(thumbnails, tx...)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockDao_UpdateThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateThumbnails'
+type MockDao_UpdateThumbnails_Call struct {
+	*mock.Call
+}
+
+// UpdateThumbnails is a helper method to define mock.On call
+//   - thumbnails []mod.Thumbnail
+//   - tx ...*gorm.DB
+func (_e *MockDao_Expecter) UpdateThumbnails(thumbnails interface{}, tx ...interface{}) *MockDao_UpdateThumbnails_Call {
+	return &MockDao_UpdateThumbnails_Call{Call: _e.mock.On("UpdateThumbnails",
+		append([]interface{}{thumbnails}, tx...)...)}
+}
+
+func (_c *MockDao_UpdateThumbnails_Call) Run(run func(thumbnails []mod.Thumbnail, tx ...*gorm.DB)) *MockDao_UpdateThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []mod.Thumbnail
+		if args[0] != nil {
+			arg0 = args[0].([]mod.Thumbnail)
+		}
+		var arg1 []*gorm.DB
+		var variadicArgs []*gorm.DB
+		if len(args) > 1 {
+			variadicArgs = args[1].([]*gorm.DB)
+		}
+		arg1 = variadicArgs
+		run(
+			arg0,
+			arg1...,
+		)
+	})
+	return _c
+}
+
+func (_c *MockDao_UpdateThumbnails_Call) Return(err error) *MockDao_UpdateThumbnails_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockDao_UpdateThumbnails_Call) RunAndReturn(run func(thumbnails []mod.Thumbnail, tx ...*gorm.DB) error) *MockDao_UpdateThumbnails_Call {
 	_c.Call.Return(run)
 	return _c
 }
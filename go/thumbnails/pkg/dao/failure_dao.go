@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FailureDao interface {
+	GetFileFailures(fileIds []int, tx ...*gorm.DB) (map[int]mod.FileFailure, error)
+	SaveFileFailure(failure *mod.FileFailure, tx ...*gorm.DB) error
+	DeleteFileFailures(fileIds []int, tx ...*gorm.DB) (int64, error)
+	GetQuarantinedFiles(limit int, tx ...*gorm.DB) ([]mod.FileFailure, error)
+	DeleteQuarantinedFiles(tx ...*gorm.DB) (int64, error)
+}
+
+// GetFileFailures returns the failures of the given files keyed by file id, files that never failed are omitted
+func (d dao) GetFileFailures(fileIds []int, tx ...*gorm.DB) (map[int]mod.FileFailure, error) {
+	failures := make(map[int]mod.FileFailure)
+	if len(fileIds) == 0 {
+		return failures, nil
+	}
+
+	var rows []mod.FileFailure
+	err := d.getDb(tx...).
+		Model(&mod.FileFailure{}).
+		Where(`"fileId" IN ?`, fileIds).
+		Find(&rows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		failures[row.FileId] = row
+	}
+	return failures, nil
+}
+
+// SaveFileFailure upserts the failure of a file on its fileId, so the duplicates of a file failing at the same time
+// don't insert it twice
+func (d dao) SaveFileFailure(failure *mod.FileFailure, tx ...*gorm.DB) error {
+	return d.getDb(tx...).
+		Omit("id").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "fileId"}},
+			DoUpdates: clause.AssignmentColumns([]string{"errorClass", "attempts", "lastError", "nextAttemptAt", "quarantined", "updatedAt"}),
+		}).
+		Create(failure).
+		Error
+}
+
+// DeleteFileFailures resets the failures of the given files, quarantined or not
+func (d dao) DeleteFileFailures(fileIds []int, tx ...*gorm.DB) (int64, error) {
+	if len(fileIds) == 0 {
+		return 0, nil
+	}
+	result := d.getDb(tx...).
+		Where(`"fileId" IN ?`, fileIds).
+		Delete(&mod.FileFailure{})
+	return result.RowsAffected, result.Error
+}
+
+// GetQuarantinedFiles returns the failures of the quarantined files, most recently failed first
+func (d dao) GetQuarantinedFiles(limit int, tx ...*gorm.DB) ([]mod.FileFailure, error) {
+	var failures []mod.FileFailure
+	err := d.getDb(tx...).
+		Model(&mod.FileFailure{}).
+		Where(`"quarantined" = ?`, true).
+		Order(`"updatedAt" DESC`).
+		Limit(limit).
+		Find(&failures).
+		Error
+	return failures, err
+}
+
+// DeleteQuarantinedFiles resets the failures of every quarantined file
+func (d dao) DeleteQuarantinedFiles(tx ...*gorm.DB) (int64, error) {
+	result := d.getDb(tx...).
+		Where(`"quarantined" = ?`, true).
+		Delete(&mod.FileFailure{})
+	return result.RowsAffected, result.Error
+}
@@ -1,13 +1,24 @@
 package dao
 
 import (
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
 	"gorm.io/gorm"
 )
 
 type FileEntryDao interface {
 	GetFileEntry(token uuid.UUID, tx ...*gorm.DB) (*mod.FileEntry, error)
+	GetFileEntryById(fileId int, tx ...*gorm.DB) (*mod.FileEntry, error)
+	GetAlbumFileEntries(albumId int, tx ...*gorm.DB) ([]mod.FileEntry, error)
+	GetAlbumThumbnailFiles(albumId int, fileIds []int, tx ...*gorm.DB) ([]mod.FileEntry, error)
+	GetFilesMissingThumbnails(afterId int, limit int, tx ...*gorm.DB) ([]mod.AlbumFile, error)
+	CountFilesMissingThumbnails(afterId int, tx ...*gorm.DB) (int64, error)
+	GetFocalPoints(fileIds []int, tx ...*gorm.DB) (map[int]mod.FocalPoint, error)
+	SetFocalPoint(token uuid.UUID, focalPoint mod.FocalPoint, tx ...*gorm.DB) (bool, error)
+	SetFileSize(token uuid.UUID, size int64, tx ...*gorm.DB) error
 }
 
 func (d dao) GetFileEntry(token uuid.UUID, tx ...*gorm.DB) (*mod.FileEntry, error) {
@@ -22,3 +33,178 @@ func (d dao) GetFileEntry(token uuid.UUID, tx ...*gorm.DB) (*mod.FileEntry, erro
 	}
 	return &fileEntry, nil
 }
+
+func (d dao) GetFileEntryById(fileId int, tx ...*gorm.DB) (*mod.FileEntry, error) {
+	var fileEntry mod.FileEntry
+	err := d.getDb(tx...).
+		Model(&fileEntry).
+		Where(`"id" = ?`, fileId).
+		First(&fileEntry).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return &fileEntry, nil
+}
+
+// GetAlbumFileEntries returns the id and token of every file in an album, gorm.ErrRecordNotFound is returned if the
+// album does not exist
+func (d dao) GetAlbumFileEntries(albumId int, tx ...*gorm.DB) ([]mod.FileEntry, error) {
+	db := d.getDb(tx...)
+
+	var album mod.Album
+	err := db.
+		Model(&album).
+		Where(`"id" = ?`, albumId).
+		First(&album).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	var fileEntries []mod.FileEntry
+	err = db.
+		Model(&mod.FileEntry{}).
+		Select(`"id"`, `"token"`).
+		Where(`"albumToken" = ?`, album.AlbumToken).
+		Find(&fileEntries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return fileEntries, nil
+}
+
+// GetAlbumThumbnailFiles returns the files of an album that can get a thumbnail, or only the given ones when fileIds
+// isn't empty. files of other albums, expired files and encrypted or password protected files are left out.
+// gorm.ErrRecordNotFound is returned if the album does not exist
+func (d dao) GetAlbumThumbnailFiles(albumId int, fileIds []int, tx ...*gorm.DB) ([]mod.FileEntry, error) {
+	db := d.getDb(tx...)
+
+	var album mod.Album
+	err := db.
+		Model(&album).
+		Where(`"id" = ?`, albumId).
+		First(&album).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.
+		Model(&mod.FileEntry{}).
+		Where(`"albumToken" = ?`, album.AlbumToken).
+		Where(`"encrypted" = ?`, false).
+		Scopes(unexpired)
+	if len(fileIds) > 0 {
+		query = query.Where(`"id" IN ?`, fileIds)
+	}
+	var fileEntries []mod.FileEntry
+	err = query.
+		Order(`"id"`).
+		Find(&fileEntries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	// the password is part of the settings JSON, so it is checked here rather than in the query
+	return lo.Reject(fileEntries, func(fileEntry mod.FileEntry, _ int) bool {
+		return fileEntry.Protected()
+	}), nil
+}
+
+// GetFilesMissingThumbnails returns up to limit album files with an id above afterId that have no stored thumbnail,
+// ordered by id. encrypted and expired files are left out, whether the file type is supported is up to the caller
+func (d dao) GetFilesMissingThumbnails(afterId int, limit int, tx ...*gorm.DB) ([]mod.AlbumFile, error) {
+	var albumFiles []mod.AlbumFile
+	err := d.getDb(tx...).
+		Scopes(missingThumbnails(afterId)).
+		Select(`"file_upload_model".*`, `"album_model"."id" AS "albumId"`).
+		Order(`"file_upload_model"."id"`).
+		Limit(limit).
+		Scan(&albumFiles).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return albumFiles, nil
+}
+
+// CountFilesMissingThumbnails counts the files GetFilesMissingThumbnails would page through after afterId
+func (d dao) CountFilesMissingThumbnails(afterId int, tx ...*gorm.DB) (int64, error) {
+	var count int64
+	err := d.getDb(tx...).
+		Scopes(missingThumbnails(afterId)).
+		Count(&count).
+		Error
+	return count, err
+}
+
+func missingThumbnails(afterId int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Model(&mod.FileEntry{}).
+			Joins(`JOIN "album_model" ON "album_model"."albumToken" = "file_upload_model"."albumToken"`).
+			Where(`"file_upload_model"."id" > ?`, afterId).
+			Where(`"file_upload_model"."encrypted" = ?`, false).
+			Scopes(unexpired).
+			Where(`NOT EXISTS (SELECT 1 FROM "thumbnail_cache_model" WHERE "thumbnail_cache_model"."fileId" = "file_upload_model"."id")`)
+	}
+}
+
+// unexpired leaves out the files that have expired, expires is the epoch milliseconds the Node service removes the file
+// at
+func unexpired(db *gorm.DB) *gorm.DB {
+	return db.Where(`("file_upload_model"."expires" IS NULL OR "file_upload_model"."expires" > ?)`, time.Now().UnixMilli())
+}
+
+// GetFocalPoints returns the focal points of the given files, keyed by file id. files without a focal point are omitted
+func (d dao) GetFocalPoints(fileIds []int, tx ...*gorm.DB) (map[int]mod.FocalPoint, error) {
+	focalPoints := make(map[int]mod.FocalPoint)
+	if len(fileIds) == 0 {
+		return focalPoints, nil
+	}
+
+	var fileEntries []mod.FileEntry
+	err := d.getDb(tx...).
+		Model(&mod.FileEntry{}).
+		Select(`"id"`, `"focalPointX"`, `"focalPointY"`).
+		Where(`"id" IN ?`, fileIds).
+		Where(`"focalPointX" IS NOT NULL AND "focalPointY" IS NOT NULL`).
+		Find(&fileEntries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fileEntry := range fileEntries {
+		if focalPoint := fileEntry.FocalPoint(); focalPoint != nil {
+			focalPoints[fileEntry.Id] = *focalPoint
+		}
+	}
+	return focalPoints, nil
+}
+
+// SetFocalPoint stores the focal point of a file, returning false if no file with that token exists
+func (d dao) SetFocalPoint(token uuid.UUID, focalPoint mod.FocalPoint, tx ...*gorm.DB) (bool, error) {
+	result := d.getDb(tx...).
+		Model(&mod.FileEntry{}).
+		Where("token = ?", token).
+		Updates(map[string]interface{}{
+			"focalPointX": focalPoint.X,
+			"focalPointY": focalPoint.Y,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetFileSize stores the size of a file after its contents were rewritten
+func (d dao) SetFileSize(token uuid.UUID, size int64, tx ...*gorm.DB) error {
+	return d.getDb(tx...).
+		Model(&mod.FileEntry{}).
+		Where("token = ?", token).
+		Update("fileSize", size).
+		Error
+}
@@ -0,0 +1,290 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+)
+
+// claimCandidates is the number of claimable jobs looked at per claim, other workers may take some of them first
+const claimCandidates = 10
+
+var (
+	unfinishedJobStatuses = []mod.JobStatus{mod.JobQueued, mod.JobRunning}
+	finishedJobStatuses   = []mod.JobStatus{mod.JobDone, mod.JobFailed, mod.JobCancelled}
+)
+
+type JobDao interface {
+	CreateJob(job *mod.ThumbnailJob, tx ...*gorm.DB) error
+	ClaimJob(owner string, lease time.Duration, tx ...*gorm.DB) (*mod.ThumbnailJob, error)
+	RenewJobLease(jobId int, owner string, lease time.Duration, tx ...*gorm.DB) (bool, error)
+	ReleaseJob(job mod.ThumbnailJob, tx ...*gorm.DB) (bool, error)
+	ReleaseJobLeases(ownerPrefix string, tx ...*gorm.DB) (int64, error)
+	CancelJobs(albumId int, discardPartial bool, tx ...*gorm.DB) (int64, error)
+	HasUnfinishedJob(albumId int, tx ...*gorm.DB) (bool, error)
+	GetUnfinishedJob(albumId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error)
+	UpdateJobFiles(jobId int, revision int, files string, tx ...*gorm.DB) (bool, error)
+	GetRevisedJob(jobId int, revision int, tx ...*gorm.DB) (*mod.ThumbnailJob, error)
+	GetJob(jobId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error)
+	GetLatestJob(albumId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error)
+	DeleteFinishedJobs(before time.Time, tx ...*gorm.DB) (int64, error)
+}
+
+// CreateJob queues a job to run straight away
+func (d dao) CreateJob(job *mod.ThumbnailJob, tx ...*gorm.DB) error {
+	job.Status = mod.JobQueued
+	job.RunAfter = time.Now().UTC()
+	return d.getDb(tx...).
+		Create(job).
+		Error
+}
+
+// ClaimJob leases the unfinished job that has waited the longest to owner, counting it as an attempt. nil is returned
+// when no job is due or every due job is leased
+func (d dao) ClaimJob(owner string, lease time.Duration, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	db := d.getDb(tx...)
+	now := time.Now().UTC()
+
+	var candidates []mod.ThumbnailJob
+	err := db.
+		Model(&mod.ThumbnailJob{}).
+		Select(`"id"`).
+		Scopes(claimable(now)).
+		Order(`"runAfter"`).
+		Limit(claimCandidates).
+		Find(&candidates).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		// the conditions are checked again, so only one worker can take the job
+		result := db.
+			Model(&mod.ThumbnailJob{}).
+			Where(`"id" = ?`, *candidate.Id).
+			Scopes(claimable(now)).
+			Updates(map[string]interface{}{
+				"status":     mod.JobRunning,
+				"leaseOwner": owner,
+				"leaseUntil": now.Add(lease),
+				"attempts":   gorm.Expr(`"attempts" + 1`),
+				"updatedAt":  now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		var job mod.ThumbnailJob
+		err := db.
+			Model(&job).
+			Where(`"id" = ?`, *candidate.Id).
+			First(&job).
+			Error
+		if err != nil {
+			return nil, err
+		}
+		return &job, nil
+	}
+	return nil, nil
+}
+
+func claimable(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Where(`"status" IN ?`, unfinishedJobStatuses).
+			Where(`"runAfter" <= ?`, now).
+			Where(`("leaseUntil" IS NULL OR "leaseUntil" < ?)`, now)
+	}
+}
+
+// RenewJobLease extends the lease of a running job, returning false if owner no longer holds it or the job was
+// cancelled
+func (d dao) RenewJobLease(jobId int, owner string, lease time.Duration, tx ...*gorm.DB) (bool, error) {
+	now := time.Now().UTC()
+	result := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"id" = ?`, jobId).
+		Where(`"leaseOwner" = ?`, owner).
+		Where(`"status" = ?`, mod.JobRunning).
+		Updates(map[string]interface{}{
+			"leaseUntil": now.Add(lease),
+			"updatedAt":  now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseJob stores the Status, RunAfter, LastError and Attempts of a job and gives up its lease, returning false if the lease
+// owner no longer holds it. a cancelled job can only be released as cancelled, and a job can only be released as done
+// when no files were merged into it after the Revision the worker ran
+func (d dao) ReleaseJob(job mod.ThumbnailJob, tx ...*gorm.DB) (bool, error) {
+	current := mod.JobRunning
+	if job.Status == mod.JobCancelled {
+		current = mod.JobCancelled
+	}
+	db := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"id" = ?`, *job.Id).
+		Where(`"leaseOwner" = ?`, job.LeaseOwner).
+		Where(`"status" = ?`, current)
+	if job.Status == mod.JobDone {
+		db = db.Where(`"revision" = ?`, job.Revision)
+	}
+	result := db.
+		Updates(map[string]interface{}{
+			"status":     job.Status,
+			"runAfter":   job.RunAfter.UTC(),
+			"lastError":  job.LastError,
+			"attempts":   job.Attempts,
+			"leaseOwner": nil,
+			"leaseUntil": nil,
+			"updatedAt":  time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseJobLeases gives up the leases of every owner starting with ownerPrefix, so the jobs a previous run of an
+// instance held are resumed without waiting for their leases to run out
+func (d dao) ReleaseJobLeases(ownerPrefix string, tx ...*gorm.DB) (int64, error) {
+	result := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"leaseOwner" LIKE ?`, ownerPrefix+"%").
+		Where(`"status" IN ?`, unfinishedJobStatuses).
+		Updates(map[string]interface{}{
+			"status":     mod.JobQueued,
+			"leaseOwner": nil,
+			"leaseUntil": nil,
+			"updatedAt":  time.Now().UTC(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// CancelJobs cancels the queued and running jobs of an album. the lease of a running job is kept, so its worker can
+// still release it once it has stopped
+func (d dao) CancelJobs(albumId int, discardPartial bool, tx ...*gorm.DB) (int64, error) {
+	result := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"albumId" = ?`, albumId).
+		Where(`"status" IN ?`, unfinishedJobStatuses).
+		Updates(map[string]interface{}{
+			"status":         mod.JobCancelled,
+			"discardPartial": discardPartial,
+			"updatedAt":      time.Now().UTC(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// HasUnfinishedJob reports whether an album has a queued or running job
+func (d dao) HasUnfinishedJob(albumId int, tx ...*gorm.DB) (bool, error) {
+	var count int64
+	err := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"albumId" = ?`, albumId).
+		Where(`"status" IN ?`, unfinishedJobStatuses).
+		Count(&count).
+		Error
+	return count > 0, err
+}
+
+// GetUnfinishedJob returns the last queued or running job of an album, nil is returned when the album has none
+func (d dao) GetUnfinishedJob(albumId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var jobs []mod.ThumbnailJob
+	err := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"albumId" = ?`, albumId).
+		Where(`"status" IN ?`, unfinishedJobStatuses).
+		Order(`"id" DESC`).
+		Limit(1).
+		Find(&jobs).
+		Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// UpdateJobFiles replaces the files of an unfinished job and raises its revision, returning false if the job finished
+// or its files were changed since revision
+func (d dao) UpdateJobFiles(jobId int, revision int, files string, tx ...*gorm.DB) (bool, error) {
+	result := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"id" = ?`, jobId).
+		Where(`"revision" = ?`, revision).
+		Where(`"status" IN ?`, unfinishedJobStatuses).
+		Updates(map[string]interface{}{
+			"files":     files,
+			"revision":  revision + 1,
+			"updatedAt": time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetRevisedJob returns a job if files were merged into it after revision, nil is returned when they weren't or the
+// job doesn't exist
+func (d dao) GetRevisedJob(jobId int, revision int, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var jobs []mod.ThumbnailJob
+	err := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"id" = ?`, jobId).
+		Where(`"revision" > ?`, revision).
+		Limit(1).
+		Find(&jobs).
+		Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// GetJob returns a job by id, nil is returned when it doesn't exist
+func (d dao) GetJob(jobId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var jobs []mod.ThumbnailJob
+	err := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"id" = ?`, jobId).
+		Limit(1).
+		Find(&jobs).
+		Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// GetLatestJob returns the last job queued for an album, nil is returned when the album has none
+func (d dao) GetLatestJob(albumId int, tx ...*gorm.DB) (*mod.ThumbnailJob, error) {
+	var jobs []mod.ThumbnailJob
+	err := d.getDb(tx...).
+		Model(&mod.ThumbnailJob{}).
+		Where(`"albumId" = ?`, albumId).
+		Order(`"id" DESC`).
+		Limit(1).
+		Find(&jobs).
+		Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// DeleteFinishedJobs removes the done, failed and cancelled jobs last updated before the given time
+func (d dao) DeleteFinishedJobs(before time.Time, tx ...*gorm.DB) (int64, error) {
+	result := d.getDb(tx...).
+		Where(`"status" IN ?`, finishedJobStatuses).
+		Where(`"updatedAt" < ?`, before.UTC()).
+		Delete(&mod.ThumbnailJob{})
+	return result.RowsAffected, result.Error
+}
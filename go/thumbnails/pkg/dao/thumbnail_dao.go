@@ -1,73 +1,425 @@
 package dao
 
 import (
-	"context"
-	"encoding/base64"
 	"fmt"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/store"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// thumbnailCacheTTL matches the TTL the Node service uses for the same keys
+const thumbnailCacheTTL = time.Hour * 24 * 365
+
 type ThumbnailDao interface {
 	SaveThumbnails(thumbnails []mod.Thumbnail, tx ...*gorm.DB) ([]mod.Thumbnail, error)
+	GetThumbnails(fileIds []int, tx ...*gorm.DB) (map[int][]byte, error)
+	GetThumbnailFileIds(fileIds []int, tx ...*gorm.DB) ([]int, error)
+	GetThumbnailsByRenderKey(renderKeys []string, tx ...*gorm.DB) (map[string][]byte, error)
+	GetThumbnailRowsByRenderKey(renderKeys []string, tx ...*gorm.DB) (map[string]mod.Thumbnail, error)
+	DeleteThumbnails(fileIds []int, tx ...*gorm.DB) (int64, error)
+	GetStaleThumbnails(version int, afterId int, limit int, tx ...*gorm.DB) ([]mod.StaleThumbnail, error)
+	UpdateThumbnails(thumbnails []mod.Thumbnail, tx ...*gorm.DB) error
+	MoveThumbnails(afterId int, limit int, tx ...*gorm.DB) (MoveResult, error)
+}
+
+// MoveResult describes a page of rows moved to the store's backend
+type MoveResult struct {
+	// LastId is the id of the last row looked at, 0 once no rows are left
+	LastId int
+	Moved  int
+	Failed int
 }
 
+// SaveThumbnails writes the Content of the thumbnails to the store and upserts their rows by file id, the row of a file
+// that already has a thumbnail is replaced and its updatedAt set
 func (d dao) SaveThumbnails(thumbnails []mod.Thumbnail, tx ...*gorm.DB) ([]mod.Thumbnail, error) {
-	err := d.getDb(tx...).
+	db := d.getDb(tx...)
+
+	fileIds := lo.Map(thumbnails, func(thumbnail mod.Thumbnail, _ int) int {
+		return thumbnail.FileId
+	})
+	var previous []mod.Thumbnail
+	err := db.
+		Model(&mod.Thumbnail{}).
+		Select(`"id"`, `"fileId"`, `"location"`).
+		Where(`"fileId" IN ?`, fileIds).
+		Find(&previous).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range thumbnails {
+		if err := d.store.Put(&thumbnails[i], thumbnails[i].Content); err != nil {
+			return nil, fmt.Errorf("failed to store thumbnail of file %d: %w", thumbnails[i].FileId, err)
+		}
+	}
+
+	// ON CONFLICT is understood by both postgres and sqlite, "fileId" has a unique index in both
+	err = db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "fileId"}},
+			DoUpdates: clause.AssignmentColumns([]string{"data", "blob", "location", "renderKey", "pipelineVersion", "crop", "updatedAt"}),
+		}).
 		Create(&thumbnails).
 		Error
 	if err != nil {
 		return nil, err
 	}
+	d.deleteUnreferenced(previous, tx...)
 	go func() {
-		err := d.storeRedis(thumbnails)
+		err := d.storeCache(thumbnails)
 		if err != nil {
-			log.Error().Err(err).Msg("failed to store thumbnails in Redis")
+			log.Error().Err(err).Msg("failed to store thumbnails in cache")
 		}
 	}()
 	return thumbnails, nil
 }
 
-func (d dao) storeRedis(thumbnails []mod.Thumbnail) error {
-	keyValuePairs := make(map[string]interface{}, len(thumbnails))
+func (d dao) storeCache(thumbnails []mod.Thumbnail) error {
+	keyValuePairs := make(map[string][]byte, len(thumbnails))
 	for _, thumbnail := range thumbnails {
-		key := fmt.Sprintf("thumbnail:%d", thumbnail.FileId)
-		value, err := base64.StdEncoding.DecodeString(thumbnail.Data)
-		if err != nil {
-			log.Error().Err(err).Int("fileId", thumbnail.FileId).Msg("failed to decode thumbnail data")
+		if len(thumbnail.Content) == 0 {
 			continue
 		}
-		keyValuePairs[key] = value
+		keyValuePairs[thumbnailCacheKey(thumbnail.FileId)] = thumbnail.Content
 	}
 
 	if len(keyValuePairs) == 0 {
-		log.Warn().Msg("no valid thumbnails to store in Redis")
+		log.Warn().Msg("no valid thumbnails to store in cache")
 		return nil
 	}
 
-	_, err := d.redisClient.MSet(context.Background(), keyValuePairs).Result()
+	return d.cache.SetMany(keyValuePairs, thumbnailCacheTTL)
+}
+
+// GetThumbnails returns the stored thumbnails of the given files keyed by file id. the cache is read first, and the
+// thumbnails only found in the store are put back into it. files without a thumbnail are omitted
+func (d dao) GetThumbnails(fileIds []int, tx ...*gorm.DB) (map[int][]byte, error) {
+	thumbnails := make(map[int][]byte, len(fileIds))
+	if len(fileIds) == 0 {
+		return thumbnails, nil
+	}
+
+	keys := lo.Map(fileIds, func(fileId int, _ int) string {
+		return thumbnailCacheKey(fileId)
+	})
+	cached, err := d.cache.GetMany(keys)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to store thumbnails in Redis")
-		return err
+		log.Error().Err(err).Msg("failed to get thumbnails from cache")
+	}
+
+	var missing []int
+	for i, fileId := range fileIds {
+		if i < len(cached) && len(cached[i]) > 0 {
+			thumbnails[fileId] = cached[i]
+			continue
+		}
+		missing = append(missing, fileId)
+	}
+	if len(missing) == 0 {
+		return thumbnails, nil
+	}
+
+	var stored []mod.Thumbnail
+	err = d.getDb(tx...).
+		Model(&mod.Thumbnail{}).
+		Where(`"fileId" IN ?`, missing).
+		Find(&stored).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	repopulate := make(map[string][]byte, len(stored))
+	for _, thumbnail := range stored {
+		value, err := d.store.Get(thumbnail)
+		if err != nil {
+			log.Error().Err(err).Int("fileId", thumbnail.FileId).Msg("failed to read thumbnail from store")
+			continue
+		}
+		thumbnails[thumbnail.FileId] = value
+		repopulate[thumbnailCacheKey(thumbnail.FileId)] = value
+	}
+
+	if len(repopulate) > 0 {
+		if err := d.cache.SetMany(repopulate, thumbnailCacheTTL); err != nil {
+			log.Error().Err(err).Msg("failed to store thumbnails in cache")
+		}
+	}
+	return thumbnails, nil
+}
+
+// GetThumbnailFileIds returns the ids of the given files that have a stored thumbnail
+func (d dao) GetThumbnailFileIds(fileIds []int, tx ...*gorm.DB) ([]int, error) {
+	var stored []int
+	if len(fileIds) == 0 {
+		return stored, nil
 	}
+	err := d.getDb(tx...).
+		Model(&mod.Thumbnail{}).
+		Where(`"fileId" IN ?`, fileIds).
+		Pluck("fileId", &stored).
+		Error
+	return stored, err
+}
 
-	// Set expiration for each key
-	var expireErrors []error
-	for key := range keyValuePairs {
-		success, err := d.redisClient.Expire(context.Background(), key, time.Hour*24*365).Result()
+// GetThumbnailsByRenderKey returns a stored thumbnail for each render key that has one, keyed by render key. keys
+// without a readable thumbnail are omitted
+func (d dao) GetThumbnailsByRenderKey(renderKeys []string, tx ...*gorm.DB) (map[string][]byte, error) {
+	rows, err := d.GetThumbnailRowsByRenderKey(renderKeys, tx...)
+	if err != nil {
+		return nil, err
+	}
+	return lo.MapValues(rows, func(row mod.Thumbnail, _ string) []byte {
+		return row.Content
+	}), nil
+}
+
+// GetThumbnailRowsByRenderKey is GetThumbnailsByRenderKey returning the rows, with their Content read from the store
+func (d dao) GetThumbnailRowsByRenderKey(renderKeys []string, tx ...*gorm.DB) (map[string]mod.Thumbnail, error) {
+	rows := make(map[string]mod.Thumbnail, len(renderKeys))
+	if len(renderKeys) == 0 {
+		return rows, nil
+	}
+
+	db := d.getDb(tx...)
+	// every file with the same contents has a row, only the first of each key is read
+	firstIds := db.
+		Model(&mod.Thumbnail{}).
+		Select(`MIN("id")`).
+		Where(`"renderKey" IN ?`, lo.Uniq(renderKeys)).
+		Group(`"renderKey"`)
+
+	var stored []mod.Thumbnail
+	err := db.
+		Model(&mod.Thumbnail{}).
+		Where(`"id" IN (?)`, firstIds).
+		Find(&stored).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, thumbnail := range stored {
+		value, err := d.store.Get(thumbnail)
 		if err != nil {
-			expireErrors = append(expireErrors, err)
-		} else if !success {
-			expireErrors = append(expireErrors, fmt.Errorf("key %s does not exist", key))
+			log.Error().Err(err).Int("fileId", thumbnail.FileId).Msg("failed to read thumbnail from store")
+			continue
 		}
+		thumbnail.Content = value
+		rows[*thumbnail.RenderKey] = thumbnail
 	}
+	return rows, nil
+}
 
-	if len(expireErrors) > 0 {
-		return fmt.Errorf("failed to set expiration for %d keys", len(expireErrors))
+// DeleteThumbnails removes the stored thumbnails of the given files from the database, the store and the cache,
+// returning the number of rows deleted
+func (d dao) DeleteThumbnails(fileIds []int, tx ...*gorm.DB) (int64, error) {
+	if len(fileIds) == 0 {
+		return 0, nil
 	}
+	db := d.getDb(tx...)
 
+	var rows []mod.Thumbnail
+	err := db.
+		Model(&mod.Thumbnail{}).
+		Select(`"id"`, `"fileId"`, `"location"`).
+		Where(`"fileId" IN ?`, fileIds).
+		Find(&rows).
+		Error
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.
+		Where(`"fileId" IN ?`, fileIds).
+		Delete(&mod.Thumbnail{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	d.deleteUnreferenced(rows, tx...)
+
+	keys := lo.Map(fileIds, func(fileId int, _ int) string {
+		return thumbnailCacheKey(fileId)
+	})
+	if err := d.cache.Delete(keys...); err != nil {
+		return result.RowsAffected, err
+	}
+	return result.RowsAffected, nil
+}
+
+// GetStaleThumbnails returns up to limit thumbnails with an id above afterId that were rendered by a pipeline older than
+// version, ordered by id
+func (d dao) GetStaleThumbnails(version int, afterId int, limit int, tx ...*gorm.DB) ([]mod.StaleThumbnail, error) {
+	var stale []mod.StaleThumbnail
+	err := d.getDb(tx...).
+		Model(&mod.Thumbnail{}).
+		Joins(`JOIN "file_upload_model" ON "file_upload_model"."id" = "thumbnail_cache_model"."fileId"`).
+		Select(`"file_upload_model".*`, `"thumbnail_cache_model"."id" AS "thumbnailId"`, `"thumbnail_cache_model"."crop"`).
+		Where(`"thumbnail_cache_model"."pipelineVersion" < ?`, version).
+		Where(`"thumbnail_cache_model"."id" > ?`, afterId).
+		Order(`"thumbnail_cache_model"."id"`).
+		Limit(limit).
+		Scan(&stale).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return stale, nil
+}
+
+// UpdateThumbnails replaces the stored thumbnails of existing rows with their Content, along with the render key,
+// pipeline version and crop, and refreshes the cache
+func (d dao) UpdateThumbnails(thumbnails []mod.Thumbnail, tx ...*gorm.DB) error {
+	if len(thumbnails) == 0 {
+		return nil
+	}
+	db := d.getDb(tx...)
+
+	ids := lo.Map(thumbnails, func(thumbnail mod.Thumbnail, _ int) int {
+		return *thumbnail.Id
+	})
+	var previous []mod.Thumbnail
+	err := db.
+		Model(&mod.Thumbnail{}).
+		Select(`"id"`, `"fileId"`, `"location"`).
+		Where(`"id" IN ?`, ids).
+		Find(&previous).
+		Error
+	if err != nil {
+		return err
+	}
+
+	for i := range thumbnails {
+		thumbnail := &thumbnails[i]
+		if err := d.store.Put(thumbnail, thumbnail.Content); err != nil {
+			return fmt.Errorf("failed to store thumbnail of file %d: %w", thumbnail.FileId, err)
+		}
+		err := db.
+			Model(&mod.Thumbnail{}).
+			Where(`"id" = ?`, *thumbnail.Id).
+			Updates(map[string]interface{}{
+				"data":            thumbnail.Data,
+				"blob":            thumbnail.Blob,
+				"location":        thumbnail.Location,
+				"renderKey":       thumbnail.RenderKey,
+				"pipelineVersion": thumbnail.PipelineVersion,
+				"crop":            thumbnail.Crop,
+				"updatedAt":       time.Now(),
+			}).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+	d.deleteUnreferenced(previous, tx...)
+
+	if err := d.storeCache(thumbnails); err != nil {
+		log.Error().Err(err).Msg("failed to store thumbnails in cache")
+	}
 	return nil
 }
+
+// MoveThumbnails moves up to limit rows with an id above afterId to the backend the store writes to. rows that can't
+// be read are counted as failed and left where they are
+func (d dao) MoveThumbnails(afterId int, limit int, tx ...*gorm.DB) (MoveResult, error) {
+	db := d.getDb(tx...)
+
+	var rows []mod.Thumbnail
+	err := db.
+		Model(&mod.Thumbnail{}).
+		Where(`"id" > ?`, afterId).
+		Order(`"id"`).
+		Limit(limit).
+		Find(&rows).
+		Error
+	if err != nil {
+		return MoveResult{}, err
+	}
+
+	var result MoveResult
+	var moved []mod.Thumbnail
+	for _, row := range rows {
+		result.LastId = *row.Id
+		if store.BackendOf(row) == d.store.Backend() {
+			continue
+		}
+
+		data, err := d.store.Get(row)
+		if err != nil {
+			log.Error().Err(err).Int("fileId", row.FileId).Msg("failed to read thumbnail to move")
+			result.Failed++
+			continue
+		}
+		target := row
+		if err := d.store.Put(&target, data); err != nil {
+			return result, err
+		}
+
+		err = db.
+			Model(&mod.Thumbnail{}).
+			Where(`"id" = ?`, *row.Id).
+			Updates(map[string]interface{}{
+				"data":     target.Data,
+				"blob":     target.Blob,
+				"location": target.Location,
+			}).
+			Error
+		if err != nil {
+			return result, err
+		}
+		moved = append(moved, row)
+		result.Moved++
+	}
+
+	d.deleteUnreferenced(moved, tx...)
+	return result, nil
+}
+
+// deleteUnreferenced removes the objects of rows that no remaining row points at. objects are content addressed, so
+// files with identical thumbnails share one
+func (d dao) deleteUnreferenced(rows []mod.Thumbnail, tx ...*gorm.DB) {
+	rows = lo.UniqBy(lo.Filter(rows, func(row mod.Thumbnail, _ int) bool {
+		return row.Location != nil && *row.Location != ""
+	}), func(row mod.Thumbnail) string {
+		return *row.Location
+	})
+	if len(rows) == 0 {
+		return
+	}
+
+	locations := lo.Map(rows, func(row mod.Thumbnail, _ int) string {
+		return *row.Location
+	})
+	var referenced []string
+	err := d.getDb(tx...).
+		Model(&mod.Thumbnail{}).
+		Where(`"location" IN ?`, locations).
+		Distinct().
+		Pluck("location", &referenced).
+		Error
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find referenced thumbnails, leaving their objects in the store")
+		return
+	}
+
+	for _, row := range rows {
+		if lo.Contains(referenced, *row.Location) {
+			continue
+		}
+		if err := d.store.Delete(row); err != nil {
+			log.Error().Err(err).Str("location", *row.Location).Msg("failed to delete thumbnail from store")
+		}
+	}
+}
+
+// thumbnailCacheKey is the key the Node service reads album thumbnails from
+func thumbnailCacheKey(fileId int) string {
+	return fmt.Sprintf("thumbnail:%d", fileId)
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+// AlbumJobStatusDto is the progress of the latest thumbnail job of an album
+type AlbumJobStatusDto struct {
+	AlbumId    int              `json:"albumId" example:"12"`
+	JobId      int              `json:"jobId" example:"40"`
+	State      string           `json:"state" example:"running" enums:"queued,running,done,failed,cancelled"`
+	Attempt    int              `json:"attempt" example:"1" description:"Run of the job, retries start a new run"`
+	Total      int              `json:"total" example:"120" description:"Files of the job"`
+	Processed  int              `json:"processed" example:"80" description:"Files whose thumbnail was stored"`
+	Skipped    int              `json:"skipped" example:"3" description:"Files of an unsupported type"`
+	Failed     int              `json:"failed" example:"1" description:"Files that failed to render or store"`
+	Failures   []FileFailureDto `json:"failures,omitempty" description:"Reasons of the first 100 failed files"`
+	LastError  string           `json:"lastError,omitempty"`
+	StartedAt  *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"`
+	EtaSeconds int              `json:"etaSeconds,omitempty" example:"42" description:"Estimated seconds left of a running job"`
+}
+
+// FileFailureDto is a file of an album job that could not get a thumbnail
+type FileFailureDto struct {
+	FileId int    `json:"fileId" example:"7"`
+	Reason string `json:"reason" example:"unsupported file type"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+// BackfillStatusDto is the progress of the current or last thumbnail backfill run
+type BackfillStatusDto struct {
+	Running    bool       `json:"running" example:"true"`
+	Cursor     int        `json:"cursor" example:"1250" description:"Id of the last file handled"`
+	Remaining  int64      `json:"remaining" example:"4000" description:"Files without a thumbnail after the cursor when the run started"`
+	Processed  int        `json:"processed" example:"350" description:"Files handed to the batch processor"`
+	Skipped    int        `json:"skipped" example:"12" description:"Protected or unsupported files, and files of albums already being processed"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+}
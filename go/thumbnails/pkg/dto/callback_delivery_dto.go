@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+// CallbackDeliveryDto is an attempt at delivering the completion event of an album thumbnail job
+type CallbackDeliveryDto struct {
+	JobId      int       `json:"jobId" example:"40"`
+	DeliveryId string    `json:"deliveryId" example:"0b6c6f3e-2f1d-4a4e-9a51-3d8f0f1c2b7a" description:"Shared by every attempt of an event, sent in the X-Thumbnail-Delivery header"`
+	Target     string    `json:"target" example:"https://waifuvault.moe/rest/thumbnails/callback" description:"Callback URL, or redis:<channel> for the configured channel"`
+	Attempt    int       `json:"attempt" example:"1"`
+	StatusCode *int      `json:"statusCode,omitempty" example:"200" description:"Response status of the callback URL"`
+	Error      string    `json:"error,omitempty"`
+	Delivered  bool      `json:"delivered" example:"true"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -2,12 +2,14 @@ package dto
 
 import "github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
 
-// FileEntryDto represents a file entry for thumbnail generation
+// FileEntryDto represents a file entry for thumbnail generation, it is built from the file entry and never from a request
 type FileEntryDto struct {
-	Id                   int    `json:"id" example:"1" validate:"required" description:"Unique identifier for the file"`
-	FullFileNameOnSystem string `json:"fileOnDisk" example:"uploads/image.jpg" validate:"required" description:"Path to the file on the server"`
-	MediaType            string `json:"mediaType" example:"image/jpeg" validate:"required" description:"MIME type of the file"`
-	Extension            string `json:"extension" example:"jpg" validate:"required" description:"File extension"`
+	Id                   int             `json:"id" example:"1" validate:"required" description:"Unique identifier for the file"`
+	FullFileNameOnSystem string          `json:"fileOnDisk" example:"uploads/image.jpg" validate:"required" description:"Name of the file in the upload directory, read from the file entry"`
+	MediaType            string          `json:"mediaType" example:"image/jpeg" validate:"required" description:"MIME type of the file"`
+	Extension            string          `json:"extension" example:"jpg" validate:"required" description:"File extension"`
+	Checksum             string          `json:"checksum,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" description:"Checksum of the file, files with the same checksum share one rendered thumbnail"`
+	FocalPoint           *mod.FocalPoint `json:"-"`
 }
 
 func FromModel(model mod.FileEntry) FileEntryDto {
@@ -16,5 +18,7 @@ func FromModel(model mod.FileEntry) FileEntryDto {
 		FullFileNameOnSystem: model.FullFileNameOnSystem(),
 		MediaType:            model.MediaType,
 		Extension:            model.Extension,
+		Checksum:             model.Checksum,
+		FocalPoint:           model.FocalPoint(),
 	}
 }
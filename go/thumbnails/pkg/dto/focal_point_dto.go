@@ -0,0 +1,7 @@
+package dto
+
+// FocalPointDto is the payload used to set the focal point of a file
+type FocalPointDto struct {
+	X float64 `json:"x" example:"0.5" validate:"required" description:"Horizontal position of the focal point, from 0 (left) to 1 (right)"`
+	Y float64 `json:"y" example:"0.25" validate:"required" description:"Vertical position of the focal point, from 0 (top) to 1 (bottom)"`
+}
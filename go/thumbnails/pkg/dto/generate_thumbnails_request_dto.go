@@ -0,0 +1,7 @@
+package dto
+
+// GenerateThumbnailsRequestDto narrows an album thumbnail job to some of the album's files, the files are looked up by
+// the service so only files of the album can be named
+type GenerateThumbnailsRequestDto struct {
+	FileIds []int `json:"fileIds" example:"1,2,3" description:"Ids of the album files to render, every file of the album when empty"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+// PurgeResultDto describes what a purge removed
+type PurgeResultDto struct {
+	Files            int   `json:"files" example:"12"`
+	StoredThumbnails int64 `json:"storedThumbnails" example:"10"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+// QuarantinedFileDto is an album file the thumbnail jobs skip after it failed to render
+type QuarantinedFileDto struct {
+	FileId     int       `json:"fileId" example:"12"`
+	ErrorClass string    `json:"errorClass" example:"transient" enums:"transient,unsupported,missing" description:"transient files are quarantined once they run out of attempts, the other classes straight away"`
+	Attempts   int       `json:"attempts" example:"3"`
+	LastError  string    `json:"lastError" example:"VipsJpeg: premature end of JPEG file"`
+	FailedAt   time.Time `json:"failedAt" description:"When the file last failed"`
+}
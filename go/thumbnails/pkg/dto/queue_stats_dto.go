@@ -0,0 +1,19 @@
+package dto
+
+// QueueClassDto is the queue of a priority class of the thumbnail worker pool
+type QueueClassDto struct {
+	Queued        int   `json:"queued" example:"12" description:"Files waiting for a worker"`
+	Running       int   `json:"running" example:"3"`
+	Started       int64 `json:"started" example:"4200" description:"Files started since the instance started"`
+	OldestWaitMs  int64 `json:"oldestWaitMs" example:"850" description:"Milliseconds the file first in line has been waiting"`
+	AverageWaitMs int64 `json:"averageWaitMs" example:"120" description:"Mean milliseconds the started files waited for a worker"`
+}
+
+// QueueStatsDto is the thumbnail worker pool of an instance
+type QueueStatsDto struct {
+	Workers     int           `json:"workers" example:"4"`
+	InUseBytes  int64         `json:"inUseBytes" example:"268435456" description:"Estimated decode memory of the files being rendered"`
+	MaxBytes    int64         `json:"maxBytes" example:"1073741824" description:"Cap on the estimated decode memory, 0 when disabled"`
+	Interactive QueueClassDto `json:"interactive" description:"Thumbnails requests are waiting for"`
+	Batch       QueueClassDto `json:"batch" description:"Files of album jobs and the backfill"`
+}
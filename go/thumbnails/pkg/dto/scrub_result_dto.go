@@ -0,0 +1,18 @@
+package dto
+
+import "github.com/waifuvault/WaifuVault/thumbnails/pkg/scrub"
+
+// ScrubResultDto describes the metadata removed from a stored file
+type ScrubResultDto struct {
+	Format  string   `json:"format" example:"jpeg"`
+	Removed []string `json:"removed" example:"EXIF,XMP"`
+	Changed bool     `json:"changed" example:"true"`
+}
+
+func ScrubResultFromResult(result scrub.Result) ScrubResultDto {
+	return ScrubResultDto{
+		Format:  result.Format,
+		Removed: append([]string{}, result.Removed...),
+		Changed: result.Changed(),
+	}
+}
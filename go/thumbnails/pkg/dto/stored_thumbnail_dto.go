@@ -0,0 +1,19 @@
+package dto
+
+// ThumbnailBatchRequestDto lists the files to return stored thumbnails for
+type ThumbnailBatchRequestDto struct {
+	FileIds []int `json:"fileIds" example:"1,2,3" validate:"required"`
+}
+
+// StoredThumbnailsDto holds the stored thumbnails of a batch, Missing lists the requested files without one
+type StoredThumbnailsDto struct {
+	Thumbnails []StoredThumbnailDto `json:"thumbnails"`
+	Missing    []int                `json:"missing"`
+}
+
+// StoredThumbnailDto is the thumbnail of a single file, Data is base64 encoded in JSON
+type StoredThumbnailDto struct {
+	FileId      int    `json:"fileId" example:"1"`
+	ContentType string `json:"contentType" example:"image/webp"`
+	Data        []byte `json:"data" swaggertype:"string" format:"base64"`
+}
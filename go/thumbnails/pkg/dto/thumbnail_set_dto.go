@@ -0,0 +1,15 @@
+package dto
+
+// ThumbnailSetDto is the JSON manifest of a responsive thumbnail set
+type ThumbnailSetDto struct {
+	Variants []ThumbnailVariantDto `json:"variants"`
+}
+
+// ThumbnailVariantDto is a single width of a thumbnail set, Data is base64 encoded in JSON
+type ThumbnailVariantDto struct {
+	Width       int    `json:"width" example:"800"`
+	Descriptor  string `json:"descriptor" example:"800w"`
+	ContentType string `json:"contentType" example:"image/webp"`
+	Data        []byte `json:"data" swaggertype:"string" format:"base64"`
+	Quality     string `json:"quality,omitempty" example:"62"`
+}
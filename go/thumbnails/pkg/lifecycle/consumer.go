@@ -0,0 +1,166 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+)
+
+const (
+	// Stream is the Redis stream the Node service adds file lifecycle events to
+	Stream = "file-lifecycle"
+	// Group is the consumer group shared by every thumbnail instance, so each event is handled once
+	Group = "thumbnails"
+
+	batchSize = 100
+	// claimIdle is how long an event stays pending on another consumer before it is taken over, which covers
+	// instances that stopped without acknowledging
+	claimIdle = time.Minute
+)
+
+// Purger removes the thumbnails of files
+type Purger interface {
+	Purge(targets []thumbnail.PurgeTarget) (thumbnail.PurgeResult, error)
+}
+
+// Consumer purges thumbnails for the file lifecycle events on Stream
+type Consumer struct {
+	client     *redis.Client
+	purger     Purger
+	name       string
+	block      time.Duration
+	retryDelay time.Duration
+}
+
+func NewConsumer(client *redis.Client, purger Purger) *Consumer {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		name = Group
+	}
+	return &Consumer{
+		client:     client,
+		purger:     purger,
+		name:       name,
+		block:      time.Second * 5,
+		retryDelay: time.Second * 5,
+	}
+}
+
+// Run consumes events until ctx is done. events are acknowledged once their thumbnails were purged, failed ones stay
+// pending and are retried, including the ones left by a previous run
+func (c *Consumer) Run(ctx context.Context) error {
+	if err := c.ensureGroup(ctx); err != nil {
+		return err
+	}
+
+	pending := true
+	for ctx.Err() == nil {
+		if pending {
+			c.claimStale(ctx)
+		}
+
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    Group,
+			Consumer: c.name,
+			Streams:  []string{Stream, lo.Ternary(pending, "0", ">")},
+			Count:    batchSize,
+			Block:    c.block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			log.Error().Err(err).Msg("failed to read file lifecycle events")
+			c.wait(ctx)
+			continue
+		}
+
+		var messages []redis.XMessage
+		for _, stream := range streams {
+			messages = append(messages, stream.Messages...)
+		}
+		if pending && len(messages) == 0 {
+			pending = false
+			continue
+		}
+
+		if err := c.handle(ctx, messages); err != nil {
+			log.Error().Err(err).Msg("failed to purge thumbnails for file lifecycle events")
+			pending = true
+			c.wait(ctx)
+		}
+	}
+	return ctx.Err()
+}
+
+// handle purges the thumbnails of a batch of events and acknowledges them, malformed events are acknowledged and dropped
+func (c *Consumer) handle(ctx context.Context, messages []redis.XMessage) error {
+	var targets []thumbnail.PurgeTarget
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+		event, err := parseEvent(message.Values)
+		if err != nil {
+			log.Warn().Err(err).Str("id", message.ID).Msg("dropping file lifecycle event")
+			continue
+		}
+		log.Debug().Str("type", string(event.Type)).Int("fileId", event.FileId).Msg("file lifecycle event")
+		targets = append(targets, event.target())
+	}
+
+	if len(targets) > 0 {
+		if _, err := c.purger.Purge(targets); err != nil {
+			return err
+		}
+	}
+	return c.client.XAck(ctx, Stream, Group, ids...).Err()
+}
+
+// ensureGroup creates the stream and consumer group, starting from the beginning of the stream so events added before
+// the first instance started are not lost
+func (c *Consumer) ensureGroup(ctx context.Context) error {
+	err := c.client.XGroupCreateMkStream(ctx, Stream, Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// claimStale moves events pending on other consumers for longer than claimIdle to this one
+func (c *Consumer) claimStale(ctx context.Context) {
+	start := "0-0"
+	for {
+		_, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   Stream,
+			Group:    Group,
+			Consumer: c.name,
+			MinIdle:  claimIdle,
+			Start:    start,
+			Count:    batchSize,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error().Err(err).Msg("failed to claim stale file lifecycle events")
+			}
+			return
+		}
+		if next == "0-0" || next == start {
+			return
+		}
+		start = next
+	}
+}
+
+func (c *Consumer) wait(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(c.retryDelay):
+	}
+}
@@ -0,0 +1,127 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+)
+
+func setupTestRedis(t *testing.T) *redis.Client {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	return redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+}
+
+func newTestConsumer(client *redis.Client, purger Purger) *Consumer {
+	return &Consumer{
+		client:     client,
+		purger:     purger,
+		name:       "test",
+		block:      time.Millisecond * 20,
+		retryDelay: time.Millisecond * 20,
+	}
+}
+
+func addEvent(t *testing.T, client *redis.Client, values map[string]interface{}) {
+	err := client.XAdd(context.Background(), &redis.XAddArgs{Stream: Stream, Values: values}).Err()
+	assert.NoError(t, err)
+}
+
+// runUntil runs the consumer until done reports true or a second passes
+func runUntil(t *testing.T, consumer *Consumer, done func() bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() {
+		stopped <- consumer.Run(ctx)
+	}()
+
+	assert.Eventually(t, done, time.Second, time.Millisecond*10)
+	cancel()
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+}
+
+func pendingCount(t *testing.T, client *redis.Client) int64 {
+	pending, err := client.XPending(context.Background(), Stream, Group).Result()
+	assert.NoError(t, err)
+	return pending.Count
+}
+
+func TestConsumer_PurgesAndAcknowledgesEvents(t *testing.T) {
+	// given
+	client := setupTestRedis(t)
+	purger := NewMockPurger(t)
+	token := uuid.New()
+	addEvent(t, client, map[string]interface{}{"type": "deleted", "fileId": "7", "token": token.String()})
+	addEvent(t, client, map[string]interface{}{"type": "exploded", "fileId": "8"})
+	purged := make(chan []thumbnail.PurgeTarget, 1)
+	purger.EXPECT().Purge(mock.Anything).Run(func(targets []thumbnail.PurgeTarget) {
+		purged <- targets
+	}).Return(thumbnail.PurgeResult{Files: 1}, nil).Once()
+
+	// when
+	runUntil(t, newTestConsumer(client, purger), func() bool {
+		return len(purged) == 1 && pendingCount(t, client) == 0
+	})
+
+	// then
+	assert.Equal(t, []thumbnail.PurgeTarget{{FileId: 7, Token: token}}, <-purged)
+}
+
+func TestConsumer_RetriesFailedPurges(t *testing.T) {
+	// given
+	client := setupTestRedis(t)
+	purger := NewMockPurger(t)
+	addEvent(t, client, map[string]interface{}{"type": "protected", "fileId": "7"})
+	var calls atomic.Int32
+	countCall := func([]thumbnail.PurgeTarget) { calls.Add(1) }
+	purger.EXPECT().Purge([]thumbnail.PurgeTarget{{FileId: 7}}).Run(countCall).Return(thumbnail.PurgeResult{}, errors.New("connection refused")).Once()
+	purger.EXPECT().Purge([]thumbnail.PurgeTarget{{FileId: 7}}).Run(countCall).Return(thumbnail.PurgeResult{Files: 1}, nil).Once()
+
+	// when
+	runUntil(t, newTestConsumer(client, purger), func() bool {
+		return calls.Load() == 2 && pendingCount(t, client) == 0
+	})
+
+	// then
+	assert.Equal(t, int64(0), pendingCount(t, client))
+}
+
+func TestConsumer_ResumesPendingEvents(t *testing.T) {
+	// given
+	client := setupTestRedis(t)
+	purger := NewMockPurger(t)
+	consumer := newTestConsumer(client, purger)
+	assert.NoError(t, consumer.ensureGroup(context.Background()))
+	addEvent(t, client, map[string]interface{}{"type": "replaced", "fileId": "9"})
+	// a previous run read the event but stopped before acknowledging it
+	_, err := client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    Group,
+		Consumer: consumer.name,
+		Streams:  []string{Stream, ">"},
+	}).Result()
+	assert.NoError(t, err)
+	purger.EXPECT().Purge([]thumbnail.PurgeTarget{{FileId: 9}}).Return(thumbnail.PurgeResult{Files: 1}, nil).Once()
+
+	// when
+	runUntil(t, consumer, func() bool {
+		return pendingCount(t, client) == 0
+	})
+
+	// then
+	purger.AssertNumberOfCalls(t, "Purge", 1)
+}
@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/thumbnail"
+)
+
+// EventType is what happened to a file
+type EventType string
+
+const (
+	EventDeleted   EventType = "deleted"
+	EventExpired   EventType = "expired"
+	EventReplaced  EventType = "replaced"
+	EventProtected EventType = "protected"
+)
+
+var errInvalidEvent = errors.New("invalid lifecycle event")
+
+// Event is a file lifecycle event published by the Node service. every type makes the file's thumbnails stale, the
+// type is kept for logging
+type Event struct {
+	Type   EventType
+	FileId int
+	Token  uuid.UUID
+}
+
+// target returns the thumbnails to purge for the event
+func (e Event) target() thumbnail.PurgeTarget {
+	return thumbnail.PurgeTarget{
+		FileId: e.FileId,
+		Token:  e.Token,
+	}
+}
+
+// parseEvent reads the `type`, `fileId` and `token` fields of a stream entry, at least one of fileId and token is required
+func parseEvent(values map[string]interface{}) (Event, error) {
+	var event Event
+
+	eventType, _ := values["type"].(string)
+	switch EventType(eventType) {
+	case EventDeleted, EventExpired, EventReplaced, EventProtected:
+		event.Type = EventType(eventType)
+	default:
+		return Event{}, fmt.Errorf("%w: unknown type %q", errInvalidEvent, eventType)
+	}
+
+	if fileId, _ := values["fileId"].(string); fileId != "" {
+		id, err := strconv.Atoi(fileId)
+		if err != nil || id <= 0 {
+			return Event{}, fmt.Errorf("%w: file id %q", errInvalidEvent, fileId)
+		}
+		event.FileId = id
+	}
+
+	if token, _ := values["token"].(string); token != "" {
+		parsed, err := uuid.Parse(token)
+		if err != nil {
+			return Event{}, fmt.Errorf("%w: token %q", errInvalidEvent, token)
+		}
+		event.Token = parsed
+	}
+
+	if event.FileId == 0 && event.Token == uuid.Nil {
+		return Event{}, fmt.Errorf("%w: fileId or token is required", errInvalidEvent)
+	}
+	return event, nil
+}
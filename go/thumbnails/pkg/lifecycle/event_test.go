@@ -0,0 +1,53 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEvent(t *testing.T) {
+	// given
+	token := uuid.New()
+
+	// when
+	event, err := parseEvent(map[string]interface{}{"type": "expired", "fileId": "12", "token": token.String()})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, Event{Type: EventExpired, FileId: 12, Token: token}, event)
+}
+
+func TestParseEvent_TokenOnly(t *testing.T) {
+	// given
+	token := uuid.New()
+
+	// when
+	event, err := parseEvent(map[string]interface{}{"type": "replaced", "token": token.String()})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, Event{Type: EventReplaced, Token: token}, event)
+}
+
+func TestParseEvent_Invalid(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unknown type":    {"type": "renamed", "fileId": "1"},
+		"missing type":    {"fileId": "1"},
+		"invalid file id": {"type": "deleted", "fileId": "abc"},
+		"negative id":     {"type": "deleted", "fileId": "-1"},
+		"invalid token":   {"type": "deleted", "token": "not-a-uuid"},
+		"no file":         {"type": "deleted"},
+	}
+	for name, values := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			_, err := parseEvent(values)
+
+			// then
+			assert.True(t, errors.Is(err, errInvalidEvent))
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package mod
+
+type Album struct {
+	Id         int    `json:"id" gorm:"column:id;primary_key;auto_increment"`
+	AlbumToken string `json:"albumToken" gorm:"column:albumToken"`
+}
+
+func (a Album) TableName() string {
+	return "album_model"
+}
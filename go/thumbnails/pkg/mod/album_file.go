@@ -0,0 +1,7 @@
+package mod
+
+// AlbumFile is a file together with the id of the album it belongs to
+type AlbumFile struct {
+	FileEntry `gorm:"embedded"`
+	AlbumId   int `json:"albumId" gorm:"column:albumId"`
+}
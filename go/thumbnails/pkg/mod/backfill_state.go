@@ -0,0 +1,17 @@
+package mod
+
+import "time"
+
+// BackfillState is the progress of a background job, kept in the db so the job resumes from it after a restart
+type BackfillState struct {
+	Id   *int   `json:"id" gorm:"column:id"`
+	Name string `json:"name" gorm:"column:name"`
+	// Cursor is the id of the last file the job handled
+	Cursor    int       `json:"cursor" gorm:"column:cursor"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updatedAt"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:createdAt"`
+}
+
+func (s *BackfillState) TableName() string {
+	return "thumbnail_backfill_state_model"
+}
@@ -0,0 +1,24 @@
+package mod
+
+import "time"
+
+// CallbackDelivery is one attempt at delivering the completion event of a thumbnail job, kept for debugging callbacks
+type CallbackDelivery struct {
+	Id      *int `json:"id" gorm:"column:id"`
+	JobId   int  `json:"jobId" gorm:"column:jobId"`
+	AlbumId int  `json:"albumId" gorm:"column:albumId"`
+	// DeliveryId is shared by every attempt of an event
+	DeliveryId string `json:"deliveryId" gorm:"column:deliveryId"`
+	// Target is the callback URL, or redis:<channel>
+	Target     string    `json:"target" gorm:"column:target"`
+	Attempt    int       `json:"attempt" gorm:"column:attempt"`
+	StatusCode *int      `json:"statusCode" gorm:"column:statusCode"`
+	Error      *string   `json:"error" gorm:"column:error"`
+	Delivered  bool      `json:"delivered" gorm:"column:delivered"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"column:updatedAt"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"column:createdAt"`
+}
+
+func (d *CallbackDelivery) TableName() string {
+	return "thumbnail_callback_delivery_model"
+}
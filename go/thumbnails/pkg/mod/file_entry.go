@@ -1,13 +1,28 @@
 package mod
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
 
 type FileEntry struct {
-	Id        int       `json:"id" gorm:"column:id;primary_key;auto_increment"`
-	MediaType string    `json:"mediaType" gorm:"column:mediaType"`
-	Extension string    `json:"extension" gorm:"column:fileExtension"`
-	FileName  string    `json:"fileName" gorm:"column:fileName"`
-	Token     uuid.UUID `json:"token" gorm:"column:token"`
+	Id          int       `json:"id" gorm:"column:id;primary_key;auto_increment"`
+	MediaType   string    `json:"mediaType" gorm:"column:mediaType"`
+	Extension   string    `json:"extension" gorm:"column:fileExtension"`
+	FileName    string    `json:"fileName" gorm:"column:fileName"`
+	Token       uuid.UUID `json:"token" gorm:"column:token"`
+	Checksum    string    `json:"checksum" gorm:"column:checksum"`
+	Encrypted   bool      `json:"encrypted" gorm:"column:encrypted"`
+	AlbumToken  *string   `json:"albumToken" gorm:"column:albumToken"`
+	Settings    *string   `json:"settings" gorm:"column:settings"`
+	FocalPointX *float64  `json:"focalPointX" gorm:"column:focalPointX"`
+	FocalPointY *float64  `json:"focalPointY" gorm:"column:focalPointY"`
+}
+
+// fileSettings is the part of the settings JSON the Node service stores that thumbnails depend on
+type fileSettings struct {
+	Password string `json:"password"`
 }
 
 func (f FileEntry) TableName() string {
@@ -20,3 +35,30 @@ func (f FileEntry) FullFileNameOnSystem() string {
 	}
 	return f.FileName
 }
+
+// FocalPoint returns the stored focal point, or nil if the file does not have one
+func (f FileEntry) FocalPoint() *FocalPoint {
+	if f.FocalPointX == nil || f.FocalPointY == nil {
+		return nil
+	}
+	return &FocalPoint{
+		X: *f.FocalPointX,
+		Y: *f.FocalPointY,
+	}
+}
+
+// Protected reports whether the file is encrypted or password protected, the Node service never shows thumbnails of
+// those. settings that can't be read are treated as protected
+func (f FileEntry) Protected() bool {
+	if f.Encrypted {
+		return true
+	}
+	if f.Settings == nil || *f.Settings == "" {
+		return false
+	}
+	var settings fileSettings
+	if err := json.Unmarshal([]byte(*f.Settings), &settings); err != nil {
+		return true
+	}
+	return settings.Password != ""
+}
@@ -0,0 +1,34 @@
+package mod
+
+import "time"
+
+// FailureClass is the kind of error a file failed to render with
+type FailureClass string
+
+const (
+	// FailureTransient may not happen again, the file is retried with backoff until it runs out of attempts
+	FailureTransient FailureClass = "transient"
+	// FailureUnsupported is a file of a type the pipeline can't render, it is quarantined straight away
+	FailureUnsupported FailureClass = "unsupported"
+	// FailureMissing is a file that isn't on disk, it is quarantined straight away
+	FailureMissing FailureClass = "missing"
+)
+
+// FileFailure tracks the failed renders of an album file. album jobs skip the file until NextAttemptAt, and skip a
+// quarantined file until its failure is reset
+type FileFailure struct {
+	Id         *int         `json:"id" gorm:"column:id"`
+	FileId     int          `json:"fileId" gorm:"column:fileId"`
+	ErrorClass FailureClass `json:"errorClass" gorm:"column:errorClass"`
+	Attempts   int          `json:"attempts" gorm:"column:attempts"`
+	LastError  string       `json:"lastError" gorm:"column:lastError"`
+	// NextAttemptAt is when a transient failure may be retried, it is nil once the file is quarantined
+	NextAttemptAt *time.Time `json:"nextAttemptAt" gorm:"column:nextAttemptAt"`
+	Quarantined   bool       `json:"quarantined" gorm:"column:quarantined"`
+	UpdatedAt     time.Time  `json:"updatedAt" gorm:"column:updatedAt"`
+	CreatedAt     time.Time  `json:"createdAt" gorm:"column:createdAt"`
+}
+
+func (f *FileFailure) TableName() string {
+	return "thumbnail_file_failure_model"
+}
@@ -0,0 +1,7 @@
+package mod
+
+// FocalPoint is the point of interest of a file, normalised to the 0-1 range on both axes
+type FocalPoint struct {
+	X float64 `json:"x" example:"0.5"`
+	Y float64 `json:"y" example:"0.25"`
+}
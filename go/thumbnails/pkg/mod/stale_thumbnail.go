@@ -0,0 +1,8 @@
+package mod
+
+// StaleThumbnail is a stored thumbnail rendered by an older pipeline, together with the file it was rendered from
+type StaleThumbnail struct {
+	FileEntry   `gorm:"embedded"`
+	ThumbnailId int    `json:"thumbnailId" gorm:"column:thumbnailId"`
+	Crop        string `json:"crop" gorm:"column:crop"`
+}
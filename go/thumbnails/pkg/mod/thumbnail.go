@@ -3,9 +3,19 @@ package mod
 import "time"
 
 type Thumbnail struct {
-	Id        *int      `json:"id" gorm:"column:id"`
-	Data      string    `json:"thumbnail" gorm:"column:data"`
-	FileId    int       `json:"fileId" gorm:"column:fileId"`
+	Id *int `json:"id" gorm:"column:id"`
+	// Data is the base64 encoded thumbnail, empty when the thumbnail is kept in Blob or at Location
+	Data     string  `json:"thumbnail" gorm:"column:data"`
+	Blob     []byte  `json:"-" gorm:"column:blob"`
+	Location *string `json:"location" gorm:"column:location"`
+	FileId   int     `json:"fileId" gorm:"column:fileId"`
+	// RenderKey identifies the contents and render options the thumbnail was made from, files sharing it reuse the row
+	RenderKey *string `json:"renderKey" gorm:"column:renderKey"`
+	// PipelineVersion is the version of the pipeline that rendered the thumbnail, older versions are re-rendered
+	PipelineVersion int    `json:"pipelineVersion" gorm:"column:pipelineVersion"`
+	Crop            string `json:"crop" gorm:"column:crop"`
+	// Content is the thumbnail itself, the thumbnail store decides which column keeps it when the row is saved
+	Content   []byte    `json:"-" gorm:"-"`
 	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updatedAt"`
 	CreatedAt time.Time `json:"createdAt" gorm:"column:createdAt"`
 }
@@ -0,0 +1,51 @@
+package mod
+
+import "time"
+
+// JobStatus is the state of a ThumbnailJob
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+	// JobCancelled is set when the job is cancelled, a running job is stopped by its worker at the next lease renewal
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Finished reports whether a job with the status won't run again
+func (s JobStatus) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// ThumbnailJob is a persisted request to generate the thumbnails of album files. a worker holds the job while LeaseUntil
+// is in the future, jobs whose lease ran out are claimed again
+type ThumbnailJob struct {
+	Id      *int      `json:"id" gorm:"column:id"`
+	AlbumId int       `json:"albumId" gorm:"column:albumId"`
+	Crop    string    `json:"crop" gorm:"column:crop"`
+	Status  JobStatus `json:"status" gorm:"column:status"`
+	// Files is the JSON encoded list of files to generate thumbnails for
+	Files      string     `json:"-" gorm:"column:files"`
+	Attempts   int        `json:"attempts" gorm:"column:attempts"`
+	RunAfter   time.Time  `json:"runAfter" gorm:"column:runAfter"`
+	LeaseOwner *string    `json:"leaseOwner" gorm:"column:leaseOwner"`
+	LeaseUntil *time.Time `json:"leaseUntil" gorm:"column:leaseUntil"`
+	LastError  *string    `json:"lastError" gorm:"column:lastError"`
+	// CallbackUrl receives the completion event of the job, besides the configured channel
+	CallbackUrl *string `json:"callbackUrl" gorm:"column:callbackUrl"`
+	// DiscardPartial is set by a cancellation that drops the thumbnails rendered but not stored yet instead of storing them
+	DiscardPartial bool `json:"discardPartial" gorm:"column:discardPartial"`
+	// Force renders the files that already have a stored thumbnail again instead of skipping them
+	Force bool `json:"force" gorm:"column:force"`
+	// Revision is raised every time files are merged into the job, the worker running it picks up the files of a newer
+	// revision
+	Revision  int       `json:"revision" gorm:"column:revision"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updatedAt"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:createdAt"`
+}
+
+func (j *ThumbnailJob) TableName() string {
+	return "thumbnail_job_model"
+}
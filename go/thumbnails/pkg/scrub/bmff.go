@@ -0,0 +1,499 @@
+package scrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// maxMetaBoxSize caps how much of a HEIF meta box is read into memory
+const maxMetaBoxSize = 16 * 1024 * 1024
+
+var (
+	// xmpUUID is the user type of the uuid box holding XMP in MP4 files
+	xmpUUID = []byte{0xbe, 0x7a, 0xcf, 0xcb, 0x97, 0xa9, 0x42, 0xe8, 0x9c, 0x71, 0x99, 0x94, 0x91, 0xe3, 0xaf, 0xac}
+
+	heifBrands = []string{"heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1", "avif", "avis"}
+
+	// emptyExifItem is a HEIF Exif item with no tags: the offset to the TIFF header, then an empty big endian IFD
+	emptyExifItem = []byte{0, 0, 0, 0, 'M', 'M', 0x00, 0x2a, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0}
+
+	emptyXMPHead = []byte(`<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?><x:xmpmeta xmlns:x="adobe:ns:meta/"/>`)
+	emptyXMPTail = []byte(`<?xpacket end="w"?>`)
+)
+
+// box is an ISO base media file format box, offsets are absolute
+type box struct {
+	boxType    string
+	start      int64
+	headerSize int64
+	size       int64
+}
+
+func (b box) payloadStart() int64 {
+	return b.start + b.headerSize
+}
+
+func (b box) end() int64 {
+	return b.start + b.size
+}
+
+// isBMFF reports whether the header looks like an ISO base media file (MP4, MOV, HEIF)
+func isBMFF(header []byte) bool {
+	if len(header) < 8 {
+		return false
+	}
+	return lo.Contains([]string{"ftyp", "moov", "mdat", "wide", "free", "skip"}, string(header[4:8]))
+}
+
+// scrubBMFF blanks the metadata of an MP4, MOV or HEIF file in place. nothing is moved, so chunk and item offsets stay valid:
+// movie user data and metadata boxes become zero filled free boxes and HEIF Exif and XMP items are overwritten with empty ones.
+// orientation (the track matrix, irot and imir) and colour (colr) live outside of these boxes and are kept
+func scrubBMFF(r io.ReaderAt, w io.WriterAt, size int64) (string, []string, error) {
+	boxes, err := readBoxes(r, 0, size)
+	if err != nil {
+		return "", nil, err
+	}
+
+	format := "mov"
+	var removed []string
+	for _, b := range boxes {
+		switch b.boxType {
+		case "ftyp":
+			format, err = bmffFormat(r, b)
+		case "moov":
+			var movieRemoved []string
+			movieRemoved, err = scrubMovie(r, w, b)
+			removed = append(removed, movieRemoved...)
+		case "meta":
+			var metaRemoved []string
+			metaRemoved, err = scrubTopLevelMeta(r, w, b)
+			removed = append(removed, metaRemoved...)
+		case "uuid":
+			var isXMP bool
+			isXMP, err = isXMPBox(r, b)
+			if err == nil && isXMP {
+				err = freeBox(w, b)
+				removed = append(removed, "XMP")
+			}
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return format, removed, nil
+}
+
+// scrubMovie frees the user data and metadata boxes of the movie and each of its tracks
+func scrubMovie(r io.ReaderAt, w io.WriterAt, moov box) ([]string, error) {
+	children, err := readBoxes(r, moov.payloadStart(), moov.end())
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, child := range children {
+		switch child.boxType {
+		case "udta", "meta":
+			if err := freeBox(w, child); err != nil {
+				return nil, err
+			}
+			removed = append(removed, child.boxType)
+		case "uuid":
+			isXMP, err := isXMPBox(r, child)
+			if err != nil {
+				return nil, err
+			}
+			if isXMP {
+				if err := freeBox(w, child); err != nil {
+					return nil, err
+				}
+				removed = append(removed, "XMP")
+			}
+		case "trak":
+			trackRemoved, err := scrubMovie(r, w, child)
+			if err != nil {
+				return nil, err
+			}
+			removed = append(removed, trackRemoved...)
+		}
+	}
+	return removed, nil
+}
+
+// scrubTopLevelMeta blanks the Exif and XMP items of a HEIF image, other top level metadata boxes are freed
+func scrubTopLevelMeta(r io.ReaderAt, w io.WriterAt, meta box) ([]string, error) {
+	if meta.size > maxMetaBoxSize {
+		return nil, fmt.Errorf("%w: meta box is too large", ErrMalformedFile)
+	}
+	data := make([]byte, meta.size)
+	if _, err := r.ReadAt(data, meta.start); err != nil {
+		return nil, err
+	}
+
+	// meta is a full box, its children start after the version and flags
+	childrenStart := meta.headerSize + 4
+	children, err := readBoxes(bytes.NewReader(data), childrenStart, meta.size)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, found := lo.Find(children, func(b box) bool { return b.boxType == "hdlr" })
+	if !found || handler.payloadStart()+12 > handler.end() || string(data[handler.payloadStart()+8:handler.payloadStart()+12]) != "pict" {
+		if err := freeBox(w, meta); err != nil {
+			return nil, err
+		}
+		return []string{"meta"}, nil
+	}
+
+	iinf, hasIinf := lo.Find(children, func(b box) bool { return b.boxType == "iinf" })
+	iloc, hasIloc := lo.Find(children, func(b box) bool { return b.boxType == "iloc" })
+	if !hasIinf || !hasIloc {
+		return nil, nil
+	}
+
+	items, err := parseItemInfo(data[iinf.start:iinf.end()], iinf.headerSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	idatStart := int64(-1)
+	if idat, hasIdat := lo.Find(children, func(b box) bool { return b.boxType == "idat" }); hasIdat {
+		idatStart = meta.start + idat.payloadStart()
+	}
+
+	locations, err := parseItemLocations(data[iloc.start:iloc.end()], iloc.headerSize, idatStart)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for id, kind := range items {
+		extents, found := locations[id]
+		if !found {
+			continue
+		}
+		replacement := emptyExifItem
+		if kind == "XMP" {
+			replacement = emptyXMPPacket(extentsLength(extents))
+		}
+		if err := overwriteExtents(w, extents, replacement); err != nil {
+			return nil, err
+		}
+		removed = append(removed, kind)
+	}
+	return removed, nil
+}
+
+// extent is a byte range of an item, the offset is absolute
+type extent struct {
+	offset int64
+	length int64
+}
+
+func extentsLength(extents []extent) int64 {
+	return lo.SumBy(extents, func(e extent) int64 { return e.length })
+}
+
+// parseItemInfo returns the ids of the Exif and XMP items listed in an iinf box
+func parseItemInfo(iinf []byte, headerSize int64) (map[uint32]string, error) {
+	reader := &byteReader{data: iinf, pos: int(headerSize)}
+	version := reader.u8()
+	reader.skip(3)
+	entryCount := uint32(reader.u16())
+	if version > 0 {
+		reader.pos -= 2
+		entryCount = reader.u32()
+	}
+	if reader.err != nil {
+		return nil, reader.err
+	}
+
+	boxes, err := readBoxes(bytes.NewReader(iinf), int64(reader.pos), int64(len(iinf)))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[uint32]string)
+	for _, infe := range lo.Slice(boxes, 0, int(entryCount)) {
+		if infe.boxType != "infe" {
+			continue
+		}
+		entry := &byteReader{data: iinf[:infe.end()], pos: int(infe.payloadStart())}
+		infeVersion := entry.u8()
+		entry.skip(3)
+		if infeVersion < 2 {
+			continue
+		}
+		id := uint32(entry.u16())
+		if infeVersion == 3 {
+			entry.pos -= 2
+			id = entry.u32()
+		}
+		entry.skip(2)
+		itemType := entry.fourCC()
+		entry.cString()
+		contentType := ""
+		if itemType == "mime" {
+			contentType = entry.cString()
+		}
+		if entry.err != nil {
+			return nil, entry.err
+		}
+
+		switch {
+		case itemType == "Exif":
+			items[id] = "EXIF"
+		case itemType == "mime" && strings.HasPrefix(contentType, "application/rdf+xml"):
+			items[id] = "XMP"
+		}
+	}
+	return items, nil
+}
+
+// parseItemLocations returns the file extents of every item in an iloc box. items stored in idat are resolved
+// against idatStart, items built from other items are skipped
+func parseItemLocations(iloc []byte, headerSize int64, idatStart int64) (map[uint32][]extent, error) {
+	reader := &byteReader{data: iloc, pos: int(headerSize)}
+	version := reader.u8()
+	reader.skip(3)
+	sizes := reader.u8()
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0f)
+	sizes = reader.u8()
+	baseOffsetSize, indexSize := int(sizes>>4), 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0x0f)
+	}
+
+	itemCount := uint32(reader.u16())
+	if version == 2 {
+		reader.pos -= 2
+		itemCount = reader.u32()
+	}
+
+	locations := make(map[uint32][]extent)
+	for range itemCount {
+		id := uint32(reader.u16())
+		if version == 2 {
+			reader.pos -= 2
+			id = reader.u32()
+		}
+		constructionMethod := 0
+		if version == 1 || version == 2 {
+			constructionMethod = int(reader.u16() & 0x0f)
+		}
+		reader.skip(2)
+		baseOffset := int64(reader.uint(baseOffsetSize))
+		extentCount := int(reader.u16())
+
+		var extents []extent
+		for range extentCount {
+			reader.uint(indexSize)
+			offset := int64(reader.uint(offsetSize))
+			length := int64(reader.uint(lengthSize))
+			extents = append(extents, extent{offset: baseOffset + offset, length: length})
+		}
+		if reader.err != nil {
+			return nil, reader.err
+		}
+
+		switch {
+		case constructionMethod == 0:
+			locations[id] = extents
+		case constructionMethod == 1 && idatStart >= 0:
+			locations[id] = lo.Map(extents, func(e extent, _ int) extent {
+				return extent{offset: idatStart + e.offset, length: e.length}
+			})
+		}
+	}
+	return locations, nil
+}
+
+// overwriteExtents writes the replacement across the extents of an item, zero filling whatever is left
+func overwriteExtents(w io.WriterAt, extents []extent, replacement []byte) error {
+	for _, e := range extents {
+		if e.length <= 0 {
+			continue
+		}
+		chunk := make([]byte, e.length)
+		n := copy(chunk, replacement)
+		replacement = replacement[n:]
+		if _, err := w.WriteAt(chunk, e.offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emptyXMPPacket builds an XMP packet with no properties, padded with whitespace to the given length
+func emptyXMPPacket(length int64) []byte {
+	minimum := int64(len(emptyXMPHead) + len(emptyXMPTail))
+	if length < minimum {
+		return bytes.Repeat([]byte(" "), int(length))
+	}
+	packet := bytes.Clone(emptyXMPHead)
+	packet = append(packet, bytes.Repeat([]byte(" "), int(length-minimum))...)
+	return append(packet, emptyXMPTail...)
+}
+
+// freeBox turns a box into a zero filled free box of the same size
+func freeBox(w io.WriterAt, b box) error {
+	if _, err := w.WriteAt([]byte("free"), b.start+4); err != nil {
+		return err
+	}
+
+	zeroFrom := b.start + 8
+	if isLargeBox(b) {
+		// keep the 64 bit size, which is stored straight after the type
+		zeroFrom += 8
+	}
+
+	zeros := make([]byte, 32*1024)
+	for offset := zeroFrom; offset < b.end(); offset += int64(len(zeros)) {
+		n := min(int64(len(zeros)), b.end()-offset)
+		if _, err := w.WriteAt(zeros[:n], offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isLargeBox reports whether the box header has a 64 bit size
+func isLargeBox(b box) bool {
+	userTypeSize := lo.Ternary[int64](b.boxType == "uuid", 16, 0)
+	return b.headerSize-userTypeSize == 16
+}
+
+func isXMPBox(r io.ReaderAt, b box) (bool, error) {
+	if b.headerSize < 24 {
+		return false, nil
+	}
+	userType := make([]byte, 16)
+	if _, err := r.ReadAt(userType, b.payloadStart()-16); err != nil {
+		return false, err
+	}
+	return bytes.Equal(userType, xmpUUID), nil
+}
+
+func bmffFormat(r io.ReaderAt, ftyp box) (string, error) {
+	brand := make([]byte, 4)
+	if _, err := r.ReadAt(brand, ftyp.payloadStart()); err != nil {
+		return "", err
+	}
+	switch {
+	case lo.Contains(heifBrands, string(brand)):
+		return "heif", nil
+	case string(brand) == "qt  ":
+		return "mov", nil
+	}
+	return "mp4", nil
+}
+
+// readBoxes lists the boxes between start and end
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return nil, err
+		}
+		b := box{
+			boxType:    string(header[4:8]),
+			start:      offset,
+			headerSize: 8,
+			size:       int64(binary.BigEndian.Uint32(header[0:4])),
+		}
+
+		switch b.size {
+		case 0:
+			b.size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, err
+			}
+			b.size = int64(binary.BigEndian.Uint64(header[8:16]))
+			b.headerSize = 16
+		}
+		if b.boxType == "uuid" {
+			b.headerSize += 16
+		}
+		if b.size < b.headerSize || b.end() > end {
+			return nil, fmt.Errorf("%w: bad %q box size", ErrMalformedFile, b.boxType)
+		}
+
+		boxes = append(boxes, b)
+		offset = b.end()
+	}
+	return boxes, nil
+}
+
+// byteReader reads big endian values, remembering the first out of range read
+type byteReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (b *byteReader) next(n int) []byte {
+	if b.err != nil || b.pos+n > len(b.data) || n < 0 {
+		if b.err == nil {
+			b.err = fmt.Errorf("%w: truncated box", ErrMalformedFile)
+		}
+		return make([]byte, max(n, 0))
+	}
+	value := b.data[b.pos : b.pos+n]
+	b.pos += n
+	return value
+}
+
+func (b *byteReader) skip(n int) {
+	b.next(n)
+}
+
+func (b *byteReader) u8() byte {
+	return b.next(1)[0]
+}
+
+func (b *byteReader) u16() uint16 {
+	return binary.BigEndian.Uint16(b.next(2))
+}
+
+func (b *byteReader) u32() uint32 {
+	return binary.BigEndian.Uint32(b.next(4))
+}
+
+// uint reads an unsigned value of 0, 4 or 8 bytes as used by iloc
+func (b *byteReader) uint(size int) uint64 {
+	switch size {
+	case 0:
+		return 0
+	case 4:
+		return uint64(b.u32())
+	case 8:
+		return binary.BigEndian.Uint64(b.next(8))
+	}
+	b.err = fmt.Errorf("%w: unsupported iloc field size %d", ErrMalformedFile, size)
+	return 0
+}
+
+func (b *byteReader) fourCC() string {
+	return string(b.next(4))
+}
+
+func (b *byteReader) cString() string {
+	end := bytes.IndexByte(b.data[min(b.pos, len(b.data)):], 0)
+	if end == -1 {
+		b.err = fmt.Errorf("%w: unterminated string", ErrMalformedFile)
+		return ""
+	}
+	value := string(b.data[b.pos : b.pos+end])
+	b.pos += end + 1
+	return value
+}
@@ -0,0 +1,131 @@
+package scrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bmffBox(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	out = append(out, boxType...)
+	return append(out, body...)
+}
+
+func fullBox(boxType string, version byte, payload ...[]byte) []byte {
+	return bmffBox(boxType, append([][]byte{{version, 0, 0, 0}}, payload...)...)
+}
+
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+func TestScrubFile_MP4(t *testing.T) {
+	// given
+	ftyp := bmffBox("ftyp", []byte("isom\x00\x00\x02\x00isommp41"))
+	movieHeader := fullBox("mvhd", 0, make([]byte, 96))
+	movieUserData := bmffBox("udta", bmffBox("\xa9xyz", []byte("+51.5-000.1/")))
+	trackUserData := bmffBox("udta", bmffBox("\xa9nam", []byte("holiday")))
+	track := bmffBox("trak", fullBox("tkhd", 0, make([]byte, 80)), trackUserData)
+	mdat := bmffBox("mdat", []byte("frames"))
+	file := bytes.Join([][]byte{ftyp, bmffBox("moov", movieHeader, movieUserData, track), mdat}, nil)
+	path := writeTempFile(t, "video.mp4", file)
+
+	// when
+	result, err := ScrubFile(path)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "mp4", result.Format)
+	assert.Equal(t, []string{"udta"}, result.Removed)
+	scrubbed, _ := os.ReadFile(path)
+	assert.Len(t, scrubbed, len(file))
+	freedMovieData := append(binary.BigEndian.AppendUint32(nil, uint32(len(movieUserData))), "free"...)
+	freedMovieData = append(freedMovieData, make([]byte, len(movieUserData)-8)...)
+	assert.Contains(t, string(scrubbed), string(freedMovieData))
+	assert.NotContains(t, string(scrubbed), "holiday")
+	assert.True(t, bytes.HasSuffix(scrubbed, mdat))
+}
+
+func TestScrubFile_HEIF(t *testing.T) {
+	// given
+	ftyp := bmffBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	handler := fullBox("hdlr", 0, []byte("\x00\x00\x00\x00pict"), make([]byte, 13))
+	itemInfo := fullBox("iinf", 0, []byte{0, 3},
+		fullBox("infe", 2, []byte{0, 1, 0, 0}, []byte("hvc1\x00")),
+		fullBox("infe", 2, []byte{0, 2, 0, 0}, []byte("Exif\x00")),
+		fullBox("infe", 2, []byte{0, 3, 0, 0}, []byte("mime\x00application/rdf+xml\x00")),
+	)
+	image := []byte("coded image")
+	exif := append([]byte{0, 0, 0, 6, 'E', 'x', 'i', 'f', 0, 0}, littleEndianExif(6)...)
+	xmp := bytes.Repeat([]byte("x"), 160)
+
+	location := func(id uint16, offset, length int) []byte {
+		entry := binary.BigEndian.AppendUint16(nil, id)
+		entry = append(entry, 0, 0, 0, 1)
+		entry = binary.BigEndian.AppendUint32(entry, uint32(offset))
+		return binary.BigEndian.AppendUint32(entry, uint32(length))
+	}
+	buildFile := func(mdatStart int) []byte {
+		itemLocations := fullBox("iloc", 0, []byte{0x44, 0x00, 0, 3},
+			location(1, mdatStart+8, len(image)),
+			location(2, mdatStart+8+len(image), len(exif)),
+			location(3, mdatStart+8+len(image)+len(exif), len(xmp)),
+		)
+		meta := fullBox("meta", 0, handler, itemInfo, itemLocations)
+		return bytes.Join([][]byte{ftyp, meta, bmffBox("mdat", image, exif, xmp)}, nil)
+	}
+	headerLength := len(buildFile(0)) - len(image) - len(exif) - len(xmp) - 8
+	file := buildFile(headerLength)
+	path := writeTempFile(t, "photo.heic", file)
+
+	// when
+	result, err := ScrubFile(path)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "heif", result.Format)
+	assert.ElementsMatch(t, []string{"EXIF", "XMP"}, result.Removed)
+	scrubbed, _ := os.ReadFile(path)
+	assert.Len(t, scrubbed, len(file))
+	assert.Equal(t, file[:headerLength+8+len(image)], scrubbed[:headerLength+8+len(image)])
+	exifStart := headerLength + 8 + len(image)
+	expectedExif := make([]byte, len(exif))
+	copy(expectedExif, emptyExifItem)
+	assert.Equal(t, expectedExif, scrubbed[exifStart:exifStart+len(exif)])
+	assert.Equal(t, emptyXMPPacket(int64(len(xmp))), scrubbed[exifStart+len(exif):])
+}
+
+func TestScrubFile_Unchanged(t *testing.T) {
+	// given
+	file := bytes.Join([][]byte{bmffBox("ftyp", []byte("isom\x00\x00\x02\x00")), bmffBox("mdat", []byte("frames"))}, nil)
+	path := writeTempFile(t, "video.mp4", file)
+	before, _ := os.Stat(path)
+
+	// when
+	result, err := ScrubFile(path)
+
+	// then
+	assert.NoError(t, err)
+	assert.False(t, result.Changed())
+	after, _ := os.Stat(path)
+	assert.True(t, os.SameFile(before, after))
+}
+
+func TestScrubFile_UnsupportedFormat(t *testing.T) {
+	// given
+	path := writeTempFile(t, "notes.txt", []byte("just some text"))
+
+	// when
+	_, err := ScrubFile(path)
+
+	// then
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
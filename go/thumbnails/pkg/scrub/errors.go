@@ -0,0 +1,13 @@
+package scrub
+
+import "errors"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	ErrMalformedFile     = errors.New("malformed file")
+	ErrFileNotFound      = errors.New("file not found")
+	ErrEncryptedFile     = errors.New("encrypted files cannot be scrubbed")
+
+	// errUnchanged aborts replacing a file when there is nothing to write back
+	errUnchanged = errors.New("file unchanged")
+)
@@ -0,0 +1,62 @@
+package scrub
+
+import "encoding/binary"
+
+const orientationTag = 0x0112
+
+// readOrientation returns the orientation stored in the first IFD of a TIFF structured EXIF block, 0 if there is none
+func readOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := range count {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == orientationTag {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 0
+			}
+			return orientation
+		}
+	}
+	return 0
+}
+
+// orientationExif builds a TIFF structured EXIF block that only holds the orientation tag
+func orientationExif(orientation int) []byte {
+	tiff := []byte{'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08}
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientationTag)
+	// type SHORT, count 1, the value is left aligned in the 4 byte field
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = binary.BigEndian.AppendUint16(tiff, 0)
+	// no next IFD
+	return binary.BigEndian.AppendUint32(tiff, 0)
+}
+
+// keepsOrientation reports whether the orientation needs to be written back, 1 is the default and can be dropped
+func keepsOrientation(orientation int) bool {
+	return orientation > 1
+}
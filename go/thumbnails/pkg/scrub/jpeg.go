@@ -0,0 +1,130 @@
+package scrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	markerSOI  = 0xd8
+	markerEOI  = 0xd9
+	markerSOS  = 0xda
+	markerAPP0 = 0xe0
+	markerAPP1 = 0xe1
+	markerAPP2 = 0xe2
+	markerAPP3 = 0xe3
+	markerAPPF = 0xef
+	markerAPPE = 0xee
+	markerCOM  = 0xfe
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// scrubJPEG copies the segments of a JPEG that are needed to decode it, the ICC profile and the orientation.
+// EXIF is rebuilt with only the orientation, everything after the end of the image (such as MPF previews) is dropped.
+// the entropy coded data is copied as is so nothing is re-encoded
+func scrubJPEG(data []byte) ([]byte, []string, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != markerSOI {
+		return nil, nil, fmt.Errorf("%w: missing JPEG start of image", ErrMalformedFile)
+	}
+
+	var removed []string
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[0:2])
+
+	pos := 2
+	for pos < len(data) {
+		marker, next, err := readJPEGMarker(data, pos)
+		if err != nil {
+			return nil, nil, err
+		}
+		if marker == markerEOI {
+			out.Write([]byte{0xff, markerEOI})
+			if next < len(data) {
+				removed = append(removed, "trailing data")
+			}
+			return out.Bytes(), removed, nil
+		}
+
+		if next+2 > len(data) {
+			return nil, nil, fmt.Errorf("%w: truncated JPEG segment", ErrMalformedFile)
+		}
+		length := int(binary.BigEndian.Uint16(data[next : next+2]))
+		end := next + length
+		if length < 2 || end > len(data) {
+			return nil, nil, fmt.Errorf("%w: bad JPEG segment length", ErrMalformedFile)
+		}
+		payload := data[next+2 : end]
+
+		switch {
+		case marker == markerSOS:
+			scanEnd := jpegScanEnd(data, end)
+			out.Write(data[pos:scanEnd])
+			pos = scanEnd
+			continue
+		case marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader):
+			removed = append(removed, "EXIF")
+			if orientation := readOrientation(payload[len(exifHeader):]); keepsOrientation(orientation) {
+				exif := append(bytes.Clone(exifHeader), orientationExif(orientation)...)
+				writeJPEGSegment(out, markerAPP1, exif)
+			}
+		case marker == markerAPP1:
+			removed = append(removed, "XMP")
+		case marker == markerAPP2 && bytes.HasPrefix(payload, iccHeader):
+			out.Write(data[pos:end])
+		case marker == markerAPP0, marker == markerAPPE:
+			// JFIF and Adobe segments describe how to decode the colour data
+			out.Write(data[pos:end])
+		case marker == 0xed:
+			removed = append(removed, "IPTC")
+		case marker == markerCOM:
+			removed = append(removed, "comment")
+		case marker == markerAPP2 || (marker >= markerAPP3 && marker <= markerAPPF):
+			removed = append(removed, fmt.Sprintf("APP%d", marker-markerAPP0))
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	return nil, nil, fmt.Errorf("%w: missing JPEG end of image", ErrMalformedFile)
+}
+
+// readJPEGMarker reads the marker at pos, skipping fill bytes, and returns the offset just after it
+func readJPEGMarker(data []byte, pos int) (byte, int, error) {
+	if data[pos] != 0xff {
+		return 0, 0, fmt.Errorf("%w: expected JPEG marker at offset %d", ErrMalformedFile, pos)
+	}
+	for pos < len(data) && data[pos] == 0xff {
+		pos++
+	}
+	if pos >= len(data) {
+		return 0, 0, fmt.Errorf("%w: truncated JPEG marker", ErrMalformedFile)
+	}
+	return data[pos], pos + 1, nil
+}
+
+// jpegScanEnd returns the offset of the first marker after the entropy coded data that starts at pos.
+// inside the scan 0xFF is followed by a stuffed zero or a restart marker
+func jpegScanEnd(data []byte, pos int) int {
+	for pos+1 < len(data) {
+		if data[pos] == 0xff {
+			next := data[pos+1]
+			if next != 0x00 && (next < 0xd0 || next > 0xd7) && next != 0xff {
+				return pos
+			}
+		}
+		pos++
+	}
+	return len(data)
+}
+
+func writeJPEGSegment(out *bytes.Buffer, marker byte, payload []byte) {
+	out.Write([]byte{0xff, marker})
+	_ = binary.Write(out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+}
@@ -0,0 +1,102 @@
+package scrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// littleEndianExif builds an EXIF block with an orientation tag and a second tag standing in for GPS data
+func littleEndianExif(orientation int) []byte {
+	tiff := []byte{'I', 'I', 0x2a, 0x00, 0x08, 0x00, 0x00, 0x00}
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	// GPS IFD pointer
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x8825)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 4)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0x1234)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+	return binary.LittleEndian.AppendUint32(tiff, 0)
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	var out bytes.Buffer
+	writeJPEGSegment(&out, marker, payload)
+	return out.Bytes()
+}
+
+func TestReadOrientation(t *testing.T) {
+	// then
+	assert.Equal(t, 6, readOrientation(littleEndianExif(6)))
+	assert.Equal(t, 8, readOrientation(orientationExif(8)))
+	assert.Equal(t, 0, readOrientation([]byte("not exif")))
+}
+
+func TestScrubJPEG(t *testing.T) {
+	// given
+	jfif := jpegSegment(markerAPP0, []byte("JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00"))
+	icc := jpegSegment(markerAPP2, append([]byte("ICC_PROFILE\x00\x01\x01"), []byte("profile")...))
+	quantisation := jpegSegment(0xdb, []byte{0x00, 0x01, 0x02})
+	scanHeader := jpegSegment(markerSOS, []byte{0x01, 0x01, 0x00, 0x00, 0x3f, 0x00})
+	entropy := []byte{0x12, 0xff, 0x00, 0x34, 0xff, 0xd0, 0x56}
+	var file bytes.Buffer
+	file.Write([]byte{0xff, markerSOI})
+	file.Write(jfif)
+	file.Write(jpegSegment(markerAPP1, append([]byte("Exif\x00\x00"), littleEndianExif(6)...)))
+	file.Write(jpegSegment(markerAPP1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")))
+	file.Write(icc)
+	file.Write(jpegSegment(0xed, []byte("Photoshop 3.0\x00owner")))
+	file.Write(jpegSegment(markerCOM, []byte("taken at home")))
+	file.Write(quantisation)
+	file.Write(scanHeader)
+	file.Write(entropy)
+	file.Write([]byte{0xff, markerEOI})
+	file.WriteString("trailing preview")
+
+	// when
+	scrubbed, removed, err := scrubJPEG(file.Bytes())
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"EXIF", "XMP", "IPTC", "comment", "trailing data"}, removed)
+	var expected bytes.Buffer
+	expected.Write([]byte{0xff, markerSOI})
+	expected.Write(jfif)
+	expected.Write(jpegSegment(markerAPP1, append([]byte("Exif\x00\x00"), orientationExif(6)...)))
+	expected.Write(icc)
+	expected.Write(quantisation)
+	expected.Write(scanHeader)
+	expected.Write(entropy)
+	expected.Write([]byte{0xff, markerEOI})
+	assert.Equal(t, expected.Bytes(), scrubbed)
+}
+
+func TestScrubJPEG_DefaultOrientationIsDropped(t *testing.T) {
+	// given
+	var file bytes.Buffer
+	file.Write([]byte{0xff, markerSOI})
+	file.Write(jpegSegment(markerAPP1, append([]byte("Exif\x00\x00"), littleEndianExif(1)...)))
+	file.Write([]byte{0xff, markerEOI})
+
+	// when
+	scrubbed, removed, err := scrubJPEG(file.Bytes())
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"EXIF"}, removed)
+	assert.Equal(t, []byte{0xff, markerSOI, 0xff, markerEOI}, scrubbed)
+}
+
+func TestScrubJPEG_Malformed(t *testing.T) {
+	// when
+	_, _, err := scrubJPEG([]byte{0xff, markerSOI, 0xff, 0xe1, 0xff, 0xff})
+
+	// then
+	assert.ErrorIs(t, err, ErrMalformedFile)
+}
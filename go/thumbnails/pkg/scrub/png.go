@@ -0,0 +1,76 @@
+package scrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/samber/lo"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngKeptAncillaryChunks are the optional chunks that affect how the image looks, every other optional chunk is dropped
+var pngKeptAncillaryChunks = []string{
+	"tRNS", "gAMA", "cHRM", "sRGB", "iCCP", "sBIT", "bKGD", "pHYs", "hIST", "sPLT",
+	"cICP", "mDCv", "cLLi", "acTL", "fcTL", "fdAT",
+}
+
+// scrubPNG drops text, time and EXIF chunks from a PNG. EXIF is rebuilt with only the orientation and
+// the image data chunks are copied untouched
+func scrubPNG(data []byte) ([]byte, []string, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, nil, fmt.Errorf("%w: missing PNG signature", ErrMalformedFile)
+	}
+
+	var removed []string
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, nil, fmt.Errorf("%w: truncated PNG %q chunk", ErrMalformedFile, chunkType)
+		}
+
+		// critical chunks start with an upper case letter and are always needed to decode the image
+		critical := chunkType[0] >= 'A' && chunkType[0] <= 'Z'
+		switch {
+		case critical, lo.Contains(pngKeptAncillaryChunks, chunkType):
+			out.Write(data[pos:end])
+		case chunkType == "eXIf":
+			removed = append(removed, "EXIF")
+			if orientation := readOrientation(data[pos+8 : pos+8+length]); keepsOrientation(orientation) {
+				writePNGChunk(out, "eXIf", orientationExif(orientation))
+			}
+		case chunkType == "tEXt", chunkType == "zTXt", chunkType == "iTXt":
+			removed = append(removed, "text")
+		default:
+			removed = append(removed, chunkType)
+		}
+
+		pos = end
+		if chunkType == "IEND" {
+			if pos < len(data) {
+				removed = append(removed, "trailing data")
+			}
+			return out.Bytes(), removed, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: missing PNG IEND chunk", ErrMalformedFile)
+}
+
+func writePNGChunk(out *bytes.Buffer, chunkType string, payload []byte) {
+	_ = binary.Write(out, binary.BigEndian, uint32(len(payload)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(payload)
+	out.WriteString(chunkType)
+	out.Write(payload)
+	_ = binary.Write(out, binary.BigEndian, crc.Sum32())
+}
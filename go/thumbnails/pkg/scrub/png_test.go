@@ -0,0 +1,57 @@
+package scrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pngChunk(chunkType string, payload []byte) []byte {
+	var out bytes.Buffer
+	writePNGChunk(&out, chunkType, payload)
+	return out.Bytes()
+}
+
+func TestScrubPNG(t *testing.T) {
+	// given
+	header := pngChunk("IHDR", make([]byte, 13))
+	profile := pngChunk("iCCP", []byte("icc\x00\x00data"))
+	data := pngChunk("IDAT", []byte("pixels"))
+	end := pngChunk("IEND", nil)
+	var file bytes.Buffer
+	file.Write(pngSignature)
+	file.Write(header)
+	file.Write(pngChunk("tEXt", []byte("Author\x00someone")))
+	file.Write(pngChunk("eXIf", littleEndianExif(3)))
+	file.Write(pngChunk("tIME", make([]byte, 7)))
+	file.Write(profile)
+	file.Write(data)
+	file.Write(end)
+
+	// when
+	scrubbed, removed, err := scrubPNG(file.Bytes())
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"text", "EXIF", "tIME"}, removed)
+	var expected bytes.Buffer
+	expected.Write(pngSignature)
+	expected.Write(header)
+	expected.Write(pngChunk("eXIf", orientationExif(3)))
+	expected.Write(profile)
+	expected.Write(data)
+	expected.Write(end)
+	assert.Equal(t, expected.Bytes(), scrubbed)
+}
+
+func TestWritePNGChunk_Checksum(t *testing.T) {
+	// when
+	chunk := pngChunk("eXIf", []byte("data"))
+
+	// then
+	assert.Equal(t, uint32(4), binary.BigEndian.Uint32(chunk[0:4]))
+	assert.Equal(t, crc32.ChecksumIEEE([]byte("eXIfdata")), binary.BigEndian.Uint32(chunk[12:16]))
+}
@@ -0,0 +1,163 @@
+package scrub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/samber/lo"
+)
+
+// Result describes what was removed from a file
+type Result struct {
+	// Format is the detected container: jpeg, png, webp, heif, mp4 or mov
+	Format string
+	// Removed lists the metadata blocks that were removed or blanked
+	Removed []string
+}
+
+// Changed reports whether any metadata was removed
+func (r Result) Changed() bool {
+	return len(r.Removed) > 0
+}
+
+// ContentType returns the MIME type of the detected format
+func (r Result) ContentType() string {
+	switch r.Format {
+	case "jpeg":
+		return "image/jpeg"
+	case "png":
+		return "image/png"
+	case "webp":
+		return "image/webp"
+	case "heif":
+		return "image/heif"
+	case "mov":
+		return "video/quicktime"
+	}
+	return "video/mp4"
+}
+
+// imageScrubbers rebuild small container formats in memory
+var imageScrubbers = map[string]func([]byte) ([]byte, []string, error){
+	"jpeg": scrubJPEG,
+	"png":  scrubPNG,
+	"webp": scrubWebP,
+}
+
+// detectImageFormat returns the format of a JPEG, PNG or WebP header
+func detectImageFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xff, 0xd8, 0xff}):
+		return "jpeg"
+	case bytes.HasPrefix(header, pngSignature):
+		return "png"
+	case len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		return "webp"
+	}
+	return ""
+}
+
+// ScrubFile removes privacy sensitive metadata from the file at path. the scrubbed copy is written next to the
+// original and renamed over it, so readers never see a partial file. the file is left alone if nothing was removed
+func ScrubFile(path string) (Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Result{}, err
+	}
+
+	header := make([]byte, 16)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnsupportedFormat, err)
+	}
+	header = header[:n]
+
+	if format := detectImageFormat(header); format != "" {
+		return scrubImageFile(file, path, format, info)
+	}
+	if isBMFF(header) {
+		return scrubBMFFFile(file, path, info)
+	}
+	return Result{}, ErrUnsupportedFormat
+}
+
+// scrubImageFile rebuilds an image without its metadata
+func scrubImageFile(file *os.File, path, format string, info os.FileInfo) (Result, error) {
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, info.Size()))
+	if err != nil {
+		return Result{}, err
+	}
+
+	scrubbed, removed, err := imageScrubbers[format](data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Format: format, Removed: lo.Uniq(removed)}
+	if !result.Changed() {
+		return result, nil
+	}
+
+	return result, replaceFile(path, info.Mode(), func(tmp *os.File) error {
+		_, err := tmp.Write(scrubbed)
+		return err
+	})
+}
+
+// scrubBMFFFile copies the video or HEIF image and blanks the metadata of the copy in place, the media data is never read into memory
+func scrubBMFFFile(file *os.File, path string, info os.FileInfo) (Result, error) {
+	var result Result
+	err := replaceFile(path, info.Mode(), func(tmp *os.File) error {
+		if _, err := io.Copy(tmp, io.NewSectionReader(file, 0, info.Size())); err != nil {
+			return err
+		}
+
+		format, removed, err := scrubBMFF(tmp, tmp, info.Size())
+		if err != nil {
+			return err
+		}
+		result = Result{Format: format, Removed: lo.Uniq(removed)}
+		if !result.Changed() {
+			return errUnchanged
+		}
+		return nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return result, nil
+	}
+	return result, err
+}
+
+// replaceFile writes a temporary file in the same directory and renames it over path once write succeeds
+func replaceFile(path string, mode os.FileMode, write func(tmp *os.File) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".scrub-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
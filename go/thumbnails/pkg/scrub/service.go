@@ -0,0 +1,94 @@
+package scrub
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/waifuvault/WaifuVault/shared/utils"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+)
+
+type Service interface {
+	// ScrubByToken removes metadata from a stored file and writes the scrubbed copy back in its place
+	ScrubByToken(fileToken uuid.UUID) (Result, error)
+
+	// ScrubUpload removes metadata from an uploaded file and returns the scrubbed copy
+	ScrubUpload(header *multipart.FileHeader) ([]byte, Result, error)
+}
+
+type service struct {
+	dao     dao.Dao
+	baseUrl string
+}
+
+func NewService(daoService dao.Dao) Service {
+	return &service{
+		dao:     daoService,
+		baseUrl: utils.FileBaseUrl,
+	}
+}
+
+func (s service) ScrubByToken(fileToken uuid.UUID) (Result, error) {
+	fileEntry, err := s.dao.GetFileEntry(fileToken)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %s", ErrFileNotFound, err)
+	}
+	if fileEntry.Encrypted {
+		return Result{}, ErrEncryptedFile
+	}
+
+	path := s.baseUrl + "/" + fileEntry.FullFileNameOnSystem()
+	result, err := ScrubFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	if !result.Changed() {
+		return result, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := s.dao.SetFileSize(fileToken, info.Size()); err != nil {
+		log.Error().Err(err).Str("token", fileToken.String()).Msg("failed to update the size of a scrubbed file")
+	}
+	return result, nil
+}
+
+func (s service) ScrubUpload(header *multipart.FileHeader) ([]byte, Result, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, Result{}, err
+	}
+	defer file.Close()
+
+	tempFile, err := os.CreateTemp("", "scrub-*")
+	if err != nil {
+		return nil, Result{}, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	if _, err := io.Copy(tempFile, file); err != nil {
+		return nil, Result{}, fmt.Errorf("failed to copy file content: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return nil, Result{}, err
+	}
+
+	result, err := ScrubFile(tempFile.Name())
+	if err != nil {
+		return nil, Result{}, err
+	}
+
+	scrubbed, err := os.ReadFile(tempFile.Name())
+	if err != nil {
+		return nil, Result{}, err
+	}
+	return scrubbed, result, nil
+}
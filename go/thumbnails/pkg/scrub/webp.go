@@ -0,0 +1,88 @@
+package scrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/samber/lo"
+)
+
+const (
+	webpFlagXMP  = 0x04
+	webpFlagEXIF = 0x08
+)
+
+// webpKeptChunks are the chunks needed to decode and colour manage a WebP
+var webpKeptChunks = []string{"VP8X", "ICCP", "ANIM", "ANMF", "ALPH", "VP8 ", "VP8L"}
+
+// scrubWebP drops the XMP and unknown chunks of a WebP, EXIF is rebuilt with only the orientation.
+// the VP8X flags are updated to match the chunks that are left
+func scrubWebP(data []byte) ([]byte, []string, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, nil, fmt.Errorf("%w: missing WebP RIFF header", ErrMalformedFile)
+	}
+
+	var removed []string
+	hasVP8X, keptExif := false, false
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+
+	pos := 12
+	for pos+8 <= len(data) {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size
+		if end > len(data) {
+			return nil, nil, fmt.Errorf("%w: truncated WebP %q chunk", ErrMalformedFile, fourCC)
+		}
+		// chunks are padded to an even size
+		padded := min(end+size%2, len(data))
+
+		switch {
+		case fourCC == "VP8X":
+			// the extended header must be the first chunk
+			if body.Len() != 4 || size < 10 {
+				return nil, nil, fmt.Errorf("%w: misplaced WebP VP8X chunk", ErrMalformedFile)
+			}
+			hasVP8X = true
+			body.Write(data[pos:padded])
+		case lo.Contains(webpKeptChunks, fourCC):
+			body.Write(data[pos:padded])
+		case fourCC == "EXIF":
+			removed = append(removed, "EXIF")
+			if orientation := readOrientation(bytes.TrimPrefix(data[pos+8:end], exifHeader)); hasVP8X && keepsOrientation(orientation) {
+				writeWebPChunk(&body, "EXIF", orientationExif(orientation))
+				keptExif = true
+			}
+		case fourCC == "XMP ":
+			removed = append(removed, "XMP")
+		default:
+			removed = append(removed, fourCC)
+		}
+		pos = padded
+	}
+
+	out := body.Bytes()
+	if hasVP8X {
+		// the VP8X flags follow "WEBP" and the chunk header
+		out[12] &^= webpFlagXMP
+		if !keptExif {
+			out[12] &^= webpFlagEXIF
+		}
+	}
+
+	result := make([]byte, 0, len(out)+8)
+	result = append(result, "RIFF"...)
+	result = binary.LittleEndian.AppendUint32(result, uint32(len(out)))
+	return append(result, out...), removed, nil
+}
+
+func writeWebPChunk(body *bytes.Buffer, fourCC string, payload []byte) {
+	body.WriteString(fourCC)
+	_ = binary.Write(body, binary.LittleEndian, uint32(len(payload)))
+	body.Write(payload)
+	if len(payload)%2 == 1 {
+		body.WriteByte(0)
+	}
+}
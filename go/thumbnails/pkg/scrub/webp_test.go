@@ -0,0 +1,52 @@
+package scrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildRiff(chunks ...[]byte) []byte {
+	body := append([]byte("WEBP"), bytes.Join(chunks, nil)...)
+	out := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	return append(out, body...)
+}
+
+func riffChunk(fourCC string, payload []byte) []byte {
+	var out bytes.Buffer
+	writeWebPChunk(&out, fourCC, payload)
+	return out.Bytes()
+}
+
+func TestScrubWebP(t *testing.T) {
+	// given
+	vp8x := riffChunk("VP8X", []byte{0x20 | webpFlagEXIF | webpFlagXMP, 0, 0, 0, 9, 0, 0, 9, 0, 0})
+	profile := riffChunk("ICCP", []byte("profile"))
+	image := riffChunk("VP8L", []byte{0x2f, 1, 2, 3, 4})
+	file := buildRiff(vp8x, profile, image, riffChunk("EXIF", littleEndianExif(1)), riffChunk("XMP ", []byte("<x:xmpmeta/>")))
+
+	// when
+	scrubbed, removed, err := scrubWebP(file)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"EXIF", "XMP"}, removed)
+	expectedHeader := riffChunk("VP8X", []byte{0x20, 0, 0, 0, 9, 0, 0, 9, 0, 0})
+	assert.Equal(t, buildRiff(expectedHeader, profile, image), scrubbed)
+}
+
+func TestScrubWebP_KeepsOrientation(t *testing.T) {
+	// given
+	vp8x := riffChunk("VP8X", []byte{webpFlagEXIF, 0, 0, 0, 9, 0, 0, 9, 0, 0})
+	image := riffChunk("VP8L", []byte{0x2f, 1, 2, 3, 4})
+	file := buildRiff(vp8x, image, riffChunk("EXIF", append([]byte("Exif\x00\x00"), littleEndianExif(6)...)))
+
+	// when
+	scrubbed, _, err := scrubWebP(file)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, buildRiff(vp8x, image, riffChunk("EXIF", orientationExif(6))), scrubbed)
+}
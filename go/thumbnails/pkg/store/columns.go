@@ -0,0 +1,70 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+type base64Store struct{}
+
+// NewBase64Store keeps thumbnails base64 encoded in the data column
+func NewBase64Store() ThumbnailStore {
+	return base64Store{}
+}
+
+func (base64Store) Backend() string {
+	return BackendBase64
+}
+
+func (base64Store) Put(row *mod.Thumbnail, data []byte) error {
+	row.Data = base64.StdEncoding.EncodeToString(data)
+	row.Blob = nil
+	row.Location = nil
+	return nil
+}
+
+func (base64Store) Get(row mod.Thumbnail) ([]byte, error) {
+	if row.Data == "" {
+		return nil, fmt.Errorf("%w: file %d", ErrNotFound, row.FileId)
+	}
+	data, err := base64.StdEncoding.DecodeString(row.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: file %d: %s", ErrInvalidRow, row.FileId, err)
+	}
+	return data, nil
+}
+
+func (base64Store) Delete(mod.Thumbnail) error {
+	return nil
+}
+
+type columnStore struct{}
+
+// NewColumnStore keeps thumbnails in the binary blob column, a third smaller than base64
+func NewColumnStore() ThumbnailStore {
+	return columnStore{}
+}
+
+func (columnStore) Backend() string {
+	return BackendColumn
+}
+
+func (columnStore) Put(row *mod.Thumbnail, data []byte) error {
+	row.Data = ""
+	row.Blob = data
+	row.Location = nil
+	return nil
+}
+
+func (columnStore) Get(row mod.Thumbnail) ([]byte, error) {
+	if len(row.Blob) == 0 {
+		return nil, fmt.Errorf("%w: file %d", ErrNotFound, row.FileId)
+	}
+	return row.Blob, nil
+}
+
+func (columnStore) Delete(mod.Thumbnail) error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Config selects the backend new thumbnails are written to. the column backends are always readable, the directory
+// and S3 backends once they are configured, so rows written before a switch keep working
+type Config struct {
+	Backend string
+	Dir     string
+	S3      S3Config
+}
+
+// ConfigFromEnv reads THUMBNAIL_STORE, THUMBNAIL_STORE_DIR and the THUMBNAIL_S3_* variables
+func ConfigFromEnv() Config {
+	config := Config{
+		Backend: strings.ToLower(strings.TrimSpace(os.Getenv("THUMBNAIL_STORE"))),
+		Dir:     os.Getenv("THUMBNAIL_STORE_DIR"),
+		S3: S3Config{
+			Endpoint:  os.Getenv("THUMBNAIL_S3_ENDPOINT"),
+			Bucket:    os.Getenv("THUMBNAIL_S3_BUCKET"),
+			Region:    os.Getenv("THUMBNAIL_S3_REGION"),
+			AccessKey: os.Getenv("THUMBNAIL_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("THUMBNAIL_S3_SECRET_KEY"),
+			Prefix:    os.Getenv("THUMBNAIL_S3_PREFIX"),
+		},
+	}
+	if config.Backend == "" {
+		config.Backend = BackendBase64
+	}
+	if config.S3.Region == "" {
+		config.S3.Region = "us-east-1"
+	}
+	return config
+}
+
+// New returns a store writing to config.Backend that reads the rows of every configured backend
+func New(config Config) (ThumbnailStore, error) {
+	backends := []ThumbnailStore{NewBase64Store(), NewColumnStore()}
+	if config.Dir != "" {
+		dir, err := NewDirStore(config.Dir)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, dir)
+	}
+	if config.S3.Endpoint != "" {
+		s3, err := NewS3Store(config.S3)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, s3)
+	}
+
+	for _, backend := range backends {
+		if backend.Backend() == config.Backend {
+			return NewMultiStore(backend, backends...), nil
+		}
+	}
+	switch config.Backend {
+	case BackendDir:
+		return nil, fmt.Errorf("thumbnail store %s needs THUMBNAIL_STORE_DIR", config.Backend)
+	case BackendS3:
+		return nil, fmt.Errorf("thumbnail store %s needs THUMBNAIL_S3_ENDPOINT and THUMBNAIL_S3_BUCKET", config.Backend)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, config.Backend)
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+type dirStore struct {
+	dir string
+}
+
+// NewDirStore keeps thumbnails in dir named by the sha256 of their content, so identical thumbnails share a file
+func NewDirStore(dir string) (ThumbnailStore, error) {
+	if dir == "" {
+		return nil, errors.New("thumbnail store directory is not set")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirStore{dir: dir}, nil
+}
+
+func (s *dirStore) Backend() string {
+	return BackendDir
+}
+
+func (s *dirStore) Put(row *mod.Thumbnail, data []byte) error {
+	key := contentKey(data)
+	path := s.path(key)
+
+	// the content is in the name, so an existing file already holds these bytes
+	if _, err := os.Stat(path); err != nil {
+		if err := writeAtomic(path, data); err != nil {
+			return err
+		}
+	}
+
+	row.Data = ""
+	row.Blob = nil
+	row.Location = location(BackendDir, key)
+	return nil
+}
+
+func (s *dirStore) Get(row mod.Thumbnail) ([]byte, error) {
+	key, err := s.key(row)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: file %d", ErrNotFound, row.FileId)
+	}
+	return data, err
+}
+
+func (s *dirStore) Delete(row mod.Thumbnail) error {
+	key, err := s.key(row)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// key returns the content key of a row, rejecting anything that isn't a sha256 so a row can't point outside the directory
+func (s *dirStore) key(row mod.Thumbnail) (string, error) {
+	key, err := locationKey(row, BackendDir)
+	if err != nil {
+		return "", err
+	}
+	if decoded, err := hex.DecodeString(key); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("%w: file %d has an invalid content key", ErrInvalidRow, row.FileId)
+	}
+	return key, nil
+}
+
+// path shards files over two directory levels so no directory grows too large
+func (s *dirStore) path(key string) string {
+	return filepath.Join(s.dir, key[0:2], key[2:4], key)
+}
+
+// contentKey is the hex sha256 of a thumbnail
+func contentKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeAtomic writes a temporary file next to path and renames it into place, so readers never see a partial file
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
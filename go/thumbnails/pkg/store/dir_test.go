@@ -0,0 +1,77 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+func TestDirStore_PutGetDelete(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	store, err := NewDirStore(dir)
+	assert.NoError(t, err)
+	row := mod.Thumbnail{FileId: 1, Data: "stale"}
+	data := []byte("thumbnail")
+	key := contentKey(data)
+
+	// when
+	err = store.Put(&row, data)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "dir:"+key, *row.Location)
+	assert.Empty(t, row.Data)
+	assert.FileExists(t, filepath.Join(dir, key[0:2], key[2:4], key))
+
+	stored, err := store.Get(row)
+	assert.NoError(t, err)
+	assert.Equal(t, data, stored)
+
+	assert.NoError(t, store.Delete(row))
+	_, err = store.Get(row)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(row))
+}
+
+func TestDirStore_IdenticalContentSharesFile(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	store, err := NewDirStore(dir)
+	assert.NoError(t, err)
+	first, second := mod.Thumbnail{FileId: 1}, mod.Thumbnail{FileId: 2}
+
+	// when
+	assert.NoError(t, store.Put(&first, []byte("thumbnail")))
+	assert.NoError(t, store.Put(&second, []byte("thumbnail")))
+
+	// then
+	assert.Equal(t, *first.Location, *second.Location)
+	var files int
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, _ error) error {
+		if !info.IsDir() {
+			files++
+		}
+		return nil
+	})
+	assert.Equal(t, 1, files)
+}
+
+func TestDirStore_RejectsInvalidLocation(t *testing.T) {
+	// given
+	store, err := NewDirStore(t.TempDir())
+	assert.NoError(t, err)
+	escape := "dir:../../../etc/passwd"
+	other := "s3:" + contentKey([]byte("thumbnail"))
+
+	// when
+	_, escapeErr := store.Get(mod.Thumbnail{Location: &escape})
+	_, otherErr := store.Get(mod.Thumbnail{Location: &other})
+
+	// then
+	assert.ErrorIs(t, escapeErr, ErrInvalidRow)
+	assert.ErrorIs(t, otherErr, ErrInvalidRow)
+}
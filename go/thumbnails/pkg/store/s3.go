@@ -0,0 +1,146 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+// S3Config locates a bucket of an S3 compatible object store, addressed path style so MinIO, Garage and similar work
+type S3Config struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// Prefix is prepended to every object key
+	Prefix string
+}
+
+type s3Store struct {
+	config      S3Config
+	endpoint    *url.URL
+	credentials credentials
+	client      *http.Client
+	now         func() time.Time
+}
+
+// NewS3Store keeps thumbnails in an S3 bucket named by the sha256 of their content, so identical thumbnails share an object
+func NewS3Store(config S3Config) (ThumbnailStore, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("thumbnail store S3 endpoint and bucket must be set")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid thumbnail store S3 endpoint %q", config.Endpoint)
+	}
+	return &s3Store{
+		config:   config,
+		endpoint: endpoint,
+		credentials: credentials{
+			accessKey: config.AccessKey,
+			secretKey: config.SecretKey,
+			region:    config.Region,
+			service:   "s3",
+		},
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+func (s *s3Store) Backend() string {
+	return BackendS3
+}
+
+func (s *s3Store) Put(row *mod.Thumbnail, data []byte) error {
+	key := contentKey(data)
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", http.DetectContentType(data))
+
+	resp, err := s.do(req, sha256Hex(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError(resp, key)
+	}
+
+	row.Data = ""
+	row.Blob = nil
+	row.Location = location(BackendS3, key)
+	return nil
+}
+
+func (s *s3Store) Get(row mod.Thumbnail) ([]byte, error) {
+	key, err := locationKey(row, BackendS3)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: file %d", ErrNotFound, row.FileId)
+	}
+	return nil, s.responseError(resp, key)
+}
+
+func (s *s3Store) Delete(row mod.Thumbnail) error {
+	key, err := locationKey(row, BackendS3)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return s.responseError(resp, key)
+}
+
+func (s *s3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.credentials.sign(req, payloadHash, s.now())
+	return s.client.Do(req)
+}
+
+func (s *s3Store) objectURL(key string) string {
+	objectURL := *s.endpoint
+	objectURL.Path = objectURL.Path + "/" + s.config.Bucket + "/" + s.config.Prefix + key
+	return objectURL.String()
+}
+
+// responseError includes the start of the S3 error document, which names the failing condition
+func (s *s3Store) responseError(resp *http.Response, key string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s of %s failed with %s: %s", resp.Request.Method, key, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+// s3StandIn is a local stand-in for an S3 compatible service, it checks every request is signed with the test
+// credentials and keeps objects in memory
+type s3StandIn struct {
+	t       *testing.T
+	signer  credentials
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newS3StandIn(t *testing.T) (*s3StandIn, S3Config) {
+	standIn := &s3StandIn{
+		t:       t,
+		signer:  credentials{accessKey: "access", secretKey: "secret", region: "us-east-1", service: "s3"},
+		objects: make(map[string][]byte),
+	}
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	return standIn, S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "thumbnails",
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Prefix:    "album/",
+	}
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !s.validSignature(r, body) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<Error><Code>SignatureDoesNotMatch</Code></Error>"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[r.URL.Path] = body
+	case http.MethodGet:
+		object, found := s.objects[r.URL.Path]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(object)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// validSignature signs a copy of the request the way the server would and compares the result
+func (s *s3StandIn) validSignature(r *http.Request, body []byte) bool {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != sha256Hex(body) {
+		return false
+	}
+	date, err := time.Parse(sigV4DateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	expected, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	signed := strings.Split(strings.Split(r.Header.Get("Authorization"), "SignedHeaders=")[1], ",")[0]
+	for _, name := range strings.Split(signed, ";") {
+		if name != "host" && !strings.HasPrefix(name, "x-amz-") {
+			expected.Header.Set(name, r.Header.Get(name))
+		}
+	}
+	s.signer.sign(expected, payloadHash, date)
+	return expected.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (s *s3StandIn) object(path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object, found := s.objects[path]
+	return object, found
+}
+
+func TestS3Store_PutGetDelete(t *testing.T) {
+	// given
+	standIn, config := newS3StandIn(t)
+	s3, err := NewS3Store(config)
+	assert.NoError(t, err)
+	row := mod.Thumbnail{FileId: 1}
+	data := []byte("RIFF\x00\x00\x00\x00WEBPVP8 thumbnail")
+
+	// when
+	err = s3.Put(&row, data)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "s3:"+contentKey(data), *row.Location)
+	object, found := standIn.object("/thumbnails/album/" + contentKey(data))
+	assert.True(t, found)
+	assert.Equal(t, data, object)
+
+	stored, err := s3.Get(row)
+	assert.NoError(t, err)
+	assert.Equal(t, data, stored)
+
+	assert.NoError(t, s3.Delete(row))
+	_, found = standIn.object("/thumbnails/album/" + contentKey(data))
+	assert.False(t, found)
+	_, err = s3.Get(row)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestS3Store_WrongCredentials(t *testing.T) {
+	// given
+	_, config := newS3StandIn(t)
+	config.SecretKey = "wrong"
+	s3, err := NewS3Store(config)
+	assert.NoError(t, err)
+	row := mod.Thumbnail{FileId: 1}
+
+	// when
+	err = s3.Put(&row, []byte("thumbnail"))
+
+	// then
+	assert.ErrorContains(t, err, "SignatureDoesNotMatch")
+	assert.Nil(t, row.Location)
+}
+
+func TestNewS3Store_InvalidConfig(t *testing.T) {
+	_, err := NewS3Store(S3Config{Bucket: "thumbnails"})
+	assert.Error(t, err)
+
+	_, err = NewS3Store(S3Config{Endpoint: "not a url", Bucket: "thumbnails"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+// Backends a thumbnail_cache_model row can be kept in
+const (
+	// BackendBase64 keeps the thumbnail base64 encoded in the data column, which the Node service has always read
+	BackendBase64 = "base64"
+	// BackendColumn keeps the thumbnail in the binary blob column
+	BackendColumn = "db"
+	// BackendDir keeps the thumbnail in a content addressed local directory
+	BackendDir = "dir"
+	// BackendS3 keeps the thumbnail in an S3 compatible object store
+	BackendS3 = "s3"
+)
+
+var (
+	ErrNotFound       = errors.New("thumbnail not found in store")
+	ErrUnknownBackend = errors.New("unknown thumbnail store")
+	ErrInvalidRow     = errors.New("invalid thumbnail row")
+)
+
+// ThumbnailStore keeps the bytes of the album thumbnails, the thumbnail_cache_model row records where they are
+type ThumbnailStore interface {
+	// Backend is the name of the backend new thumbnails are written to
+	Backend() string
+	// Put stores data and sets the columns of row that locate it
+	Put(row *mod.Thumbnail, data []byte) error
+	// Get reads the thumbnail of a row
+	Get(row mod.Thumbnail) ([]byte, error)
+	// Delete removes the thumbnail of a row, rows kept in their own columns have nothing to remove
+	Delete(row mod.Thumbnail) error
+}
+
+// BackendOf returns the backend holding the thumbnail of a row
+func BackendOf(row mod.Thumbnail) string {
+	if row.Location != nil && *row.Location != "" {
+		backend, _, _ := strings.Cut(*row.Location, ":")
+		return backend
+	}
+	if row.Blob != nil {
+		return BackendColumn
+	}
+	return BackendBase64
+}
+
+// location returns the location of an object in an external backend
+func location(backend, key string) *string {
+	value := backend + ":" + key
+	return &value
+}
+
+// locationKey returns the object key of a row kept in an external backend
+func locationKey(row mod.Thumbnail, backend string) (string, error) {
+	if row.Location == nil {
+		return "", fmt.Errorf("%w: file %d has no location", ErrInvalidRow, row.FileId)
+	}
+	prefix, key, found := strings.Cut(*row.Location, ":")
+	if !found || prefix != backend || key == "" {
+		return "", fmt.Errorf("%w: file %d is not in %s", ErrInvalidRow, row.FileId, backend)
+	}
+	return key, nil
+}
+
+// multiStore writes to one backend and reads the rows of every configured backend, so rows can be moved between
+// backends while the service runs
+type multiStore struct {
+	writer   ThumbnailStore
+	backends map[string]ThumbnailStore
+}
+
+// NewMultiStore returns a store writing to writer that reads from writer and readers by the backend of each row
+func NewMultiStore(writer ThumbnailStore, readers ...ThumbnailStore) ThumbnailStore {
+	backends := make(map[string]ThumbnailStore, len(readers)+1)
+	for _, reader := range readers {
+		backends[reader.Backend()] = reader
+	}
+	backends[writer.Backend()] = writer
+	return &multiStore{
+		writer:   writer,
+		backends: backends,
+	}
+}
+
+func (s *multiStore) Backend() string {
+	return s.writer.Backend()
+}
+
+func (s *multiStore) Put(row *mod.Thumbnail, data []byte) error {
+	return s.writer.Put(row, data)
+}
+
+func (s *multiStore) Get(row mod.Thumbnail) ([]byte, error) {
+	backend, err := s.backendOf(row)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Get(row)
+}
+
+func (s *multiStore) Delete(row mod.Thumbnail) error {
+	backend, err := s.backendOf(row)
+	if err != nil {
+		return err
+	}
+	return backend.Delete(row)
+}
+
+func (s *multiStore) backendOf(row mod.Thumbnail) (ThumbnailStore, error) {
+	name := BackendOf(row)
+	backend, found := s.backends[name]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, name)
+	}
+	return backend, nil
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+func TestBackendOf(t *testing.T) {
+	dirLocation := "dir:abc"
+	empty := ""
+
+	assert.Equal(t, BackendBase64, BackendOf(mod.Thumbnail{Data: "dGh1bWJuYWls"}))
+	assert.Equal(t, BackendColumn, BackendOf(mod.Thumbnail{Blob: []byte("thumbnail")}))
+	assert.Equal(t, BackendDir, BackendOf(mod.Thumbnail{Location: &dirLocation}))
+	assert.Equal(t, BackendBase64, BackendOf(mod.Thumbnail{Location: &empty}))
+}
+
+func TestColumnStores_RoundTrip(t *testing.T) {
+	for _, store := range []ThumbnailStore{NewBase64Store(), NewColumnStore()} {
+		t.Run(store.Backend(), func(t *testing.T) {
+			// given
+			location := "dir:abc"
+			row := mod.Thumbnail{FileId: 1, Location: &location}
+
+			// when
+			err := store.Put(&row, []byte("thumbnail"))
+
+			// then
+			assert.NoError(t, err)
+			assert.Nil(t, row.Location)
+			assert.Equal(t, store.Backend(), BackendOf(row))
+			data, err := store.Get(row)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("thumbnail"), data)
+		})
+	}
+}
+
+func TestBase64Store_Empty(t *testing.T) {
+	_, err := NewBase64Store().Get(mod.Thumbnail{FileId: 1})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMultiStore_ReadsEveryBackend(t *testing.T) {
+	// given
+	dir, err := NewDirStore(t.TempDir())
+	assert.NoError(t, err)
+	multi := NewMultiStore(NewColumnStore(), NewBase64Store(), dir)
+	legacy := mod.Thumbnail{FileId: 1}
+	assert.NoError(t, NewBase64Store().Put(&legacy, []byte("legacy")))
+	onDisk := mod.Thumbnail{FileId: 2}
+	assert.NoError(t, dir.Put(&onDisk, []byte("on disk")))
+
+	// when
+	written := mod.Thumbnail{FileId: 3}
+	assert.NoError(t, multi.Put(&written, []byte("new")))
+	legacyData, legacyErr := multi.Get(legacy)
+	onDiskData, onDiskErr := multi.Get(onDisk)
+	writtenData, writtenErr := multi.Get(written)
+
+	// then
+	assert.Equal(t, BackendColumn, multi.Backend())
+	assert.Equal(t, BackendColumn, BackendOf(written))
+	assert.NoError(t, legacyErr)
+	assert.Equal(t, []byte("legacy"), legacyData)
+	assert.NoError(t, onDiskErr)
+	assert.Equal(t, []byte("on disk"), onDiskData)
+	assert.NoError(t, writtenErr)
+	assert.Equal(t, []byte("new"), writtenData)
+}
+
+func TestMultiStore_UnconfiguredBackend(t *testing.T) {
+	// given
+	multi := NewMultiStore(NewBase64Store())
+	location := "s3:abc"
+
+	// when
+	_, err := multi.Get(mod.Thumbnail{Location: &location})
+
+	// then
+	assert.ErrorIs(t, err, ErrUnknownBackend)
+}
+
+func TestNew(t *testing.T) {
+	// when
+	dirStore, dirErr := New(Config{Backend: BackendDir, Dir: t.TempDir()})
+	_, missingDirErr := New(Config{Backend: BackendDir})
+	_, unknownErr := New(Config{Backend: "tape"})
+
+	// then
+	assert.NoError(t, dirErr)
+	assert.Equal(t, BackendDir, dirStore.Backend())
+	assert.ErrorContains(t, missingDirErr, "THUMBNAIL_STORE_DIR")
+	assert.ErrorIs(t, unknownErr, ErrUnknownBackend)
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_STORE", " S3 ")
+	t.Setenv("THUMBNAIL_S3_ENDPOINT", "http://minio:9000")
+	t.Setenv("THUMBNAIL_S3_BUCKET", "thumbnails")
+	t.Setenv("THUMBNAIL_S3_REGION", "")
+
+	// when
+	config := ConfigFromEnv()
+
+	// then
+	assert.Equal(t, BackendS3, config.Backend)
+	assert.Equal(t, "http://minio:9000", config.S3.Endpoint)
+	assert.Equal(t, "us-east-1", config.S3.Region)
+}
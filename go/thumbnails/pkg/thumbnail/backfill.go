@@ -0,0 +1,314 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+const (
+	DefaultBackfillRate = 2
+	// backfillStateName is the db row holding the id of the last file the backfill handled, so a restarted run carries
+	// on from it
+	backfillStateName = "backfill"
+	// backfillLockKey is taken for every page when a locker is configured, so instances share one run
+	backfillLockKey          = "thumbnail-backfill"
+	backfillLockTTL          = 10 * time.Minute
+	backfillLockPollInterval = 5 * time.Second
+)
+
+// BackfillConfig controls the job that generates the missing thumbnails of album files
+type BackfillConfig struct {
+	// BatchSize is the number of files read and generated per page
+	BatchSize int
+	// FilesPerSecond limits how fast files are handed to the batch processor, 0 disables the limit
+	FilesPerSecond float64
+	// Interval starts a run periodically, 0 only runs on demand
+	Interval time.Duration
+}
+
+// BackfillConfigFromEnv reads THUMBNAIL_BACKFILL_BATCH_SIZE, THUMBNAIL_BACKFILL_RATE and THUMBNAIL_BACKFILL_INTERVAL
+func BackfillConfigFromEnv() BackfillConfig {
+	config := BackfillConfig{
+		BatchSize:      DefaultBatchSize,
+		FilesPerSecond: DefaultBackfillRate,
+	}
+	if raw := os.Getenv("THUMBNAIL_BACKFILL_BATCH_SIZE"); raw != "" {
+		batchSize, err := strconv.Atoi(raw)
+		if err != nil || batchSize <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_BACKFILL_BATCH_SIZE")
+		} else {
+			config.BatchSize = batchSize
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_BACKFILL_RATE"); raw != "" {
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_BACKFILL_RATE")
+		} else {
+			config.FilesPerSecond = rate
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_BACKFILL_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_BACKFILL_INTERVAL")
+		} else {
+			config.Interval = interval
+		}
+	}
+	return config
+}
+
+// BackfillStatus is the progress of the current or last backfill run
+type BackfillStatus struct {
+	Running bool
+	// Cursor is the id of the last file handled
+	Cursor int
+	// Remaining is the number of files without a thumbnail after the cursor when the run started
+	Remaining int64
+	// Processed is the number of files handed to the batch processor
+	Processed int
+	// Skipped is the number of protected or unsupported files, and of files whose album was already being processed
+	Skipped    int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	LastError  string
+}
+
+// backfill pages through the album files without a stored thumbnail by id and generates them with the batch processor
+type backfill struct {
+	dao               dao.Dao
+	processor         Processor
+	locker            cache.Locker
+	config            BackfillConfig
+	newBatchProcessor func(files []dto.FileEntryDto, albumId int) BatchProcessor
+	sleep             func(time.Duration)
+
+	mu     sync.Mutex
+	status BackfillStatus
+}
+
+func newBackfill(daoService dao.Dao, processor Processor, pool *WorkerPool, failures FailureConfig, locker cache.Locker, config BackfillConfig) *backfill {
+	return &backfill{
+		dao:       daoService,
+		processor: processor,
+		locker:    locker,
+		config:    config,
+		newBatchProcessor: func(files []dto.FileEntryDto, albumId int) BatchProcessor {
+			return NewBatchProcessor(daoService, processor, pool, failures, files, albumId, CropNone, false)
+		},
+		sleep: time.Sleep,
+	}
+}
+
+// schedule resumes a run interrupted by a restart, then starts one every interval
+func (b *backfill) schedule() {
+	if b.loadCursor() > 0 {
+		log.Info().Msg("resuming thumbnail backfill")
+		_, _ = b.start(false)
+	}
+	if b.config.Interval <= 0 {
+		return
+	}
+	for range time.Tick(b.config.Interval) {
+		if _, err := b.start(false); err != nil && !errors.Is(err, ErrBackfillRunning) {
+			log.Error().Err(err).Msg("failed to start thumbnail backfill")
+		}
+	}
+}
+
+// start runs the backfill in the background, restart discards the stored cursor and starts from the first file
+func (b *backfill) start(restart bool) (BackfillStatus, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.status.Running {
+		return b.status, ErrBackfillRunning
+	}
+	b.status = BackfillStatus{
+		Running:   true,
+		StartedAt: time.Now(),
+	}
+
+	go b.run(restart)
+	return b.status, nil
+}
+
+func (b *backfill) getStatus() BackfillStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
+
+func (b *backfill) run(restart bool) {
+	err := b.backfill(restart)
+
+	b.update(func(status *BackfillStatus) {
+		status.Running = false
+		status.FinishedAt = time.Now()
+		if err != nil {
+			status.LastError = err.Error()
+		}
+	})
+
+	status := b.getStatus()
+	if err != nil {
+		log.Error().Err(err).Int("cursor", status.Cursor).Msg("thumbnail backfill failed")
+		return
+	}
+	log.Info().Int("processed", status.Processed).Int("skipped", status.Skipped).Msg("thumbnail backfill finished")
+}
+
+func (b *backfill) backfill(restart bool) error {
+	if restart {
+		if err := b.saveCursor(0); err != nil {
+			return err
+		}
+	}
+
+	cursor := b.loadCursor()
+	remaining, err := b.dao.CountFilesMissingThumbnails(cursor)
+	if err != nil {
+		return err
+	}
+	b.update(func(status *BackfillStatus) {
+		status.Cursor = cursor
+		status.Remaining = remaining
+	})
+
+	for {
+		unlock, held := b.lock()
+		if !held {
+			b.sleep(backfillLockPollInterval)
+			continue
+		}
+
+		done, err := b.page()
+		unlock()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// page generates the next page of files after the stored cursor, done is true once no files are left
+func (b *backfill) page() (bool, error) {
+	// another instance may have moved the cursor while we waited for the lock
+	cursor := b.loadCursor()
+	albumFiles, err := b.dao.GetFilesMissingThumbnails(cursor, b.config.BatchSize)
+	if err != nil {
+		return false, err
+	}
+	if len(albumFiles) == 0 {
+		// later runs start over, retrying the files that failed in this one
+		return true, b.saveCursor(0)
+	}
+
+	started := time.Now()
+	processed, skipped := b.generate(albumFiles)
+
+	cursor = albumFiles[len(albumFiles)-1].Id
+	if err := b.saveCursor(cursor); err != nil {
+		return false, err
+	}
+	b.update(func(status *BackfillStatus) {
+		status.Cursor = cursor
+		status.Processed += processed
+		status.Skipped += skipped
+	})
+
+	b.throttle(processed, time.Since(started))
+	return false, nil
+}
+
+// generate hands the supported files of a page to the batch processor, one album at a time
+func (b *backfill) generate(albumFiles []mod.AlbumFile) (processed int, skipped int) {
+	var albumIds []int
+	filesByAlbum := make(map[int][]dto.FileEntryDto)
+	for _, albumFile := range albumFiles {
+		file := dto.FromModel(albumFile.FileEntry)
+		if albumFile.Protected() || !b.processor.SupportsFile(file) {
+			skipped++
+			continue
+		}
+		if _, found := filesByAlbum[albumFile.AlbumId]; !found {
+			albumIds = append(albumIds, albumFile.AlbumId)
+		}
+		filesByAlbum[albumFile.AlbumId] = append(filesByAlbum[albumFile.AlbumId], file)
+	}
+
+	for _, albumId := range albumIds {
+		files := filesByAlbum[albumId]
+		if err := b.newBatchProcessor(files, albumId).Process(context.Background()); err != nil {
+			log.Warn().Err(err).Int("albumId", albumId).Msg("skipping album in thumbnail backfill")
+			skipped += len(files)
+			continue
+		}
+		processed += len(files)
+	}
+	return processed, skipped
+}
+
+// throttle waits until processed files at the configured rate would have taken at least elapsed
+func (b *backfill) throttle(processed int, elapsed time.Duration) {
+	if delay := throttleDelay(processed, b.config.FilesPerSecond, elapsed); delay > 0 {
+		b.sleep(delay)
+	}
+}
+
+// lock takes the backfill lock when a locker is configured
+func (b *backfill) lock() (func(), bool) {
+	return lockJob(b.locker, backfillLockKey, backfillLockTTL)
+}
+
+// throttleDelay returns how much longer processed files should take at filesPerSecond than the elapsed time, a rate
+// of 0 disables the limit
+func throttleDelay(processed int, filesPerSecond float64, elapsed time.Duration) time.Duration {
+	if filesPerSecond <= 0 || processed == 0 {
+		return 0
+	}
+	budget := time.Duration(float64(processed) / filesPerSecond * float64(time.Second))
+	return max(budget-elapsed, 0)
+}
+
+// lockJob takes the lock of a background job when a locker is configured, a failing locker doesn't stop the job
+func lockJob(locker cache.Locker, key string, ttl time.Duration) (func(), bool) {
+	noop := func() {}
+	if locker == nil {
+		return noop, true
+	}
+	unlock, held, err := locker.TryLock(key, ttl)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to take job lock")
+		return noop, true
+	}
+	return lo.Ternary(held, unlock, noop), held
+}
+
+func (b *backfill) loadCursor() int {
+	cursor, err := b.dao.GetBackfillCursor(backfillStateName)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load backfill cursor")
+	}
+	return cursor
+}
+
+func (b *backfill) saveCursor(cursor int) error {
+	return b.dao.SaveBackfillCursor(backfillStateName, cursor)
+}
+
+func (b *backfill) update(apply func(status *BackfillStatus)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	apply(&b.status)
+}
@@ -0,0 +1,241 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+)
+
+type processedAlbum struct {
+	albumId int
+	fileIds []int
+}
+
+type testBackfill struct {
+	*backfill
+	mu     sync.Mutex
+	albums []processedAlbum
+	sleeps []time.Duration
+	cursor int
+}
+
+// newTestBackfill keeps the cursor of the backfill in the test instead of the db
+func newTestBackfill(t *testing.T, daoService *dao.MockDao, processor Processor, config BackfillConfig) *testBackfill {
+	tb := &testBackfill{}
+	tb.backfill = newBackfill(daoService, processor, nil, FailureConfig{}, nil, config)
+	daoService.EXPECT().GetBackfillCursor(backfillStateName).RunAndReturn(func(string, ...*gorm.DB) (int, error) {
+		tb.mu.Lock()
+		defer tb.mu.Unlock()
+		return tb.cursor, nil
+	}).Maybe()
+	daoService.EXPECT().SaveBackfillCursor(backfillStateName, mock.Anything).RunAndReturn(func(_ string, cursor int, _ ...*gorm.DB) error {
+		tb.mu.Lock()
+		defer tb.mu.Unlock()
+		tb.cursor = cursor
+		return nil
+	}).Maybe()
+	tb.newBatchProcessor = func(files []dto.FileEntryDto, albumId int) BatchProcessor {
+		batchProcessor := NewMockBatchProcessor(t)
+		batchProcessor.EXPECT().Process(mock.Anything).RunAndReturn(func(context.Context) error {
+			tb.mu.Lock()
+			defer tb.mu.Unlock()
+			tb.albums = append(tb.albums, processedAlbum{
+				albumId: albumId,
+				fileIds: lo.Map(files, func(f dto.FileEntryDto, _ int) int { return f.Id }),
+			})
+			return nil
+		})
+		return batchProcessor
+	}
+	tb.sleep = func(d time.Duration) {
+		tb.sleeps = append(tb.sleeps, d)
+	}
+	return tb
+}
+
+func albumFile(id int, albumId int, extension string) mod.AlbumFile {
+	return mod.AlbumFile{
+		FileEntry: mod.FileEntry{
+			Id:        id,
+			Token:     uuid.New(),
+			FileName:  "file",
+			Extension: extension,
+			MediaType: "image/" + extension,
+		},
+		AlbumId: albumId,
+	}
+}
+
+func supportsPng(processor *MockProcessor) {
+	processor.EXPECT().SupportsFile(mock.Anything).RunAndReturn(func(file dto.FileEntryDto) bool {
+		return file.Extension == "png"
+	}).Maybe()
+}
+
+func TestBackfill_GeneratesPagesByAlbum(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	supportsPng(processor)
+	password := `{"password":"hash"}`
+	protected := albumFile(4, 2, "png")
+	protected.Settings = &password
+	daoService.EXPECT().CountFilesMissingThumbnails(0).Return(int64(5), nil)
+	daoService.EXPECT().GetFilesMissingThumbnails(0, 3).Return([]mod.AlbumFile{
+		albumFile(1, 1, "png"),
+		albumFile(2, 2, "png"),
+		albumFile(3, 1, "png"),
+	}, nil)
+	daoService.EXPECT().GetFilesMissingThumbnails(3, 3).Return([]mod.AlbumFile{
+		protected,
+		albumFile(5, 2, "pdf"),
+	}, nil)
+	daoService.EXPECT().GetFilesMissingThumbnails(5, 3).Return(nil, nil)
+	tb := newTestBackfill(t, daoService, processor, BackfillConfig{BatchSize: 3})
+
+	// when
+	err := tb.backfill.backfill(false)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []processedAlbum{{albumId: 1, fileIds: []int{1, 3}}, {albumId: 2, fileIds: []int{2}}}, tb.albums)
+	status := tb.getStatus()
+	assert.Equal(t, 5, status.Cursor)
+	assert.Equal(t, int64(5), status.Remaining)
+	assert.Equal(t, 3, status.Processed)
+	assert.Equal(t, 2, status.Skipped)
+	assert.Equal(t, 0, tb.loadCursor())
+}
+
+func TestBackfill_ResumesFromStoredCursor(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	supportsPng(processor)
+	daoService.EXPECT().CountFilesMissingThumbnails(40).Return(int64(1), nil)
+	daoService.EXPECT().GetFilesMissingThumbnails(40, 10).Return([]mod.AlbumFile{albumFile(41, 7, "png")}, nil)
+	daoService.EXPECT().GetFilesMissingThumbnails(41, 10).Return(nil, errors.New("connection refused"))
+	tb := newTestBackfill(t, daoService, processor, BackfillConfig{BatchSize: 10})
+	assert.NoError(t, tb.saveCursor(40))
+
+	// when
+	err := tb.backfill.backfill(false)
+
+	// then
+	assert.Error(t, err)
+	assert.Equal(t, []processedAlbum{{albumId: 7, fileIds: []int{41}}}, tb.albums)
+	assert.Equal(t, 41, tb.loadCursor())
+}
+
+func TestBackfill_RestartDiscardsCursor(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	daoService.EXPECT().CountFilesMissingThumbnails(0).Return(int64(0), nil)
+	daoService.EXPECT().GetFilesMissingThumbnails(0, 10).Return(nil, nil)
+	tb := newTestBackfill(t, daoService, processor, BackfillConfig{BatchSize: 10})
+	assert.NoError(t, tb.saveCursor(40))
+
+	// when
+	err := tb.backfill.backfill(true)
+
+	// then
+	assert.NoError(t, err)
+	assert.Empty(t, tb.albums)
+}
+
+func TestBackfill_SkipsAlbumsBeingProcessed(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	supportsPng(processor)
+	tb := newTestBackfill(t, daoService, processor, BackfillConfig{BatchSize: 10})
+	tb.newBatchProcessor = func(files []dto.FileEntryDto, albumId int) BatchProcessor {
+		batchProcessor := NewMockBatchProcessor(t)
+		batchProcessor.EXPECT().Process(mock.Anything).Return(errors.New("albumId 1 is already being processed"))
+		return batchProcessor
+	}
+
+	// when
+	processed, skipped := tb.generate([]mod.AlbumFile{albumFile(1, 1, "png"), albumFile(2, 1, "png")})
+
+	// then
+	assert.Equal(t, 0, processed)
+	assert.Equal(t, 2, skipped)
+}
+
+func TestBackfill_Throttle(t *testing.T) {
+	// given
+	tb := newTestBackfill(t, dao.NewMockDao(t), NewMockProcessor(t), BackfillConfig{FilesPerSecond: 10})
+
+	// when
+	tb.throttle(5, 100*time.Millisecond)
+	tb.throttle(5, time.Second)
+	tb.throttle(0, 0)
+
+	// then
+	assert.Equal(t, []time.Duration{400 * time.Millisecond}, tb.sleeps)
+}
+
+func TestBackfill_StartWhileRunning(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	release := make(chan struct{})
+	daoService.EXPECT().CountFilesMissingThumbnails(0).RunAndReturn(func(int, ...*gorm.DB) (int64, error) {
+		<-release
+		return 0, nil
+	})
+	daoService.EXPECT().GetFilesMissingThumbnails(0, 10).Return(nil, nil)
+	tb := newTestBackfill(t, daoService, NewMockProcessor(t), BackfillConfig{BatchSize: 10})
+
+	// when
+	first, err := tb.start(false)
+	assert.NoError(t, err)
+	_, secondErr := tb.start(false)
+	close(release)
+
+	// then
+	assert.True(t, first.Running)
+	assert.True(t, errors.Is(secondErr, ErrBackfillRunning))
+	assert.Eventually(t, func() bool {
+		return !tb.getStatus().Running
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, tb.getStatus().FinishedAt.IsZero())
+}
+
+func TestBackfillConfigFromEnv(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_BACKFILL_BATCH_SIZE", "20")
+	t.Setenv("THUMBNAIL_BACKFILL_RATE", "0.5")
+	t.Setenv("THUMBNAIL_BACKFILL_INTERVAL", "6h")
+
+	// when
+	config := BackfillConfigFromEnv()
+
+	// then
+	assert.Equal(t, BackfillConfig{BatchSize: 20, FilesPerSecond: 0.5, Interval: 6 * time.Hour}, config)
+}
+
+func TestBackfillConfigFromEnv_InvalidValues(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_BACKFILL_BATCH_SIZE", "0")
+	t.Setenv("THUMBNAIL_BACKFILL_RATE", "fast")
+	t.Setenv("THUMBNAIL_BACKFILL_INTERVAL", "")
+
+	// when
+	config := BackfillConfigFromEnv()
+
+	// then
+	assert.Equal(t, BackfillConfig{BatchSize: DefaultBatchSize, FilesPerSecond: DefaultBackfillRate}, config)
+}
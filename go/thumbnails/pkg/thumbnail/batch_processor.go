@@ -1,11 +1,14 @@
 package thumbnail
 
 import (
-	"encoding/base64"
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
@@ -13,65 +16,126 @@ import (
 
 // BatchProcessor handles processing and saving thumbnails in batches
 type BatchProcessor interface {
-	Process() error
-	thumbnailWorker(wg *sync.WaitGroup, filesChan <-chan dto.FileEntryDto, resultsChan chan<- mod.Thumbnail)
-	batchProcess(resultsChan <-chan mod.Thumbnail, done chan<- struct{})
+	Process(ctx context.Context) error
+	renderFile(ctx context.Context, file dto.FileEntryDto, resultsChan chan<- mod.Thumbnail)
+	batchProcess(ctx context.Context, resultsChan <-chan mod.Thumbnail, done chan<- struct{})
+}
+
+// jobCancellation is the cause a job run is cancelled with, discard drops the thumbnails rendered but not stored yet
+// instead of storing them
+type jobCancellation struct {
+	discard bool
+}
+
+func (c jobCancellation) Error() string {
+	return ErrJobCancelled.Error()
+}
+
+func (c jobCancellation) Unwrap() error {
+	return ErrJobCancelled
+}
+
+// discarding reports whether a cancelled run drops its unsaved thumbnails, runs cancelled for another reason store them
+func discarding(ctx context.Context) bool {
+	var cancellation jobCancellation
+	return errors.As(context.Cause(ctx), &cancellation) && cancellation.discard
 }
 
 type batchProcessor struct {
-	dao         dao.Dao
-	processor   Processor
-	files       []dto.FileEntryDto
-	albumID     int
-	workerCount int
-	batchSize   int
+	dao       dao.Dao
+	processor Processor
+	pool      *WorkerPool
+	failures  FailureConfig
+	files     []dto.FileEntryDto
+	albumID   int
+	crop      CropMode
+	// force renders the files that already have a stored thumbnail instead of skipping them
+	force       bool
+	focalPoints map[int]mod.FocalPoint
+	// duplicates holds the files that get the thumbnail of the rendered file with the same render key
+	duplicates map[int][]int
+	stored     map[string][]byte
+	// existing holds the files that already have a stored thumbnail, it is empty when forced
+	existing map[int]bool
+	// failed holds the failures of files that failed before, it is only read once the files are fed to the pool
+	failed    map[int]mod.FileFailure
+	batchSize int
+	// progress is nil unless the files are processed for a job
+	progress *progressTracker
+	// saveErrs is only read once batchProcess is done
+	saveErrs []error
+}
+
+// NewBatchProcessor creates a new batch processor that renders its files on the shared pool
+func NewBatchProcessor(daoService dao.Dao, processor Processor, pool *WorkerPool, failures FailureConfig, files []dto.FileEntryDto, albumID int, crop CropMode, force bool) BatchProcessor {
+	return newTrackedBatchProcessor(daoService, processor, pool, failures, files, albumID, crop, force, nil)
 }
 
-// NewBatchProcessor creates a new batch processor
-func NewBatchProcessor(daoService dao.Dao, processor Processor, files []dto.FileEntryDto, albumID int) BatchProcessor {
+// newTrackedBatchProcessor creates a batch processor that reports the files it handles to the progress of a job
+func newTrackedBatchProcessor(daoService dao.Dao, processor Processor, pool *WorkerPool, failures FailureConfig, files []dto.FileEntryDto, albumID int, crop CropMode, force bool, progress *progressTracker) BatchProcessor {
 	return &batchProcessor{
-		dao:         daoService,
-		processor:   processor,
-		files:       files,
-		albumID:     albumID,
-		workerCount: DefaultWorkerCount,
-		batchSize:   DefaultBatchSize,
+		dao:       daoService,
+		processor: processor,
+		pool:      pool,
+		failures:  failures,
+		files:     files,
+		albumID:   albumID,
+		crop:      crop,
+		force:     force,
+		batchSize: DefaultBatchSize,
+		progress:  progress,
 	}
 }
 
-// Process runs the batch thumbnail generation process
-func (bp *batchProcessor) Process() error {
+// Process runs the batch thumbnail generation process, the errors of the batches that failed to save are returned so
+// the job can be retried. files that already have a thumbnail are skipped unless forced. files that fail to render
+// are skipped until their retry is due. cancelling ctx stops feeding files to the pool and interrupts the renders in
+// flight, the cause of the cancellation is returned
+func (bp *batchProcessor) Process(ctx context.Context) error {
 	if _, loaded := albumProcessing.LoadOrStore(bp.albumID, true); loaded {
-		return fmt.Errorf("albumId %d is already being processed", bp.albumID)
+		return fmt.Errorf("%w: albumId %d is already being processed", ErrAlbumProcessing, bp.albumID)
 	}
 	// Ensure the processing flag is removed when done
 	defer albumProcessing.Delete(bp.albumID)
 
-	filesChan := make(chan dto.FileEntryDto)
-	resultsChan := make(chan mod.Thumbnail)
-	batchSaveDone := make(chan struct{})
-
-	var wg sync.WaitGroup
+	// Focal points only matter when cropping, so only look them up then
+	if bp.crop != CropNone {
+		fileIds := lo.Map(bp.files, func(f dto.FileEntryDto, _ int) int {
+			return f.Id
+		})
+		focalPoints, err := bp.dao.GetFocalPoints(fileIds)
+		if err != nil {
+			log.Err(err).Msgf("failed to load focal points for album %d, falling back to %s crop", bp.albumID, bp.crop)
+		}
+		bp.focalPoints = focalPoints
+	}
 
-	// Start worker goroutines
-	for i := 0; i < bp.workerCount; i++ {
-		wg.Add(1)
-		go bp.thumbnailWorker(&wg, filesChan, resultsChan)
+	bp.loadFailures()
+	bp.loadExisting()
+	files := bp.groupDuplicates()
+	if !bp.force {
+		bp.loadStored(files)
 	}
 
-	// Feed files to workers
-	go func() {
-		for _, f := range bp.files {
-			filesChan <- f
-		}
-		close(filesChan)
-	}()
+	resultsChan := make(chan mod.Thumbnail)
+	batchSaveDone := make(chan struct{})
 
 	// Start batch processing goroutine
-	go bp.batchProcess(resultsChan, batchSaveDone)
+	go bp.batchProcess(ctx, resultsChan, batchSaveDone)
 
-	// Wait for workers to finish
+	// Feed files to the pool until the run is cancelled, then wait for the ones it was given
 	go func() {
+		var wg sync.WaitGroup
+		for _, f := range files {
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			bp.pool.submit(ctx, bp.albumID, bp.decodeCost(f), func() {
+				defer wg.Done()
+				bp.renderFile(ctx, f, resultsChan)
+			})
+		}
 		wg.Wait()
 		close(resultsChan)
 	}()
@@ -79,37 +143,191 @@ func (bp *batchProcessor) Process() error {
 	// Wait for batch processing to complete
 	<-batchSaveDone
 
-	return nil
+	if ctx.Err() != nil {
+		return errors.Join(append(bp.saveErrs, context.Cause(ctx))...)
+	}
+	return errors.Join(bp.saveErrs...)
 }
 
-// thumbnailWorker processes individual files and sends results to the result channel
-func (bp *batchProcessor) thumbnailWorker(wg *sync.WaitGroup, filesChan <-chan dto.FileEntryDto, resultsChan chan<- mod.Thumbnail) {
-	defer wg.Done()
+// renderFile runs on a pool worker, it renders a file and sends the thumbnail of it and its duplicates to the result
+// channel
+func (bp *batchProcessor) renderFile(ctx context.Context, file dto.FileEntryDto, resultsChan chan<- mod.Thumbnail) {
+	if ctx.Err() != nil {
+		// the file was queued before the run was cancelled
+		return
+	}
+	fileIds := append([]int{file.Id}, bp.duplicates[file.Id]...)
+	if !bp.processor.SupportsFile(file) {
+		bp.recordFailures(fmt.Errorf("%w: %s", ErrUnsupportedFileType, file.MediaType), fileIds)
+		bp.progress.skipped("unsupported file type", fileIds...)
+		return
+	}
 
-	for file := range filesChan {
-		if !bp.processor.SupportsFile(file) {
-			continue
+	options := bp.optionsFor(file)
+	renderKey := options.renderKey(file.Checksum)
+	thumbnailBytes, found := bp.stored[renderKey]
+	if !found {
+		var err error
+		thumbnailBytes, err = bp.processor.GenerateThumbnail(ctx, file, options)
+		if err != nil && ctx.Err() != nil {
+			// an interrupted render is not a failure of the file
+			log.Debug().Int("fileId", file.Id).Msg("thumbnail generation cancelled")
+			return
 		}
-
-		thumbnailBytes, err := bp.processor.GenerateThumbnail(file, false)
 		if err != nil {
 			log.Err(err).Msgf("failed to generate thumbnail for file %s", file.FullFileNameOnSystem)
-			continue
+			bp.recordFailures(err, fileIds)
+			bp.progress.failed(err, fileIds...)
+			return
 		}
+	}
 
+	for _, fileId := range fileIds {
 		resultsChan <- mod.Thumbnail{
-			Data:   base64.StdEncoding.EncodeToString(thumbnailBytes),
-			FileId: file.Id,
+			Content:         thumbnailBytes,
+			FileId:          fileId,
+			RenderKey:       lo.EmptyableToPtr(renderKey),
+			PipelineVersion: PipelineVersion,
+			Crop:            string(bp.crop),
+		}
+	}
+}
+
+// decodeCost is the memory the pool reserves for the render of a file, files that aren't decoded cost nothing
+func (bp *batchProcessor) decodeCost(file dto.FileEntryDto) int64 {
+	if !bp.processor.SupportsFile(file) {
+		return 0
+	}
+	if _, found := bp.stored[bp.optionsFor(file).renderKey(file.Checksum)]; found {
+		return 0
+	}
+	return bp.processor.DecodeCost(file)
+}
+
+// loadFailures looks up the files that failed before, files that are quarantined or whose retry isn't due are skipped
+func (bp *batchProcessor) loadFailures() {
+	if len(bp.files) == 0 {
+		return
+	}
+	fileIds := lo.Map(bp.files, func(f dto.FileEntryDto, _ int) int {
+		return f.Id
+	})
+	failed, err := bp.dao.GetFileFailures(fileIds)
+	if err != nil {
+		log.Err(err).Msgf("failed to load file failures for album %d, rendering every file", bp.albumID)
+	}
+	bp.failed = failed
+}
+
+// loadExisting looks up the files that already have a stored thumbnail, nothing is looked up when forced
+func (bp *batchProcessor) loadExisting() {
+	if bp.force || len(bp.files) == 0 {
+		return
+	}
+	fileIds := lo.Map(bp.files, func(f dto.FileEntryDto, _ int) int {
+		return f.Id
+	})
+	existing, err := bp.dao.GetThumbnailFileIds(fileIds)
+	if err != nil {
+		log.Err(err).Msgf("failed to load the stored thumbnails of album %d, rendering every file", bp.albumID)
+	}
+	bp.existing = lo.SliceToMap(existing, func(fileId int) (int, bool) {
+		return fileId, true
+	})
+}
+
+// recordFailures stores the failure of the files of a render, the file and its duplicates share the failure
+func (bp *batchProcessor) recordFailures(err error, fileIds []int) {
+	now := time.Now()
+	for _, fileId := range fileIds {
+		failure := bp.failures.nextFailure(bp.failed[fileId], fileId, err, now)
+		if saveErr := bp.dao.SaveFileFailure(&failure); saveErr != nil {
+			log.Err(saveErr).Int("fileId", fileId).Msg("failed to record thumbnail failure")
+		} else if failure.Quarantined {
+			log.Warn().Int("fileId", fileId).Str("class", string(failure.ErrorClass)).Msg("quarantined file after failing to render it")
+		}
+	}
+}
+
+// clearFailures resets the failures of files that got their thumbnail
+func (bp *batchProcessor) clearFailures(fileIds []int) {
+	recovered := lo.Filter(fileIds, func(fileId int, _ int) bool {
+		_, failed := bp.failed[fileId]
+		return failed
+	})
+	if len(recovered) == 0 {
+		return
+	}
+	if _, err := bp.dao.DeleteFileFailures(recovered); err != nil {
+		log.Err(err).Msgf("failed to reset the thumbnail failures of album %d", bp.albumID)
+	}
+}
+
+// groupDuplicates returns the files to render, files with the same render key as an earlier file are left out and
+// recorded as its duplicates. files that already have a thumbnail or are held back after failing are left out and
+// skipped
+func (bp *batchProcessor) groupDuplicates() []dto.FileEntryDto {
+	bp.duplicates = make(map[int][]int)
+	rendering := make(map[string]int)
+	now := time.Now()
+
+	var files []dto.FileEntryDto
+	for _, file := range bp.files {
+		if bp.existing[file.Id] {
+			bp.progress.skipped("thumbnail already stored", file.Id)
+			continue
 		}
+		if reason, held := heldBack(bp.failed[file.Id], now); held {
+			bp.progress.skipped(reason, file.Id)
+			continue
+		}
+		renderKey := bp.optionsFor(file).renderKey(file.Checksum)
+		if fileId, found := rendering[renderKey]; found && renderKey != "" {
+			bp.duplicates[fileId] = append(bp.duplicates[fileId], file.Id)
+			continue
+		}
+		rendering[renderKey] = file.Id
+		files = append(files, file)
+	}
+	return files
+}
+
+// loadStored looks up thumbnails stored for other files with the same contents, those files are not decoded again.
+// forced runs render every file so they don't look them up
+func (bp *batchProcessor) loadStored(files []dto.FileEntryDto) {
+	renderKeys := lo.FilterMap(files, func(file dto.FileEntryDto, _ int) (string, bool) {
+		renderKey := bp.optionsFor(file).renderKey(file.Checksum)
+		return renderKey, renderKey != ""
+	})
+	if len(renderKeys) == 0 {
+		return
 	}
+
+	stored, err := bp.dao.GetThumbnailsByRenderKey(renderKeys)
+	if err != nil {
+		log.Err(err).Msgf("failed to load stored thumbnails for album %d, rendering every file", bp.albumID)
+	}
+	bp.stored = stored
+}
+
+// optionsFor returns the render options for a file, album thumbnails are never animated
+func (bp *batchProcessor) optionsFor(file dto.FileEntryDto) Options {
+	options := Options{Crop: bp.crop}
+	if focalPoint, ok := bp.focalPoints[file.Id]; ok {
+		options.FocalPoint = &focalPoint
+	}
+	return options
 }
 
 // batchProcessor collects thumbnails and saves them in batches
-func (bp *batchProcessor) batchProcess(resultsChan <-chan mod.Thumbnail, done chan<- struct{}) {
+func (bp *batchProcessor) batchProcess(ctx context.Context, resultsChan <-chan mod.Thumbnail, done chan<- struct{}) {
 	var batch []mod.Thumbnail
 	batchCount := 0
 
 	for thumbnail := range resultsChan {
+		if discarding(ctx) {
+			continue
+		}
 		batch = append(batch, thumbnail)
 
 		// When we reach batch size, save the batch
@@ -121,8 +339,12 @@ func (bp *batchProcessor) batchProcess(resultsChan <-chan mod.Thumbnail, done ch
 			_, err := bp.dao.SaveThumbnails(batchToSave)
 			if err != nil {
 				log.Err(err).Msgf("failed to save thumbnail batch %d", batchCount)
+				bp.saveErrs = append(bp.saveErrs, err)
+				bp.progress.failed(err, thumbnailFileIds(batchToSave)...)
 			} else {
 				log.Debug().Msgf("saved thumbnail batch %d with %d thumbnails", batchCount, len(batchToSave))
+				bp.clearFailures(thumbnailFileIds(batchToSave))
+				bp.progress.processed(thumbnailFileIds(batchToSave)...)
 			}
 
 			// Reset the batch
@@ -131,16 +353,28 @@ func (bp *batchProcessor) batchProcess(resultsChan <-chan mod.Thumbnail, done ch
 		}
 	}
 
-	// Save any remaining thumbnails
-	if len(batch) > 0 {
+	// Save any remaining thumbnails, a cancelled run stores them unless it discards them
+	if discarding(ctx) && len(batch) > 0 {
+		log.Debug().Msgf("discarded final thumbnail batch with %d thumbnails", len(batch))
+	} else if len(batch) > 0 {
 		_, err := bp.dao.SaveThumbnails(batch)
 		if err != nil {
 			log.Err(err).Msgf("failed to save final thumbnail batch")
+			bp.saveErrs = append(bp.saveErrs, err)
+			bp.progress.failed(err, thumbnailFileIds(batch)...)
 		} else {
 			log.Debug().Msgf("saved final thumbnail batch with %d thumbnails", len(batch))
+			bp.clearFailures(thumbnailFileIds(batch))
+			bp.progress.processed(thumbnailFileIds(batch)...)
 		}
 	}
 
 	// Signal that all batches have been processed
 	close(done)
 }
+
+func thumbnailFileIds(thumbnails []mod.Thumbnail) []int {
+	return lo.Map(thumbnails, func(thumbnail mod.Thumbnail, _ int) int {
+		return thumbnail.FileId
+	})
+}
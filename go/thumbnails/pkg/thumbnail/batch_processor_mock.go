@@ -5,7 +5,7 @@
 package thumbnail
 
 import (
-	"sync"
+	"context"
 
 	mock "github.com/stretchr/testify/mock"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
@@ -40,16 +40,16 @@ func (_m *MockBatchProcessor) EXPECT() *MockBatchProcessor_Expecter {
 }
 
 // Process provides a mock function for the type MockBatchProcessor
-func (_mock *MockBatchProcessor) Process() error {
-	ret := _mock.Called()
+func (_mock *MockBatchProcessor) Process(ctx context.Context) error {
+	ret := _mock.Called(ctx)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Process")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func() error); ok {
-		r0 = returnFunc()
+	if returnFunc, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = returnFunc(ctx)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -62,13 +62,20 @@ type MockBatchProcessor_Process_Call struct {
 }
 
 // Process is a helper method to define mock.On call
-func (_e *MockBatchProcessor_Expecter) Process() *MockBatchProcessor_Process_Call {
-	return &MockBatchProcessor_Process_Call{Call: _e.mock.On("Process")}
+//   - ctx context.Context
+func (_e *MockBatchProcessor_Expecter) Process(ctx interface{}) *MockBatchProcessor_Process_Call {
+	return &MockBatchProcessor_Process_Call{Call: _e.mock.On("Process", ctx)}
 }
 
-func (_c *MockBatchProcessor_Process_Call) Run(run func()) *MockBatchProcessor_Process_Call {
+func (_c *MockBatchProcessor_Process_Call) Run(run func(ctx context.Context)) *MockBatchProcessor_Process_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run()
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
 	})
 	return _c
 }
@@ -78,14 +85,14 @@ func (_c *MockBatchProcessor_Process_Call) Return(err error) *MockBatchProcessor
 	return _c
 }
 
-func (_c *MockBatchProcessor_Process_Call) RunAndReturn(run func() error) *MockBatchProcessor_Process_Call {
+func (_c *MockBatchProcessor_Process_Call) RunAndReturn(run func(ctx context.Context) error) *MockBatchProcessor_Process_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
 // batchProcess provides a mock function for the type MockBatchProcessor
-func (_mock *MockBatchProcessor) batchProcess(resultsChan <-chan mod.Thumbnail, done chan<- struct{}) {
-	_mock.Called(resultsChan, done)
+func (_mock *MockBatchProcessor) batchProcess(ctx context.Context, resultsChan <-chan mod.Thumbnail, done chan<- struct{}) {
+	_mock.Called(ctx, resultsChan, done)
 	return
 }
 
@@ -95,25 +102,31 @@ type MockBatchProcessor_batchProcess_Call struct {
 }
 
 // batchProcess is a helper method to define mock.On call
+//   - ctx context.Context
 //   - resultsChan <-chan mod.Thumbnail
 //   - done chan<- struct{}
-func (_e *MockBatchProcessor_Expecter) batchProcess(resultsChan interface{}, done interface{}) *MockBatchProcessor_batchProcess_Call {
-	return &MockBatchProcessor_batchProcess_Call{Call: _e.mock.On("batchProcess", resultsChan, done)}
+func (_e *MockBatchProcessor_Expecter) batchProcess(ctx interface{}, resultsChan interface{}, done interface{}) *MockBatchProcessor_batchProcess_Call {
+	return &MockBatchProcessor_batchProcess_Call{Call: _e.mock.On("batchProcess", ctx, resultsChan, done)}
 }
 
-func (_c *MockBatchProcessor_batchProcess_Call) Run(run func(resultsChan <-chan mod.Thumbnail, done chan<- struct{})) *MockBatchProcessor_batchProcess_Call {
+func (_c *MockBatchProcessor_batchProcess_Call) Run(run func(ctx context.Context, resultsChan <-chan mod.Thumbnail, done chan<- struct{})) *MockBatchProcessor_batchProcess_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 <-chan mod.Thumbnail
+		var arg0 context.Context
 		if args[0] != nil {
-			arg0 = args[0].(<-chan mod.Thumbnail)
+			arg0 = args[0].(context.Context)
 		}
-		var arg1 chan<- struct{}
+		var arg1 <-chan mod.Thumbnail
 		if args[1] != nil {
-			arg1 = args[1].(chan<- struct{})
+			arg1 = args[1].(<-chan mod.Thumbnail)
+		}
+		var arg2 chan<- struct{}
+		if args[2] != nil {
+			arg2 = args[2].(chan<- struct{})
 		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
@@ -124,39 +137,39 @@ func (_c *MockBatchProcessor_batchProcess_Call) Return() *MockBatchProcessor_bat
 	return _c
 }
 
-func (_c *MockBatchProcessor_batchProcess_Call) RunAndReturn(run func(resultsChan <-chan mod.Thumbnail, done chan<- struct{})) *MockBatchProcessor_batchProcess_Call {
+func (_c *MockBatchProcessor_batchProcess_Call) RunAndReturn(run func(ctx context.Context, resultsChan <-chan mod.Thumbnail, done chan<- struct{})) *MockBatchProcessor_batchProcess_Call {
 	_c.Run(run)
 	return _c
 }
 
-// thumbnailWorker provides a mock function for the type MockBatchProcessor
-func (_mock *MockBatchProcessor) thumbnailWorker(wg *sync.WaitGroup, filesChan <-chan dto.FileEntryDto, resultsChan chan<- mod.Thumbnail) {
-	_mock.Called(wg, filesChan, resultsChan)
+// renderFile provides a mock function for the type MockBatchProcessor
+func (_mock *MockBatchProcessor) renderFile(ctx context.Context, file dto.FileEntryDto, resultsChan chan<- mod.Thumbnail) {
+	_mock.Called(ctx, file, resultsChan)
 	return
 }
 
-// MockBatchProcessor_thumbnailWorker_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'thumbnailWorker'
-type MockBatchProcessor_thumbnailWorker_Call struct {
+// MockBatchProcessor_renderFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'renderFile'
+type MockBatchProcessor_renderFile_Call struct {
 	*mock.Call
 }
 
-// thumbnailWorker is a helper method to define mock.On call
-//   - wg *sync.WaitGroup
-//   - filesChan <-chan dto.FileEntryDto
+// renderFile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - file dto.FileEntryDto
 //   - resultsChan chan<- mod.Thumbnail
-func (_e *MockBatchProcessor_Expecter) thumbnailWorker(wg interface{}, filesChan interface{}, resultsChan interface{}) *MockBatchProcessor_thumbnailWorker_Call {
-	return &MockBatchProcessor_thumbnailWorker_Call{Call: _e.mock.On("thumbnailWorker", wg, filesChan, resultsChan)}
+func (_e *MockBatchProcessor_Expecter) renderFile(ctx interface{}, file interface{}, resultsChan interface{}) *MockBatchProcessor_renderFile_Call {
+	return &MockBatchProcessor_renderFile_Call{Call: _e.mock.On("renderFile", ctx, file, resultsChan)}
 }
 
-func (_c *MockBatchProcessor_thumbnailWorker_Call) Run(run func(wg *sync.WaitGroup, filesChan <-chan dto.FileEntryDto, resultsChan chan<- mod.Thumbnail)) *MockBatchProcessor_thumbnailWorker_Call {
+func (_c *MockBatchProcessor_renderFile_Call) Run(run func(ctx context.Context, file dto.FileEntryDto, resultsChan chan<- mod.Thumbnail)) *MockBatchProcessor_renderFile_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 *sync.WaitGroup
+		var arg0 context.Context
 		if args[0] != nil {
-			arg0 = args[0].(*sync.WaitGroup)
+			arg0 = args[0].(context.Context)
 		}
-		var arg1 <-chan dto.FileEntryDto
+		var arg1 dto.FileEntryDto
 		if args[1] != nil {
-			arg1 = args[1].(<-chan dto.FileEntryDto)
+			arg1 = args[1].(dto.FileEntryDto)
 		}
 		var arg2 chan<- mod.Thumbnail
 		if args[2] != nil {
@@ -171,12 +184,12 @@ func (_c *MockBatchProcessor_thumbnailWorker_Call) Run(run func(wg *sync.WaitGro
 	return _c
 }
 
-func (_c *MockBatchProcessor_thumbnailWorker_Call) Return() *MockBatchProcessor_thumbnailWorker_Call {
+func (_c *MockBatchProcessor_renderFile_Call) Return() *MockBatchProcessor_renderFile_Call {
 	_c.Call.Return()
 	return _c
 }
 
-func (_c *MockBatchProcessor_thumbnailWorker_Call) RunAndReturn(run func(wg *sync.WaitGroup, filesChan <-chan dto.FileEntryDto, resultsChan chan<- mod.Thumbnail)) *MockBatchProcessor_thumbnailWorker_Call {
+func (_c *MockBatchProcessor_renderFile_Call) RunAndReturn(run func(ctx context.Context, file dto.FileEntryDto, resultsChan chan<- mod.Thumbnail)) *MockBatchProcessor_renderFile_Call {
 	_c.Run(run)
 	return _c
 }
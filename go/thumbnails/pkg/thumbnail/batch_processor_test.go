@@ -1,18 +1,32 @@
 package thumbnail
 
 import (
-	"encoding/base64"
+	"bytes"
+	"context"
 	"errors"
-	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
 )
 
+var testFailureConfig = FailureConfig{MaxAttempts: DefaultFileMaxAttempts, RetryDelay: DefaultFileRetryDelay}
+
+// noFileFailures stubs the failure lookup of a run whose files never failed
+func noFileFailures(daoService *dao.MockDao) {
+	daoService.On("GetFileFailures", mock.Anything).Return(map[int]mod.FileFailure{}, nil)
+}
+
+// noStoredThumbnails stubs the lookup of the files that already have a thumbnail, for a run whose files have none
+func noStoredThumbnails(daoService *dao.MockDao) {
+	daoService.On("GetThumbnailFileIds", mock.Anything).Return([]int{}, nil)
+}
+
 func TestNewBatchProcessor_CreatesProcessorWithDefaults(t *testing.T) {
 	// given
 	daoService := dao.NewMockDao(t)
@@ -23,12 +37,12 @@ func TestNewBatchProcessor_CreatesProcessorWithDefaults(t *testing.T) {
 	albumID := 123
 
 	// when
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// then
 	assert.NotNil(t, bp)
 	batchProc := bp.(*batchProcessor)
-	assert.Equal(t, DefaultWorkerCount, batchProc.workerCount)
+	assert.NotNil(t, batchProc.pool)
 	assert.Equal(t, DefaultBatchSize, batchProc.batchSize)
 	assert.Equal(t, albumID, batchProc.albumID)
 	assert.Equal(t, files, batchProc.files)
@@ -44,13 +58,13 @@ func TestBatchProcessor_Process_AlreadyProcessing(t *testing.T) {
 	albumProcessing.Store(albumID, true)
 	defer albumProcessing.Delete(albumID)
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrAlbumProcessing)
 	assert.Contains(t, err.Error(), "already being processed")
 }
 
@@ -61,10 +75,10 @@ func TestBatchProcessor_Process_EmptyFileList(t *testing.T) {
 	files := []dto.FileEntryDto{}
 	albumID := 789
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
 	assert.NoError(t, err)
@@ -73,7 +87,10 @@ func TestBatchProcessor_Process_EmptyFileList(t *testing.T) {
 func TestBatchProcessor_Process_SingleFile(t *testing.T) {
 	// given
 	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
 	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
 	files := []dto.FileEntryDto{
 		{
 			Id:                   1,
@@ -86,18 +103,18 @@ func TestBatchProcessor_Process_SingleFile(t *testing.T) {
 
 	expectedThumbnail := []byte("thumbnail-data")
 	processor.On("SupportsFile", files[0]).Return(true)
-	processor.On("GenerateThumbnail", files[0], false).Return(expectedThumbnail, nil)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return(expectedThumbnail, nil)
 
 	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
 		return len(thumbnails) == 1 &&
 			thumbnails[0].FileId == 1 &&
-			thumbnails[0].Data == base64.StdEncoding.EncodeToString(expectedThumbnail)
+			bytes.Equal(thumbnails[0].Content, expectedThumbnail)
 	})).Return([]mod.Thumbnail{{FileId: 1}}, nil)
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
 	assert.NoError(t, err)
@@ -108,7 +125,10 @@ func TestBatchProcessor_Process_SingleFile(t *testing.T) {
 func TestBatchProcessor_Process_MultipleFiles(t *testing.T) {
 	// given
 	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
 	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
 	files := []dto.FileEntryDto{
 		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"},
 		{Id: 2, MediaType: "image/png", Extension: "png", FullFileNameOnSystem: "test2.png"},
@@ -118,17 +138,17 @@ func TestBatchProcessor_Process_MultipleFiles(t *testing.T) {
 
 	for _, file := range files {
 		processor.On("SupportsFile", file).Return(true)
-		processor.On("GenerateThumbnail", file, false).Return([]byte("thumbnail"), nil)
+		processor.On("GenerateThumbnail", mock.Anything, file, Options{}).Return([]byte("thumbnail"), nil)
 	}
 
 	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
 		return len(thumbnails) == 3
 	})).Return(make([]mod.Thumbnail, 3), nil)
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
 	assert.NoError(t, err)
@@ -139,7 +159,10 @@ func TestBatchProcessor_Process_MultipleFiles(t *testing.T) {
 func TestBatchProcessor_Process_UnsupportedFileSkipped(t *testing.T) {
 	// given
 	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
 	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
 	files := []dto.FileEntryDto{
 		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"},
 		{Id: 2, MediaType: "text/plain", Extension: "txt", FullFileNameOnSystem: "test2.txt"},
@@ -148,19 +171,22 @@ func TestBatchProcessor_Process_UnsupportedFileSkipped(t *testing.T) {
 	albumID := 303
 
 	processor.On("SupportsFile", files[0]).Return(true)
-	processor.On("GenerateThumbnail", files[0], false).Return([]byte("thumbnail1"), nil)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return([]byte("thumbnail1"), nil)
 	processor.On("SupportsFile", files[1]).Return(false)
 	processor.On("SupportsFile", files[2]).Return(true)
-	processor.On("GenerateThumbnail", files[2], false).Return([]byte("thumbnail3"), nil)
+	processor.On("GenerateThumbnail", mock.Anything, files[2], Options{}).Return([]byte("thumbnail3"), nil)
+	daoService.On("SaveFileFailure", mock.MatchedBy(func(failure *mod.FileFailure) bool {
+		return failure.FileId == 2 && failure.ErrorClass == mod.FailureUnsupported && failure.Quarantined
+	})).Return(nil)
 
 	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
 		return len(thumbnails) == 2
 	})).Return(make([]mod.Thumbnail, 2), nil)
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
 	assert.NoError(t, err)
@@ -171,7 +197,10 @@ func TestBatchProcessor_Process_UnsupportedFileSkipped(t *testing.T) {
 func TestBatchProcessor_Process_GenerateThumbnailError(t *testing.T) {
 	// given
 	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
 	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
 	files := []dto.FileEntryDto{
 		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"},
 		{Id: 2, MediaType: "image/png", Extension: "png", FullFileNameOnSystem: "test2.png"},
@@ -179,18 +208,22 @@ func TestBatchProcessor_Process_GenerateThumbnailError(t *testing.T) {
 	albumID := 404
 
 	processor.On("SupportsFile", files[0]).Return(true)
-	processor.On("GenerateThumbnail", files[0], false).Return(nil, errors.New("generation failed"))
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return(nil, errors.New("generation failed"))
 	processor.On("SupportsFile", files[1]).Return(true)
-	processor.On("GenerateThumbnail", files[1], false).Return([]byte("thumbnail2"), nil)
+	processor.On("GenerateThumbnail", mock.Anything, files[1], Options{}).Return([]byte("thumbnail2"), nil)
+	daoService.On("SaveFileFailure", mock.MatchedBy(func(failure *mod.FileFailure) bool {
+		return failure.FileId == 1 && failure.ErrorClass == mod.FailureTransient && failure.Attempts == 1 &&
+			failure.LastError == "generation failed" && !failure.Quarantined && failure.NextAttemptAt != nil
+	})).Return(nil)
 
 	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
 		return len(thumbnails) == 1 && thumbnails[0].FileId == 2
 	})).Return([]mod.Thumbnail{{FileId: 2}}, nil)
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
 	assert.NoError(t, err)
@@ -201,23 +234,26 @@ func TestBatchProcessor_Process_GenerateThumbnailError(t *testing.T) {
 func TestBatchProcessor_Process_SaveThumbnailsError(t *testing.T) {
 	// given
 	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
 	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
 	files := []dto.FileEntryDto{
 		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test.jpg"},
 	}
 	albumID := 505
 
 	processor.On("SupportsFile", files[0]).Return(true)
-	processor.On("GenerateThumbnail", files[0], false).Return([]byte("thumbnail"), nil)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return([]byte("thumbnail"), nil)
 	daoService.On("SaveThumbnails", mock.Anything).Return([]mod.Thumbnail{}, errors.New("database error"))
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
-	assert.NoError(t, err)
+	assert.ErrorContains(t, err, "database error")
 	processor.AssertExpectations(t)
 	daoService.AssertExpectations(t)
 }
@@ -225,7 +261,10 @@ func TestBatchProcessor_Process_SaveThumbnailsError(t *testing.T) {
 func TestBatchProcessor_Process_BatchSizeReached(t *testing.T) {
 	// given
 	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
 	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
 
 	files := make([]dto.FileEntryDto, 55)
 	for i := 0; i < 55; i++ {
@@ -236,7 +275,7 @@ func TestBatchProcessor_Process_BatchSizeReached(t *testing.T) {
 			FullFileNameOnSystem: "test.jpg",
 		}
 		processor.On("SupportsFile", files[i]).Return(true)
-		processor.On("GenerateThumbnail", files[i], false).Return([]byte("thumbnail"), nil)
+		processor.On("GenerateThumbnail", mock.Anything, files[i], Options{}).Return([]byte("thumbnail"), nil)
 	}
 	albumID := 606
 
@@ -248,10 +287,10 @@ func TestBatchProcessor_Process_BatchSizeReached(t *testing.T) {
 		return len(thumbnails) == 5
 	})).Return(make([]mod.Thumbnail, 5), nil).Once()
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
 	assert.NoError(t, err)
@@ -259,7 +298,7 @@ func TestBatchProcessor_Process_BatchSizeReached(t *testing.T) {
 	daoService.AssertExpectations(t)
 }
 
-func TestBatchProcessor_ThumbnailWorker_ProcessesFiles(t *testing.T) {
+func TestBatchProcessor_RenderFile(t *testing.T) {
 	// given
 	processor := NewMockProcessor(t)
 	file := dto.FileEntryDto{
@@ -271,30 +310,22 @@ func TestBatchProcessor_ThumbnailWorker_ProcessesFiles(t *testing.T) {
 
 	expectedThumbnail := []byte("thumbnail-data")
 	processor.On("SupportsFile", file).Return(true)
-	processor.On("GenerateThumbnail", file, false).Return(expectedThumbnail, nil)
+	processor.On("GenerateThumbnail", mock.Anything, file, Options{}).Return(expectedThumbnail, nil)
 
 	bp := &batchProcessor{
 		processor: processor,
 	}
 
-	filesChan := make(chan dto.FileEntryDto, 1)
 	resultsChan := make(chan mod.Thumbnail, 1)
-	var wg sync.WaitGroup
-
-	filesChan <- file
-	close(filesChan)
-
-	wg.Add(1)
 
 	// when
-	go bp.thumbnailWorker(&wg, filesChan, resultsChan)
-	wg.Wait()
+	bp.renderFile(context.Background(), file, resultsChan)
 	close(resultsChan)
 
 	// then
 	result := <-resultsChan
 	assert.Equal(t, file.Id, result.FileId)
-	assert.Equal(t, base64.StdEncoding.EncodeToString(expectedThumbnail), result.Data)
+	assert.Equal(t, expectedThumbnail, result.Content)
 	processor.AssertExpectations(t)
 }
 
@@ -305,8 +336,8 @@ func TestBatchProcessor_BatchProcess_SavesBatches(t *testing.T) {
 	thumbnails := make([]mod.Thumbnail, 55)
 	for i := 0; i < 55; i++ {
 		thumbnails[i] = mod.Thumbnail{
-			FileId: i + 1,
-			Data:   "thumbnail-data",
+			FileId:  i + 1,
+			Content: []byte("thumbnail-data"),
 		}
 	}
 
@@ -332,7 +363,7 @@ func TestBatchProcessor_BatchProcess_SavesBatches(t *testing.T) {
 	close(resultsChan)
 
 	// when
-	go bp.batchProcess(resultsChan, done)
+	go bp.batchProcess(context.Background(), resultsChan, done)
 	<-done
 
 	// then
@@ -346,8 +377,8 @@ func TestBatchProcessor_BatchProcess_SavesRemainingThumbnails(t *testing.T) {
 	thumbnails := make([]mod.Thumbnail, 3)
 	for i := 0; i < 3; i++ {
 		thumbnails[i] = mod.Thumbnail{
-			FileId: i + 1,
-			Data:   "thumbnail-data",
+			FileId:  i + 1,
+			Content: []byte("thumbnail-data"),
 		}
 	}
 
@@ -369,7 +400,7 @@ func TestBatchProcessor_BatchProcess_SavesRemainingThumbnails(t *testing.T) {
 	close(resultsChan)
 
 	// when
-	go bp.batchProcess(resultsChan, done)
+	go bp.batchProcess(context.Background(), resultsChan, done)
 	<-done
 
 	// then
@@ -383,13 +414,354 @@ func TestBatchProcessor_Process_CleansUpAlbumProcessingFlag(t *testing.T) {
 	files := []dto.FileEntryDto{}
 	albumID := 999
 
-	bp := NewBatchProcessor(daoService, processor, files, albumID)
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
 
 	// when
-	err := bp.Process()
+	err := bp.Process(context.Background())
 
 	// then
 	assert.NoError(t, err)
 	_, loaded := albumProcessing.Load(albumID)
 	assert.False(t, loaded)
 }
+
+func TestBatchProcessor_Process_CropUsesFocalPoints(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
+	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	files := []dto.FileEntryDto{
+		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "one.jpg"},
+		{Id: 2, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "two.jpg"},
+	}
+	albumID := 1001
+	focalPoint := mod.FocalPoint{X: 0.1, Y: 0.9}
+
+	daoService.On("GetFocalPoints", []int{1, 2}).Return(map[int]mod.FocalPoint{1: focalPoint}, nil)
+	processor.On("SupportsFile", mock.Anything).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{Crop: CropEntropy, FocalPoint: &focalPoint}).Return([]byte("focal"), nil)
+	processor.On("GenerateThumbnail", mock.Anything, files[1], Options{Crop: CropEntropy}).Return([]byte("entropy"), nil)
+	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
+		return len(thumbnails) == 2
+	})).Return([]mod.Thumbnail{}, nil)
+
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropEntropy, false)
+
+	// when
+	err := bp.Process(context.Background())
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestBatchProcessor_Process_IdenticalFilesRenderedOnce(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
+	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	files := []dto.FileEntryDto{
+		{Id: 1, Checksum: "abc", MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "one.jpg"},
+		{Id: 2, Checksum: "abc", MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "two.jpg"},
+		{Id: 3, Checksum: "def", MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "three.jpg"},
+	}
+	albumID := 1002
+
+	daoService.On("GetThumbnailsByRenderKey", []string{"checksum:abc:static", "checksum:def:static"}).Return(map[string][]byte{
+		"checksum:def:static": []byte("stored"),
+	}, nil)
+	processor.On("SupportsFile", mock.Anything).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return([]byte("rendered"), nil).Once()
+	var saved []mod.Thumbnail
+	daoService.On("SaveThumbnails", mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(0).([]mod.Thumbnail)
+	}).Return([]mod.Thumbnail{}, nil)
+
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false)
+
+	// when
+	err := bp.Process(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	contents := make(map[int]string)
+	for _, thumbnail := range saved {
+		contents[thumbnail.FileId] = string(thumbnail.Content)
+		assert.Equal(t, "checksum:"+files[thumbnail.FileId-1].Checksum+":static", *thumbnail.RenderKey)
+	}
+	assert.Equal(t, map[int]string{1: "rendered", 2: "rendered", 3: "stored"}, contents)
+}
+
+func TestBatchProcessor_Process_ReportsProgress(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
+	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	files := []dto.FileEntryDto{
+		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"},
+		{Id: 2, MediaType: "image/png", Extension: "png", FullFileNameOnSystem: "test2.png"},
+		{Id: 3, MediaType: "text/plain", Extension: "txt", FullFileNameOnSystem: "test3.txt"},
+	}
+	albumID := 606
+
+	processor.On("SupportsFile", files[0]).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return([]byte("thumbnail1"), nil)
+	processor.On("SupportsFile", files[1]).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[1], Options{}).Return(nil, errors.New("generation failed"))
+	processor.On("SupportsFile", files[2]).Return(false)
+	daoService.On("SaveFileFailure", mock.Anything).Return(nil)
+	daoService.On("SaveThumbnails", mock.Anything).Return([]mod.Thumbnail{{FileId: 1}}, nil)
+
+	id := 1
+	tracker := newProgressHub(cache.NewLRUCache(1024*1024)).track(mod.ThumbnailJob{Id: &id, AlbumId: albumID}, len(files))
+	bp := newTrackedBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false, tracker)
+
+	// when
+	err := bp.Process(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tracker.progress.Processed)
+	assert.Equal(t, 1, tracker.progress.Skipped)
+	assert.Equal(t, 1, tracker.progress.Failed)
+	assert.Equal(t, []FileFailure{{FileId: 2, Reason: "generation failed"}}, tracker.progress.Failures)
+}
+
+// cancellingBatchProcessor renders the first of two files and cancels the run with cause while doing so
+func cancellingBatchProcessor(t *testing.T, daoService dao.Dao, cause error) (BatchProcessor, context.Context) {
+	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	files := []dto.FileEntryDto{
+		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"},
+		{Id: 2, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test2.jpg"},
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	t.Cleanup(func() { cancel(nil) })
+	processor.On("SupportsFile", mock.Anything).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return([]byte("thumbnail1"), nil).Run(func(mock.Arguments) {
+		cancel(cause)
+	})
+
+	// one worker, so the second file is only rendered after the cancellation
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, 1), testFailureConfig, files, 707, CropNone, false)
+	return bp, ctx
+}
+
+func TestBatchProcessor_Process_CancelStoresPartialBatch(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
+	bp, ctx := cancellingBatchProcessor(t, daoService, jobCancellation{})
+	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
+		return len(thumbnails) == 1 && thumbnails[0].FileId == 1
+	})).Return([]mod.Thumbnail{{FileId: 1}}, nil)
+
+	// when
+	err := bp.Process(ctx)
+
+	// then
+	assert.ErrorIs(t, err, ErrJobCancelled)
+	daoService.AssertExpectations(t)
+}
+
+func TestBatchProcessor_Process_CancelDiscardsPartialBatch(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
+	bp, ctx := cancellingBatchProcessor(t, daoService, jobCancellation{discard: true})
+
+	// when
+	err := bp.Process(ctx)
+
+	// then the dao mock fails the test if the rendered thumbnail is stored
+	assert.ErrorIs(t, err, ErrJobCancelled)
+}
+
+func TestBatchProcessor_Process_CancelledBeforeStart(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	noFileFailures(daoService)
+	noStoredThumbnails(daoService)
+	processor := NewMockProcessor(t)
+	files := []dto.FileEntryDto{{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, 808, CropNone, false)
+
+	// when
+	err := bp.Process(ctx)
+
+	// then
+	assert.ErrorIs(t, err, context.Canceled)
+	processor.AssertNotCalled(t, "GenerateThumbnail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBatchProcessor_RenderFile_InterruptedRenderIsNotAFailure(t *testing.T) {
+	// given
+	processor := NewMockProcessor(t)
+	file := dto.FileEntryDto{Id: 1, MediaType: "video/mp4", Extension: "mp4", FullFileNameOnSystem: "test.mp4"}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	processor.On("SupportsFile", file).Return(true)
+	processor.EXPECT().GenerateThumbnail(mock.Anything, file, Options{}).RunAndReturn(func(context.Context, dto.FileEntryDto, Options) ([]byte, error) {
+		cancel(jobCancellation{})
+		return nil, context.Cause(ctx)
+	})
+	id := 1
+	tracker := newProgressHub(cache.NewLRUCache(1024*1024)).track(mod.ThumbnailJob{Id: &id, AlbumId: 909}, 1)
+	bp := newTrackedBatchProcessor(dao.NewMockDao(t), processor, nil, testFailureConfig, []dto.FileEntryDto{file}, 909, CropNone, false, tracker).(*batchProcessor)
+
+	resultsChan := make(chan mod.Thumbnail, 1)
+
+	// when
+	bp.renderFile(ctx, file, resultsChan)
+
+	// then
+	assert.Empty(t, resultsChan)
+	assert.Equal(t, 0, tracker.progress.Failed)
+}
+
+func TestBatchProcessor_Process_HeldBackFilesSkipped(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	files := []dto.FileEntryDto{
+		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"},
+		{Id: 2, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test2.jpg"},
+		{Id: 3, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test3.jpg"},
+		{Id: 4, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test4.jpg"},
+	}
+	albumID := 1010
+	later := time.Now().Add(time.Hour)
+	earlier := time.Now().Add(-time.Minute)
+	failureId := 7
+
+	daoService.On("GetFileFailures", []int{1, 2, 3, 4}).Return(map[int]mod.FileFailure{
+		2: {FileId: 2, ErrorClass: mod.FailureMissing, Attempts: 1, LastError: "file not found", Quarantined: true},
+		3: {FileId: 3, ErrorClass: mod.FailureTransient, Attempts: 1, LastError: "timeout", NextAttemptAt: &later},
+		4: {Id: &failureId, FileId: 4, ErrorClass: mod.FailureTransient, Attempts: 1, LastError: "timeout", NextAttemptAt: &earlier},
+	}, nil)
+	noStoredThumbnails(daoService)
+	processor.On("SupportsFile", mock.Anything).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return([]byte("thumbnail1"), nil)
+	processor.On("GenerateThumbnail", mock.Anything, files[3], Options{}).Return([]byte("thumbnail4"), nil)
+	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
+		return len(thumbnails) == 2
+	})).Return([]mod.Thumbnail{}, nil)
+	daoService.On("DeleteFileFailures", []int{4}).Return(int64(1), nil)
+
+	id := 1
+	tracker := newProgressHub(cache.NewLRUCache(1024*1024)).track(mod.ThumbnailJob{Id: &id, AlbumId: albumID}, len(files))
+	bp := newTrackedBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false, tracker)
+
+	// when
+	err := bp.Process(context.Background())
+
+	// then the quarantined file and the file waiting for its retry are skipped, the file due for a retry is rendered
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tracker.progress.Processed)
+	assert.Equal(t, 2, tracker.progress.Skipped)
+	assert.Equal(t, "quarantined after failing: file not found", tracker.outcomes[2].Reason)
+	assert.Contains(t, tracker.outcomes[3].Reason, "retrying after")
+	processor.AssertNotCalled(t, "GenerateThumbnail", mock.Anything, files[1], mock.Anything)
+	processor.AssertNotCalled(t, "GenerateThumbnail", mock.Anything, files[2], mock.Anything)
+	daoService.AssertExpectations(t)
+}
+
+func TestBatchProcessor_Process_LastAttemptQuarantinesFile(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	files := []dto.FileEntryDto{{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"}}
+	earlier := time.Now().Add(-time.Minute)
+	failureId := 3
+
+	daoService.On("GetFileFailures", []int{1}).Return(map[int]mod.FileFailure{
+		1: {Id: &failureId, FileId: 1, ErrorClass: mod.FailureTransient, Attempts: DefaultFileMaxAttempts - 1, NextAttemptAt: &earlier},
+	}, nil)
+	noStoredThumbnails(daoService)
+	processor.On("SupportsFile", files[0]).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return(nil, errors.New("corrupt image"))
+	daoService.On("SaveFileFailure", mock.MatchedBy(func(failure *mod.FileFailure) bool {
+		return *failure.Id == failureId && failure.Attempts == DefaultFileMaxAttempts && failure.Quarantined &&
+			failure.NextAttemptAt == nil && failure.LastError == "corrupt image"
+	})).Return(nil)
+
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, 1011, CropNone, false)
+
+	// when
+	err := bp.Process(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	daoService.AssertExpectations(t)
+}
+
+func TestBatchProcessor_Process_FilesWithThumbnailSkipped(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	noFileFailures(daoService)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	files := []dto.FileEntryDto{
+		{Id: 1, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"},
+		{Id: 2, MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test2.jpg"},
+	}
+	albumID := 1212
+
+	daoService.On("GetThumbnailFileIds", []int{1, 2}).Return([]int{1}, nil)
+	processor.On("SupportsFile", files[1]).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[1], Options{}).Return([]byte("thumbnail2"), nil)
+	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
+		return len(thumbnails) == 1 && thumbnails[0].FileId == 2
+	})).Return([]mod.Thumbnail{}, nil)
+
+	id := 1
+	tracker := newProgressHub(cache.NewLRUCache(1024*1024)).track(mod.ThumbnailJob{Id: &id, AlbumId: albumID}, len(files))
+	bp := newTrackedBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, albumID, CropNone, false, tracker)
+
+	// when
+	err := bp.Process(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tracker.progress.Processed)
+	assert.Equal(t, 1, tracker.progress.Skipped)
+	assert.Equal(t, "thumbnail already stored", tracker.outcomes[1].Reason)
+	processor.AssertNotCalled(t, "GenerateThumbnail", mock.Anything, files[0], mock.Anything)
+	daoService.AssertExpectations(t)
+}
+
+func TestBatchProcessor_Process_ForceRendersFilesWithThumbnail(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	noFileFailures(daoService)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	files := []dto.FileEntryDto{{Id: 1, Checksum: "abc", MediaType: "image/jpeg", Extension: "jpg", FullFileNameOnSystem: "test1.jpg"}}
+
+	processor.On("SupportsFile", files[0]).Return(true)
+	processor.On("GenerateThumbnail", mock.Anything, files[0], Options{}).Return([]byte("thumbnail1"), nil)
+	daoService.On("SaveThumbnails", mock.MatchedBy(func(thumbnails []mod.Thumbnail) bool {
+		return len(thumbnails) == 1 && string(thumbnails[0].Content) == "thumbnail1"
+	})).Return([]mod.Thumbnail{}, nil)
+
+	bp := NewBatchProcessor(daoService, processor, newTestPool(t, DefaultPoolWorkers), testFailureConfig, files, 1313, CropNone, true)
+
+	// when
+	err := bp.Process(context.Background())
+
+	// then neither the stored thumbnail of the file nor one with the same render key is used
+	assert.NoError(t, err)
+	daoService.AssertNotCalled(t, "GetThumbnailFileIds", mock.Anything)
+	daoService.AssertNotCalled(t, "GetThumbnailsByRenderKey", mock.Anything)
+	daoService.AssertExpectations(t)
+}
@@ -0,0 +1,70 @@
+package thumbnail
+
+import (
+	"fmt"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/samber/lo"
+)
+
+// hdrTransfers are the ffprobe color_transfer values of PQ (HDR10, Dolby Vision) and HLG video
+var hdrTransfers = []string{"smpte2084", "arib-std-b67"}
+
+// tonemapFilter converts an HDR frame to linear light, tone maps it and converts it back to SDR BT.709
+const tonemapFilter = "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p"
+
+// isHDR reports whether the first video stream uses an HDR transfer function
+func (p ProbeData) isHDR() bool {
+	if len(p.Streams) == 0 {
+		return false
+	}
+	return lo.Contains(hdrTransfers, p.Streams[0].ColorTransfer)
+}
+
+// videoFilter builds the ffmpeg filter chain, HDR frames are tone mapped before they are scaled
+func videoFilter(options Options, hdr bool) string {
+	scale := "scale=-1:200"
+	if options.cropped() {
+		// cropped frames are scaled to cover the square so vips can crop them
+		scale = fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=increase", options.width(), options.width())
+	} else if options.Width != 0 {
+		scale = fmt.Sprintf("scale=%d:-2", options.Width)
+	}
+	if hdr {
+		return tonemapFilter + "," + scale
+	}
+	return scale
+}
+
+// exportWebp exports the image as WebP. the embedded ICC profile is converted to sRGB unless the caller asked to keep it,
+// in which case the pixels are left untouched and the original profile is written into the WebP container.
+// with a byte budget the encoder settles on the best quality that fits and records it in the thumbnail
+func exportWebp(vipsImage *vips.ImageRef, options Options) ([]byte, error) {
+	var profile []byte
+	if options.KeepProfile && vipsImage.HasICCProfile() {
+		profile = vipsImage.GetICCProfile()
+	} else if err := vipsImage.OptimizeICCProfile(); err != nil {
+		return nil, fmt.Errorf("failed to convert colour profile to sRGB: %w", err)
+	}
+
+	encode := func(img *vips.ImageRef, params *vips.WebpExportParams) ([]byte, error) {
+		thumbnail, _, err := img.ExportWebp(params)
+		if err != nil {
+			return nil, err
+		}
+		if profile != nil {
+			return embedICCProfile(thumbnail, profile)
+		}
+		return thumbnail, nil
+	}
+
+	if options.MaxBytes == 0 {
+		return encode(vipsImage, nil)
+	}
+
+	thumbnail, encoding, err := encodeWithinBudget(vipsImage, encode, options.MaxBytes, options.MinQuality)
+	if err != nil {
+		return nil, err
+	}
+	return tagEncoding(thumbnail, encoding)
+}
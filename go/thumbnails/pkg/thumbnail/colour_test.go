@@ -0,0 +1,44 @@
+package thumbnail
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeData_IsHDR(t *testing.T) {
+	// given
+	cases := map[string]bool{
+		`{"format":{"duration":"1"},"streams":[{"color_transfer":"smpte2084","color_primaries":"bt2020"}]}`:    true,
+		`{"format":{"duration":"1"},"streams":[{"color_transfer":"arib-std-b67","color_primaries":"bt2020"}]}`: true,
+		`{"format":{"duration":"1"},"streams":[{"color_transfer":"bt709","color_primaries":"bt709"}]}`:         false,
+		`{"format":{"duration":"1"}}`: false,
+	}
+
+	for output, expected := range cases {
+		// when
+		var probe ProbeData
+		err := json.Unmarshal([]byte(output), &probe)
+
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, expected, probe.isHDR(), output)
+	}
+}
+
+func TestVideoFilter_TonemapsHDRBeforeScaling(t *testing.T) {
+	// when
+	sdr := videoFilter(Options{}, false)
+	hdr := videoFilter(Options{}, true)
+	croppedHdr := videoFilter(Options{Crop: CropCentre}, true)
+	sized := videoFilter(Options{Width: 800}, false)
+
+	// then
+	assert.Equal(t, "scale=-1:200", sdr)
+	assert.Equal(t, "scale=800:-2", sized)
+	assert.True(t, strings.HasPrefix(hdr, tonemapFilter+","))
+	assert.True(t, strings.HasSuffix(hdr, ",scale=-1:200"))
+	assert.True(t, strings.HasSuffix(croppedHdr, ",scale=400:400:force_original_aspect_ratio=increase"))
+}
@@ -8,9 +8,36 @@ import (
 
 // Configuration constants
 const (
-	DefaultWorkerCount    = 4
 	DefaultBatchSize      = 50
 	DefaultThumbnailWidth = 400
+	MinThumbnailWidth     = 16
+	MaxThumbnailWidth     = 2048
+	MaxThumbnailVariants  = 12
+	MaxDPR                = 4
+	MaxStoredBatchSize    = 500
+)
+
+// PipelineVersion is bumped whenever a change to the pipeline changes the thumbnails it renders, such as the size, the
+// encoder or the chosen video frame. cached thumbnails of older versions are no longer found and stored ones are
+// re-rendered in the background. version 1 is the pipeline from before versioning, its cache keys carry no version
+const PipelineVersion = 1
+
+// Decode cost estimates the worker pool admits files by
+const (
+	// decodedPixelBytes is the memory of a decoded RGBA pixel
+	decodedPixelBytes = 4
+	// compressedImageRatio is how much an image of a format whose header can't be read is assumed to grow when decoded
+	compressedImageRatio = 10
+	minDecodeCost        = 1 << 20
+	// fallbackDecodeCost is used for files that can't be read
+	fallbackDecodeCost = 32 << 20
+	videoDecodeCost    = 64 << 20
+)
+
+// Generation lock timings, the lock outlives the slowest video frame extraction
+const (
+	generationLockTTL          = 2 * time.Minute
+	generationLockPollInterval = 200 * time.Millisecond
 )
 
 // Global variables used throughout the package
@@ -25,6 +52,10 @@ type ProbeData struct {
 	Format struct {
 		Duration string `json:"duration"`
 	} `json:"format"`
+	Streams []struct {
+		ColorTransfer  string `json:"color_transfer"`
+		ColorPrimaries string `json:"color_primaries"`
+	} `json:"streams"`
 }
 
 // globalFloat64 returns a random float64 in a thread-safe manner
@@ -0,0 +1,121 @@
+package thumbnail
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+// CropMode is the strategy used to pick the area kept when cropping a thumbnail to a square
+type CropMode string
+
+const (
+	CropNone      CropMode = ""
+	CropAttention CropMode = "attention"
+	CropEntropy   CropMode = "entropy"
+	CropCentre    CropMode = "centre"
+)
+
+// AllCropModes lists every crop mode, including CropNone
+var AllCropModes = []CropMode{CropNone, CropAttention, CropEntropy, CropCentre}
+
+// ParseCropMode converts a query value into a CropMode, an empty value means no cropping
+func ParseCropMode(value string) (CropMode, error) {
+	switch CropMode(strings.ToLower(value)) {
+	case CropNone:
+		return CropNone, nil
+	case CropAttention:
+		return CropAttention, nil
+	case CropEntropy:
+		return CropEntropy, nil
+	case CropCentre, "center":
+		return CropCentre, nil
+	}
+	return CropNone, fmt.Errorf("%w: %s", ErrInvalidCropMode, value)
+}
+
+// ValidateFocalPoint makes sure both coordinates of the focal point are within the 0-1 range
+func ValidateFocalPoint(focalPoint mod.FocalPoint) error {
+	if !inUnitRange(focalPoint.X) || !inUnitRange(focalPoint.Y) {
+		return fmt.Errorf("%w: (%v, %v) must be between 0 and 1", ErrInvalidFocalPoint, focalPoint.X, focalPoint.Y)
+	}
+	return nil
+}
+
+func inUnitRange(v float64) bool {
+	return !math.IsNaN(v) && v >= 0 && v <= 1
+}
+
+// interesting maps the crop mode to the libvips strategy
+func (c CropMode) interesting() vips.Interesting {
+	switch c {
+	case CropAttention:
+		return vips.InterestingAttention
+	case CropEntropy:
+		return vips.InterestingEntropy
+	case CropCentre:
+		return vips.InterestingCentre
+	}
+	return vips.InterestingNone
+}
+
+// focalCropArea calculates the largest square (up to size) centred as close to the focal point as the image bounds allow
+func focalCropArea(width, height, size int, focalPoint mod.FocalPoint) (left, top, cropWidth, cropHeight int) {
+	cropWidth = min(size, width)
+	cropHeight = min(size, height)
+
+	left = int(math.Round(focalPoint.X*float64(width))) - cropWidth/2
+	top = int(math.Round(focalPoint.Y*float64(height))) - cropHeight/2
+
+	left = max(0, min(left, width-cropWidth))
+	top = max(0, min(top, height-cropHeight))
+	return left, top, cropWidth, cropHeight
+}
+
+// coverDimensions returns the box a thumbnail must fit into so its shorter side is exactly size
+func coverDimensions(width, height, size int) (coverWidth, coverHeight int) {
+	if width == 0 || height == 0 {
+		return size, size
+	}
+	if width >= height {
+		return int(math.Ceil(float64(size) * float64(width) / float64(height))), size
+	}
+	return size, int(math.Ceil(float64(size) * float64(height) / float64(width)))
+}
+
+// loadFocalCroppedThumbnail shrinks the image so it covers a size x size square, then crops that square around the focal point
+func loadFocalCroppedThumbnail(filePath string, size int, focalPoint mod.FocalPoint, importParams *vips.ImportParams) (*vips.ImageRef, error) {
+	// shrink-on-load is cheap, so use a first pass to learn the oriented aspect ratio
+	probe, err := vips.LoadThumbnailFromFile(filePath, size, size, vips.InterestingNone, vips.SizeDown, importParams)
+	if err != nil {
+		return nil, err
+	}
+
+	vipsImage := probe
+	if probe.Width() != probe.Height() && max(probe.Width(), probe.Height()) >= size {
+		coverWidth, coverHeight := coverDimensions(probe.Width(), probe.Height(), size)
+		probe.Close()
+		vipsImage, err = vips.LoadThumbnailFromFile(filePath, coverWidth, coverHeight, vips.InterestingNone, vips.SizeDown, importParams)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cropAroundFocalPoint(vipsImage, size, focalPoint); err != nil {
+		vipsImage.Close()
+		return nil, err
+	}
+	return vipsImage, nil
+}
+
+// cropAroundFocalPoint crops an already shrunk image to a square around the focal point
+func cropAroundFocalPoint(vipsImage *vips.ImageRef, size int, focalPoint mod.FocalPoint) error {
+	left, top, cropWidth, cropHeight := focalCropArea(vipsImage.Width(), vipsImage.Height(), size, focalPoint)
+	if cropWidth == vipsImage.Width() && cropHeight == vipsImage.Height() {
+		return nil
+	}
+	return vipsImage.ExtractArea(left, top, cropWidth, cropHeight)
+}
@@ -0,0 +1,95 @@
+package thumbnail
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+func TestParseCropMode_ValidModes(t *testing.T) {
+	// given
+	cases := map[string]CropMode{
+		"":          CropNone,
+		"attention": CropAttention,
+		"ENTROPY":   CropEntropy,
+		"centre":    CropCentre,
+		"center":    CropCentre,
+	}
+
+	for value, expected := range cases {
+		// when
+		result, err := ParseCropMode(value)
+
+		// then
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, result, value)
+	}
+}
+
+func TestParseCropMode_InvalidMode(t *testing.T) {
+	// when
+	result, err := ParseCropMode("faces")
+
+	// then
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidCropMode))
+	assert.Equal(t, CropNone, result)
+}
+
+func TestValidateFocalPoint(t *testing.T) {
+	// then
+	assert.NoError(t, ValidateFocalPoint(mod.FocalPoint{X: 0, Y: 1}))
+	assert.NoError(t, ValidateFocalPoint(mod.FocalPoint{X: 0.5, Y: 0.25}))
+	assert.True(t, errors.Is(ValidateFocalPoint(mod.FocalPoint{X: -0.1, Y: 0.5}), ErrInvalidFocalPoint))
+	assert.True(t, errors.Is(ValidateFocalPoint(mod.FocalPoint{X: 0.5, Y: 1.5}), ErrInvalidFocalPoint))
+	assert.True(t, errors.Is(ValidateFocalPoint(mod.FocalPoint{X: math.NaN(), Y: 0.5}), ErrInvalidFocalPoint))
+}
+
+func TestFocalCropArea_CentredOnFocalPoint(t *testing.T) {
+	// when
+	left, top, width, height := focalCropArea(800, 400, 400, mod.FocalPoint{X: 0.5, Y: 0.5})
+
+	// then
+	assert.Equal(t, 200, left)
+	assert.Equal(t, 0, top)
+	assert.Equal(t, 400, width)
+	assert.Equal(t, 400, height)
+}
+
+func TestFocalCropArea_ClampsToImageBounds(t *testing.T) {
+	// when
+	leftEdge, _, _, _ := focalCropArea(800, 400, 400, mod.FocalPoint{X: 0, Y: 0.5})
+	rightEdge, _, _, _ := focalCropArea(800, 400, 400, mod.FocalPoint{X: 1, Y: 0.5})
+	_, bottomEdge, _, _ := focalCropArea(400, 1000, 400, mod.FocalPoint{X: 0.5, Y: 0.95})
+
+	// then
+	assert.Equal(t, 0, leftEdge)
+	assert.Equal(t, 400, rightEdge)
+	assert.Equal(t, 600, bottomEdge)
+}
+
+func TestFocalCropArea_SmallImage(t *testing.T) {
+	// when
+	left, top, width, height := focalCropArea(300, 200, 400, mod.FocalPoint{X: 0.9, Y: 0.1})
+
+	// then
+	assert.Equal(t, 0, left)
+	assert.Equal(t, 0, top)
+	assert.Equal(t, 300, width)
+	assert.Equal(t, 200, height)
+}
+
+func TestCoverDimensions(t *testing.T) {
+	// when
+	landscapeWidth, landscapeHeight := coverDimensions(400, 200, 400)
+	portraitWidth, portraitHeight := coverDimensions(300, 400, 400)
+
+	// then
+	assert.Equal(t, 800, landscapeWidth)
+	assert.Equal(t, 400, landscapeHeight)
+	assert.Equal(t, 400, portraitWidth)
+	assert.Equal(t, 534, portraitHeight)
+}
@@ -0,0 +1,225 @@
+package thumbnail
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultQuality is the WebP quality libvips uses when no export parameters are given
+	DefaultQuality = 75
+	// DefaultMinQuality is the lowest quality the budget search goes down to unless configured otherwise
+	DefaultMinQuality = 40
+	// MinByteBudget is the smallest byte budget that can be requested
+	MinByteBudget = 1024
+
+	nearLosslessQuality = 60
+	// alphaLevels is the number of alpha levels kept when the alpha plane is made lossy
+	alphaLevels = 16
+
+	encodingChunkFourCC = "WVQL"
+	// encodingChunkOverhead is reserved from the budget for the chunks describing the encoding and render time,
+	// including a VP8X header
+	encodingChunkOverhead = 64
+)
+
+// Encoding describes how a thumbnail was encoded to fit its byte budget
+type Encoding struct {
+	Quality      int
+	NearLossless bool
+	LossyAlpha   bool
+}
+
+// String formats the encoding for the X-Thumbnail-Quality header, e.g. "62", "near-lossless" or "40; alpha=lossy"
+func (e Encoding) String() string {
+	value := strconv.Itoa(e.Quality)
+	if e.NearLossless {
+		value = "near-lossless"
+	}
+	if e.LossyAlpha {
+		value += "; alpha=lossy"
+	}
+	return value
+}
+
+// ReadEncoding returns the encoding stored in a thumbnail that was encoded with a byte budget
+func ReadEncoding(thumbnail []byte) (Encoding, bool) {
+	payload, found := findWebpChunk(thumbnail, encodingChunkFourCC)
+	if !found {
+		return Encoding{}, false
+	}
+
+	var encoding Encoding
+	for _, field := range strings.Split(string(payload), ";") {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "q":
+			encoding.Quality, _ = strconv.Atoi(value)
+		case "nl":
+			encoding.NearLossless = value == "1"
+		case "la":
+			encoding.LossyAlpha = value == "1"
+		}
+	}
+	return encoding, true
+}
+
+// ValidateBudget makes sure the requested byte budget and quality floor are usable, 0 means no budget and the default
+// quality floor
+func ValidateBudget(maxBytes, minQuality int) error {
+	if maxBytes != 0 && maxBytes < MinByteBudget {
+		return fmt.Errorf("%w: maxBytes must be at least %d", ErrInvalidBudget, MinByteBudget)
+	}
+	if minQuality < 0 || minQuality > 100 {
+		return fmt.Errorf("%w: minQuality must be between 1 and 100, or 0 for the default of %d", ErrInvalidBudget, DefaultMinQuality)
+	}
+	return nil
+}
+
+// budgetFromEnv reads the default byte budget applied when a request does not ask for one
+func budgetFromEnv() (maxBytes, minQuality int) {
+	maxBytes, _ = strconv.Atoi(os.Getenv("THUMBNAIL_MAX_BYTES"))
+	minQuality, _ = strconv.Atoi(os.Getenv("THUMBNAIL_MIN_QUALITY"))
+	if err := ValidateBudget(maxBytes, minQuality); err != nil {
+		log.Error().Err(err).Msg("ignoring invalid thumbnail byte budget configuration")
+		return 0, 0
+	}
+	return maxBytes, minQuality
+}
+
+// webpEncoder encodes the image with the given parameters, nil uses the libvips defaults
+type webpEncoder func(vipsImage *vips.ImageRef, params *vips.WebpExportParams) ([]byte, error)
+
+// encodeWithinBudget tries progressively smaller encodings until the thumbnail fits in maxBytes:
+// the default quality, near-lossless, a search down to the quality floor and finally a lossy alpha plane.
+// if nothing fits the smallest encoding is returned, the floor is never crossed
+func encodeWithinBudget(vipsImage *vips.ImageRef, encode webpEncoder, maxBytes, minQuality int) ([]byte, Encoding, error) {
+	if minQuality == 0 {
+		minQuality = DefaultMinQuality
+	}
+	minQuality = min(minQuality, DefaultQuality)
+	limit := maxBytes - encodingChunkOverhead
+
+	lossy := func(img *vips.ImageRef, quality int) ([]byte, error) {
+		params := vips.NewWebpExportParams()
+		params.Quality = quality
+		return encode(img, params)
+	}
+
+	thumbnail, err := lossy(vipsImage, DefaultQuality)
+	if err != nil || len(thumbnail) <= limit {
+		return thumbnail, Encoding{Quality: DefaultQuality}, err
+	}
+
+	// flat graphics are often smaller near-lossless than lossy
+	params := vips.NewWebpExportParams()
+	params.NearLossless = true
+	params.Quality = nearLosslessQuality
+	nearLossless, err := encode(vipsImage, params)
+	if err != nil {
+		return nil, Encoding{}, err
+	}
+	if len(nearLossless) <= limit {
+		return nearLossless, Encoding{Quality: nearLosslessQuality, NearLossless: true}, nil
+	}
+
+	thumbnail, quality, err := searchQuality(func(q int) ([]byte, error) {
+		return lossy(vipsImage, q)
+	}, minQuality, DefaultQuality-1, limit)
+	if err != nil || len(thumbnail) <= limit || !vipsImage.HasAlpha() {
+		return thumbnail, Encoding{Quality: quality}, err
+	}
+
+	// govips does not expose alpha_q, so the alpha plane is made lossy by reducing it to a few levels
+	quantised, err := vipsImage.Copy()
+	if err != nil {
+		return nil, Encoding{}, err
+	}
+	defer quantised.Close()
+	if err := quantiseAlpha(quantised); err != nil {
+		return nil, Encoding{}, err
+	}
+
+	lossyAlpha, quality, err := searchQuality(func(q int) ([]byte, error) {
+		return lossy(quantised, q)
+	}, minQuality, DefaultQuality-1, limit)
+	if err != nil {
+		return nil, Encoding{}, err
+	}
+	if len(lossyAlpha) > limit {
+		log.Debug().Int("size", len(lossyAlpha)).Int("budget", maxBytes).Msg("thumbnail does not fit its byte budget at the minimum quality")
+	}
+	return lossyAlpha, Encoding{Quality: quality, LossyAlpha: true}, nil
+}
+
+// searchQuality binary searches for the highest quality between low and high that fits the limit.
+// the encoding at low is returned when none fit
+func searchQuality(encode func(quality int) ([]byte, error), low, high, limit int) ([]byte, int, error) {
+	floor := low
+	var best []byte
+	bestQuality := 0
+	for low <= high {
+		quality := (low + high) / 2
+		thumbnail, err := encode(quality)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(thumbnail) <= limit {
+			best, bestQuality = thumbnail, quality
+			low = quality + 1
+		} else {
+			high = quality - 1
+		}
+	}
+
+	if best == nil {
+		thumbnail, err := encode(floor)
+		return thumbnail, floor, err
+	}
+	return best, bestQuality, nil
+}
+
+// quantiseAlpha reduces the alpha band to alphaLevels levels so it compresses far better losslessly
+func quantiseAlpha(vipsImage *vips.ImageRef) error {
+	bands := vipsImage.Bands()
+	step := 255.0 / float64(alphaLevels-1)
+
+	down := make([]float64, bands)
+	offset := make([]float64, bands)
+	up := make([]float64, bands)
+	for i := range bands {
+		down[i], up[i] = 1, 1
+	}
+	down[bands-1] = 1 / step
+	offset[bands-1] = 0.5
+	up[bands-1] = step
+
+	if err := vipsImage.Linear(down, offset); err != nil {
+		return err
+	}
+	if err := vipsImage.Cast(vips.BandFormatUchar); err != nil {
+		return err
+	}
+	if err := vipsImage.Linear(up, make([]float64, bands)); err != nil {
+		return err
+	}
+	return vipsImage.Cast(vips.BandFormatUchar)
+}
+
+// tagEncoding records the encoding in the thumbnail so it can be reported even when served from a cache
+func tagEncoding(thumbnail []byte, encoding Encoding) ([]byte, error) {
+	payload := fmt.Sprintf("q=%d;nl=%d;la=%d", encoding.Quality, boolToInt(encoding.NearLossless), boolToInt(encoding.LossyAlpha))
+	return addWebpChunk(thumbnail, riffChunk{fourCC: encodingChunkFourCC, payload: []byte(payload)})
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package thumbnail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEncoder produces a WebP padded to the size returned for the export parameters
+func fakeEncoder(sizes func(params *vips.WebpExportParams) int, calls *[]vips.WebpExportParams) webpEncoder {
+	return func(_ *vips.ImageRef, params *vips.WebpExportParams) ([]byte, error) {
+		*calls = append(*calls, *params)
+		chunk := vp8lChunk(100, 100, false)
+		chunk.payload = append(chunk.payload, make([]byte, sizes(params))...)
+		return buildWebp(chunk), nil
+	}
+}
+
+func TestEncodeWithinBudget_DefaultQualityFits(t *testing.T) {
+	// given
+	var calls []vips.WebpExportParams
+	encode := fakeEncoder(func(*vips.WebpExportParams) int { return 1000 }, &calls)
+
+	// when
+	thumbnail, encoding, err := encodeWithinBudget(nil, encode, 4096, 0)
+
+	// then
+	assert.NoError(t, err)
+	assert.NotNil(t, thumbnail)
+	assert.Equal(t, Encoding{Quality: DefaultQuality}, encoding)
+	assert.Len(t, calls, 1)
+}
+
+func TestEncodeWithinBudget_PrefersNearLossless(t *testing.T) {
+	// given
+	var calls []vips.WebpExportParams
+	encode := fakeEncoder(func(params *vips.WebpExportParams) int {
+		if params.NearLossless {
+			return 2000
+		}
+		return 8000
+	}, &calls)
+
+	// when
+	_, encoding, err := encodeWithinBudget(nil, encode, 4096, 0)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, Encoding{Quality: nearLosslessQuality, NearLossless: true}, encoding)
+	assert.Len(t, calls, 2)
+}
+
+func TestSearchQuality_FindsHighestQualityThatFits(t *testing.T) {
+	// given
+	var tried []int
+	encode := func(quality int) ([]byte, error) {
+		tried = append(tried, quality)
+		return make([]byte, quality*100), nil
+	}
+
+	// when
+	thumbnail, quality, err := searchQuality(encode, 40, 74, 6250)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 62, quality)
+	assert.Len(t, thumbnail, 6200)
+	assert.LessOrEqual(t, len(tried), 6)
+}
+
+func TestSearchQuality_NeverGoesBelowFloor(t *testing.T) {
+	// given
+	encode := func(quality int) ([]byte, error) {
+		return make([]byte, quality*100), nil
+	}
+
+	// when
+	thumbnail, quality, err := searchQuality(encode, 40, 74, 100)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 40, quality)
+	assert.Len(t, thumbnail, 4000)
+}
+
+func TestTagEncoding_RoundTrip(t *testing.T) {
+	// given
+	webp := buildWebp(vp8lChunk(64, 64, true))
+	encoding := Encoding{Quality: 45, LossyAlpha: true}
+
+	// when
+	tagged, err := tagEncoding(webp, encoding)
+	read, found := ReadEncoding(tagged)
+
+	// then
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, encoding, read)
+	assert.Equal(t, "45; alpha=lossy", read.String())
+	_, found = ReadEncoding(webp)
+	assert.False(t, found)
+}
+
+func TestValidateBudget(t *testing.T) {
+	// then
+	assert.NoError(t, ValidateBudget(0, 0))
+	assert.NoError(t, ValidateBudget(20000, 30))
+	assert.True(t, errors.Is(ValidateBudget(100, 0), ErrInvalidBudget))
+	assert.True(t, errors.Is(ValidateBudget(20000, 101), ErrInvalidBudget))
+	assert.ErrorContains(t, ValidateBudget(20000, -1), "or 0 for the default of 40")
+}
@@ -7,6 +7,18 @@ var (
 	ErrFailedToDownload         = errors.New("failed to download file from URL")
 	ErrFailedToExtractExtension = errors.New("failed to determine file extension")
 	ErrFileNotFound             = errors.New("file not found")
+	ErrAlbumNotFound            = errors.New("album not found")
 	ErrInvalidURL               = errors.New("invalid URL")
 	ErrFileTooLarge             = errors.New("file too large")
+	ErrInvalidCropMode          = errors.New("invalid crop mode")
+	ErrInvalidFocalPoint        = errors.New("invalid focal point")
+	ErrInvalidWidth             = errors.New("invalid thumbnail width")
+	ErrInvalidDPR               = errors.New("invalid device pixel ratio")
+	ErrInvalidBudget            = errors.New("invalid byte budget")
+	ErrInvalidFileIds           = errors.New("invalid file ids")
+	ErrBackfillRunning          = errors.New("backfill is already running")
+	ErrAlbumProcessing          = errors.New("album is already being processed")
+	ErrJobNotFound              = errors.New("thumbnail job not found")
+	ErrJobCancelled             = errors.New("thumbnail job cancelled")
+	ErrFileFailureNotFound      = errors.New("file has no recorded failure")
 )
@@ -0,0 +1,96 @@
+package thumbnail
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+const (
+	DefaultFileMaxAttempts = 3
+	DefaultFileRetryDelay  = 10 * time.Minute
+	// fileMaxRetryDelay caps the backoff of a file that keeps failing transiently
+	fileMaxRetryDelay = 24 * time.Hour
+	// quarantineListLimit is the number of quarantined files listed
+	quarantineListLimit = 500
+)
+
+// FailureConfig controls how album jobs retry the files that failed to render
+type FailureConfig struct {
+	// MaxAttempts is the number of transient failures after which a file is quarantined
+	MaxAttempts int
+	// RetryDelay is how long a file is skipped after its first transient failure, it doubles with every failure
+	RetryDelay time.Duration
+}
+
+// FailureConfigFromEnv reads THUMBNAIL_FILE_MAX_ATTEMPTS and THUMBNAIL_FILE_RETRY_DELAY
+func FailureConfigFromEnv() FailureConfig {
+	config := FailureConfig{
+		MaxAttempts: DefaultFileMaxAttempts,
+		RetryDelay:  DefaultFileRetryDelay,
+	}
+	if raw := os.Getenv("THUMBNAIL_FILE_MAX_ATTEMPTS"); raw != "" {
+		attempts, err := strconv.Atoi(raw)
+		if err != nil || attempts <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_FILE_MAX_ATTEMPTS")
+		} else {
+			config.MaxAttempts = attempts
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_FILE_RETRY_DELAY"); raw != "" {
+		delay, err := time.ParseDuration(raw)
+		if err != nil || delay < 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_FILE_RETRY_DELAY")
+		} else {
+			config.RetryDelay = delay
+		}
+	}
+	return config
+}
+
+// classifyFailure returns the class of a render error, errors that aren't known to be permanent are transient
+func classifyFailure(err error) mod.FailureClass {
+	switch {
+	case errors.Is(err, ErrUnsupportedFileType):
+		return mod.FailureUnsupported
+	case errors.Is(err, ErrFileNotFound), errors.Is(err, fs.ErrNotExist):
+		return mod.FailureMissing
+	default:
+		return mod.FailureTransient
+	}
+}
+
+// nextFailure returns the failure of a file after it failed again with err, previous is the zero value for a file that
+// never failed. a transient failure is retried after a delay doubling with every attempt, any other failure or the last
+// attempt quarantines the file
+func (c FailureConfig) nextFailure(previous mod.FileFailure, fileId int, err error, now time.Time) mod.FileFailure {
+	failure := previous
+	failure.FileId = fileId
+	failure.ErrorClass = classifyFailure(err)
+	failure.Attempts++
+	failure.LastError = err.Error()
+	failure.NextAttemptAt = nil
+	failure.Quarantined = failure.ErrorClass != mod.FailureTransient || failure.Attempts >= c.MaxAttempts
+	if !failure.Quarantined {
+		nextAttemptAt := now.Add(doublingDelay(c.RetryDelay, fileMaxRetryDelay, failure.Attempts))
+		failure.NextAttemptAt = &nextAttemptAt
+	}
+	return failure
+}
+
+// heldBack returns why a file that failed before isn't rendered by this run, ok is false when the file may be rendered
+func heldBack(failure mod.FileFailure, now time.Time) (reason string, ok bool) {
+	switch {
+	case failure.Quarantined:
+		return "quarantined after failing: " + failure.LastError, true
+	case failure.NextAttemptAt != nil && failure.NextAttemptAt.After(now):
+		return "retrying after " + failure.NextAttemptAt.UTC().Format(time.RFC3339), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,97 @@
+package thumbnail
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	// given
+	_, notExist := os.Open("/does/not/exist")
+
+	// when / then
+	assert.Equal(t, mod.FailureUnsupported, classifyFailure(fmt.Errorf("%w: text/plain", ErrUnsupportedFileType)))
+	assert.Equal(t, mod.FailureMissing, classifyFailure(fmt.Errorf("%w: a.jpg", ErrFileNotFound)))
+	assert.Equal(t, mod.FailureMissing, classifyFailure(notExist))
+	assert.Equal(t, mod.FailureTransient, classifyFailure(errors.New("VipsJpeg: premature end of file")))
+}
+
+func TestFailureConfig_NextFailure_BacksOff(t *testing.T) {
+	// given
+	config := FailureConfig{MaxAttempts: 4, RetryDelay: time.Minute}
+	now := time.Now()
+
+	// when
+	first := config.nextFailure(mod.FileFailure{}, 1, errors.New("timeout"), now)
+	second := config.nextFailure(first, 1, errors.New("timeout"), now)
+	third := config.nextFailure(second, 1, errors.New("timeout"), now)
+
+	// then
+	assert.Equal(t, now.Add(time.Minute), *first.NextAttemptAt)
+	assert.Equal(t, now.Add(2*time.Minute), *second.NextAttemptAt)
+	assert.Equal(t, now.Add(4*time.Minute), *third.NextAttemptAt)
+	assert.Equal(t, 3, third.Attempts)
+	assert.False(t, third.Quarantined)
+}
+
+func TestFailureConfig_NextFailure_Quarantines(t *testing.T) {
+	// given
+	config := FailureConfig{MaxAttempts: 2, RetryDelay: time.Minute}
+	now := time.Now()
+	failureId := 5
+
+	// when
+	unsupported := config.nextFailure(mod.FileFailure{}, 1, ErrUnsupportedFileType, now)
+	exhausted := config.nextFailure(mod.FileFailure{Id: &failureId, Attempts: 1}, 2, errors.New("timeout"), now)
+
+	// then
+	assert.True(t, unsupported.Quarantined)
+	assert.Equal(t, mod.FailureUnsupported, unsupported.ErrorClass)
+	assert.Nil(t, unsupported.NextAttemptAt)
+	assert.True(t, exhausted.Quarantined)
+	assert.Equal(t, &failureId, exhausted.Id)
+	assert.Equal(t, 2, exhausted.Attempts)
+	assert.Nil(t, exhausted.NextAttemptAt)
+}
+
+func TestFailureConfig_RetryDelayIsCapped(t *testing.T) {
+	// given
+	config := FailureConfig{MaxAttempts: 100, RetryDelay: time.Hour}
+	now := time.Now()
+
+	// when
+	failure := config.nextFailure(mod.FileFailure{Attempts: 49}, 1, errors.New("timeout"), now)
+
+	// then
+	assert.Equal(t, now.Add(fileMaxRetryDelay), *failure.NextAttemptAt)
+}
+
+func TestFailureConfigFromEnv(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_FILE_MAX_ATTEMPTS", "5")
+	t.Setenv("THUMBNAIL_FILE_RETRY_DELAY", "1m")
+
+	// when
+	config := FailureConfigFromEnv()
+
+	// then
+	assert.Equal(t, FailureConfig{MaxAttempts: 5, RetryDelay: time.Minute}, config)
+}
+
+func TestFailureConfigFromEnv_InvalidValues(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_FILE_MAX_ATTEMPTS", "0")
+	t.Setenv("THUMBNAIL_FILE_RETRY_DELAY", "soon")
+
+	// when
+	config := FailureConfigFromEnv()
+
+	// then
+	assert.Equal(t, FailureConfig{MaxAttempts: DefaultFileMaxAttempts, RetryDelay: DefaultFileRetryDelay}, config)
+}
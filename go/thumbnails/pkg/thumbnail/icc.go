@@ -0,0 +1,181 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/samber/lo"
+)
+
+const (
+	vp8xFlagAlpha = 0x10
+	vp8xFlagICC   = 0x20
+)
+
+var errInvalidWebp = errors.New("invalid webp container")
+
+type riffChunk struct {
+	fourCC  string
+	payload []byte
+}
+
+// embedICCProfile writes an ICCP chunk into a WebP file. libvips is only able to embed its own sRGB profile in WebP,
+// so the original profile is added at the container level, upgrading simple files to the extended (VP8X) format
+func embedICCProfile(webp []byte, profile []byte) ([]byte, error) {
+	vp8x, body, err := extendedWebpChunks(webp)
+	if err != nil {
+		return nil, err
+	}
+
+	vp8x.payload[0] |= vp8xFlagICC
+	body = lo.Filter(body, func(chunk riffChunk, _ int) bool {
+		return chunk.fourCC != "ICCP"
+	})
+
+	out := []riffChunk{*vp8x, {fourCC: "ICCP", payload: profile}}
+	return writeWebpChunks(append(out, body...)), nil
+}
+
+// addWebpChunk appends a chunk that WebP decoders ignore, upgrading simple files to the extended (VP8X) format
+func addWebpChunk(webp []byte, chunk riffChunk) ([]byte, error) {
+	vp8x, body, err := extendedWebpChunks(webp)
+	if err != nil {
+		return nil, err
+	}
+
+	body = lo.Filter(body, func(c riffChunk, _ int) bool {
+		return c.fourCC != chunk.fourCC
+	})
+
+	out := append([]riffChunk{*vp8x}, body...)
+	return writeWebpChunks(append(out, chunk)), nil
+}
+
+// findWebpChunk returns the payload of the first chunk with the given FourCC
+func findWebpChunk(webp []byte, fourCC string) ([]byte, bool) {
+	chunks, err := parseWebpChunks(webp)
+	if err != nil {
+		return nil, false
+	}
+	chunk, found := lo.Find(chunks, func(c riffChunk) bool {
+		return c.fourCC == fourCC
+	})
+	return chunk.payload, found
+}
+
+// extendedWebpChunks splits a WebP file into a copy of its VP8X header, creating one for simple files, and the remaining chunks
+func extendedWebpChunks(webp []byte) (*riffChunk, []riffChunk, error) {
+	chunks, err := parseWebpChunks(webp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var vp8x *riffChunk
+	var body []riffChunk
+	for _, chunk := range chunks {
+		if chunk.fourCC == "VP8X" {
+			payload := make([]byte, len(chunk.payload))
+			copy(payload, chunk.payload)
+			vp8x = &riffChunk{fourCC: "VP8X", payload: payload}
+			continue
+		}
+		body = append(body, chunk)
+	}
+
+	if vp8x == nil {
+		header, err := newVP8XChunk(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		vp8x = header
+	}
+	return vp8x, body, nil
+}
+
+// newVP8XChunk creates the extended header for a simple lossy (VP8) or lossless (VP8L) WebP file
+func newVP8XChunk(chunks []riffChunk) (*riffChunk, error) {
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: no image data", errInvalidWebp)
+	}
+
+	var width, height int
+	var flags byte
+	image := chunks[0]
+	switch image.fourCC {
+	case "VP8 ":
+		// 3 byte frame tag, 3 byte start code, then 14 bit width and height
+		if len(image.payload) < 10 || !bytes.Equal(image.payload[3:6], []byte{0x9d, 0x01, 0x2a}) {
+			return nil, fmt.Errorf("%w: bad VP8 header", errInvalidWebp)
+		}
+		width = int(binary.LittleEndian.Uint16(image.payload[6:8]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(image.payload[8:10]) & 0x3fff)
+	case "VP8L":
+		// 1 byte signature, then 14 bit width-1, 14 bit height-1 and the alpha hint
+		if len(image.payload) < 5 || image.payload[0] != 0x2f {
+			return nil, fmt.Errorf("%w: bad VP8L header", errInvalidWebp)
+		}
+		bits := binary.LittleEndian.Uint32(image.payload[1:5])
+		width = int(bits&0x3fff) + 1
+		height = int((bits>>14)&0x3fff) + 1
+		if (bits>>28)&1 == 1 {
+			flags |= vp8xFlagAlpha
+		}
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q chunk", errInvalidWebp, image.fourCC)
+	}
+
+	payload := make([]byte, 10)
+	payload[0] = flags
+	putUint24(payload[4:7], width-1)
+	putUint24(payload[7:10], height-1)
+	return &riffChunk{fourCC: "VP8X", payload: payload}, nil
+}
+
+func parseWebpChunks(webp []byte) ([]riffChunk, error) {
+	if len(webp) < 12 || string(webp[0:4]) != "RIFF" || string(webp[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("%w: missing RIFF header", errInvalidWebp)
+	}
+
+	var chunks []riffChunk
+	data := webp[12:]
+	for len(data) >= 8 {
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if 8+size > len(data) {
+			return nil, fmt.Errorf("%w: truncated %q chunk", errInvalidWebp, string(data[0:4]))
+		}
+		chunks = append(chunks, riffChunk{fourCC: string(data[0:4]), payload: data[8 : 8+size]})
+		// chunks are padded to an even size
+		next := 8 + size + size%2
+		if next > len(data) {
+			break
+		}
+		data = data[next:]
+	}
+	return chunks, nil
+}
+
+func writeWebpChunks(chunks []riffChunk) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, chunk := range chunks {
+		body.WriteString(chunk.fourCC)
+		_ = binary.Write(&body, binary.LittleEndian, uint32(len(chunk.payload)))
+		body.Write(chunk.payload)
+		if len(chunk.payload)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+
+	out := make([]byte, 0, body.Len()+8)
+	out = append(out, "RIFF"...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(body.Len()))
+	return append(out, body.Bytes()...)
+}
+
+func putUint24(b []byte, v int) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
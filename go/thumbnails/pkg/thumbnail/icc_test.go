@@ -0,0 +1,114 @@
+package thumbnail
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildWebp(chunks ...riffChunk) []byte {
+	return writeWebpChunks(chunks)
+}
+
+func vp8lChunk(width, height int, alpha bool) riffChunk {
+	bits := uint32(width-1) | uint32(height-1)<<14
+	if alpha {
+		bits |= 1 << 28
+	}
+	payload := []byte{0x2f, 0, 0, 0, 0, 0xaa}
+	binary.LittleEndian.PutUint32(payload[1:5], bits)
+	return riffChunk{fourCC: "VP8L", payload: payload}
+}
+
+func TestEmbedICCProfile_SimpleLossless(t *testing.T) {
+	// given
+	webp := buildWebp(vp8lChunk(400, 300, true))
+	profile := []byte("icc-profile")
+
+	// when
+	result, err := embedICCProfile(webp, profile)
+
+	// then
+	assert.NoError(t, err)
+	chunks, err := parseWebpChunks(result)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, "VP8X", chunks[0].fourCC)
+	assert.Equal(t, byte(vp8xFlagICC|vp8xFlagAlpha), chunks[0].payload[0])
+	assert.Equal(t, []byte{0x8f, 0x01, 0x00, 0x2b, 0x01, 0x00}, chunks[0].payload[4:10])
+	assert.Equal(t, "ICCP", chunks[1].fourCC)
+	assert.Equal(t, profile, chunks[1].payload)
+	assert.Equal(t, "VP8L", chunks[2].fourCC)
+	assert.Equal(t, uint32(len(result)-8), binary.LittleEndian.Uint32(result[4:8]))
+}
+
+func TestEmbedICCProfile_SimpleLossy(t *testing.T) {
+	// given
+	payload := []byte{0, 0, 0, 0x9d, 0x01, 0x2a, 0, 0, 0, 0, 0xff}
+	binary.LittleEndian.PutUint16(payload[6:8], 640)
+	binary.LittleEndian.PutUint16(payload[8:10], 480)
+	webp := buildWebp(riffChunk{fourCC: "VP8 ", payload: payload})
+
+	// when
+	result, err := embedICCProfile(webp, []byte("odd"))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(result)%2)
+	chunks, err := parseWebpChunks(result)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, byte(vp8xFlagICC), chunks[0].payload[0])
+	assert.Equal(t, []byte{0x7f, 0x02, 0x00, 0xdf, 0x01, 0x00}, chunks[0].payload[4:10])
+	assert.Equal(t, []byte("odd"), chunks[1].payload)
+	assert.Equal(t, payload, chunks[2].payload)
+}
+
+func TestEmbedICCProfile_ReplacesExistingProfile(t *testing.T) {
+	// given
+	vp8x := riffChunk{fourCC: "VP8X", payload: []byte{vp8xFlagICC | 0x02, 0, 0, 0, 1, 0, 0, 1, 0, 0}}
+	webp := buildWebp(vp8x, riffChunk{fourCC: "ICCP", payload: []byte("srgb")}, riffChunk{fourCC: "ANIM", payload: make([]byte, 6)})
+
+	// when
+	result, err := embedICCProfile(webp, []byte("display-p3"))
+
+	// then
+	assert.NoError(t, err)
+	chunks, err := parseWebpChunks(result)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, vp8x.payload, chunks[0].payload)
+	assert.Equal(t, []byte("display-p3"), chunks[1].payload)
+	assert.Equal(t, "ANIM", chunks[2].fourCC)
+}
+
+func TestEmbedICCProfile_InvalidWebp(t *testing.T) {
+	// when
+	_, err := embedICCProfile([]byte("not a webp file"), []byte("icc"))
+
+	// then
+	assert.True(t, errors.Is(err, errInvalidWebp))
+}
+
+func TestAddWebpChunk_ReplacesChunkWithSameFourCC(t *testing.T) {
+	// given
+	webp := buildWebp(vp8lChunk(10, 10, false))
+
+	// when
+	first, err := addWebpChunk(webp, riffChunk{fourCC: "TEST", payload: []byte("one")})
+	assert.NoError(t, err)
+	second, err := addWebpChunk(first, riffChunk{fourCC: "TEST", payload: []byte("two")})
+
+	// then
+	assert.NoError(t, err)
+	chunks, err := parseWebpChunks(second)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, "VP8X", chunks[0].fourCC)
+	assert.Equal(t, "VP8L", chunks[1].fourCC)
+	payload, found := findWebpChunk(second, "TEST")
+	assert.True(t, found)
+	assert.Equal(t, []byte("two"), payload)
+}
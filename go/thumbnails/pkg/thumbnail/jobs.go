@@ -0,0 +1,472 @@
+package thumbnail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+const (
+	DefaultJobWorkers     = 2
+	DefaultJobLease       = 2 * time.Minute
+	DefaultJobMaxAttempts = 5
+	DefaultJobRetention   = 24 * time.Hour
+	// jobPollInterval is how often idle workers look for jobs queued by other instances or due for a retry
+	jobPollInterval  = 5 * time.Second
+	jobPruneInterval = time.Hour
+	jobRetryDelay    = 30 * time.Second
+	jobMaxRetryDelay = 30 * time.Minute
+	// jobBusyDelay is how long a job waits when its album is being processed by another job of this instance
+	jobBusyDelay = 10 * time.Second
+	// jobCancelCheckInterval caps the time between lease renewals, a job cancelled on another instance is stopped at
+	// the next renewal
+	jobCancelCheckInterval = 5 * time.Second
+	// jobMergeAttempts is how often files are merged into a job whose files changed under the merge before they get a
+	// job of their own
+	jobMergeAttempts = 3
+	// callbackDeliveryLimit is the number of completion event deliveries listed per album
+	callbackDeliveryLimit = 50
+)
+
+// JobConfig controls the workers that run the persisted album thumbnail jobs
+type JobConfig struct {
+	// Workers is the number of jobs this instance runs at once
+	Workers int
+	// Lease is how long a job stays claimed without being renewed, a job of a crashed instance is resumed after it
+	Lease time.Duration
+	// MaxAttempts is the number of runs after which a failing job is marked failed
+	MaxAttempts int
+	// Retention is how long done and failed jobs are kept
+	Retention time.Duration
+	// InstanceId identifies this instance across restarts, it must not be shared by instances running at once. a
+	// restarted instance releases the leases of its previous run straight away, without it they run out
+	InstanceId string
+}
+
+// JobConfigFromEnv reads THUMBNAIL_JOB_WORKERS, THUMBNAIL_JOB_LEASE, THUMBNAIL_JOB_MAX_ATTEMPTS,
+// THUMBNAIL_JOB_RETENTION and THUMBNAIL_INSTANCE_ID
+func JobConfigFromEnv() JobConfig {
+	config := JobConfig{
+		Workers:     DefaultJobWorkers,
+		Lease:       DefaultJobLease,
+		MaxAttempts: DefaultJobMaxAttempts,
+		Retention:   DefaultJobRetention,
+	}
+	if raw := os.Getenv("THUMBNAIL_JOB_WORKERS"); raw != "" {
+		workers, err := strconv.Atoi(raw)
+		if err != nil || workers <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_JOB_WORKERS")
+		} else {
+			config.Workers = workers
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_JOB_LEASE"); raw != "" {
+		lease, err := time.ParseDuration(raw)
+		if err != nil || lease < 10*time.Second {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_JOB_LEASE")
+		} else {
+			config.Lease = lease
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_JOB_MAX_ATTEMPTS"); raw != "" {
+		attempts, err := strconv.Atoi(raw)
+		if err != nil || attempts <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_JOB_MAX_ATTEMPTS")
+		} else {
+			config.MaxAttempts = attempts
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_JOB_RETENTION"); raw != "" {
+		retention, err := time.ParseDuration(raw)
+		if err != nil || retention <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_JOB_RETENTION")
+		} else {
+			config.Retention = retention
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_INSTANCE_ID"); raw != "" {
+		if strings.Contains(raw, ":") {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_INSTANCE_ID")
+		} else {
+			config.InstanceId = raw
+		}
+	}
+	return config
+}
+
+// jobRunner claims the persisted album jobs of every instance and runs them with the batch processor. a job is leased
+// while it runs, so a job of an instance that stopped is picked up again once its lease runs out
+type jobRunner struct {
+	dao    dao.Dao
+	config JobConfig
+	// owner is the lease owner of this run, it starts with the InstanceId when one is set
+	owner             string
+	progress          *progressHub
+	notifier          callback.Notifier
+	newBatchProcessor func(files []dto.FileEntryDto, albumId int, crop CropMode, force bool, progress *progressTracker) BatchProcessor
+	wake              chan struct{}
+	// running holds the runningJob of every job run by this instance by job id
+	running sync.Map
+}
+
+// runningJob is a job run by this instance, cancel stops its run
+type runningJob struct {
+	albumId int
+	cancel  context.CancelCauseFunc
+}
+
+// newJobRunner creates the job runner, notifier is optional and sends the completion events of finished jobs
+func newJobRunner(daoService dao.Dao, processor Processor, pool *WorkerPool, failures FailureConfig, progress *progressHub, notifier callback.Notifier, config JobConfig) *jobRunner {
+	instance := config.InstanceId
+	if instance == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "thumbnails"
+		}
+		instance = host
+	}
+	return &jobRunner{
+		dao:      daoService,
+		config:   config,
+		owner:    fmt.Sprintf("%s:%s", instance, uuid.NewString()),
+		progress: progress,
+		notifier: notifier,
+		newBatchProcessor: func(files []dto.FileEntryDto, albumId int, crop CropMode, force bool, progress *progressTracker) BatchProcessor {
+			return newTrackedBatchProcessor(daoService, processor, pool, failures, files, albumId, crop, force, progress)
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// start resumes the jobs the previous run of this instance held and starts the workers. the leases are only released
+// for an InstanceId, a host name can be shared by processes running at once
+func (r *jobRunner) start() {
+	if r.config.InstanceId != "" {
+		released, err := r.dao.ReleaseJobLeases(r.config.InstanceId + ":")
+		if err != nil {
+			log.Error().Err(err).Msg("failed to release thumbnail job leases of the previous run")
+		} else if released > 0 {
+			log.Info().Int64("jobs", released).Msg("resuming thumbnail jobs")
+		}
+	}
+
+	for i := 0; i < r.config.Workers; i++ {
+		go r.work()
+	}
+	go r.prune()
+}
+
+// notify wakes an idle worker, so a job queued on this instance doesn't wait for the next poll
+func (r *jobRunner) notify() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *jobRunner) work() {
+	for {
+		job, err := r.dao.ClaimJob(r.owner, r.config.Lease)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to claim thumbnail job")
+		}
+		if job == nil {
+			select {
+			case <-r.wake:
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+		r.run(*job)
+	}
+}
+
+// run processes a claimed job while renewing its lease, then stores the outcome and gives the lease up
+func (r *jobRunner) run(job mod.ThumbnailJob) {
+	logger := log.With().Int("jobId", *job.Id).Int("albumId", job.AlbumId).Int("attempt", job.Attempts).Logger()
+
+	// checked before tracking, the progress of the album is that of the job already running
+	if _, busy := albumProcessing.Load(job.AlbumId); busy {
+		r.postpone(job, nil, ErrAlbumProcessing)
+		return
+	}
+
+	var decoded []dto.FileEntryDto
+	err := json.Unmarshal([]byte(job.Files), &decoded)
+	progress := r.progress.track(job, len(decoded))
+	if err != nil {
+		// the files won't decode on a retry either
+		logger.Error().Err(err).Msg("thumbnail job has invalid files")
+		r.release(job, progress, mod.JobFailed, time.Now(), err)
+		return
+	}
+	files := newJobFiles(job.Revision, decoded)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	r.running.Store(*job.Id, runningJob{albumId: job.AlbumId, cancel: cancel})
+	stopRenewing := r.renew(job, cancel, files, progress)
+	started := time.Now()
+	err = r.process(ctx, job, files, progress)
+	stopRenewing()
+	r.running.Delete(*job.Id)
+	cancel(nil)
+
+	switch {
+	case err == nil:
+		job.Revision = files.lastRevision()
+		logger.Debug().Int("files", files.count()).Dur("took", time.Since(started)).Msg("thumbnail job done")
+		r.release(job, progress, mod.JobDone, time.Now(), nil)
+	case errors.Is(err, ErrJobCancelled):
+		logger.Info().Dur("took", time.Since(started)).Bool("discarded", discarding(ctx)).Msg("thumbnail job cancelled")
+		r.release(job, progress, mod.JobCancelled, time.Now(), nil)
+	case errors.Is(err, ErrAlbumProcessing):
+		r.postpone(job, progress, err)
+	case job.Attempts >= r.config.MaxAttempts:
+		logger.Error().Err(err).Msg("thumbnail job failed")
+		r.release(job, progress, mod.JobFailed, time.Now(), err)
+	default:
+		delay := doublingDelay(jobRetryDelay, jobMaxRetryDelay, job.Attempts)
+		logger.Warn().Err(err).Dur("retryIn", delay).Msg("thumbnail job failed, retrying")
+		r.release(job, progress, mod.JobQueued, time.Now().Add(delay), err)
+	}
+}
+
+// postpone queues a job again while its album is processed by another job or the backfill. waiting is not a failure of
+// the job, so the attempt its claim counted is given back
+func (r *jobRunner) postpone(job mod.ThumbnailJob, progress *progressTracker, cause error) {
+	job.Attempts = max(job.Attempts-1, 0)
+	r.release(job, progress, mod.JobQueued, time.Now().Add(jobBusyDelay), cause)
+}
+
+// process renders the files of a job, then the files merged into it while those rendered, until none are left
+func (r *jobRunner) process(ctx context.Context, job mod.ThumbnailJob, files *jobFiles, progress *progressTracker) error {
+	batch := files.take()
+	for {
+		if err := r.newBatchProcessor(batch, job.AlbumId, CropMode(job.Crop), job.Force, progress).Process(ctx); err != nil {
+			return err
+		}
+		// files merged since the last renewal
+		r.pickUp(*job.Id, files, progress)
+		if batch = files.take(); len(batch) == 0 {
+			return nil
+		}
+	}
+}
+
+// pickUp queues the files merged into a running job after the revision it last saw and counts them in its progress
+func (r *jobRunner) pickUp(jobId int, files *jobFiles, progress *progressTracker) {
+	revised, err := r.dao.GetRevisedJob(jobId, files.lastRevision())
+	if err != nil {
+		log.Error().Err(err).Int("jobId", jobId).Msg("failed to check a thumbnail job for merged files")
+		return
+	}
+	if revised == nil {
+		return
+	}
+	var merged []dto.FileEntryDto
+	if err := json.Unmarshal([]byte(revised.Files), &merged); err != nil {
+		log.Error().Err(err).Int("jobId", jobId).Msg("thumbnail job has invalid merged files")
+		return
+	}
+	if added := files.merge(revised.Revision, merged); added > 0 {
+		log.Debug().Int("jobId", jobId).Int("files", added).Msg("picked up files merged into thumbnail job")
+		progress.grow(added)
+	}
+}
+
+// cancel stops the job of an album run by this instance straight away, jobs run by other instances are stopped at
+// their next lease renewal
+func (r *jobRunner) cancel(albumId int, discard bool) {
+	if r == nil {
+		return
+	}
+	r.running.Range(func(_, value any) bool {
+		if job := value.(runningJob); job.albumId == albumId {
+			job.cancel(jobCancellation{discard: discard})
+		}
+		return true
+	})
+}
+
+// renew extends the lease of a running job until the returned func is called and picks up the files merged into it, the
+// run is cancelled once the job is cancelled or removed
+func (r *jobRunner) renew(job mod.ThumbnailJob, cancel context.CancelCauseFunc, files *jobFiles, progress *progressTracker) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(min(r.config.Lease/3, jobCancelCheckInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				held, err := r.dao.RenewJobLease(*job.Id, r.owner, r.config.Lease)
+				if err != nil {
+					log.Error().Err(err).Int("jobId", *job.Id).Msg("failed to renew thumbnail job lease")
+				} else if !held {
+					r.interrupt(job, cancel)
+					return
+				} else {
+					r.pickUp(*job.Id, files, progress)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// interrupt cancels the run of a job whose lease could not be renewed because it was cancelled or removed with its
+// album, a job whose lease was taken by another worker keeps running
+func (r *jobRunner) interrupt(job mod.ThumbnailJob, cancel context.CancelCauseFunc) {
+	current, err := r.dao.GetJob(*job.Id)
+	switch {
+	case err != nil:
+		log.Error().Err(err).Int("jobId", *job.Id).Msg("failed to check a thumbnail job that lost its lease")
+	case current == nil:
+		// the thumbnails of a removed album can't be stored
+		cancel(jobCancellation{discard: true})
+	case current.Status == mod.JobCancelled:
+		cancel(jobCancellation{discard: current.DiscardPartial})
+	default:
+		log.Warn().Int("jobId", *job.Id).Msg("lost the lease of a running thumbnail job")
+	}
+}
+
+func (r *jobRunner) release(job mod.ThumbnailJob, progress *progressTracker, status mod.JobStatus, runAfter time.Time, cause error) {
+	job.Status = status
+	job.RunAfter = runAfter.UTC()
+	job.LeaseOwner = &r.owner
+	job.LastError = nil
+	if cause != nil {
+		lastError := cause.Error()
+		job.LastError = &lastError
+	}
+
+	held, err := r.dao.ReleaseJob(job)
+	if err == nil && !held && status == mod.JobDone {
+		// files were merged after the last check, the job runs again for them
+		job.Status = mod.JobQueued
+		if held, err = r.dao.ReleaseJob(job); held {
+			status = mod.JobQueued
+		}
+	}
+	if err == nil && !held && status != mod.JobCancelled {
+		// the job may have been cancelled after the last renewal, it stays cancelled
+		job.Status = mod.JobCancelled
+		job.LastError = nil
+		if held, err = r.dao.ReleaseJob(job); held {
+			status, cause = mod.JobCancelled, nil
+		}
+	}
+	progress.finish(status, cause)
+	if err != nil {
+		log.Error().Err(err).Int("jobId", *job.Id).Msg("failed to store thumbnail job result")
+		return
+	}
+	if !held {
+		log.Warn().Int("jobId", *job.Id).Msg("thumbnail job was claimed by another worker before it finished")
+		return
+	}
+	if status.Finished() {
+		r.complete(job, progress)
+	}
+}
+
+// complete sends the completion event of a job that won't run again
+func (r *jobRunner) complete(job mod.ThumbnailJob, progress *progressTracker) {
+	if r.notifier == nil || progress == nil {
+		return
+	}
+	r.notifier.Notify(progress.completion(), lo.FromPtr(job.CallbackUrl))
+}
+
+// prune removes the finished jobs older than the retention
+func (r *jobRunner) prune() {
+	for range time.Tick(jobPruneInterval) {
+		if _, err := r.dao.DeleteFinishedJobs(time.Now().Add(-r.config.Retention)); err != nil {
+			log.Error().Err(err).Msg("failed to remove finished thumbnail jobs")
+		}
+	}
+}
+
+// doublingDelay is the wait before the retry of a job or file, base after the first attempt and doubled with every
+// attempt after it up to limit
+func doublingDelay(base, limit time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// jobFiles holds the files of a running job by id. files merged into the job after it was claimed are queued once and
+// rendered after the files before them
+type jobFiles struct {
+	mu       sync.Mutex
+	revision int
+	seen     map[int]struct{}
+	pending  []dto.FileEntryDto
+}
+
+func newJobFiles(revision int, files []dto.FileEntryDto) *jobFiles {
+	jobFiles := &jobFiles{seen: make(map[int]struct{})}
+	jobFiles.merge(revision, files)
+	return jobFiles
+}
+
+// merge queues the files not seen before and records the revision they are from, the number queued is returned
+func (f *jobFiles) merge(revision int, files []dto.FileEntryDto) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revision = max(f.revision, revision)
+	added := 0
+	for _, file := range files {
+		if _, seen := f.seen[file.Id]; seen {
+			continue
+		}
+		f.seen[file.Id] = struct{}{}
+		f.pending = append(f.pending, file)
+		added++
+	}
+	return added
+}
+
+// take returns the queued files and empties the queue
+func (f *jobFiles) take() []dto.FileEntryDto {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pending := f.pending
+	f.pending = nil
+	return pending
+}
+
+func (f *jobFiles) lastRevision() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.revision
+}
+
+func (f *jobFiles) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.seen)
+}
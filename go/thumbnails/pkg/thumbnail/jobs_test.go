@@ -0,0 +1,447 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+)
+
+func newTestJobRunner(t *testing.T, daoService dao.Dao, processErr error) *jobRunner {
+	runner := newJobRunner(daoService, nil, nil, FailureConfig{}, newProgressHub(cache.NewLRUCache(1024*1024)), nil, JobConfig{Workers: 1, Lease: time.Minute, MaxAttempts: 3})
+	runner.newBatchProcessor = func(files []dto.FileEntryDto, albumId int, crop CropMode, _ bool, _ *progressTracker) BatchProcessor {
+		batchProcessor := NewMockBatchProcessor(t)
+		batchProcessor.EXPECT().Process(mock.Anything).Return(processErr)
+		return batchProcessor
+	}
+	return runner
+}
+
+func claimedJob(attempts int, files string) mod.ThumbnailJob {
+	id := 7
+	return mod.ThumbnailJob{
+		Id:       &id,
+		AlbumId:  3,
+		Crop:     string(CropAttention),
+		Status:   mod.JobRunning,
+		Files:    files,
+		Attempts: attempts,
+	}
+}
+
+// expectNoMergedFiles stubs the check for files merged into the job after a run
+func expectNoMergedFiles(daoService *dao.MockDao) {
+	daoService.EXPECT().GetRevisedJob(7, 0).Return(nil, nil)
+}
+
+// expectRelease captures the job passed to ReleaseJob
+func expectRelease(daoService *dao.MockDao) *mod.ThumbnailJob {
+	released := &mod.ThumbnailJob{}
+	daoService.EXPECT().ReleaseJob(mock.Anything).RunAndReturn(func(job mod.ThumbnailJob, _ ...*gorm.DB) (bool, error) {
+		*released = job
+		return true, nil
+	})
+	return released
+}
+
+func TestJobRunner_Run_Done(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newTestJobRunner(t, daoService, nil)
+	var processed []dto.FileEntryDto
+	var processedCrop CropMode
+	runner.newBatchProcessor = func(files []dto.FileEntryDto, albumId int, crop CropMode, _ bool, _ *progressTracker) BatchProcessor {
+		processed, processedCrop = files, crop
+		batchProcessor := NewMockBatchProcessor(t)
+		batchProcessor.EXPECT().Process(mock.Anything).Return(nil)
+		return batchProcessor
+	}
+	expectNoMergedFiles(daoService)
+	released := expectRelease(daoService)
+
+	// when
+	runner.run(claimedJob(1, `[{"id":1,"extension":"png"}]`))
+
+	// then
+	assert.Equal(t, []dto.FileEntryDto{{Id: 1, Extension: "png"}}, processed)
+	assert.Equal(t, CropAttention, processedCrop)
+	assert.Equal(t, mod.JobDone, released.Status)
+	assert.Equal(t, runner.owner, *released.LeaseOwner)
+	assert.Nil(t, released.LastError)
+	progress, tracked := runner.progress.load(3)
+	assert.True(t, tracked)
+	assert.Equal(t, mod.JobDone, progress.State)
+	assert.Equal(t, 1, progress.Total)
+	assert.False(t, progress.FinishedAt.IsZero())
+}
+
+func TestJobRunner_Run_NotifiesCompletion(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	notifier := callback.NewMockNotifier(t)
+	runner := newTestJobRunner(t, daoService, nil)
+	runner.notifier = notifier
+	runner.newBatchProcessor = func(files []dto.FileEntryDto, albumId int, crop CropMode, _ bool, progress *progressTracker) BatchProcessor {
+		batchProcessor := NewMockBatchProcessor(t)
+		batchProcessor.EXPECT().Process(mock.Anything).RunAndReturn(func(context.Context) error {
+			progress.processed(1)
+			progress.skipped("unsupported file type", 2)
+			return nil
+		})
+		return batchProcessor
+	}
+	expectNoMergedFiles(daoService)
+	expectRelease(daoService)
+	job := claimedJob(1, `[{"id":1,"extension":"png"},{"id":2,"extension":"txt"}]`)
+	callbackUrl := "https://waifuvault.moe/callback"
+	job.CallbackUrl = &callbackUrl
+	var event callback.Event
+	notifier.EXPECT().Notify(mock.Anything, callbackUrl).Run(func(notified callback.Event, _ string) {
+		event = notified
+	})
+
+	// when
+	runner.run(job)
+
+	// then
+	assert.Equal(t, 7, event.JobId)
+	assert.Equal(t, 3, event.AlbumId)
+	assert.Equal(t, string(mod.JobDone), event.State)
+	assert.Equal(t, 2, event.Total)
+	assert.Equal(t, []callback.FileOutcome{
+		{FileId: 1, Outcome: callback.OutcomeProcessed},
+		{FileId: 2, Outcome: callback.OutcomeSkipped, Reason: "unsupported file type"},
+	}, event.Files)
+}
+
+func TestJobRunner_Run_RetryDoesNotNotify(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newTestJobRunner(t, daoService, errors.New("database error"))
+	runner.notifier = callback.NewMockNotifier(t)
+	released := expectRelease(daoService)
+
+	// when
+	runner.run(claimedJob(1, `[]`))
+
+	// then the notifier mock fails the test if a completion event is sent
+	assert.Equal(t, mod.JobQueued, released.Status)
+}
+
+func TestJobRunner_Run_RetriesWithBackoff(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newTestJobRunner(t, daoService, errors.New("database error"))
+	released := expectRelease(daoService)
+
+	// when
+	before := time.Now()
+	runner.run(claimedJob(2, `[]`))
+
+	// then
+	assert.Equal(t, mod.JobQueued, released.Status)
+	assert.WithinDuration(t, before.Add(time.Minute), released.RunAfter, 5*time.Second)
+	assert.Equal(t, "database error", *released.LastError)
+}
+
+func TestJobRunner_Run_FailsAfterMaxAttempts(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newTestJobRunner(t, daoService, errors.New("database error"))
+	released := expectRelease(daoService)
+
+	// when
+	runner.run(claimedJob(3, `[]`))
+
+	// then
+	assert.Equal(t, mod.JobFailed, released.Status)
+	assert.Equal(t, "database error", *released.LastError)
+}
+
+func TestJobRunner_Run_WaitsForAlbumBeingProcessed(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newJobRunner(daoService, nil, nil, FailureConfig{}, newProgressHub(cache.NewLRUCache(1024*1024)), nil, JobConfig{Workers: 1, Lease: time.Minute, MaxAttempts: 3})
+	released := expectRelease(daoService)
+	albumProcessing.Store(3, true)
+	defer albumProcessing.Delete(3)
+
+	// when
+	runner.run(claimedJob(1, `[]`))
+
+	// then
+	assert.Equal(t, mod.JobQueued, released.Status)
+	assert.True(t, released.RunAfter.After(time.Now()))
+	_, tracked := runner.progress.load(3)
+	assert.False(t, tracked)
+}
+
+func TestJobRunner_Run_AlbumBusyIsNotAFailure(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newTestJobRunner(t, daoService, fmt.Errorf("%w: albumId 3", ErrAlbumProcessing))
+	released := expectRelease(daoService)
+
+	// when
+	runner.run(claimedJob(3, `[]`))
+
+	// then
+	assert.Equal(t, mod.JobQueued, released.Status)
+	assert.True(t, released.RunAfter.After(time.Now()))
+}
+
+func TestJobRunner_Run_AlbumBusyKeepsAttempts(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newJobRunner(daoService, nil, nil, FailureConfig{}, newProgressHub(cache.NewLRUCache(1024*1024)), nil, JobConfig{Workers: 1, Lease: time.Minute, MaxAttempts: 3})
+	released := expectRelease(daoService)
+	albumProcessing.Store(3, true)
+	defer albumProcessing.Delete(3)
+
+	// when the job is claimed more often than it may be attempted while the album stays busy
+	attempts := 0
+	for range 5 {
+		runner.run(claimedJob(attempts+1, `[]`))
+		attempts = released.Attempts
+	}
+
+	// then
+	assert.Equal(t, 0, attempts)
+	assert.Equal(t, mod.JobQueued, released.Status)
+}
+
+func TestJobRunner_Cancel_StopsJobOfAlbum(t *testing.T) {
+	// given
+	runner := newJobRunner(dao.NewMockDao(t), nil, nil, FailureConfig{}, nil, nil, JobConfig{})
+	running, cancelRunning := context.WithCancelCause(context.Background())
+	other, cancelOther := context.WithCancelCause(context.Background())
+	defer cancelOther(nil)
+	runner.running.Store(7, runningJob{albumId: 3, cancel: cancelRunning})
+	runner.running.Store(8, runningJob{albumId: 4, cancel: cancelOther})
+
+	// when
+	runner.cancel(3, false)
+
+	// then
+	assert.ErrorIs(t, context.Cause(running), ErrJobCancelled)
+	assert.NoError(t, other.Err())
+}
+
+func TestJobRunner_Run_InvalidFiles(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newJobRunner(daoService, nil, nil, FailureConfig{}, newProgressHub(cache.NewLRUCache(1024*1024)), nil, JobConfig{Workers: 1, Lease: time.Minute, MaxAttempts: 3})
+	released := expectRelease(daoService)
+
+	// when
+	runner.run(claimedJob(1, `not json`))
+
+	// then
+	assert.Equal(t, mod.JobFailed, released.Status)
+	assert.NotNil(t, released.LastError)
+}
+
+func TestJobRunner_Run_Cancelled(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	notifier := callback.NewMockNotifier(t)
+	runner := newTestJobRunner(t, daoService, nil)
+	runner.notifier = notifier
+	runner.newBatchProcessor = func(files []dto.FileEntryDto, albumId int, crop CropMode, _ bool, _ *progressTracker) BatchProcessor {
+		batchProcessor := NewMockBatchProcessor(t)
+		batchProcessor.EXPECT().Process(mock.Anything).RunAndReturn(func(ctx context.Context) error {
+			runner.cancel(albumId, true)
+			<-ctx.Done()
+			assert.True(t, discarding(ctx))
+			return context.Cause(ctx)
+		})
+		return batchProcessor
+	}
+	released := expectRelease(daoService)
+	notifier.EXPECT().Notify(mock.MatchedBy(func(event callback.Event) bool {
+		return event.State == string(mod.JobCancelled)
+	}), "")
+
+	// when
+	runner.run(claimedJob(1, `[]`))
+
+	// then
+	assert.Equal(t, mod.JobCancelled, released.Status)
+	assert.Nil(t, released.LastError)
+	_, running := runner.running.Load(7)
+	assert.False(t, running)
+}
+
+func TestJobRunner_Release_KeepsJobCancelledMeanwhile(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newTestJobRunner(t, daoService, nil)
+	expectNoMergedFiles(daoService)
+	var statuses []mod.JobStatus
+	daoService.EXPECT().ReleaseJob(mock.Anything).RunAndReturn(func(job mod.ThumbnailJob, _ ...*gorm.DB) (bool, error) {
+		statuses = append(statuses, job.Status)
+		return job.Status == mod.JobCancelled, nil
+	})
+
+	// when
+	runner.run(claimedJob(1, `[]`))
+
+	// then
+	assert.Equal(t, []mod.JobStatus{mod.JobDone, mod.JobQueued, mod.JobCancelled}, statuses)
+	progress, _ := runner.progress.load(3)
+	assert.Equal(t, mod.JobCancelled, progress.State)
+}
+
+func TestJobRunner_Run_PicksUpMergedFiles(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newTestJobRunner(t, daoService, nil)
+	var rounds [][]dto.FileEntryDto
+	runner.newBatchProcessor = func(files []dto.FileEntryDto, albumId int, crop CropMode, _ bool, _ *progressTracker) BatchProcessor {
+		rounds = append(rounds, files)
+		batchProcessor := NewMockBatchProcessor(t)
+		batchProcessor.EXPECT().Process(mock.Anything).Return(nil)
+		return batchProcessor
+	}
+	revised := claimedJob(1, `[{"id":1,"extension":"png"},{"id":2,"extension":"jpg"}]`)
+	revised.Revision = 1
+	daoService.EXPECT().GetRevisedJob(7, 0).Return(&revised, nil)
+	daoService.EXPECT().GetRevisedJob(7, 1).Return(nil, nil)
+	released := expectRelease(daoService)
+
+	// when
+	runner.run(claimedJob(1, `[{"id":1,"extension":"png"}]`))
+
+	// then the merged file is rendered after the first one, which isn't rendered again
+	assert.Equal(t, [][]dto.FileEntryDto{{{Id: 1, Extension: "png"}}, {{Id: 2, Extension: "jpg"}}}, rounds)
+	assert.Equal(t, mod.JobDone, released.Status)
+	assert.Equal(t, 1, released.Revision)
+	progress, _ := runner.progress.load(3)
+	assert.Equal(t, 2, progress.Total)
+}
+
+func TestJobRunner_Release_RequeuesJobWithLateMergedFiles(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newTestJobRunner(t, daoService, nil)
+	expectNoMergedFiles(daoService)
+	var statuses []mod.JobStatus
+	daoService.EXPECT().ReleaseJob(mock.Anything).RunAndReturn(func(job mod.ThumbnailJob, _ ...*gorm.DB) (bool, error) {
+		statuses = append(statuses, job.Status)
+		// files merged after the last check raised the revision
+		return job.Status != mod.JobDone, nil
+	})
+
+	// when
+	runner.run(claimedJob(1, `[]`))
+
+	// then
+	assert.Equal(t, []mod.JobStatus{mod.JobDone, mod.JobQueued}, statuses)
+	progress, _ := runner.progress.load(3)
+	assert.Equal(t, mod.JobQueued, progress.State)
+}
+
+func TestJobRunner_Interrupt(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   *mod.ThumbnailJob
+		cancelled bool
+		discard   bool
+	}{
+		{name: "cancelled", current: &mod.ThumbnailJob{Status: mod.JobCancelled}, cancelled: true},
+		{name: "cancelled discarding", current: &mod.ThumbnailJob{Status: mod.JobCancelled, DiscardPartial: true}, cancelled: true, discard: true},
+		{name: "removed with its album", current: nil, cancelled: true, discard: true},
+		{name: "lease taken", current: &mod.ThumbnailJob{Status: mod.JobRunning}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			daoService := dao.NewMockDao(t)
+			runner := newTestJobRunner(t, daoService, nil)
+			daoService.EXPECT().GetJob(7).Return(tt.current, nil)
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+
+			// when
+			runner.interrupt(claimedJob(1, `[]`), cancel)
+
+			// then
+			assert.Equal(t, tt.cancelled, errors.Is(context.Cause(ctx), ErrJobCancelled))
+			assert.Equal(t, tt.discard, discarding(ctx))
+		})
+	}
+}
+
+func TestJobRunner_Start_ReleasesLeasesOfPreviousRun(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newJobRunner(daoService, nil, nil, FailureConfig{}, nil, nil, JobConfig{InstanceId: "thumbnails-1"})
+	daoService.EXPECT().ReleaseJobLeases("thumbnails-1:").Return(2, nil)
+
+	// when
+	runner.start()
+
+	// then
+	assert.True(t, strings.HasPrefix(runner.owner, "thumbnails-1:"))
+}
+
+func TestJobRunner_Start_WithoutInstanceIdKeepsLeases(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	runner := newJobRunner(daoService, nil, nil, FailureConfig{}, nil, nil, JobConfig{})
+
+	// when
+	runner.start()
+
+	// then another process on the same host keeps the jobs it runs
+	daoService.AssertNotCalled(t, "ReleaseJobLeases", mock.Anything)
+}
+
+func TestJobRunner_NotifyDoesNotBlock(t *testing.T) {
+	runner := newJobRunner(dao.NewMockDao(t), nil, nil, FailureConfig{}, nil, nil, JobConfig{})
+	var missing *jobRunner
+
+	runner.notify()
+	runner.notify()
+	missing.notify()
+
+	assert.Len(t, runner.wake, 1)
+}
+
+func TestDoublingDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, doublingDelay(jobRetryDelay, jobMaxRetryDelay, 1))
+	assert.Equal(t, time.Minute, doublingDelay(jobRetryDelay, jobMaxRetryDelay, 2))
+	assert.Equal(t, 4*time.Minute, doublingDelay(jobRetryDelay, jobMaxRetryDelay, 4))
+	assert.Equal(t, jobMaxRetryDelay, doublingDelay(jobRetryDelay, jobMaxRetryDelay, 12))
+	assert.Equal(t, jobMaxRetryDelay, doublingDelay(jobRetryDelay, jobMaxRetryDelay, 1000))
+}
+
+func TestJobConfigFromEnv(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_JOB_WORKERS", "4")
+	t.Setenv("THUMBNAIL_JOB_LEASE", "5m")
+	t.Setenv("THUMBNAIL_JOB_MAX_ATTEMPTS", "0")
+	t.Setenv("THUMBNAIL_JOB_RETENTION", "1s")
+	t.Setenv("THUMBNAIL_INSTANCE_ID", "thumbnails-1")
+
+	// when
+	config := JobConfigFromEnv()
+
+	// then
+	assert.Equal(t, 4, config.Workers)
+	assert.Equal(t, 5*time.Minute, config.Lease)
+	assert.Equal(t, DefaultJobMaxAttempts, config.MaxAttempts)
+	assert.Equal(t, time.Second, config.Retention)
+	assert.Equal(t, "thumbnails-1", config.InstanceId)
+}
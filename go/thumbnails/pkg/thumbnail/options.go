@@ -0,0 +1,101 @@
+package thumbnail
+
+import (
+	"fmt"
+
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+// Options controls how a single thumbnail is rendered
+type Options struct {
+	// Animate keeps the animation of animated images
+	Animate bool
+	// Crop crops the thumbnail to a square using the given strategy, CropNone keeps the aspect ratio
+	Crop CropMode
+	// FocalPoint overrides the crop strategy with a user supplied point of interest
+	FocalPoint *mod.FocalPoint
+	// Width is the width of the thumbnail in pixels, 0 uses DefaultThumbnailWidth
+	Width int
+	// KeepProfile embeds the original ICC profile instead of converting the thumbnail to sRGB
+	KeepProfile bool
+	// MaxBytes is the byte budget of the encoded thumbnail, 0 uses the configured default
+	MaxBytes int
+	// MinQuality is the lowest quality the encoder may use to fit MaxBytes, 0 uses the configured default
+	MinQuality int
+}
+
+// key returns the cache key suffix for the options and pipeline version, default options keep the original animate
+// only keys
+func (o Options) key() string {
+	key := "static"
+	if o.Animate {
+		key = "animated"
+	}
+	if o.cropped() {
+		key += ":" + string(o.Crop)
+	}
+	if o.Width != 0 {
+		key += fmt.Sprintf(":w%d", o.Width)
+	}
+	if o.KeepProfile {
+		key += ":icc"
+	}
+	if o.MaxBytes != 0 {
+		key += fmt.Sprintf(":b%d", o.MaxBytes)
+	}
+	if o.MinQuality != 0 {
+		key += fmt.Sprintf(":q%d", o.MinQuality)
+	}
+	return versionKey(key, PipelineVersion)
+}
+
+// versionKey stamps a cache key with the pipeline version, so keys of other versions are misses
+func versionKey(key string, version int) string {
+	if version <= 1 {
+		return key
+	}
+	return fmt.Sprintf("%s:v%d", key, version)
+}
+
+// renderKey identifies a thumbnail of a file's contents rendered with the options, so files uploaded more than once
+// share it. files without a checksum have none. the focal point only changes cropped thumbnails
+func (o Options) renderKey(checksum string) string {
+	if checksum == "" {
+		return ""
+	}
+	key := fmt.Sprintf("checksum:%s:%s", checksum, o.key())
+	if o.cropped() && o.FocalPoint != nil {
+		key += fmt.Sprintf(":f%g,%g", o.FocalPoint.X, o.FocalPoint.Y)
+	}
+	return key
+}
+
+// cropped reports whether the thumbnail should be cropped to a square
+func (o Options) cropped() bool {
+	return o.Crop != CropNone
+}
+
+// width returns the requested thumbnail width, falling back to the default
+func (o Options) width() int {
+	if o.Width == 0 {
+		return DefaultThumbnailWidth
+	}
+	return o.Width
+}
+
+// withWidth returns a copy of the options rendering at the given width
+func (o Options) withWidth(width int) Options {
+	o.Width = width
+	return o
+}
+
+// withBudget fills in the configured byte budget when the options do not have one
+func (o Options) withBudget(maxBytes, minQuality int) Options {
+	if o.MaxBytes == 0 {
+		o.MaxBytes = maxBytes
+	}
+	if o.MinQuality == 0 {
+		o.MinQuality = minQuality
+	}
+	return o
+}
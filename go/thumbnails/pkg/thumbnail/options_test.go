@@ -0,0 +1,34 @@
+package thumbnail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+func TestOptions_Key(t *testing.T) {
+	assert.Equal(t, "static", Options{}.key())
+	assert.Equal(t, "animated:attention:w400:icc:b20000:q50", Options{
+		Animate:     true,
+		Crop:        CropAttention,
+		Width:       400,
+		KeepProfile: true,
+		MaxBytes:    20000,
+		MinQuality:  50,
+	}.key())
+}
+
+func TestOptions_RenderKey(t *testing.T) {
+	focalPoint := &mod.FocalPoint{X: 0.25, Y: 0.5}
+
+	assert.Empty(t, Options{}.renderKey(""))
+	assert.Equal(t, "checksum:abc:static", Options{}.renderKey("abc"))
+	assert.Equal(t, "checksum:abc:static", Options{FocalPoint: focalPoint}.renderKey("abc"))
+	assert.Equal(t, "checksum:abc:static:entropy:f0.25,0.5", Options{Crop: CropEntropy, FocalPoint: focalPoint}.renderKey("abc"))
+}
+
+func TestVersionKey(t *testing.T) {
+	assert.Equal(t, "static", versionKey("static", 1))
+	assert.Equal(t, "static:attention:v2", versionKey("static:attention", 2))
+}
@@ -0,0 +1,331 @@
+package thumbnail
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultPoolWorkers    = 4
+	DefaultPoolMaxBytes   = 1 << 30
+	DefaultPoolBatchShare = 20
+)
+
+// Priority is the class of work a file is rendered for
+type Priority int
+
+const (
+	// PriorityInteractive is a thumbnail a request waits for, it starts before the queued batch work
+	PriorityInteractive Priority = iota
+	// PriorityBatch is the work of album jobs and the backfill
+	PriorityBatch
+)
+
+// PoolConfig sizes the worker pool the thumbnails of requests, album jobs and the backfill are rendered on
+type PoolConfig struct {
+	// Workers is the number of files this instance renders at once
+	Workers int
+	// MaxBytes caps the estimated decode memory of the files rendered at once, 0 disables the cap. a file estimated
+	// above it is rendered on its own
+	MaxBytes int64
+	// BatchShare is the minimum percentage of the files started that is batch work while both classes wait, 0 lets
+	// interactive work starve the batch work
+	BatchShare int
+}
+
+// PoolConfigFromEnv reads THUMBNAIL_POOL_WORKERS, THUMBNAIL_POOL_MAX_BYTES and THUMBNAIL_POOL_BATCH_SHARE
+func PoolConfigFromEnv() PoolConfig {
+	config := PoolConfig{
+		Workers:    DefaultPoolWorkers,
+		MaxBytes:   DefaultPoolMaxBytes,
+		BatchShare: DefaultPoolBatchShare,
+	}
+	if raw := os.Getenv("THUMBNAIL_POOL_WORKERS"); raw != "" {
+		workers, err := strconv.Atoi(raw)
+		if err != nil || workers <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_POOL_WORKERS")
+		} else {
+			config.Workers = workers
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_POOL_MAX_BYTES"); raw != "" {
+		maxBytes, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || maxBytes < 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_POOL_MAX_BYTES")
+		} else {
+			config.MaxBytes = maxBytes
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_POOL_BATCH_SHARE"); raw != "" {
+		share, err := strconv.Atoi(raw)
+		if err != nil || share < 0 || share > 100 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_POOL_BATCH_SHARE")
+		} else {
+			config.BatchShare = share
+		}
+	}
+	return config
+}
+
+// ClassStats is the queue of a priority class
+type ClassStats struct {
+	// Queued is the number of files waiting for a worker
+	Queued  int
+	Running int
+	// Started is the number of files started since the instance started
+	Started int64
+	// OldestWait is how long the file first in line has been waiting
+	OldestWait time.Duration
+	// AverageWait is the mean time the started files waited for a worker
+	AverageWait time.Duration
+}
+
+// PoolStats is a snapshot of the worker pool
+type PoolStats struct {
+	Workers     int
+	InUseBytes  int64
+	MaxBytes    int64
+	Interactive ClassStats
+	Batch       ClassStats
+}
+
+// poolTask is a file waiting for a worker
+type poolTask struct {
+	ctx      context.Context
+	priority Priority
+	cost     int64
+	queuedAt time.Time
+	run      func()
+}
+
+// classCounters is what the pool counts per priority class, the queue lengths are read from the queues
+type classCounters struct {
+	running int
+	started int64
+	waited  time.Duration
+}
+
+// WorkerPool renders the files of every batch processor and request of the instance on a fixed number of workers.
+// interactive work starts before the queued batch work, except that batch work gets BatchShare of the files started
+// while both wait. albums take turns, so a large album doesn't hold up the others, and a file only starts once its
+// estimated decode memory fits next to the files being rendered
+type WorkerPool struct {
+	config PoolConfig
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// interactive holds the waiting interactive files in order
+	interactive []poolTask
+	// queues holds the waiting batch files by album, turns lists the albums with waiting files in the order they get a
+	// worker
+	queues      map[int][]poolTask
+	turns       []int
+	batchQueued int
+	// interactiveStreak counts the interactive files started in a row while batch files waited
+	interactiveStreak int
+	counters          [2]classCounters
+	inUse             int64
+	stopped           bool
+}
+
+// NewWorkerPool creates the pool and starts its workers
+func NewWorkerPool(config PoolConfig) *WorkerPool {
+	pool := &WorkerPool{
+		config: config,
+		queues: make(map[int][]poolTask),
+	}
+	pool.cond = sync.NewCond(&pool.mu)
+	for range max(config.Workers, 1) {
+		go pool.work()
+	}
+	return pool
+}
+
+// submit queues a batch file of an album, run is called on a worker once the album's turn comes and the cost fits. the
+// file of a cancelled run costs nothing, so its run can return straight away
+func (p *WorkerPool) submit(ctx context.Context, albumId int, cost int64, run func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queues[albumId]) == 0 {
+		p.turns = append(p.turns, albumId)
+	}
+	p.queues[albumId] = append(p.queues[albumId], poolTask{
+		ctx:      ctx,
+		priority: PriorityBatch,
+		cost:     cost,
+		queuedAt: time.Now(),
+		run:      run,
+	})
+	p.batchQueued++
+	p.cond.Signal()
+}
+
+// runInteractive runs render on a worker ahead of the queued batch work and waits for it
+func runInteractive[T any](p *WorkerPool, cost int64, render func() (T, error)) (T, error) {
+	var result T
+	var err error
+	done := make(chan struct{})
+
+	p.mu.Lock()
+	p.interactive = append(p.interactive, poolTask{
+		ctx:      context.Background(),
+		priority: PriorityInteractive,
+		cost:     cost,
+		queuedAt: time.Now(),
+		run: func() {
+			defer close(done)
+			result, err = render()
+		},
+	})
+	p.cond.Signal()
+	p.mu.Unlock()
+
+	<-done
+	return result, err
+}
+
+// Stats returns the queue depth and wait times of every priority class
+func (p *WorkerPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+
+	interactive := p.classStats(PriorityInteractive, len(p.interactive))
+	if len(p.interactive) > 0 {
+		interactive.OldestWait = now.Sub(p.interactive[0].queuedAt)
+	}
+	batch := p.classStats(PriorityBatch, p.batchQueued)
+	for _, queue := range p.queues {
+		batch.OldestWait = max(batch.OldestWait, now.Sub(queue[0].queuedAt))
+	}
+	return PoolStats{
+		Workers:     max(p.config.Workers, 1),
+		InUseBytes:  p.inUse,
+		MaxBytes:    p.config.MaxBytes,
+		Interactive: interactive,
+		Batch:       batch,
+	}
+}
+
+// classStats is called with mu held
+func (p *WorkerPool) classStats(priority Priority, queued int) ClassStats {
+	counters := p.counters[priority]
+	stats := ClassStats{
+		Queued:  queued,
+		Running: counters.running,
+		Started: counters.started,
+	}
+	if counters.started > 0 {
+		stats.AverageWait = counters.waited / time.Duration(counters.started)
+	}
+	return stats
+}
+
+// stop ends the workers once their current file is done, the files still waiting are never run
+func (p *WorkerPool) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	p.cond.Broadcast()
+}
+
+func (p *WorkerPool) work() {
+	for {
+		task, ok := p.next()
+		if !ok {
+			return
+		}
+		task.run()
+
+		p.mu.Lock()
+		p.inUse -= task.cost
+		p.counters[task.priority].running--
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	}
+}
+
+// next waits for a file to be admitted, it returns false once the pool is stopped
+func (p *WorkerPool) next() (poolTask, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for !p.stopped {
+		if task, ok := p.admit(); ok {
+			return task, true
+		}
+		p.cond.Wait()
+	}
+	return poolTask{}, false
+}
+
+// admit is called with mu held. only the next file of the class whose turn it is, and for batch work of the album whose
+// turn it is, is considered. it isn't overtaken while it waits for memory so large files aren't starved by smaller ones
+func (p *WorkerPool) admit() (poolTask, bool) {
+	priority, ok := p.nextPriority()
+	if !ok {
+		return poolTask{}, false
+	}
+	var task poolTask
+	if priority == PriorityInteractive {
+		task = p.interactive[0]
+	} else {
+		task = p.queues[p.turns[0]][0]
+	}
+	if task.ctx.Err() != nil {
+		task.cost = 0
+	}
+	if p.config.MaxBytes > 0 && p.inUse > 0 && p.inUse+task.cost > p.config.MaxBytes {
+		return poolTask{}, false
+	}
+
+	if priority == PriorityInteractive {
+		p.interactive = p.interactive[1:]
+		if p.batchQueued > 0 {
+			p.interactiveStreak++
+		}
+	} else {
+		p.popBatch()
+		p.interactiveStreak = 0
+	}
+	p.inUse += task.cost
+	counters := &p.counters[priority]
+	counters.running++
+	counters.started++
+	counters.waited += time.Since(task.queuedAt)
+	return task, true
+}
+
+// nextPriority is called with mu held, interactive work goes first unless the batch work has waited through its share
+func (p *WorkerPool) nextPriority() (Priority, bool) {
+	switch {
+	case len(p.interactive) == 0 && len(p.turns) == 0:
+		return 0, false
+	case len(p.interactive) == 0:
+		return PriorityBatch, true
+	case len(p.turns) == 0:
+		return PriorityInteractive, true
+	case p.config.BatchShare > 0 && p.interactiveStreak >= (100-p.config.BatchShare)/p.config.BatchShare:
+		return PriorityBatch, true
+	default:
+		return PriorityInteractive, true
+	}
+}
+
+// popBatch is called with mu held, it takes the next file of the album whose turn it is and moves the album to the back
+func (p *WorkerPool) popBatch() {
+	albumId := p.turns[0]
+	queue := p.queues[albumId]
+	p.turns = p.turns[1:]
+	if len(queue) > 1 {
+		p.queues[albumId] = queue[1:]
+		p.turns = append(p.turns, albumId)
+	} else {
+		delete(p.queues, albumId)
+	}
+	p.batchQueued--
+}
@@ -0,0 +1,239 @@
+package thumbnail
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestPool returns a pool whose workers are stopped when the test ends
+func newTestPool(t *testing.T, workers int) *WorkerPool {
+	return newTestPoolWithConfig(t, PoolConfig{Workers: workers})
+}
+
+func newTestPoolWithConfig(t *testing.T, config PoolConfig) *WorkerPool {
+	pool := NewWorkerPool(config)
+	t.Cleanup(pool.stop)
+	return pool
+}
+
+// blockingTask submits a task that runs until the returned release func is called, started is closed once it runs
+func blockingTask(pool *WorkerPool, ctx context.Context, albumId int, cost int64) (started chan struct{}, release func()) {
+	started = make(chan struct{})
+	released := make(chan struct{})
+	pool.submit(ctx, albumId, cost, func() {
+		close(started)
+		<-released
+	})
+	return started, func() { close(released) }
+}
+
+func TestWorkerPool_AlbumsTakeTurns(t *testing.T) {
+	// given
+	pool := newTestPool(t, 1)
+	started, release := blockingTask(pool, context.Background(), 1, 0)
+	<-started
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for _, albumId := range []int{2, 2, 2, 3, 3, 3} {
+		wg.Add(1)
+		pool.submit(context.Background(), albumId, 0, func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, albumId)
+		})
+	}
+
+	// when
+	release()
+	wg.Wait()
+
+	// then
+	assert.Equal(t, []int{2, 3, 2, 3, 2, 3}, order)
+}
+
+func TestWorkerPool_WaitsForMemory(t *testing.T) {
+	// given
+	pool := newTestPoolWithConfig(t, PoolConfig{Workers: 3, MaxBytes: 100})
+	started, release := blockingTask(pool, context.Background(), 1, 80)
+	<-started
+
+	// when
+	large, releaseLarge := blockingTask(pool, context.Background(), 2, 50)
+	small, releaseSmall := blockingTask(pool, context.Background(), 3, 10)
+
+	// then the large file waits for memory and the small file of the next album doesn't overtake it
+	assert.Never(t, func() bool {
+		select {
+		case <-large:
+			return true
+		case <-small:
+			return true
+		default:
+			return false
+		}
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	release()
+	<-large
+	<-small
+	releaseLarge()
+	releaseSmall()
+}
+
+func TestWorkerPool_FileAboveBudgetRunsAlone(t *testing.T) {
+	// given
+	pool := newTestPoolWithConfig(t, PoolConfig{Workers: 2, MaxBytes: 100})
+	done := make(chan struct{})
+
+	// when
+	pool.submit(context.Background(), 1, 500, func() {
+		close(done)
+	})
+
+	// then
+	<-done
+}
+
+func TestWorkerPool_CancelledFileIsAdmitted(t *testing.T) {
+	// given
+	pool := newTestPoolWithConfig(t, PoolConfig{Workers: 2, MaxBytes: 100})
+	started, release := blockingTask(pool, context.Background(), 1, 80)
+	defer release()
+	<-started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+
+	// when
+	pool.submit(ctx, 2, 50, func() {
+		close(done)
+	})
+
+	// then
+	<-done
+}
+
+// queueWork blocks the only worker of the pool, queues batch files of album 1 and interactive renders, and
+// returns the order the files were started in once the worker is released
+func queueWork(t *testing.T, pool *WorkerPool, batch int, interactive int) []Priority {
+	started, release := blockingTask(pool, context.Background(), 1, 0)
+	<-started
+
+	var mu sync.Mutex
+	var order []Priority
+	record := func(priority Priority) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, priority)
+	}
+	var wg sync.WaitGroup
+	for range batch {
+		wg.Add(1)
+		pool.submit(context.Background(), 1, 0, func() {
+			defer wg.Done()
+			record(PriorityBatch)
+		})
+	}
+	for range interactive {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = runInteractive(pool, 0, func() (any, error) {
+				record(PriorityInteractive)
+				return nil, nil
+			})
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return pool.Stats().Interactive.Queued == interactive
+	}, time.Second, time.Millisecond)
+
+	release()
+	wg.Wait()
+	return order
+}
+
+func TestWorkerPool_InteractiveGoesFirst(t *testing.T) {
+	// given
+	pool := newTestPoolWithConfig(t, PoolConfig{Workers: 1})
+
+	// when
+	order := queueWork(t, pool, 2, 3)
+
+	// then
+	assert.Equal(t, []Priority{
+		PriorityInteractive, PriorityInteractive, PriorityInteractive, PriorityBatch, PriorityBatch,
+	}, order)
+}
+
+func TestWorkerPool_BatchGetsItsShare(t *testing.T) {
+	// given a third of the files started is batch work
+	pool := newTestPoolWithConfig(t, PoolConfig{Workers: 1, BatchShare: 33})
+
+	// when
+	order := queueWork(t, pool, 2, 5)
+
+	// then
+	assert.Equal(t, []Priority{
+		PriorityInteractive, PriorityInteractive, PriorityBatch,
+		PriorityInteractive, PriorityInteractive, PriorityBatch,
+		PriorityInteractive,
+	}, order)
+}
+
+func TestWorkerPool_Stats(t *testing.T) {
+	// given
+	pool := newTestPoolWithConfig(t, PoolConfig{Workers: 1, MaxBytes: 100})
+	started, release := blockingTask(pool, context.Background(), 1, 40)
+	<-started
+	pool.submit(context.Background(), 2, 10, func() {})
+	pool.submit(context.Background(), 3, 10, func() {})
+	time.Sleep(10 * time.Millisecond)
+
+	// when
+	stats := pool.Stats()
+	release()
+
+	// then
+	assert.Equal(t, 1, stats.Workers)
+	assert.Equal(t, int64(40), stats.InUseBytes)
+	assert.Equal(t, int64(100), stats.MaxBytes)
+	assert.Equal(t, 2, stats.Batch.Queued)
+	assert.Equal(t, 1, stats.Batch.Running)
+	assert.Equal(t, int64(1), stats.Batch.Started)
+	assert.GreaterOrEqual(t, stats.Batch.OldestWait, 10*time.Millisecond)
+	assert.Equal(t, ClassStats{}, stats.Interactive)
+}
+
+func TestPoolConfigFromEnv(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_POOL_WORKERS", "8")
+	t.Setenv("THUMBNAIL_POOL_MAX_BYTES", "0")
+	t.Setenv("THUMBNAIL_POOL_BATCH_SHARE", "50")
+
+	// when
+	config := PoolConfigFromEnv()
+
+	// then
+	assert.Equal(t, PoolConfig{Workers: 8, MaxBytes: 0, BatchShare: 50}, config)
+}
+
+func TestPoolConfigFromEnv_InvalidValues(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_POOL_WORKERS", "0")
+	t.Setenv("THUMBNAIL_POOL_MAX_BYTES", "-1")
+	t.Setenv("THUMBNAIL_POOL_BATCH_SHARE", "101")
+
+	// when
+	config := PoolConfigFromEnv()
+
+	// then
+	assert.Equal(t, PoolConfig{Workers: DefaultPoolWorkers, MaxBytes: DefaultPoolMaxBytes, BatchShare: DefaultPoolBatchShare}, config)
+}
@@ -2,13 +2,16 @@ package thumbnail
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"io/fs"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -25,47 +28,64 @@ import (
 )
 
 type Processor interface {
-	// GenerateThumbnail creates a thumbnail for a file
-	GenerateThumbnail(fileEntry dto.FileEntryDto, animate bool) ([]byte, error)
+	// GenerateThumbnail creates a thumbnail for a file, cancelling ctx kills a running ffmpeg and skips the vips steps
+	// that haven't started
+	GenerateThumbnail(ctx context.Context, fileEntry dto.FileEntryDto, options Options) ([]byte, error)
 
 	// SupportsFile checks if the file can be processed
 	SupportsFile(fileEntry dto.FileEntryDto) bool
 
+	// DecodeCost estimates the memory rendering a file takes
+	DecodeCost(fileEntry dto.FileEntryDto) int64
+
 	// GenerateThumbnailFromMultipart creates a thumbnail for a multipart file
-	GenerateThumbnailFromMultipart(file multipart.File, header *multipart.FileHeader, animate bool) ([]byte, error)
+	GenerateThumbnailFromMultipart(file multipart.File, header *multipart.FileHeader, options Options) ([]byte, error)
 
 	// SupportsMultipartFile checks if the multipart file can be processed
 	SupportsMultipartFile(header *multipart.FileHeader) bool
 
 	// GenerateThumbnailFromURL creates a thumbnail from a URL
-	GenerateThumbnailFromURL(url string, animate bool) ([]byte, error)
+	GenerateThumbnailFromURL(url string, options Options) ([]byte, error)
+
+	// GenerateThumbnailSet creates a thumbnail for each width from a single decode of the file
+	GenerateThumbnailSet(fileEntry dto.FileEntryDto, options Options, widths []int) ([]Variant, error)
 }
 
 type processor struct {
 	baseUrl       string
 	ffmpegFormats []string
 	imageFormats  []string
+	maxBytes      int
+	minQuality    int
 }
 
 // NewProcessor creates a new thumbnail processor
 func NewProcessor(ffmpegFormats []string, supportedExtensions []string) Processor {
+	maxBytes, minQuality := budgetFromEnv()
 	return &processor{
 		baseUrl:       utils.FileBaseUrl,
 		ffmpegFormats: ffmpegFormats,
 		imageFormats:  supportedExtensions,
+		maxBytes:      maxBytes,
+		minQuality:    minQuality,
 	}
 }
 
 // GenerateThumbnail determines the file type and creates an appropriate thumbnail
-func (p *processor) GenerateThumbnail(fileEntry dto.FileEntryDto, animate bool) ([]byte, error) {
+func (p *processor) GenerateThumbnail(ctx context.Context, fileEntry dto.FileEntryDto, options Options) ([]byte, error) {
 	if !p.SupportsFile(fileEntry) {
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, fileEntry.MediaType)
 	}
+	// a file missing from disk is reported as such, the decoders fail with errors that don't say why
+	if _, err := os.Stat(p.baseUrl + "/" + fileEntry.FullFileNameOnSystem); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, fileEntry.FullFileNameOnSystem)
+	}
 
+	options = options.withBudget(p.maxBytes, p.minQuality)
 	if utils.IsImage(fileEntry.MediaType) {
-		return p.generateImageThumbnailFromFileEntry(fileEntry, animate)
+		return p.generateImageThumbnailFromFileEntry(ctx, fileEntry, options)
 	} else if utils.IsVideo(fileEntry.MediaType) {
-		return p.generateVideoThumbnail(fileEntry.FullFileNameOnSystem)
+		return p.generateVideoThumbnail(ctx, fileEntry.FullFileNameOnSystem, options)
 	}
 
 	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, fileEntry.MediaType)
@@ -76,14 +96,24 @@ func (p *processor) SupportsFile(fileEntry dto.FileEntryDto) bool {
 	return fileSupported(fileEntry, p.ffmpegFormats, p.imageFormats)
 }
 
+// DecodeCost estimates the memory of a decoded image from the dimensions in its header, or from its size when they
+// can't be read. a video costs ffmpeg and one decoded frame
+func (p *processor) DecodeCost(fileEntry dto.FileEntryDto) int64 {
+	if utils.IsVideo(fileEntry.MediaType) {
+		return videoDecodeCost
+	}
+	return imageDecodeCost(p.baseUrl + "/" + fileEntry.FullFileNameOnSystem)
+}
+
 // GenerateThumbnailFromMultipart creates a thumbnail for a multipart file
-func (p *processor) GenerateThumbnailFromMultipart(file multipart.File, header *multipart.FileHeader, animate bool) ([]byte, error) {
+func (p *processor) GenerateThumbnailFromMultipart(file multipart.File, header *multipart.FileHeader, options Options) ([]byte, error) {
 	mediaType, err := detectMimeTypeFromMultipart(header)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect mime type: %w", err)
 	}
 
 	extension := getExtensionFromFilename(header.Filename)
+	options = options.withBudget(p.maxBytes, p.minQuality)
 
 	tempFile, err := os.CreateTemp("", "thumbnail-*."+extension)
 	if err != nil {
@@ -98,14 +128,22 @@ func (p *processor) GenerateThumbnailFromMultipart(file multipart.File, header *
 	}
 
 	if utils.IsImage(mediaType) && lo.Contains(p.imageFormats, extension) {
-		return p.generateImageThumbnailFromFile(tempFile.Name(), extension, animate)
+		return p.generateImageThumbnailFromFile(context.Background(), tempFile.Name(), extension, options)
 	} else if utils.IsVideo(mediaType) && lo.Contains(p.ffmpegFormats, extension) {
-		return p.generateVideoThumbnailFromPath(tempFile.Name())
+		return p.generateVideoThumbnailFromPath(context.Background(), tempFile.Name(), options)
 	}
 
 	return nil, fmt.Errorf("%w: %s (detected: %s)", ErrUnsupportedFileType, header.Filename, mediaType)
 }
 
+// multipartDecodeCost estimates the decode memory of an upload from its size, its header isn't parsed before the render
+func multipartDecodeCost(header *multipart.FileHeader) int64 {
+	if mediaType, err := detectMimeTypeFromMultipart(header); err == nil && utils.IsVideo(mediaType) {
+		return videoDecodeCost
+	}
+	return max(header.Size*compressedImageRatio, minDecodeCost)
+}
+
 // detectMimeTypeFromMultipart detects the MIME type from a multipart file header by reading its binary content
 func detectMimeTypeFromMultipart(header *multipart.FileHeader) (string, error) {
 	file, err := header.Open()
@@ -141,34 +179,53 @@ func (p *processor) SupportsMultipartFile(header *multipart.FileHeader) bool {
 }
 
 // generateVideoThumbnail creates a thumbnail from a video file
-func (p *processor) generateVideoThumbnail(videoPath string) ([]byte, error) {
+func (p *processor) generateVideoThumbnail(ctx context.Context, videoPath string, options Options) ([]byte, error) {
 	fullPath := p.baseUrl + "/" + videoPath
-	return p.generateVideoThumbnailFromPath(fullPath)
+	return p.generateVideoThumbnailFromPath(ctx, fullPath, options)
 }
 
-// generateImageThumbnailFromFileEntry creates a thumbnail from a file entry with animate parameter
-func (p *processor) generateImageThumbnailFromFileEntry(fileEntry dto.FileEntryDto, animate bool) ([]byte, error) {
+// generateImageThumbnailFromFileEntry creates a thumbnail from a file entry with the given options
+func (p *processor) generateImageThumbnailFromFileEntry(ctx context.Context, fileEntry dto.FileEntryDto, options Options) ([]byte, error) {
 	file := p.baseUrl + "/" + fileEntry.FullFileNameOnSystem
-	return p.generateImageThumbnailFromFile(file, fileEntry.Extension, animate)
+	return p.generateImageThumbnailFromFile(ctx, file, fileEntry.Extension, options)
 }
 
-// generateImageThumbnailFromFile creates a thumbnail from an image file path
-func (p *processor) generateImageThumbnailFromFile(filePath, extension string, animate bool) ([]byte, error) {
+// generateImageThumbnailFromFile creates a thumbnail from an image file path. a vips call can't be interrupted, ctx is
+// checked before the decode and the export
+func (p *processor) generateImageThumbnailFromFile(ctx context.Context, filePath, extension string, options Options) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	vipsImage, err := p.loadImageThumbnail(filePath, extension, options)
+	if err != nil {
+		return nil, err
+	}
+	defer vipsImage.Close()
+
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+
+	return exportWebp(vipsImage, options)
+}
+
+// loadImageThumbnail decodes an image file into a thumbnail sized image that is ready to be exported
+func (p *processor) loadImageThumbnail(filePath, extension string, options Options) (*vips.ImageRef, error) {
 	if isAnimatedImage(extension) {
 		hasMultipleFrames, err := p.checkIfActuallyAnimated(filePath)
 		if err != nil {
-			return p.generateStaticThumbnail(filePath, extension)
+			return p.loadStaticThumbnail(filePath, extension, options)
 		}
 
 		if hasMultipleFrames {
-			if animate {
-				return p.generateAnimatedThumbnail(filePath, extension)
+			if options.Animate {
+				return p.loadAnimatedThumbnail(filePath, extension, options)
 			}
-			return p.generateFirstFrameThumbnail(filePath, extension)
+			return p.loadFirstFrameThumbnail(filePath, extension, options)
 		}
 	}
 
-	return p.generateStaticThumbnail(filePath, extension)
+	return p.loadStaticThumbnail(filePath, extension, options)
 }
 
 // checkIfActuallyAnimated checks if a file actually has multiple frames using LoadThumbnailFromFile
@@ -187,37 +244,30 @@ func (p *processor) checkIfActuallyAnimated(filePath string) (bool, error) {
 	return pages > 1, nil
 }
 
-// generateAnimatedThumbnail handles animated images (memory-intensive but preserves animation)
-func (p *processor) generateAnimatedThumbnail(filePath, extension string) ([]byte, error) {
+// loadAnimatedThumbnail handles animated images (memory-intensive but preserves animation).
+// focal points are not applied to animations, they fall back to the requested crop mode
+func (p *processor) loadAnimatedThumbnail(filePath, extension string, options Options) (*vips.ImageRef, error) {
 	importParams := p.getImportParams(extension)
 	vipsImage, err := vips.LoadImageFromFile(filePath, importParams)
 	if err != nil {
 		return nil, err
 	}
-	defer vipsImage.Close()
 
-	err = vipsImage.ThumbnailWithSize(DefaultThumbnailWidth, 0, vips.InterestingNone, vips.SizeDown)
-	if err != nil {
-		return nil, err
+	height := 0
+	if options.cropped() {
+		height = options.width()
 	}
-
-	if err := vipsImage.AutoRotate(); err != nil {
-		return nil, err
-	}
-
-	if err := vipsImage.RemoveMetadata("delay", "dispose", "loop", "loop_count"); err != nil {
-		return nil, err
-	}
-
-	thumbnail, _, err := vipsImage.ExportWebp(nil)
+	err = vipsImage.ThumbnailWithSize(options.width(), height, options.Crop.interesting(), vips.SizeDown)
 	if err != nil {
+		vipsImage.Close()
 		return nil, err
 	}
-	return thumbnail, nil
+
+	return p.prepareVipsImage(vipsImage)
 }
 
-// generateFirstFrameThumbnail extracts only the first frame from animated images
-func (p *processor) generateFirstFrameThumbnail(filePath, extension string) ([]byte, error) {
+// loadFirstFrameThumbnail extracts only the first frame from animated images
+func (p *processor) loadFirstFrameThumbnail(filePath, extension string, options Options) (*vips.ImageRef, error) {
 	importParams := &vips.ImportParams{}
 	if isAnimatedImage(extension) {
 		intSet := vips.IntParameter{}
@@ -225,7 +275,11 @@ func (p *processor) generateFirstFrameThumbnail(filePath, extension string) ([]b
 		importParams.NumPages = intSet
 	}
 
-	width, height, err := getResizedDimensions(filePath)
+	if options.cropped() {
+		return p.loadCroppedThumbnail(filePath, options, importParams)
+	}
+
+	width, height, err := getResizedDimensions(filePath, options.width())
 	if err != nil {
 		return nil, err
 	}
@@ -235,42 +289,87 @@ func (p *processor) generateFirstFrameThumbnail(filePath, extension string) ([]b
 		return nil, err
 	}
 
-	return p.processVipsImage(vipsImage)
+	return p.prepareVipsImage(vipsImage)
 }
 
-// generateStaticThumbnail handles static images (memory-efficient streaming approach)
-func (p *processor) generateStaticThumbnail(filePath, extension string) ([]byte, error) {
-	width, height, err := getResizedDimensions(filePath)
+// loadStaticThumbnail handles static images (memory-efficient streaming approach)
+func (p *processor) loadStaticThumbnail(filePath, extension string, options Options) (*vips.ImageRef, error) {
+	importParams := p.getImportParams(extension)
+	if options.cropped() {
+		return p.loadCroppedThumbnail(filePath, options, importParams)
+	}
+
+	width, height, err := getResizedDimensions(filePath, options.width())
 	if err != nil {
 		return nil, err
 	}
 
-	importParams := p.getImportParams(extension)
 	vipsImage, err := vips.LoadThumbnailFromFile(filePath, width, height, vips.InterestingCentre, vips.SizeDown, importParams)
 	if err != nil {
 		return nil, err
 	}
 
-	return p.processVipsImage(vipsImage)
+	return p.prepareVipsImage(vipsImage)
 }
 
-// processVipsImage applies common processing to a vips image and exports as WebP
-func (p *processor) processVipsImage(vipsImage *vips.ImageRef) ([]byte, error) {
-	defer vipsImage.Close()
+// loadCroppedThumbnail creates a square thumbnail, cropped around the focal point if the file has one
+func (p *processor) loadCroppedThumbnail(filePath string, options Options, importParams *vips.ImportParams) (*vips.ImageRef, error) {
+	var vipsImage *vips.ImageRef
+	var err error
+	if options.FocalPoint != nil {
+		vipsImage, err = loadFocalCroppedThumbnail(filePath, options.width(), *options.FocalPoint, importParams)
+	} else {
+		vipsImage, err = vips.LoadThumbnailFromFile(filePath, options.width(), options.width(), options.Crop.interesting(), vips.SizeDown, importParams)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	if err := vipsImage.AutoRotate(); err != nil {
+	return p.prepareVipsImage(vipsImage)
+}
+
+// loadVideoFrame decodes an extracted video frame, cropping it to a square using the same rules as images
+func (p *processor) loadVideoFrame(frame []byte, options Options) (*vips.ImageRef, error) {
+	if !options.cropped() {
+		vipsImage, err := vips.NewImageFromBuffer(frame)
+		if err != nil {
+			return nil, err
+		}
+		return p.prepareVipsImage(vipsImage)
+	}
+
+	if options.FocalPoint != nil {
+		vipsImage, err := vips.NewImageFromBuffer(frame)
+		if err != nil {
+			return nil, err
+		}
+		if err := cropAroundFocalPoint(vipsImage, options.width(), *options.FocalPoint); err != nil {
+			vipsImage.Close()
+			return nil, err
+		}
+		return p.prepareVipsImage(vipsImage)
+	}
+
+	vipsImage, err := vips.NewThumbnailFromBuffer(frame, options.width(), options.width(), options.Crop.interesting())
+	if err != nil {
 		return nil, err
 	}
+	return p.prepareVipsImage(vipsImage)
+}
 
-	if err := vipsImage.RemoveMetadata("delay", "dispose", "loop", "loop_count"); err != nil {
+// prepareVipsImage applies common processing to a vips image, the image is closed if processing fails
+func (p *processor) prepareVipsImage(vipsImage *vips.ImageRef) (*vips.ImageRef, error) {
+	if err := vipsImage.AutoRotate(); err != nil {
+		vipsImage.Close()
 		return nil, err
 	}
 
-	thumbnail, _, err := vipsImage.ExportWebp(nil)
-	if err != nil {
+	if err := vipsImage.RemoveMetadata("delay", "dispose", "loop", "loop_count"); err != nil {
+		vipsImage.Close()
 		return nil, err
 	}
-	return thumbnail, nil
+
+	return vipsImage, nil
 }
 
 // getImportParams returns vips import parameters for the given extension
@@ -284,9 +383,37 @@ func (p *processor) getImportParams(extension string) *vips.ImportParams {
 }
 
 // generateVideoThumbnailFromPath creates a thumbnail from a video file path (without baseUrl prefix)
-func (p *processor) generateVideoThumbnailFromPath(videoPath string) ([]byte, error) {
-	probeCmd := exec.Command("ffprobe", "-v", "error", "-show_format", "-print_format", "json", videoPath)
+func (p *processor) generateVideoThumbnailFromPath(ctx context.Context, videoPath string, options Options) ([]byte, error) {
+	frame, err := p.extractVideoFrame(ctx, videoPath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// uncropped frames are served as the JPEG ffmpeg produced unless they are over the byte budget
+	if !options.cropped() && (options.MaxBytes == 0 || len(frame) <= options.MaxBytes) {
+		return frame, nil
+	}
+
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	vipsImage, err := p.loadVideoFrame(frame, options)
+	if err != nil {
+		return nil, err
+	}
+	defer vipsImage.Close()
+
+	return exportWebp(vipsImage, options)
+}
+
+// extractVideoFrame grabs a scaled JPEG frame from a random point in the video, ffprobe and ffmpeg are killed when ctx
+// is cancelled
+func (p *processor) extractVideoFrame(ctx context.Context, videoPath string, options Options) ([]byte, error) {
+	probeCmd := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_format", "-show_streams", "-select_streams", "v:0", "-print_format", "json", videoPath)
 	probeOut, err := probeCmd.Output()
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve video metadata: %w", err)
 	}
@@ -317,15 +444,19 @@ func (p *processor) generateVideoThumbnailFromPath(videoPath string) ([]byte, er
 		"-f", "image2",
 		"-vcodec", "mjpeg",
 		"-q:v", "10",
-		"-vf", "scale=-1:200",
+		"-vf", videoFilter(options, probe.isHDR()),
 		"pipe:1",
 	}
 
-	ffmpegCmd := exec.Command("ffmpeg", ffmpegArgs...)
+	ffmpegCmd := exec.CommandContext(ctx, "ffmpeg", ffmpegArgs...)
 	var buf bytes.Buffer
 	ffmpegCmd.Stdout = &buf
 
-	if err := ffmpegCmd.Run(); err != nil {
+	err = ffmpegCmd.Run()
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to generate video thumbnail: %w", err)
 	}
 
@@ -340,7 +471,7 @@ func getExtensionFromFilename(filename string) string {
 	return strings.ToLower(filename[lastDot+1:])
 }
 
-func getResizedDimensions(filePath string) (newWidth, newHeight int, err error) {
+func getResizedDimensions(filePath string, width int) (newWidth, newHeight int, err error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return 0, 0, err
@@ -349,28 +480,46 @@ func getResizedDimensions(filePath string) (newWidth, newHeight int, err error)
 
 	config, _, err := image.DecodeConfig(file)
 	if err != nil {
-		return DefaultThumbnailWidth, 0, nil
+		return width, 0, nil
 	}
 
-	return calculateThumbnailDimensions(config.Width, config.Height)
+	return calculateThumbnailDimensions(config.Width, config.Height, width)
+}
+
+func imageDecodeCost(filePath string) int64 {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fallbackDecodeCost
+	}
+	defer file.Close()
+
+	if config, _, err := image.DecodeConfig(file); err == nil {
+		return max(int64(config.Width)*int64(config.Height)*decodedPixelBytes, minDecodeCost)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return fallbackDecodeCost
+	}
+	return max(info.Size()*compressedImageRatio, minDecodeCost)
 }
 
 // calculateThumbnailDimensions calculates scaled dimensions maintaining the aspect ratio
-func calculateThumbnailDimensions(origWidth, origHeight int) (newWidth, newHeight int, err error) {
+func calculateThumbnailDimensions(origWidth, origHeight, width int) (newWidth, newHeight int, err error) {
 	if origWidth == 0 {
 		return 0, 0, nil
 	}
 
-	newWidth = DefaultThumbnailWidth
+	newWidth = width
 	scaleFactor := float64(newWidth) / float64(origWidth)
 	newHeight = int(float64(origHeight) * scaleFactor)
 	return newWidth, newHeight, nil
 }
 
-func (p *processor) GenerateThumbnailFromURL(url string, animate bool) ([]byte, error) {
+func (p *processor) GenerateThumbnailFromURL(url string, options Options) ([]byte, error) {
 	if err := validateURL(url); err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, err)
 	}
+	options = options.withBudget(p.maxBytes, p.minQuality)
 
 	headResp, err := http.Head(url)
 	if err != nil {
@@ -440,9 +589,9 @@ func (p *processor) GenerateThumbnailFromURL(url string, animate bool) ([]byte,
 	}
 
 	if utils.IsImage(mediaType) && lo.Contains(p.imageFormats, extension) {
-		return p.generateImageThumbnailFromFile(tempFile.Name(), extension, animate)
+		return p.generateImageThumbnailFromFile(context.Background(), tempFile.Name(), extension, options)
 	} else if utils.IsVideo(mediaType) && lo.Contains(p.ffmpegFormats, extension) {
-		return p.generateVideoThumbnailFromPath(tempFile.Name())
+		return p.generateVideoThumbnailFromPath(context.Background(), tempFile.Name(), options)
 	}
 
 	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, mediaType)
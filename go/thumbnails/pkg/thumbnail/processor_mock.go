@@ -5,6 +5,7 @@
 package thumbnail
 
 import (
+	"context"
 	"mime/multipart"
 
 	mock "github.com/stretchr/testify/mock"
@@ -38,9 +39,60 @@ func (_m *MockProcessor) EXPECT() *MockProcessor_Expecter {
 	return &MockProcessor_Expecter{mock: &_m.Mock}
 }
 
+// DecodeCost provides a mock function for the type MockProcessor
+func (_mock *MockProcessor) DecodeCost(fileEntry dto.FileEntryDto) int64 {
+	ret := _mock.Called(fileEntry)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DecodeCost")
+	}
+
+	var r0 int64
+	if returnFunc, ok := ret.Get(0).(func(dto.FileEntryDto) int64); ok {
+		r0 = returnFunc(fileEntry)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	return r0
+}
+
+// MockProcessor_DecodeCost_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DecodeCost'
+type MockProcessor_DecodeCost_Call struct {
+	*mock.Call
+}
+
+// DecodeCost is a helper method to define mock.On call
+//   - fileEntry dto.FileEntryDto
+func (_e *MockProcessor_Expecter) DecodeCost(fileEntry interface{}) *MockProcessor_DecodeCost_Call {
+	return &MockProcessor_DecodeCost_Call{Call: _e.mock.On("DecodeCost", fileEntry)}
+}
+
+func (_c *MockProcessor_DecodeCost_Call) Run(run func(fileEntry dto.FileEntryDto)) *MockProcessor_DecodeCost_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 dto.FileEntryDto
+		if args[0] != nil {
+			arg0 = args[0].(dto.FileEntryDto)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockProcessor_DecodeCost_Call) Return(n int64) *MockProcessor_DecodeCost_Call {
+	_c.Call.Return(n)
+	return _c
+}
+
+func (_c *MockProcessor_DecodeCost_Call) RunAndReturn(run func(fileEntry dto.FileEntryDto) int64) *MockProcessor_DecodeCost_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GenerateThumbnail provides a mock function for the type MockProcessor
-func (_mock *MockProcessor) GenerateThumbnail(fileEntry dto.FileEntryDto, animate bool) ([]byte, error) {
-	ret := _mock.Called(fileEntry, animate)
+func (_mock *MockProcessor) GenerateThumbnail(ctx context.Context, fileEntry dto.FileEntryDto, options Options) ([]byte, error) {
+	ret := _mock.Called(ctx, fileEntry, options)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateThumbnail")
@@ -48,18 +100,18 @@ func (_mock *MockProcessor) GenerateThumbnail(fileEntry dto.FileEntryDto, animat
 
 	var r0 []byte
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(dto.FileEntryDto, bool) ([]byte, error)); ok {
-		return returnFunc(fileEntry, animate)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, dto.FileEntryDto, Options) ([]byte, error)); ok {
+		return returnFunc(ctx, fileEntry, options)
 	}
-	if returnFunc, ok := ret.Get(0).(func(dto.FileEntryDto, bool) []byte); ok {
-		r0 = returnFunc(fileEntry, animate)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, dto.FileEntryDto, Options) []byte); ok {
+		r0 = returnFunc(ctx, fileEntry, options)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(dto.FileEntryDto, bool) error); ok {
-		r1 = returnFunc(fileEntry, animate)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, dto.FileEntryDto, Options) error); ok {
+		r1 = returnFunc(ctx, fileEntry, options)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -72,25 +124,31 @@ type MockProcessor_GenerateThumbnail_Call struct {
 }
 
 // GenerateThumbnail is a helper method to define mock.On call
+//   - ctx context.Context
 //   - fileEntry dto.FileEntryDto
-//   - animate bool
-func (_e *MockProcessor_Expecter) GenerateThumbnail(fileEntry interface{}, animate interface{}) *MockProcessor_GenerateThumbnail_Call {
-	return &MockProcessor_GenerateThumbnail_Call{Call: _e.mock.On("GenerateThumbnail", fileEntry, animate)}
+//   - options Options
+func (_e *MockProcessor_Expecter) GenerateThumbnail(ctx interface{}, fileEntry interface{}, options interface{}) *MockProcessor_GenerateThumbnail_Call {
+	return &MockProcessor_GenerateThumbnail_Call{Call: _e.mock.On("GenerateThumbnail", ctx, fileEntry, options)}
 }
 
-func (_c *MockProcessor_GenerateThumbnail_Call) Run(run func(fileEntry dto.FileEntryDto, animate bool)) *MockProcessor_GenerateThumbnail_Call {
+func (_c *MockProcessor_GenerateThumbnail_Call) Run(run func(ctx context.Context, fileEntry dto.FileEntryDto, options Options)) *MockProcessor_GenerateThumbnail_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 dto.FileEntryDto
+		var arg0 context.Context
 		if args[0] != nil {
-			arg0 = args[0].(dto.FileEntryDto)
+			arg0 = args[0].(context.Context)
 		}
-		var arg1 bool
+		var arg1 dto.FileEntryDto
 		if args[1] != nil {
-			arg1 = args[1].(bool)
+			arg1 = args[1].(dto.FileEntryDto)
+		}
+		var arg2 Options
+		if args[2] != nil {
+			arg2 = args[2].(Options)
 		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
@@ -101,14 +159,14 @@ func (_c *MockProcessor_GenerateThumbnail_Call) Return(bytes []byte, err error)
 	return _c
 }
 
-func (_c *MockProcessor_GenerateThumbnail_Call) RunAndReturn(run func(fileEntry dto.FileEntryDto, animate bool) ([]byte, error)) *MockProcessor_GenerateThumbnail_Call {
+func (_c *MockProcessor_GenerateThumbnail_Call) RunAndReturn(run func(ctx context.Context, fileEntry dto.FileEntryDto, options Options) ([]byte, error)) *MockProcessor_GenerateThumbnail_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
 // GenerateThumbnailFromMultipart provides a mock function for the type MockProcessor
-func (_mock *MockProcessor) GenerateThumbnailFromMultipart(file multipart.File, header *multipart.FileHeader, animate bool) ([]byte, error) {
-	ret := _mock.Called(file, header, animate)
+func (_mock *MockProcessor) GenerateThumbnailFromMultipart(file multipart.File, header *multipart.FileHeader, options Options) ([]byte, error) {
+	ret := _mock.Called(file, header, options)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateThumbnailFromMultipart")
@@ -116,18 +174,18 @@ func (_mock *MockProcessor) GenerateThumbnailFromMultipart(file multipart.File,
 
 	var r0 []byte
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(multipart.File, *multipart.FileHeader, bool) ([]byte, error)); ok {
-		return returnFunc(file, header, animate)
+	if returnFunc, ok := ret.Get(0).(func(multipart.File, *multipart.FileHeader, Options) ([]byte, error)); ok {
+		return returnFunc(file, header, options)
 	}
-	if returnFunc, ok := ret.Get(0).(func(multipart.File, *multipart.FileHeader, bool) []byte); ok {
-		r0 = returnFunc(file, header, animate)
+	if returnFunc, ok := ret.Get(0).(func(multipart.File, *multipart.FileHeader, Options) []byte); ok {
+		r0 = returnFunc(file, header, options)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(multipart.File, *multipart.FileHeader, bool) error); ok {
-		r1 = returnFunc(file, header, animate)
+	if returnFunc, ok := ret.Get(1).(func(multipart.File, *multipart.FileHeader, Options) error); ok {
+		r1 = returnFunc(file, header, options)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -142,12 +200,12 @@ type MockProcessor_GenerateThumbnailFromMultipart_Call struct {
 // GenerateThumbnailFromMultipart is a helper method to define mock.On call
 //   - file multipart.File
 //   - header *multipart.FileHeader
-//   - animate bool
-func (_e *MockProcessor_Expecter) GenerateThumbnailFromMultipart(file interface{}, header interface{}, animate interface{}) *MockProcessor_GenerateThumbnailFromMultipart_Call {
-	return &MockProcessor_GenerateThumbnailFromMultipart_Call{Call: _e.mock.On("GenerateThumbnailFromMultipart", file, header, animate)}
+//   - options Options
+func (_e *MockProcessor_Expecter) GenerateThumbnailFromMultipart(file interface{}, header interface{}, options interface{}) *MockProcessor_GenerateThumbnailFromMultipart_Call {
+	return &MockProcessor_GenerateThumbnailFromMultipart_Call{Call: _e.mock.On("GenerateThumbnailFromMultipart", file, header, options)}
 }
 
-func (_c *MockProcessor_GenerateThumbnailFromMultipart_Call) Run(run func(file multipart.File, header *multipart.FileHeader, animate bool)) *MockProcessor_GenerateThumbnailFromMultipart_Call {
+func (_c *MockProcessor_GenerateThumbnailFromMultipart_Call) Run(run func(file multipart.File, header *multipart.FileHeader, options Options)) *MockProcessor_GenerateThumbnailFromMultipart_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 multipart.File
 		if args[0] != nil {
@@ -157,9 +215,9 @@ func (_c *MockProcessor_GenerateThumbnailFromMultipart_Call) Run(run func(file m
 		if args[1] != nil {
 			arg1 = args[1].(*multipart.FileHeader)
 		}
-		var arg2 bool
+		var arg2 Options
 		if args[2] != nil {
-			arg2 = args[2].(bool)
+			arg2 = args[2].(Options)
 		}
 		run(
 			arg0,
@@ -175,14 +233,14 @@ func (_c *MockProcessor_GenerateThumbnailFromMultipart_Call) Return(bytes []byte
 	return _c
 }
 
-func (_c *MockProcessor_GenerateThumbnailFromMultipart_Call) RunAndReturn(run func(file multipart.File, header *multipart.FileHeader, animate bool) ([]byte, error)) *MockProcessor_GenerateThumbnailFromMultipart_Call {
+func (_c *MockProcessor_GenerateThumbnailFromMultipart_Call) RunAndReturn(run func(file multipart.File, header *multipart.FileHeader, options Options) ([]byte, error)) *MockProcessor_GenerateThumbnailFromMultipart_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
 // GenerateThumbnailFromURL provides a mock function for the type MockProcessor
-func (_mock *MockProcessor) GenerateThumbnailFromURL(url string, animate bool) ([]byte, error) {
-	ret := _mock.Called(url, animate)
+func (_mock *MockProcessor) GenerateThumbnailFromURL(url string, options Options) ([]byte, error) {
+	ret := _mock.Called(url, options)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateThumbnailFromURL")
@@ -190,18 +248,18 @@ func (_mock *MockProcessor) GenerateThumbnailFromURL(url string, animate bool) (
 
 	var r0 []byte
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(string, bool) ([]byte, error)); ok {
-		return returnFunc(url, animate)
+	if returnFunc, ok := ret.Get(0).(func(string, Options) ([]byte, error)); ok {
+		return returnFunc(url, options)
 	}
-	if returnFunc, ok := ret.Get(0).(func(string, bool) []byte); ok {
-		r0 = returnFunc(url, animate)
+	if returnFunc, ok := ret.Get(0).(func(string, Options) []byte); ok {
+		r0 = returnFunc(url, options)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(string, bool) error); ok {
-		r1 = returnFunc(url, animate)
+	if returnFunc, ok := ret.Get(1).(func(string, Options) error); ok {
+		r1 = returnFunc(url, options)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -215,20 +273,20 @@ type MockProcessor_GenerateThumbnailFromURL_Call struct {
 
 // GenerateThumbnailFromURL is a helper method to define mock.On call
 //   - url string
-//   - animate bool
-func (_e *MockProcessor_Expecter) GenerateThumbnailFromURL(url interface{}, animate interface{}) *MockProcessor_GenerateThumbnailFromURL_Call {
-	return &MockProcessor_GenerateThumbnailFromURL_Call{Call: _e.mock.On("GenerateThumbnailFromURL", url, animate)}
+//   - options Options
+func (_e *MockProcessor_Expecter) GenerateThumbnailFromURL(url interface{}, options interface{}) *MockProcessor_GenerateThumbnailFromURL_Call {
+	return &MockProcessor_GenerateThumbnailFromURL_Call{Call: _e.mock.On("GenerateThumbnailFromURL", url, options)}
 }
 
-func (_c *MockProcessor_GenerateThumbnailFromURL_Call) Run(run func(url string, animate bool)) *MockProcessor_GenerateThumbnailFromURL_Call {
+func (_c *MockProcessor_GenerateThumbnailFromURL_Call) Run(run func(url string, options Options)) *MockProcessor_GenerateThumbnailFromURL_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
 			arg0 = args[0].(string)
 		}
-		var arg1 bool
+		var arg1 Options
 		if args[1] != nil {
-			arg1 = args[1].(bool)
+			arg1 = args[1].(Options)
 		}
 		run(
 			arg0,
@@ -243,7 +301,81 @@ func (_c *MockProcessor_GenerateThumbnailFromURL_Call) Return(bytes []byte, err
 	return _c
 }
 
-func (_c *MockProcessor_GenerateThumbnailFromURL_Call) RunAndReturn(run func(url string, animate bool) ([]byte, error)) *MockProcessor_GenerateThumbnailFromURL_Call {
+func (_c *MockProcessor_GenerateThumbnailFromURL_Call) RunAndReturn(run func(url string, options Options) ([]byte, error)) *MockProcessor_GenerateThumbnailFromURL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GenerateThumbnailSet provides a mock function for the type MockProcessor
+func (_mock *MockProcessor) GenerateThumbnailSet(fileEntry dto.FileEntryDto, options Options, widths []int) ([]Variant, error) {
+	ret := _mock.Called(fileEntry, options, widths)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenerateThumbnailSet")
+	}
+
+	var r0 []Variant
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(dto.FileEntryDto, Options, []int) ([]Variant, error)); ok {
+		return returnFunc(fileEntry, options, widths)
+	}
+	if returnFunc, ok := ret.Get(0).(func(dto.FileEntryDto, Options, []int) []Variant); ok {
+		r0 = returnFunc(fileEntry, options, widths)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Variant)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(dto.FileEntryDto, Options, []int) error); ok {
+		r1 = returnFunc(fileEntry, options, widths)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockProcessor_GenerateThumbnailSet_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenerateThumbnailSet'
+type MockProcessor_GenerateThumbnailSet_Call struct {
+	*mock.Call
+}
+
+// GenerateThumbnailSet is a helper method to define mock.On call
+//   - fileEntry dto.FileEntryDto
+//   - options Options
+//   - widths []int
+func (_e *MockProcessor_Expecter) GenerateThumbnailSet(fileEntry interface{}, options interface{}, widths interface{}) *MockProcessor_GenerateThumbnailSet_Call {
+	return &MockProcessor_GenerateThumbnailSet_Call{Call: _e.mock.On("GenerateThumbnailSet", fileEntry, options, widths)}
+}
+
+func (_c *MockProcessor_GenerateThumbnailSet_Call) Run(run func(fileEntry dto.FileEntryDto, options Options, widths []int)) *MockProcessor_GenerateThumbnailSet_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 dto.FileEntryDto
+		if args[0] != nil {
+			arg0 = args[0].(dto.FileEntryDto)
+		}
+		var arg1 Options
+		if args[1] != nil {
+			arg1 = args[1].(Options)
+		}
+		var arg2 []int
+		if args[2] != nil {
+			arg2 = args[2].([]int)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockProcessor_GenerateThumbnailSet_Call) Return(variants []Variant, err error) *MockProcessor_GenerateThumbnailSet_Call {
+	_c.Call.Return(variants, err)
+	return _c
+}
+
+func (_c *MockProcessor_GenerateThumbnailSet_Call) RunAndReturn(run func(fileEntry dto.FileEntryDto, options Options, widths []int) ([]Variant, error)) *MockProcessor_GenerateThumbnailSet_Call {
 	_c.Call.Return(run)
 	return _c
 }
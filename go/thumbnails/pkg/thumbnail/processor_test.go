@@ -2,7 +2,12 @@ package thumbnail
 
 import (
 	"bytes"
+	"context"
+	"image"
+	"image/png"
 	"mime/multipart"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -186,7 +191,7 @@ func TestCalculateThumbnailDimensions_ValidDimensions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// when
-			width, height, err := calculateThumbnailDimensions(tt.origWidth, tt.origHeight)
+			width, height, err := calculateThumbnailDimensions(tt.origWidth, tt.origHeight, DefaultThumbnailWidth)
 
 			// then
 			assert.NoError(t, err)
@@ -202,7 +207,7 @@ func TestCalculateThumbnailDimensions_ZeroWidth(t *testing.T) {
 	origHeight := 1000
 
 	// when
-	width, height, err := calculateThumbnailDimensions(origWidth, origHeight)
+	width, height, err := calculateThumbnailDimensions(origWidth, origHeight, DefaultThumbnailWidth)
 
 	// then
 	assert.NoError(t, err)
@@ -469,7 +474,7 @@ func TestProcessor_GenerateThumbnailFromMultipart_UnsupportedFileType(t *testing
 	file, _ := header.Open()
 
 	// when
-	result, err := p.GenerateThumbnailFromMultipart(file, header, false)
+	result, err := p.GenerateThumbnailFromMultipart(file, header, Options{})
 
 	// then
 	assert.Error(t, err)
@@ -486,7 +491,7 @@ func TestProcessor_GenerateThumbnail_UnsupportedFileType(t *testing.T) {
 	}
 
 	// when
-	result, err := p.GenerateThumbnail(fileEntry, false)
+	result, err := p.GenerateThumbnail(context.Background(), fileEntry, Options{})
 
 	// then
 	assert.Error(t, err)
@@ -494,6 +499,23 @@ func TestProcessor_GenerateThumbnail_UnsupportedFileType(t *testing.T) {
 	assert.ErrorIs(t, err, ErrUnsupportedFileType)
 }
 
+func TestProcessor_GenerateThumbnail_MissingFile(t *testing.T) {
+	// given
+	p := newTestProcessor()
+	fileEntry := dto.FileEntryDto{
+		MediaType:            "image/jpeg",
+		Extension:            "jpg",
+		FullFileNameOnSystem: "missing.jpg",
+	}
+
+	// when
+	result, err := p.GenerateThumbnail(context.Background(), fileEntry, Options{})
+
+	// then
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
+
 func TestFileSupported_ImageWithSupportedExtension(t *testing.T) {
 	// given
 	file := dto.FileEntryDto{
@@ -604,3 +626,37 @@ func TestNewProcessor_CreatesProcessorWithCorrectConfig(t *testing.T) {
 	assert.Equal(t, ffmpegFormats, processor.ffmpegFormats)
 	assert.Equal(t, supportedExtensions, processor.imageFormats)
 }
+
+func TestExtractVideoFrame_Cancelled(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(jobCancellation{})
+
+	// when
+	_, err := (&processor{}).extractVideoFrame(ctx, "test.mp4", Options{})
+
+	// then
+	assert.ErrorIs(t, err, ErrJobCancelled)
+}
+
+func TestProcessor_DecodeCost(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 1000, 2000))))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "large.png"), encoded.Bytes(), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "unknown.avif"), make([]byte, 200<<10), 0o644))
+	p := &processor{baseUrl: dir}
+
+	// when
+	large := p.DecodeCost(dto.FileEntryDto{MediaType: "image/png", FullFileNameOnSystem: "large.png"})
+	unknown := p.DecodeCost(dto.FileEntryDto{MediaType: "image/avif", FullFileNameOnSystem: "unknown.avif"})
+	missing := p.DecodeCost(dto.FileEntryDto{MediaType: "image/png", FullFileNameOnSystem: "missing.png"})
+	video := p.DecodeCost(dto.FileEntryDto{MediaType: "video/mp4", FullFileNameOnSystem: "missing.mp4"})
+
+	// then
+	assert.Equal(t, int64(1000*2000*decodedPixelBytes), large)
+	assert.Equal(t, int64(200<<10*compressedImageRatio), unknown)
+	assert.Equal(t, int64(fallbackDecodeCost), missing)
+	assert.Equal(t, int64(videoDecodeCost), video)
+}
@@ -0,0 +1,280 @@
+package thumbnail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+const (
+	// progressTTL keeps the progress of a finished job around for the album page to pick up
+	progressTTL = 24 * time.Hour
+	// progressSaveInterval limits how often the progress of a running job is written to the shared cache
+	progressSaveInterval = time.Second
+	// maxProgressFailures caps the failure reasons kept per job, the failures are still counted
+	maxProgressFailures = 100
+)
+
+// FileFailure is a file of an album job that could not get a thumbnail
+type FileFailure struct {
+	FileId int    `json:"fileId"`
+	Reason string `json:"reason"`
+}
+
+// AlbumProgress is the progress of the latest thumbnail job of an album
+type AlbumProgress struct {
+	AlbumId int           `json:"albumId"`
+	JobId   int           `json:"jobId"`
+	State   mod.JobStatus `json:"state"`
+	Attempt int           `json:"attempt"`
+	// Total is the number of files of the job
+	Total int `json:"total"`
+	// Processed is the number of files whose thumbnail was stored
+	Processed int `json:"processed"`
+	// Skipped is the number of files of an unsupported type, quarantined or waiting for their retry
+	Skipped int `json:"skipped"`
+	// Failed is the number of files that failed to render or store
+	Failed     int           `json:"failed"`
+	Failures   []FileFailure `json:"failures,omitempty"`
+	LastError  string        `json:"lastError,omitempty"`
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+}
+
+// Finished reports whether the job won't make more progress
+func (p AlbumProgress) Finished() bool {
+	return p.State.Finished()
+}
+
+// ETA estimates the time left from the rate files were handled at so far, it is 0 when there's nothing to go on
+func (p AlbumProgress) ETA(now time.Time) time.Duration {
+	handled := p.Processed + p.Skipped + p.Failed
+	if p.State != mod.JobRunning || p.StartedAt.IsZero() || handled == 0 || handled >= p.Total {
+		return 0
+	}
+	elapsed := now.Sub(p.StartedAt)
+	return time.Duration(float64(elapsed) / float64(handled) * float64(p.Total-handled))
+}
+
+func progressKey(albumId int) string {
+	return fmt.Sprintf("album-progress:%d", albumId)
+}
+
+// progressHub shares the progress of album jobs. subscribers on the instance running a job get every change, the
+// latest progress is kept in the thumbnail cache for the other instances
+type progressHub struct {
+	cache cache.ThumbnailCache
+
+	mu          sync.Mutex
+	subscribers map[int]map[chan AlbumProgress]struct{}
+}
+
+func newProgressHub(thumbnailCache cache.ThumbnailCache) *progressHub {
+	return &progressHub{
+		cache:       thumbnailCache,
+		subscribers: make(map[int]map[chan AlbumProgress]struct{}),
+	}
+}
+
+// subscribe returns the progress changes of an album made on this instance until the returned func is called
+func (h *progressHub) subscribe(albumId int) (<-chan AlbumProgress, func()) {
+	// slow subscribers miss intermediate changes rather than hold up the workers
+	updates := make(chan AlbumProgress, 16)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[albumId] == nil {
+		h.subscribers[albumId] = make(map[chan AlbumProgress]struct{})
+	}
+	h.subscribers[albumId][updates] = struct{}{}
+
+	return updates, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[albumId], updates)
+		if len(h.subscribers[albumId]) == 0 {
+			delete(h.subscribers, albumId)
+		}
+	}
+}
+
+func (h *progressHub) publish(progress AlbumProgress, save bool) {
+	if save {
+		h.save(progress)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for updates := range h.subscribers[progress.AlbumId] {
+		select {
+		case updates <- progress:
+		default:
+		}
+	}
+}
+
+func (h *progressHub) save(progress AlbumProgress) {
+	encoded, err := json.Marshal(progress)
+	if err == nil {
+		err = h.cache.Set(progressKey(progress.AlbumId), encoded, progressTTL)
+	}
+	if err != nil {
+		log.Error().Err(err).Int("albumId", progress.AlbumId).Msg("failed to save album job progress")
+	}
+}
+
+// load returns the last saved progress of an album from any instance
+func (h *progressHub) load(albumId int) (AlbumProgress, bool) {
+	var progress AlbumProgress
+	encoded, err := h.cache.Get(progressKey(albumId))
+	if err == nil {
+		err = json.Unmarshal(encoded, &progress)
+	}
+	if err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			log.Error().Err(err).Int("albumId", albumId).Msg("failed to load album job progress")
+		}
+		return AlbumProgress{}, false
+	}
+	return progress, true
+}
+
+// track starts the progress of a claimed job
+func (h *progressHub) track(job mod.ThumbnailJob, total int) *progressTracker {
+	tracker := &progressTracker{
+		hub: h,
+		progress: AlbumProgress{
+			AlbumId:   job.AlbumId,
+			JobId:     *job.Id,
+			State:     mod.JobRunning,
+			Attempt:   job.Attempts,
+			Total:     total,
+			StartedAt: time.Now(),
+		},
+		outcomes: make(map[int]callback.FileOutcome),
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.publishLocked(true)
+	return tracker
+}
+
+// progressTracker counts the files of a running job, a nil tracker ignores every update
+type progressTracker struct {
+	hub *progressHub
+
+	mu       sync.Mutex
+	progress AlbumProgress
+	// outcomes is what happened to every file, only the completion event carries them
+	outcomes map[int]callback.FileOutcome
+	savedAt  time.Time
+}
+
+func (t *progressTracker) processed(fileIds ...int) {
+	t.update(func(progress *AlbumProgress) {
+		progress.Processed += len(fileIds)
+		t.record(callback.OutcomeProcessed, "", fileIds)
+	})
+}
+
+func (t *progressTracker) skipped(reason string, fileIds ...int) {
+	t.update(func(progress *AlbumProgress) {
+		progress.Skipped += len(fileIds)
+		t.record(callback.OutcomeSkipped, reason, fileIds)
+	})
+}
+
+// grow counts the files merged into the running job
+func (t *progressTracker) grow(files int) {
+	t.update(func(progress *AlbumProgress) {
+		progress.Total += files
+	})
+}
+
+func (t *progressTracker) failed(reason error, fileIds ...int) {
+	t.update(func(progress *AlbumProgress) {
+		progress.Failed += len(fileIds)
+		for _, fileId := range fileIds {
+			if len(progress.Failures) >= maxProgressFailures {
+				break
+			}
+			progress.Failures = append(progress.Failures, FileFailure{FileId: fileId, Reason: reason.Error()})
+		}
+		t.record(callback.OutcomeFailed, reason.Error(), fileIds)
+	})
+}
+
+// record is called with mu held
+func (t *progressTracker) record(outcome string, reason string, fileIds []int) {
+	for _, fileId := range fileIds {
+		t.outcomes[fileId] = callback.FileOutcome{FileId: fileId, Outcome: outcome, Reason: reason}
+	}
+}
+
+// completion returns the completion event of the finished job with the outcome of every file, ordered by file id
+func (t *progressTracker) completion() callback.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	files := lo.Values(t.outcomes)
+	slices.SortFunc(files, func(a, b callback.FileOutcome) int {
+		return a.FileId - b.FileId
+	})
+	return callback.Event{
+		Type:       callback.EventAlbumFinished,
+		JobId:      t.progress.JobId,
+		AlbumId:    t.progress.AlbumId,
+		State:      string(t.progress.State),
+		Attempt:    t.progress.Attempt,
+		Total:      t.progress.Total,
+		Processed:  t.progress.Processed,
+		Skipped:    t.progress.Skipped,
+		Failed:     t.progress.Failed,
+		Error:      t.progress.LastError,
+		Files:      files,
+		FinishedAt: t.progress.FinishedAt,
+	}
+}
+
+// finish records the outcome of the job run, a job queued again is waiting for a retry
+func (t *progressTracker) finish(state mod.JobStatus, cause error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.State = state
+	t.progress.FinishedAt = time.Now()
+	if cause != nil {
+		t.progress.LastError = cause.Error()
+	}
+	t.publishLocked(true)
+}
+
+func (t *progressTracker) update(apply func(progress *AlbumProgress)) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	apply(&t.progress)
+	t.publishLocked(time.Since(t.savedAt) >= progressSaveInterval)
+}
+
+// publishLocked is called with mu held, so subscribers get the changes in order
+func (t *progressTracker) publishLocked(save bool) {
+	progress := t.progress
+	progress.Failures = append([]FileFailure(nil), t.progress.Failures...)
+	if save {
+		t.savedAt = time.Now()
+	}
+	t.hub.publish(progress, save)
+}
@@ -0,0 +1,125 @@
+package thumbnail
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+func trackedJob(hub *progressHub, total int) *progressTracker {
+	id := 9
+	return hub.track(mod.ThumbnailJob{Id: &id, AlbumId: 4, Attempts: 1}, total)
+}
+
+func TestProgressTracker_CountsFiles(t *testing.T) {
+	// given
+	hub := newProgressHub(cache.NewLRUCache(1024 * 1024))
+	updates, unsubscribe := hub.subscribe(4)
+	defer unsubscribe()
+	tracker := trackedJob(hub, 5)
+
+	// when
+	tracker.processed(1, 2)
+	tracker.skipped("unsupported file type", 5)
+	tracker.failed(errors.New("corrupt file"), 3, 4)
+	tracker.finish(mod.JobDone, nil)
+
+	// then
+	var last AlbumProgress
+	for len(updates) > 0 {
+		last = <-updates
+	}
+	assert.Equal(t, mod.JobDone, last.State)
+	assert.Equal(t, 9, last.JobId)
+	assert.Equal(t, 5, last.Total)
+	assert.Equal(t, 2, last.Processed)
+	assert.Equal(t, 1, last.Skipped)
+	assert.Equal(t, 2, last.Failed)
+	assert.Equal(t, []FileFailure{{FileId: 3, Reason: "corrupt file"}, {FileId: 4, Reason: "corrupt file"}}, last.Failures)
+	assert.True(t, last.Finished())
+
+	saved, found := hub.load(4)
+	assert.True(t, found)
+	assert.Equal(t, last.Failures, saved.Failures)
+	assert.Equal(t, mod.JobDone, saved.State)
+	assert.True(t, last.FinishedAt.Equal(saved.FinishedAt))
+}
+
+func TestProgressTracker_CapsFailureReasons(t *testing.T) {
+	// given
+	tracker := trackedJob(newProgressHub(cache.NewLRUCache(1024*1024)), maxProgressFailures+10)
+	fileIds := make([]int, maxProgressFailures+10)
+
+	// when
+	tracker.failed(errors.New("corrupt file"), fileIds...)
+
+	// then
+	assert.Equal(t, maxProgressFailures+10, tracker.progress.Failed)
+	assert.Len(t, tracker.progress.Failures, maxProgressFailures)
+}
+
+func TestProgressTracker_Completion(t *testing.T) {
+	// given
+	tracker := trackedJob(newProgressHub(cache.NewLRUCache(1024*1024)), 4)
+	tracker.failed(errors.New("corrupt file"), 3)
+	tracker.processed(2, 1)
+	tracker.skipped("unsupported file type", 4)
+	tracker.finish(mod.JobDone, nil)
+
+	// when
+	event := tracker.completion()
+
+	// then
+	assert.Equal(t, callback.EventAlbumFinished, event.Type)
+	assert.Equal(t, 9, event.JobId)
+	assert.Equal(t, 4, event.AlbumId)
+	assert.Equal(t, string(mod.JobDone), event.State)
+	assert.Equal(t, 2, event.Processed)
+	assert.Equal(t, 1, event.Skipped)
+	assert.Equal(t, 1, event.Failed)
+	assert.Equal(t, []callback.FileOutcome{
+		{FileId: 1, Outcome: callback.OutcomeProcessed},
+		{FileId: 2, Outcome: callback.OutcomeProcessed},
+		{FileId: 3, Outcome: callback.OutcomeFailed, Reason: "corrupt file"},
+		{FileId: 4, Outcome: callback.OutcomeSkipped, Reason: "unsupported file type"},
+	}, event.Files)
+	assert.False(t, event.FinishedAt.IsZero())
+}
+
+func TestProgressTracker_NilIgnoresUpdates(t *testing.T) {
+	var tracker *progressTracker
+
+	assert.NotPanics(t, func() {
+		tracker.processed(1)
+		tracker.failed(errors.New("corrupt file"), 1)
+		tracker.finish(mod.JobDone, nil)
+	})
+}
+
+func TestProgressHub_Unsubscribe(t *testing.T) {
+	// given
+	hub := newProgressHub(cache.NewLRUCache(1024 * 1024))
+	updates, unsubscribe := hub.subscribe(4)
+
+	// when
+	unsubscribe()
+	trackedJob(hub, 1)
+
+	// then
+	assert.Empty(t, updates)
+	assert.Empty(t, hub.subscribers)
+}
+
+func TestAlbumProgress_ETA(t *testing.T) {
+	now := time.Now()
+	running := AlbumProgress{State: mod.JobRunning, Total: 10, Processed: 3, Skipped: 1, StartedAt: now.Add(-8 * time.Second)}
+
+	assert.Equal(t, 12*time.Second, running.ETA(now))
+	assert.Zero(t, AlbumProgress{State: mod.JobRunning, Total: 10, StartedAt: now}.ETA(now))
+	assert.Zero(t, AlbumProgress{State: mod.JobDone, Total: 10, Processed: 5, StartedAt: now.Add(-time.Second)}.ETA(now))
+}
@@ -0,0 +1,115 @@
+package thumbnail
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// PurgeTarget is a file whose thumbnails are removed. a deleted file may only be known by one of the two, so a zero
+// FileId or a nil Token is skipped
+type PurgeTarget struct {
+	FileId int
+	Token  uuid.UUID
+}
+
+// PurgeResult describes what a purge removed
+type PurgeResult struct {
+	// Files is the number of files whose thumbnails were purged
+	Files int
+	// StoredThumbnails is the number of album thumbnails deleted from the database
+	StoredThumbnails int64
+}
+
+// PurgeByToken removes every cached thumbnail of a file token, and its stored album thumbnail when the file still exists
+func (s service) PurgeByToken(fileToken uuid.UUID) (PurgeResult, error) {
+	target := PurgeTarget{Token: fileToken}
+
+	fileEntry, err := s.dao.GetFileEntry(fileToken)
+	switch {
+	case err == nil:
+		target.FileId = fileEntry.Id
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return PurgeResult{}, err
+	}
+	return s.Purge([]PurgeTarget{target})
+}
+
+// PurgeByFileId removes the stored album thumbnail of a file, and its cached thumbnails when the file still exists
+func (s service) PurgeByFileId(fileId int) (PurgeResult, error) {
+	if fileId <= 0 {
+		return PurgeResult{}, fmt.Errorf("%w: %d", ErrInvalidFileIds, fileId)
+	}
+	target := PurgeTarget{FileId: fileId}
+
+	fileEntry, err := s.dao.GetFileEntryById(fileId)
+	switch {
+	case err == nil:
+		target.Token = fileEntry.Token
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return PurgeResult{}, err
+	}
+	return s.Purge([]PurgeTarget{target})
+}
+
+// PurgeByAlbum removes the cached and stored thumbnails of every file in an album
+func (s service) PurgeByAlbum(albumId int) (PurgeResult, error) {
+	fileEntries, err := s.dao.GetAlbumFileEntries(albumId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return PurgeResult{}, fmt.Errorf("%w: %d", ErrAlbumNotFound, albumId)
+		}
+		return PurgeResult{}, err
+	}
+
+	// a running job would store the purged thumbnails again
+	if _, err := s.dao.CancelJobs(albumId, true); err != nil {
+		log.Error().Err(err).Int("albumId", albumId).Msg("failed to cancel the thumbnail jobs of a purged album")
+	} else {
+		s.jobs.cancel(albumId, true)
+	}
+
+	targets := make([]PurgeTarget, 0, len(fileEntries))
+	for _, fileEntry := range fileEntries {
+		targets = append(targets, PurgeTarget{FileId: fileEntry.Id, Token: fileEntry.Token})
+	}
+	return s.Purge(targets)
+}
+
+// Purge removes the token keyed thumbnails from the cache and the stored album thumbnails of the given files. every
+// target is attempted, the errors are joined
+func (s service) Purge(targets []PurgeTarget) (PurgeResult, error) {
+	var errs []error
+	for _, token := range lo.Uniq(lo.FilterMap(targets, func(target PurgeTarget, _ int) (uuid.UUID, bool) {
+		return target.Token, target.Token != uuid.Nil
+	})) {
+		if err := s.cache.DeleteMatching(token.String() + ":*"); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove thumbnails of %s from cache: %w", token.String(), err))
+		}
+	}
+
+	fileIds := lo.Uniq(lo.FilterMap(targets, func(target PurgeTarget, _ int) (int, bool) {
+		return target.FileId, target.FileId > 0
+	}))
+	var deleted int64
+	if len(fileIds) > 0 {
+		var err error
+		if deleted, err = s.dao.DeleteThumbnails(fileIds); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	result := PurgeResult{
+		Files:            len(targets),
+		StoredThumbnails: deleted,
+	}
+	if err := errors.Join(errs...); err != nil {
+		return result, err
+	}
+	log.Debug().Int("files", result.Files).Int64("storedThumbnails", deleted).Msg("purged thumbnails")
+	return result, nil
+}
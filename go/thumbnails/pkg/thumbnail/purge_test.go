@@ -0,0 +1,171 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+)
+
+func seedTokenKeys(t *testing.T, rdb *redis.Client, tokens ...uuid.UUID) {
+	for _, token := range tokens {
+		for _, suffix := range []string{"static", "animated", "static:attention:w200"} {
+			err := rdb.Set(context.Background(), token.String()+":"+suffix, "thumbnail", 0).Err()
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func countKeys(t *testing.T, rdb *redis.Client, pattern string) int {
+	keys, err := rdb.Keys(context.Background(), pattern).Result()
+	assert.NoError(t, err)
+	return len(keys)
+}
+
+func TestService_PurgeByToken(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	token, other := uuid.New(), uuid.New()
+	seedTokenKeys(t, mockRedis, token, other)
+	daoService.EXPECT().GetFileEntry(token).Return(&mod.FileEntry{Id: 7, Token: token}, nil)
+	daoService.EXPECT().DeleteThumbnails([]int{7}).Return(int64(1), nil)
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	result, err := svc.PurgeByToken(token)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, PurgeResult{Files: 1, StoredThumbnails: 1}, result)
+	assert.Equal(t, 0, countKeys(t, mockRedis, token.String()+":*"))
+	assert.Equal(t, 3, countKeys(t, mockRedis, other.String()+":*"))
+}
+
+func TestService_PurgeByToken_DeletedFile(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	token := uuid.New()
+	seedTokenKeys(t, mockRedis, token)
+	daoService.EXPECT().GetFileEntry(token).Return(nil, gorm.ErrRecordNotFound)
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	result, err := svc.PurgeByToken(token)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, PurgeResult{Files: 1}, result)
+	assert.Equal(t, 0, countKeys(t, mockRedis, token.String()+":*"))
+}
+
+func TestService_PurgeByToken_DatabaseError(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	token := uuid.New()
+	seedTokenKeys(t, mockRedis, token)
+	daoService.EXPECT().GetFileEntry(token).Return(nil, errors.New("connection refused"))
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	_, err := svc.PurgeByToken(token)
+
+	// then
+	assert.Error(t, err)
+	assert.Equal(t, 3, countKeys(t, mockRedis, token.String()+":*"))
+}
+
+func TestService_PurgeByFileId(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	token := uuid.New()
+	seedTokenKeys(t, mockRedis, token)
+	daoService.EXPECT().GetFileEntryById(7).Return(&mod.FileEntry{Id: 7, Token: token}, nil)
+	daoService.EXPECT().DeleteThumbnails([]int{7}).Return(int64(1), nil)
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	result, err := svc.PurgeByFileId(7)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, PurgeResult{Files: 1, StoredThumbnails: 1}, result)
+	assert.Equal(t, 0, countKeys(t, mockRedis, token.String()+":*"))
+}
+
+func TestService_PurgeByFileId_Invalid(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	_, err := svc.PurgeByFileId(0)
+
+	// then
+	assert.True(t, errors.Is(err, ErrInvalidFileIds))
+}
+
+func TestService_PurgeByAlbum(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	first, second, outside := uuid.New(), uuid.New(), uuid.New()
+	seedTokenKeys(t, mockRedis, first, second, outside)
+	daoService.EXPECT().GetAlbumFileEntries(3).Return([]mod.FileEntry{
+		{Id: 1, Token: first},
+		{Id: 2, Token: second},
+	}, nil)
+	daoService.EXPECT().CancelJobs(3, true).Return(int64(1), nil)
+	daoService.EXPECT().DeleteThumbnails([]int{1, 2}).Return(int64(2), nil)
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	result, err := svc.PurgeByAlbum(3)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, PurgeResult{Files: 2, StoredThumbnails: 2}, result)
+	assert.Equal(t, 3, countKeys(t, mockRedis, "*"))
+	assert.Equal(t, 3, countKeys(t, mockRedis, outside.String()+":*"))
+}
+
+func TestService_PurgeByAlbum_NotFound(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	daoService.EXPECT().GetAlbumFileEntries(3).Return(nil, gorm.ErrRecordNotFound)
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	_, err := svc.PurgeByAlbum(3)
+
+	// then
+	assert.True(t, errors.Is(err, ErrAlbumNotFound))
+}
+
+func TestService_Purge_ContinuesAfterDatabaseError(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	token := uuid.New()
+	seedTokenKeys(t, mockRedis, token)
+	daoService.EXPECT().DeleteThumbnails([]int{5}).Return(int64(0), errors.New("connection refused"))
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	_, err := svc.Purge([]PurgeTarget{{FileId: 5}, {Token: token}, {FileId: 5, Token: token}})
+
+	// then
+	assert.Error(t, err)
+	assert.Equal(t, 0, countKeys(t, mockRedis, token.String()+":*"))
+}
@@ -0,0 +1,74 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// renderTimeFourCC tags the WebP chunk, or the JPEG comment of video frames, holding the unix time a thumbnail was rendered
+const renderTimeFourCC = "WVRT"
+
+// stampRenderTime records when a thumbnail was rendered, so Last-Modified can be answered from the cached bytes alone.
+// thumbnails that are neither WebP nor JPEG are returned unchanged
+func stampRenderTime(thumbnail []byte, renderedAt time.Time) []byte {
+	payload := binary.BigEndian.AppendUint64(nil, uint64(renderedAt.Unix()))
+
+	if isJPEG(thumbnail) {
+		comment := append([]byte(renderTimeFourCC), payload...)
+		segment := []byte{0xff, 0xfe}
+		segment = binary.BigEndian.AppendUint16(segment, uint16(len(comment)+2))
+		segment = append(segment, comment...)
+		return bytes.Join([][]byte{thumbnail[:2], segment, thumbnail[2:]}, nil)
+	}
+
+	stamped, err := addWebpChunk(thumbnail, riffChunk{fourCC: renderTimeFourCC, payload: payload})
+	if err != nil {
+		log.Debug().Err(err).Msg("thumbnail is not a WebP, render time not recorded")
+		return thumbnail
+	}
+	return stamped
+}
+
+// ReadRenderTime returns the time the thumbnail was rendered, thumbnails cached before the time was recorded have none
+func ReadRenderTime(thumbnail []byte) (time.Time, bool) {
+	var payload []byte
+	found := false
+	if isJPEG(thumbnail) {
+		payload, found = findJPEGComment(thumbnail, renderTimeFourCC)
+	} else {
+		payload, found = findWebpChunk(thumbnail, renderTimeFourCC)
+	}
+	if !found || len(payload) != 8 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(payload)), 0), true
+}
+
+func isJPEG(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0xff && data[1] == 0xd8
+}
+
+// findJPEGComment returns the rest of the first comment segment starting with prefix, only the headers before the
+// image data are searched
+func findJPEGComment(jpeg []byte, prefix string) ([]byte, bool) {
+	for pos := 2; pos+4 <= len(jpeg) && jpeg[pos] == 0xff; {
+		marker := jpeg[pos+1]
+		// start of scan and end of image
+		if marker == 0xda || marker == 0xd9 {
+			return nil, false
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(jpeg[pos+2:pos+4]))
+		if end > len(jpeg) {
+			return nil, false
+		}
+		payload := jpeg[pos+4 : end]
+		if marker == 0xfe && bytes.HasPrefix(payload, []byte(prefix)) {
+			return payload[len(prefix):], true
+		}
+		pos = end
+	}
+	return nil, false
+}
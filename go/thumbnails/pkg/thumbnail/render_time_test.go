@@ -0,0 +1,57 @@
+package thumbnail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStampRenderTime_WebP(t *testing.T) {
+	// given
+	renderedAt := time.Unix(1760000000, 0)
+	webp := buildWebp(vp8lChunk(400, 300, false))
+
+	// when
+	stamped := stampRenderTime(webp, renderedAt)
+
+	// then
+	result, found := ReadRenderTime(stamped)
+	assert.True(t, found)
+	assert.True(t, renderedAt.Equal(result))
+	_, hasVP8L := findWebpChunk(stamped, "VP8L")
+	assert.True(t, hasVP8L)
+}
+
+func TestStampRenderTime_JPEG(t *testing.T) {
+	// given
+	renderedAt := time.Unix(1760000000, 0)
+	jpeg := []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x04, 0x00, 0x00, 0xff, 0xda, 0x00, 0x02, 0x12, 0x34, 0xff, 0xd9}
+
+	// when
+	stamped := stampRenderTime(jpeg, renderedAt)
+
+	// then
+	result, found := ReadRenderTime(stamped)
+	assert.True(t, found)
+	assert.True(t, renderedAt.Equal(result))
+	assert.Equal(t, jpeg[2:], stamped[2+4+len(renderTimeFourCC)+8:])
+}
+
+func TestReadRenderTime_Unstamped(t *testing.T) {
+	// when
+	_, fromWebp := ReadRenderTime(buildWebp(vp8lChunk(400, 300, false)))
+	_, fromOther := ReadRenderTime([]byte("not an image"))
+
+	// then
+	assert.False(t, fromWebp)
+	assert.False(t, fromOther)
+}
+
+func TestStampRenderTime_UnknownFormatIsUnchanged(t *testing.T) {
+	// when
+	stamped := stampRenderTime([]byte("not an image"), time.Now())
+
+	// then
+	assert.Equal(t, []byte("not an image"), stamped)
+}
@@ -0,0 +1,210 @@
+package thumbnail
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+)
+
+const (
+	DefaultRerenderRate = 1
+	// rerenderLockKey is taken for every page when a locker is configured, so instances don't re-render the same rows
+	rerenderLockKey          = "thumbnail-rerender"
+	rerenderLockTTL          = 10 * time.Minute
+	rerenderLockPollInterval = 5 * time.Second
+	// rerenderQueue is the pool queue of the re-renders, they take their turn like an album. album ids start at 1
+	rerenderQueue = 0
+)
+
+// RerenderConfig controls the job that re-renders stored thumbnails of older pipeline versions
+type RerenderConfig struct {
+	// BatchSize is the number of thumbnails read and re-rendered per page
+	BatchSize int
+	// FilesPerSecond is the throughput budget of the job, 0 disables it
+	FilesPerSecond float64
+}
+
+// RerenderConfigFromEnv reads THUMBNAIL_RERENDER_BATCH_SIZE and THUMBNAIL_RERENDER_RATE
+func RerenderConfigFromEnv() RerenderConfig {
+	config := RerenderConfig{
+		BatchSize:      DefaultBatchSize,
+		FilesPerSecond: DefaultRerenderRate,
+	}
+	if raw := os.Getenv("THUMBNAIL_RERENDER_BATCH_SIZE"); raw != "" {
+		batchSize, err := strconv.Atoi(raw)
+		if err != nil || batchSize <= 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_RERENDER_BATCH_SIZE")
+		} else {
+			config.BatchSize = batchSize
+		}
+	}
+	if raw := os.Getenv("THUMBNAIL_RERENDER_RATE"); raw != "" {
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 {
+			log.Warn().Str("value", raw).Msg("ignoring invalid THUMBNAIL_RERENDER_RATE")
+		} else {
+			config.FilesPerSecond = rate
+		}
+	}
+	return config
+}
+
+// rerender upgrades the stored thumbnails rendered by an older pipeline to PipelineVersion. rows are updated in place,
+// so the Node service keeps serving the old thumbnail of a file until its new one is stored
+type rerender struct {
+	dao       dao.Dao
+	processor Processor
+	pool      *WorkerPool
+	locker    cache.Locker
+	config    RerenderConfig
+	sleep     func(time.Duration)
+}
+
+func newRerender(daoService dao.Dao, processor Processor, pool *WorkerPool, locker cache.Locker, config RerenderConfig) *rerender {
+	return &rerender{
+		dao:       daoService,
+		processor: processor,
+		pool:      pool,
+		locker:    locker,
+		config:    config,
+		sleep:     time.Sleep,
+	}
+}
+
+// run pages through the stale thumbnails by id until none are left. thumbnails that fail are skipped until the next
+// start of the service
+func (r *rerender) run() {
+	if r.config.FilesPerSecond <= 0 {
+		return
+	}
+
+	cursor, upgraded, failed := 0, 0, 0
+	for {
+		unlock, held := lockJob(r.locker, rerenderLockKey, rerenderLockTTL)
+		if !held {
+			r.sleep(rerenderLockPollInterval)
+			continue
+		}
+
+		started := time.Now()
+		stale, err := r.dao.GetStaleThumbnails(PipelineVersion, cursor, r.config.BatchSize)
+		if err != nil {
+			unlock()
+			log.Error().Err(err).Int("cursor", cursor).Msg("failed to get stale thumbnails, stopping re-render")
+			return
+		}
+		if len(stale) == 0 {
+			unlock()
+			if upgraded > 0 || failed > 0 {
+				log.Info().Int("upgraded", upgraded).Int("failed", failed).Int("version", PipelineVersion).Msg("thumbnail re-render finished")
+			}
+			return
+		}
+
+		pageUpgraded, pageFailed := r.upgrade(stale)
+		unlock()
+		cursor = stale[len(stale)-1].ThumbnailId
+		upgraded += pageUpgraded
+		failed += pageFailed
+		log.Debug().Int("cursor", cursor).Int("upgraded", upgraded).Int("failed", failed).Msg("re-rendered thumbnail page")
+
+		if delay := throttleDelay(len(stale), r.config.FilesPerSecond, time.Since(started)); delay > 0 {
+			r.sleep(delay)
+		}
+	}
+}
+
+// upgrade re-renders a page of stale thumbnails with the crop they were rendered with, files with the same contents
+// are rendered once and thumbnails already upgraded for another file are reused
+func (r *rerender) upgrade(stale []mod.StaleThumbnail) (upgraded int, failed int) {
+	type pending struct {
+		row     mod.StaleThumbnail
+		file    dto.FileEntryDto
+		options Options
+	}
+
+	var rows []pending
+	for _, row := range stale {
+		file := dto.FromModel(row.FileEntry)
+		// protected files are purged by the lifecycle consumer and unsupported ones can't be rendered anymore
+		if row.Protected() || !r.processor.SupportsFile(file) {
+			failed++
+			continue
+		}
+		options := Options{Crop: CropMode(row.Crop)}
+		if options.cropped() {
+			options.FocalPoint = file.FocalPoint
+		}
+		rows = append(rows, pending{row: row, file: file, options: options})
+	}
+
+	renderKeys := lo.FilterMap(rows, func(p pending, _ int) (string, bool) {
+		renderKey := p.options.renderKey(p.file.Checksum)
+		return renderKey, renderKey != ""
+	})
+	rendered := make(map[string][]byte)
+	if len(renderKeys) > 0 {
+		stored, err := r.dao.GetThumbnailsByRenderKey(renderKeys)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to get upgraded thumbnails by render key, rendering every file")
+		}
+		for renderKey, thumbnail := range stored {
+			rendered[renderKey] = thumbnail
+		}
+	}
+
+	var thumbnails []mod.Thumbnail
+	for _, p := range rows {
+		renderKey := p.options.renderKey(p.file.Checksum)
+		thumbnail, found := rendered[renderKey]
+		if !found {
+			var err error
+			thumbnail, err = r.render(p.file, p.options)
+			if err != nil {
+				log.Err(err).Int("fileId", p.file.Id).Msg("failed to re-render thumbnail")
+				failed++
+				continue
+			}
+			if renderKey != "" {
+				rendered[renderKey] = thumbnail
+			}
+		}
+
+		thumbnails = append(thumbnails, mod.Thumbnail{
+			Id:              &p.row.ThumbnailId,
+			FileId:          p.file.Id,
+			Content:         thumbnail,
+			RenderKey:       lo.EmptyableToPtr(renderKey),
+			PipelineVersion: PipelineVersion,
+			Crop:            p.row.Crop,
+		})
+	}
+
+	if err := r.dao.UpdateThumbnails(thumbnails); err != nil {
+		log.Error().Err(err).Msg("failed to store re-rendered thumbnails")
+		return 0, len(stale)
+	}
+	return len(thumbnails), failed
+}
+
+// render renders a file on a pool worker at batch priority and waits for it, so re-renders count towards the workers
+// and decode memory of the pool
+func (r *rerender) render(file dto.FileEntryDto, options Options) ([]byte, error) {
+	var thumbnail []byte
+	var err error
+	done := make(chan struct{})
+	r.pool.submit(context.Background(), rerenderQueue, r.processor.DecodeCost(file), func() {
+		defer close(done)
+		thumbnail, err = r.processor.GenerateThumbnail(context.Background(), file, options)
+	})
+	<-done
+	return thumbnail, err
+}
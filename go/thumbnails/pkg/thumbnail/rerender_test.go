@@ -0,0 +1,186 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"gorm.io/gorm"
+)
+
+func staleThumbnail(thumbnailId int, fileId int, checksum string, crop CropMode) mod.StaleThumbnail {
+	return mod.StaleThumbnail{
+		FileEntry: mod.FileEntry{
+			Id:        fileId,
+			Token:     uuid.New(),
+			Checksum:  checksum,
+			FileName:  "file",
+			Extension: "png",
+			MediaType: "image/png",
+		},
+		ThumbnailId: thumbnailId,
+		Crop:        string(crop),
+	}
+}
+
+func newTestRerender(t *testing.T, daoService dao.Dao, processor Processor, config RerenderConfig) (*rerender, *[]time.Duration) {
+	var sleeps []time.Duration
+	r := newRerender(daoService, processor, newTestPool(t, 1), nil, config)
+	r.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	}
+	return r, &sleeps
+}
+
+func TestRerender_UpgradesStaleThumbnails(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	supportsPng(processor)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	x, y := 0.3, 0.6
+	cropped := staleThumbnail(10, 1, "", CropEntropy)
+	cropped.FocalPointX, cropped.FocalPointY = &x, &y
+	unsupported := staleThumbnail(11, 2, "", CropNone)
+	unsupported.Extension = "pdf"
+	first := staleThumbnail(12, 3, "abc", CropNone)
+	duplicate := staleThumbnail(13, 4, "abc", CropNone)
+	upgradedElsewhere := staleThumbnail(14, 5, "def", CropNone)
+
+	daoService.EXPECT().GetStaleThumbnails(PipelineVersion, 0, 5).
+		Return([]mod.StaleThumbnail{cropped, unsupported, first, duplicate, upgradedElsewhere}, nil).Once()
+	daoService.EXPECT().GetStaleThumbnails(PipelineVersion, 14, 5).Return(nil, nil).Once()
+	daoService.EXPECT().GetThumbnailsByRenderKey([]string{"checksum:abc:static", "checksum:abc:static", "checksum:def:static"}).
+		Return(map[string][]byte{"checksum:def:static": []byte("upgraded")}, nil)
+	processor.EXPECT().GenerateThumbnail(mock.Anything, mock.MatchedBy(func(file dto.FileEntryDto) bool { return file.Id == 1 }),
+		Options{Crop: CropEntropy, FocalPoint: &mod.FocalPoint{X: x, Y: y}}).Return([]byte("cropped"), nil)
+	processor.EXPECT().GenerateThumbnail(mock.Anything, mock.MatchedBy(func(file dto.FileEntryDto) bool { return file.Id == 3 }),
+		Options{}).Return([]byte("shared"), nil).Once()
+	var updated []mod.Thumbnail
+	daoService.EXPECT().UpdateThumbnails(mock.Anything).RunAndReturn(func(thumbnails []mod.Thumbnail, _ ...*gorm.DB) error {
+		updated = thumbnails
+		return nil
+	})
+	r, _ := newTestRerender(t, daoService, processor, RerenderConfig{BatchSize: 5})
+	r.config.FilesPerSecond = 1000
+
+	// when
+	r.run()
+
+	// then
+	assert.Len(t, updated, 4)
+	contents := make(map[int]string)
+	for _, thumbnail := range updated {
+		contents[*thumbnail.Id] = string(thumbnail.Content)
+		assert.Equal(t, PipelineVersion, thumbnail.PipelineVersion)
+	}
+	assert.Equal(t, map[int]string{10: "cropped", 12: "shared", 13: "shared", 14: "upgraded"}, contents)
+	assert.Equal(t, string(CropEntropy), updated[0].Crop)
+	assert.Nil(t, updated[0].RenderKey)
+	assert.Equal(t, "checksum:abc:static", *updated[1].RenderKey)
+}
+
+func TestRerender_ThrottlesToBudget(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	supportsPng(processor)
+	processor.On("DecodeCost", mock.Anything).Return(int64(1))
+	daoService.EXPECT().GetStaleThumbnails(PipelineVersion, 0, 2).
+		Return([]mod.StaleThumbnail{staleThumbnail(1, 1, "", CropNone), staleThumbnail(2, 2, "", CropNone)}, nil).Once()
+	daoService.EXPECT().GetStaleThumbnails(PipelineVersion, 2, 2).Return(nil, nil).Once()
+	processor.EXPECT().GenerateThumbnail(mock.Anything, mock.Anything, Options{}).Return([]byte("thumbnail"), nil)
+	daoService.EXPECT().UpdateThumbnails(mock.Anything).Return(nil)
+	r, sleeps := newTestRerender(t, daoService, processor, RerenderConfig{BatchSize: 2, FilesPerSecond: 0.5})
+
+	// when
+	r.run()
+
+	// then
+	assert.Len(t, *sleeps, 1)
+	assert.InDelta(t, float64(4*time.Second), float64((*sleeps)[0]), float64(time.Second))
+}
+
+func TestRerender_WaitsForPoolAdmission(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	supportsPng(processor)
+	processor.EXPECT().DecodeCost(mock.Anything).Return(int64(50))
+	rendered := make(chan struct{})
+	processor.EXPECT().GenerateThumbnail(mock.Anything, mock.Anything, Options{}).RunAndReturn(
+		func(_ context.Context, _ dto.FileEntryDto, _ Options) ([]byte, error) {
+			close(rendered)
+			return []byte("thumbnail"), nil
+		})
+	daoService.EXPECT().UpdateThumbnails(mock.Anything).Return(nil)
+	pool := newTestPoolWithConfig(t, PoolConfig{Workers: 2, MaxBytes: 100})
+	started, release := blockingTask(pool, context.Background(), 1, 80)
+	<-started
+	r := newRerender(daoService, processor, pool, nil, RerenderConfig{BatchSize: 1, FilesPerSecond: 1})
+	done := make(chan struct{})
+
+	// when
+	go func() {
+		defer close(done)
+		r.upgrade([]mod.StaleThumbnail{staleThumbnail(1, 1, "", CropNone)})
+	}()
+
+	// then the stale row isn't rendered until its decode memory fits next to the album file
+	assert.Never(t, func() bool {
+		select {
+		case <-rendered:
+			return true
+		default:
+			return false
+		}
+	}, 100*time.Millisecond, 10*time.Millisecond)
+	release()
+	<-rendered
+	<-done
+}
+
+func TestRerender_StopsOnError(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	daoService.EXPECT().GetStaleThumbnails(PipelineVersion, 0, 10).Return(nil, errors.New("db down")).Once()
+	r, sleeps := newTestRerender(t, daoService, NewMockProcessor(t), RerenderConfig{BatchSize: 10, FilesPerSecond: 1})
+
+	// when
+	r.run()
+
+	// then
+	assert.Empty(t, *sleeps)
+}
+
+func TestRerender_DisabledWithoutBudget(t *testing.T) {
+	// given
+	daoService := dao.NewMockDao(t)
+	r, _ := newTestRerender(t, daoService, NewMockProcessor(t), RerenderConfig{BatchSize: 10})
+
+	// when
+	r.run()
+
+	// then
+	daoService.AssertNotCalled(t, "GetStaleThumbnails", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRerenderConfigFromEnv(t *testing.T) {
+	// given
+	t.Setenv("THUMBNAIL_RERENDER_BATCH_SIZE", "20")
+	t.Setenv("THUMBNAIL_RERENDER_RATE", "-1")
+
+	// when
+	config := RerenderConfigFromEnv()
+
+	// then
+	assert.Equal(t, 20, config.BatchSize)
+	assert.Equal(t, float64(DefaultRerenderRate), config.FilesPerSecond)
+}
@@ -0,0 +1,172 @@
+package thumbnail
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/shared/utils"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+)
+
+// ClientHintWidths are the widths client hints are rounded up to, so browsers with odd layouts share cached thumbnails
+var ClientHintWidths = []int{200, 400, 600, 800, 1200, 1600, MaxThumbnailWidth}
+
+// DefaultSetWidths is the set rendered when no widths are requested
+var DefaultSetWidths = []int{200, 400, 800}
+
+// Variant is a single width of a responsive thumbnail set
+type Variant struct {
+	Width     int
+	Thumbnail []byte
+}
+
+// ValidateWidth makes sure a requested width is within the supported range
+func ValidateWidth(width int) error {
+	if width < MinThumbnailWidth || width > MaxThumbnailWidth {
+		return fmt.Errorf("%w: %d must be between %d and %d", ErrInvalidWidth, width, MinThumbnailWidth, MaxThumbnailWidth)
+	}
+	return nil
+}
+
+// ParseWidths parses a comma separated list of widths
+func ParseWidths(value string) ([]int, error) {
+	if value == "" {
+		return DefaultSetWidths, nil
+	}
+
+	var widths []int
+	for _, part := range strings.Split(value, ",") {
+		width, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidWidth, part)
+		}
+		if err := ValidateWidth(width); err != nil {
+			return nil, err
+		}
+		widths = append(widths, width)
+	}
+	return widths, nil
+}
+
+// ParseDPRs parses a comma separated list of device pixel ratios
+func ParseDPRs(value string) ([]float64, error) {
+	if value == "" {
+		return []float64{1}, nil
+	}
+
+	var dprs []float64
+	for _, part := range strings.Split(value, ",") {
+		dpr, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || dpr < 1 || dpr > MaxDPR {
+			return nil, fmt.Errorf("%w: %s must be between 1 and %d", ErrInvalidDPR, part, MaxDPR)
+		}
+		dprs = append(dprs, dpr)
+	}
+	return dprs, nil
+}
+
+// VariantWidths multiplies every width by every DPR and returns the distinct pixel widths in ascending order
+func VariantWidths(widths []int, dprs []float64) ([]int, error) {
+	var variants []int
+	for _, width := range widths {
+		for _, dpr := range dprs {
+			variants = append(variants, min(int(math.Round(float64(width)*dpr)), MaxThumbnailWidth))
+		}
+	}
+
+	variants = lo.Uniq(variants)
+	slices.Sort(variants)
+	if len(variants) > MaxThumbnailVariants {
+		return nil, fmt.Errorf("%w: %d variants requested, the maximum is %d", ErrInvalidWidth, len(variants), MaxThumbnailVariants)
+	}
+	return variants, nil
+}
+
+// ClientHintWidth works out the thumbnail width from the Sec-CH-Width and Sec-CH-DPR client hints.
+// Sec-CH-Width is already in physical pixels, a DPR on its own scales the default width.
+// 0 is returned when the hints are missing or resolve to the default width
+func ClientHintWidth(width int, dpr float64) int {
+	target := width
+	if target <= 0 && dpr > 0 {
+		target = int(math.Ceil(DefaultThumbnailWidth * min(dpr, MaxDPR)))
+	}
+	if target <= 0 {
+		return 0
+	}
+
+	snapped, found := lo.Find(ClientHintWidths, func(w int) bool {
+		return w >= target
+	})
+	if !found {
+		snapped = MaxThumbnailWidth
+	}
+	if snapped == DefaultThumbnailWidth {
+		return 0
+	}
+	return snapped
+}
+
+// GenerateThumbnailSet decodes the file once at the largest width and derives every other width from it
+func (p *processor) GenerateThumbnailSet(fileEntry dto.FileEntryDto, options Options, widths []int) ([]Variant, error) {
+	if !p.SupportsFile(fileEntry) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, fileEntry.MediaType)
+	}
+	if len(widths) == 0 {
+		return nil, nil
+	}
+
+	options = options.withBudget(p.maxBytes, p.minQuality)
+	options.Width = slices.Max(widths)
+	filePath := p.baseUrl + "/" + fileEntry.FullFileNameOnSystem
+
+	var base *vips.ImageRef
+	var err error
+	if utils.IsImage(fileEntry.MediaType) {
+		base, err = p.loadImageThumbnail(filePath, fileEntry.Extension, options)
+	} else {
+		var frame []byte
+		frame, err = p.extractVideoFrame(context.Background(), filePath, options)
+		if err == nil {
+			base, err = p.loadVideoFrame(frame, options)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer base.Close()
+
+	variants := make([]Variant, 0, len(widths))
+	for _, width := range widths {
+		thumbnail, err := renderVariant(base, options, width)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %dpx variant: %w", width, err)
+		}
+		variants = append(variants, Variant{Width: width, Thumbnail: thumbnail})
+	}
+	return variants, nil
+}
+
+// renderVariant resizes a copy of the decoded image to the given width and exports it
+func renderVariant(base *vips.ImageRef, options Options, width int) ([]byte, error) {
+	variant, err := base.Copy()
+	if err != nil {
+		return nil, err
+	}
+	defer variant.Close()
+
+	height := 0
+	if options.cropped() {
+		height = width
+	}
+	if err := variant.ThumbnailWithSize(width, height, vips.InterestingNone, vips.SizeDown); err != nil {
+		return nil, err
+	}
+
+	return exportWebp(variant, options)
+}
@@ -0,0 +1,69 @@
+package thumbnail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWidths(t *testing.T) {
+	// when
+	widths, err := ParseWidths("200, 400,800")
+	defaults, defaultErr := ParseWidths("")
+	_, invalidErr := ParseWidths("200,abc")
+	_, tooLargeErr := ParseWidths("4000")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []int{200, 400, 800}, widths)
+	assert.NoError(t, defaultErr)
+	assert.Equal(t, DefaultSetWidths, defaults)
+	assert.True(t, errors.Is(invalidErr, ErrInvalidWidth))
+	assert.True(t, errors.Is(tooLargeErr, ErrInvalidWidth))
+}
+
+func TestParseDPRs(t *testing.T) {
+	// when
+	dprs, err := ParseDPRs("1,1.5,2")
+	defaults, defaultErr := ParseDPRs("")
+	_, invalidErr := ParseDPRs("0.5")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 1.5, 2}, dprs)
+	assert.NoError(t, defaultErr)
+	assert.Equal(t, []float64{1}, defaults)
+	assert.True(t, errors.Is(invalidErr, ErrInvalidDPR))
+}
+
+func TestVariantWidths_MultipliesDedupesAndSorts(t *testing.T) {
+	// when
+	widths, err := VariantWidths([]int{400, 200, 1600}, []float64{1, 2})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []int{200, 400, 800, 1600, MaxThumbnailWidth}, widths)
+}
+
+func TestVariantWidths_TooManyVariants(t *testing.T) {
+	// given
+	widths := []int{100, 200, 300, 400, 500, 600, 700, 800}
+
+	// when
+	_, err := VariantWidths(widths, []float64{1, 3})
+
+	// then
+	assert.True(t, errors.Is(err, ErrInvalidWidth))
+}
+
+func TestClientHintWidth(t *testing.T) {
+	// then
+	assert.Equal(t, 0, ClientHintWidth(0, 0))
+	assert.Equal(t, 0, ClientHintWidth(0, 1))
+	assert.Equal(t, 0, ClientHintWidth(350, 0))
+	assert.Equal(t, 800, ClientHintWidth(0, 2))
+	assert.Equal(t, 600, ClientHintWidth(0, 1.25))
+	assert.Equal(t, 1200, ClientHintWidth(900, 3))
+	assert.Equal(t, MaxThumbnailWidth, ClientHintWidth(5000, 0))
+}
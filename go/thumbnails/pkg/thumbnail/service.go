@@ -1,28 +1,52 @@
 package thumbnail
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"mime/multipart"
+	"slices"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/davidbyttow/govips/v2/vips"
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
-	"golang.org/x/net/context"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
 )
 
 type Service interface {
-	GenerateThumbnails(files []dto.FileEntryDto, album int) error
-	GenerateThumbnail(header *multipart.FileHeader, animate bool) ([]byte, error)
-	GenerateThumbnailByToken(fileToken uuid.UUID, animate bool) ([]byte, error)
-	GenerateThumbnailFromURL(url string, animate bool) ([]byte, error)
+	CancelAlbumJobs(albumId int, discard bool) (int64, error)
+	GenerateThumbnail(header *multipart.FileHeader, options Options) ([]byte, error)
+	GenerateThumbnailByToken(fileToken uuid.UUID, options Options) ([]byte, error)
+	GenerateThumbnailFromURL(url string, options Options) ([]byte, error)
+	GenerateThumbnailSetByToken(fileToken uuid.UUID, options Options, widths []int) ([]Variant, error)
+	GetAlbumStatus(albumId int) (AlbumProgress, error)
 	GetAllSupportedExtensions() []string
+	GetBackfillStatus() BackfillStatus
+	GetCallbackDeliveries(albumId int) ([]mod.CallbackDelivery, error)
+	GetQuarantinedFiles() ([]mod.FileFailure, error)
+	GetQueueStats() PoolStats
+	GetStoredThumbnails(fileIds []int) (map[int][]byte, error)
 	IsAlbumLoading(album int) bool
+	Purge(targets []PurgeTarget) (PurgeResult, error)
+	PurgeByAlbum(albumId int) (PurgeResult, error)
+	PurgeByFileId(fileId int) (PurgeResult, error)
+	PurgeByToken(fileToken uuid.UUID) (PurgeResult, error)
+	QueueThumbnails(albumId int, fileIds []int, crop CropMode, force bool, callbackUrl string) error
+	ResetFileFailure(fileId int) error
+	ResetQuarantinedFiles() (int64, error)
+	SetFocalPoint(fileToken uuid.UUID, focalPoint mod.FocalPoint) error
+	StartBackfill(restart bool) (BackfillStatus, error)
+	SubscribeAlbumProgress(albumId int) (<-chan AlbumProgress, func())
 }
 
 type service struct {
@@ -30,10 +54,18 @@ type service struct {
 	processor     Processor
 	ffmpegFormats []string
 	supportedExts []string
-	redisClient   *redis.Client
+	cache         cache.ThumbnailCache
+	inflight      *singleflight.Group
+	locker        cache.Locker
+	backfill      *backfill
+	jobs          *jobRunner
+	pool          *WorkerPool
+	progress      *progressHub
+	notifier      callback.Notifier
 }
 
-func NewService(daoService dao.Dao, rdb *redis.Client) Service {
+// NewService creates the thumbnail service, locker is optional and coalesces generations across instances
+func NewService(daoService dao.Dao, thumbnailCache cache.ThumbnailCache, locker cache.Locker, notifier callback.Notifier) Service {
 	// Initialize vips library
 	vips.Startup(&vips.Config{})
 	vips.LoggingSettings(nil, vips.LogLevelError)
@@ -50,12 +82,30 @@ func NewService(daoService dao.Dao, rdb *redis.Client) Service {
 	// Create the thumbnailProcessor
 	thumbnailProcessor := NewProcessor(videoFormats, imageFormats)
 
+	// Requests, album jobs, the backfill and the pipeline re-render share the same pool
+	pool := NewWorkerPool(PoolConfigFromEnv())
+	failures := FailureConfigFromEnv()
+
+	thumbnailBackfill := newBackfill(daoService, thumbnailProcessor, pool, failures, locker, BackfillConfigFromEnv())
+	go thumbnailBackfill.schedule()
+	go newRerender(daoService, thumbnailProcessor, pool, locker, RerenderConfigFromEnv()).run()
+	progress := newProgressHub(thumbnailCache)
+	jobs := newJobRunner(daoService, thumbnailProcessor, pool, failures, progress, notifier, JobConfigFromEnv())
+	jobs.start()
+
 	return &service{
 		dao:           daoService,
 		processor:     thumbnailProcessor,
 		ffmpegFormats: videoFormats,
 		supportedExts: imageFormats,
-		redisClient:   rdb,
+		cache:         thumbnailCache,
+		inflight:      &singleflight.Group{},
+		locker:        locker,
+		backfill:      thumbnailBackfill,
+		jobs:          jobs,
+		pool:          pool,
+		progress:      progress,
+		notifier:      notifier,
 	}
 }
 
@@ -64,24 +114,232 @@ func (s service) GetAllSupportedExtensions() []string {
 	return append(s.ffmpegFormats, s.supportedExts...)
 }
 
-// IsAlbumLoading checks if an album is currently being processed
+// IsAlbumLoading checks if an album is currently being processed or has a job waiting to run on any instance
 func (s service) IsAlbumLoading(albumId int) bool {
-	_, loaded := albumProcessing.Load(albumId)
-	return loaded
+	if _, loaded := albumProcessing.Load(albumId); loaded {
+		return true
+	}
+	unfinished, err := s.dao.HasUnfinishedJob(albumId)
+	if err != nil {
+		log.Error().Err(err).Int("albumId", albumId).Msg("failed to check for unfinished thumbnail jobs")
+	}
+	return unfinished
+}
+
+// GetStoredThumbnails returns the thumbnails the batch processor stored for the given files, keyed by file id.
+// files without a thumbnail are omitted
+func (s service) GetStoredThumbnails(fileIds []int) (map[int][]byte, error) {
+	if err := ValidateFileIds(fileIds); err != nil {
+		return nil, err
+	}
+	return s.dao.GetThumbnails(lo.Uniq(fileIds))
+}
+
+// StartBackfill starts generating the missing thumbnails of album files in the background
+func (s service) StartBackfill(restart bool) (BackfillStatus, error) {
+	return s.backfill.start(restart)
+}
+
+// GetBackfillStatus returns the progress of the current or last backfill run
+func (s service) GetBackfillStatus() BackfillStatus {
+	return s.backfill.getStatus()
+}
+
+// GetAlbumStatus returns the progress of the latest job of an album. the state comes from the job, the file counts
+// from the instance running it
+func (s service) GetAlbumStatus(albumId int) (AlbumProgress, error) {
+	job, err := s.dao.GetLatestJob(albumId)
+	if err != nil {
+		return AlbumProgress{}, err
+	}
+	if job == nil {
+		return AlbumProgress{}, fmt.Errorf("%w: album %d", ErrJobNotFound, albumId)
+	}
+
+	progress, found := s.progress.load(albumId)
+	if !found || progress.JobId != *job.Id {
+		var files []dto.FileEntryDto
+		_ = json.Unmarshal([]byte(job.Files), &files)
+		progress = AlbumProgress{
+			AlbumId: albumId,
+			JobId:   *job.Id,
+			Total:   len(files),
+		}
+	}
+	progress.State = job.Status
+	progress.Attempt = job.Attempts
+	if job.LastError != nil {
+		progress.LastError = *job.LastError
+	}
+	return progress, nil
+}
+
+// SubscribeAlbumProgress returns the progress changes of the album jobs run by this instance until the returned func
+// is called
+func (s service) SubscribeAlbumProgress(albumId int) (<-chan AlbumProgress, func()) {
+	return s.progress.subscribe(albumId)
+}
+
+// CancelAlbumJobs cancels the queued and running jobs of an album. a running job stops taking files and interrupts its
+// renders, the thumbnails it rendered but hasn't stored yet are stored unless discard is set
+func (s service) CancelAlbumJobs(albumId int, discard bool) (int64, error) {
+	cancelled, err := s.dao.CancelJobs(albumId, discard)
+	if err != nil {
+		return 0, err
+	}
+	if cancelled == 0 {
+		return 0, fmt.Errorf("%w: album %d has no queued or running job", ErrJobNotFound, albumId)
+	}
+	s.jobs.cancel(albumId, discard)
+	return cancelled, nil
 }
 
-// GenerateThumbnails processes a batch of files to generate thumbnails
-func (s service) GenerateThumbnails(files []dto.FileEntryDto, albumId int) error {
-	bulkBatchProcessor := NewBatchProcessor(s.dao, s.processor, files, albumId)
-	return bulkBatchProcessor.Process()
+// GetQueueStats returns the queue depth and wait times of the interactive and batch work of this instance
+func (s service) GetQueueStats() PoolStats {
+	return s.pool.Stats()
+}
+
+// GetCallbackDeliveries returns the latest completion event deliveries of the jobs of an album, newest first
+func (s service) GetCallbackDeliveries(albumId int) ([]mod.CallbackDelivery, error) {
+	return s.dao.GetCallbackDeliveries(albumId, callbackDeliveryLimit)
+}
+
+// GetQuarantinedFiles returns the files album jobs skip until their failure is reset, most recently failed first
+func (s service) GetQuarantinedFiles() ([]mod.FileFailure, error) {
+	return s.dao.GetQuarantinedFiles(quarantineListLimit)
+}
+
+// ResetFileFailure clears the failures of a file, quarantined or waiting for a retry, so the next album job renders it
+func (s service) ResetFileFailure(fileId int) error {
+	reset, err := s.dao.DeleteFileFailures([]int{fileId})
+	if err != nil {
+		return err
+	}
+	if reset == 0 {
+		return fmt.Errorf("%w: %d", ErrFileFailureNotFound, fileId)
+	}
+	return nil
 }
 
-func (s service) GenerateThumbnail(header *multipart.FileHeader, animate bool) ([]byte, error) {
+// ResetQuarantinedFiles clears the failures of every quarantined file, files waiting for a retry keep theirs
+func (s service) ResetQuarantinedFiles() (int64, error) {
+	return s.dao.DeleteQuarantinedFiles()
+}
+
+// QueueThumbnails persists a job generating the thumbnails of the files of an album, or only of the given files of it,
+// it is run by the job workers of any instance and survives restarts. the files are looked up in the album, so files
+// of other albums and expired, protected or unsupported files are left out, and no job is queued when none remain.
+// files posted while the album has a queued or running job with the same crop, force and callback are merged into that
+// job, a running job picks them up at its next lease renewal. force renders the files that already have a thumbnail
+// again. callbackUrl is optional and gets the completion event of the job
+func (s service) QueueThumbnails(albumId int, fileIds []int, crop CropMode, force bool, callbackUrl string) error {
+	for _, fileId := range fileIds {
+		if fileId <= 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidFileIds, fileId)
+		}
+	}
+	if callbackUrl != "" {
+		if err := s.notifier.ValidateURL(callbackUrl); err != nil {
+			return err
+		}
+	}
+	files, err := s.albumThumbnailFiles(albumId, lo.Uniq(fileIds))
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	merged, err := s.mergeIntoJob(files, albumId, crop, force, callbackUrl)
+	if err != nil || merged {
+		return err
+	}
+
+	encoded, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	job := mod.ThumbnailJob{
+		AlbumId:     albumId,
+		Crop:        string(crop),
+		Force:       force,
+		Files:       string(encoded),
+		CallbackUrl: lo.EmptyableToPtr(callbackUrl),
+	}
+	if err := s.dao.CreateJob(&job); err != nil {
+		return err
+	}
+	s.jobs.notify()
+	return nil
+}
+
+// albumThumbnailFiles returns the files of an album the processor supports, or only the given ones when fileIds isn't
+// empty
+func (s service) albumThumbnailFiles(albumId int, fileIds []int) ([]dto.FileEntryDto, error) {
+	fileEntries, err := s.dao.GetAlbumThumbnailFiles(albumId, fileIds)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrAlbumNotFound, albumId)
+		}
+		return nil, err
+	}
+	files := lo.FilterMap(fileEntries, func(fileEntry mod.FileEntry, _ int) (dto.FileEntryDto, bool) {
+		file := dto.FromModel(fileEntry)
+		return file, s.processor.SupportsFile(file)
+	})
+	if len(files) == 0 {
+		log.Debug().Int("albumId", albumId).Msg("no album files to generate thumbnails for")
+	}
+	return files, nil
+}
+
+// mergeIntoJob adds the files to the unfinished job of an album, leaving out the files it already has. false is returned
+// when there's no job to merge into, or it kept changing under the merge
+func (s service) mergeIntoJob(files []dto.FileEntryDto, albumId int, crop CropMode, force bool, callbackUrl string) (bool, error) {
+	for range jobMergeAttempts {
+		job, err := s.dao.GetUnfinishedJob(albumId)
+		if err != nil || job == nil {
+			return false, err
+		}
+		if job.Crop != string(crop) || job.Force != force || (callbackUrl != "" && callbackUrl != lo.FromPtr(job.CallbackUrl)) {
+			// the files are rendered or reported differently, they get a job of their own
+			return false, nil
+		}
+
+		var queued []dto.FileEntryDto
+		if err := json.Unmarshal([]byte(job.Files), &queued); err != nil {
+			return false, err
+		}
+		queuedIds := lo.SliceToMap(queued, func(file dto.FileEntryDto) (int, struct{}) {
+			return file.Id, struct{}{}
+		})
+		added := lo.Filter(files, func(file dto.FileEntryDto, _ int) bool {
+			_, found := queuedIds[file.Id]
+			return !found
+		})
+		if len(added) == 0 {
+			return true, nil
+		}
+
+		encoded, err := json.Marshal(append(queued, added...))
+		if err != nil {
+			return false, err
+		}
+		updated, err := s.dao.UpdateJobFiles(*job.Id, job.Revision, string(encoded))
+		if err != nil {
+			return false, err
+		}
+		if updated {
+			log.Debug().Int("jobId", *job.Id).Int("albumId", albumId).Int("files", len(added)).Msg("merged files into thumbnail job")
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s service) GenerateThumbnail(header *multipart.FileHeader, options Options) ([]byte, error) {
 	if !s.processor.SupportsMultipartFile(header) {
 		return nil, fmt.Errorf("unsupported file type for: %s", header.Filename)
 	}
 
-	cacheKey, err := s.generateCacheKeyForMultipart(header, animate)
+	cacheKey, err := s.generateCacheKeyForMultipart(header, options)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to generate cache key")
 	}
@@ -90,87 +348,315 @@ func (s service) GenerateThumbnail(header *multipart.FileHeader, animate bool) (
 		return thumbnail, nil
 	}
 
-	thumbnail, err := s.processMultipartFile(header, animate)
-	if err != nil {
-		return nil, err
+	if cacheKey == "" {
+		return s.processMultipartFile(header, options)
 	}
 
-	if cacheKey != "" {
+	return s.generateOnce(cacheKey, func() ([]byte, error) {
+		thumbnail, err := s.processMultipartFile(header, options)
+		if err != nil {
+			return nil, err
+		}
 		s.storeThumbnailInCache(cacheKey, thumbnail, time.Minute*10)
+		return thumbnail, nil
+	})
+}
+
+func (s service) GenerateThumbnailByToken(fileToken uuid.UUID, options Options) ([]byte, error) {
+	cacheKey := s.getTokenCacheKey(fileToken, options)
+
+	if thumbnail := s.getThumbnailFromCache(cacheKey); thumbnail != nil {
+		return thumbnail, nil
 	}
 
-	return thumbnail, nil
+	return s.generateOnce(cacheKey, func() ([]byte, error) {
+		fileEntryModel, err := s.dao.GetFileEntry(fileToken)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, err)
+		}
+
+		fileEntryDto := dto.FromModel(*fileEntryModel)
+
+		if !s.processor.SupportsFile(fileEntryDto) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, fileEntryDto.MediaType)
+		}
+
+		options.FocalPoint = fileEntryDto.FocalPoint
+		thumbnail, err := s.renderShared(options.renderKey(fileEntryDto.Checksum), func() ([]byte, error) {
+			thumbnail, err := runInteractive(s.pool, s.processor.DecodeCost(fileEntryDto), func() ([]byte, error) {
+				// shared by every request waiting for the thumbnail, so none of them can cancel it
+				return s.processor.GenerateThumbnail(context.Background(), fileEntryDto, options)
+			})
+			if err != nil {
+				return nil, err
+			}
+			return stampRenderTime(thumbnail, time.Now()), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// the token key is kept as well, purging and focal point changes find a file's thumbnails by its token
+		s.storeThumbnailInCache(cacheKey, thumbnail, time.Hour*24*365)
+		return thumbnail, nil
+	})
 }
 
-func (s service) GenerateThumbnailByToken(fileToken uuid.UUID, animate bool) ([]byte, error) {
-	cacheKey := fmt.Sprintf("%s:%s", fileToken.String(), s.getAnimateKey(animate))
+// renderShared renders a thumbnail once for all files with the same contents. a thumbnail with the render key is looked
+// for in the cache and then in the stored album thumbnails before rendering, files without a render key always render
+func (s service) renderShared(renderKey string, render func() ([]byte, error)) ([]byte, error) {
+	if renderKey == "" {
+		return render()
+	}
+	if thumbnail := s.getThumbnailFromCache(renderKey); thumbnail != nil {
+		return thumbnail, nil
+	}
 
-	if thumbnail := s.getThumbnailFromCache(cacheKey); thumbnail != nil {
+	return s.generateOnce(renderKey, func() ([]byte, error) {
+		stored, err := s.dao.GetThumbnailRowsByRenderKey([]string{renderKey})
+		if err != nil {
+			log.Error().Err(err).Str("key", renderKey).Msg("failed to get stored thumbnail by render key")
+		}
+		row, found := stored[renderKey]
+		thumbnail := row.Content
+		if !found {
+			thumbnail, err = render()
+			if err != nil {
+				return nil, err
+			}
+		} else if _, stamped := ReadRenderTime(thumbnail); !stamped {
+			// album thumbnails aren't stamped when they are rendered, the row knows when it was stored
+			thumbnail = stampRenderTime(thumbnail, storedAt(row))
+		}
+		s.storeThumbnailInCache(renderKey, thumbnail, time.Hour*24*365)
 		return thumbnail, nil
+	})
+}
+
+// storedAt is when a stored thumbnail was last written
+func storedAt(row mod.Thumbnail) time.Time {
+	if row.UpdatedAt.IsZero() {
+		return row.CreatedAt
+	}
+	return row.UpdatedAt
+}
+
+// GenerateThumbnailSetByToken returns a thumbnail for every width, only the widths missing from the cache are rendered
+func (s service) GenerateThumbnailSetByToken(fileToken uuid.UUID, options Options, widths []int) ([]Variant, error) {
+	cacheKeys := make([]string, len(widths))
+	for i, width := range widths {
+		cacheKeys[i] = s.getTokenCacheKey(fileToken, options.withWidth(width))
+	}
+
+	variants := make([]Variant, len(widths))
+	var missing []int
+	for i, thumbnail := range s.getThumbnailsFromCache(cacheKeys) {
+		if thumbnail == nil {
+			missing = append(missing, widths[i])
+			continue
+		}
+		variants[i] = Variant{Width: widths[i], Thumbnail: thumbnail}
 	}
+	if len(missing) == 0 {
+		return variants, nil
+	}
+
+	// concurrent requests for the same set share one decode
+	setKey := fmt.Sprintf("set:%s:%s:%v", fileToken.String(), options.key(), missing)
+	result, err, _ := s.inflight.Do(setKey, func() (any, error) {
+		fileEntryModel, err := s.dao.GetFileEntry(fileToken)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, err)
+		}
 
-	fileEntryModel, err := s.dao.GetFileEntry(fileToken)
+		fileEntryDto := dto.FromModel(*fileEntryModel)
+
+		if !s.processor.SupportsFile(fileEntryDto) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, fileEntryDto.MediaType)
+		}
+
+		options.FocalPoint = fileEntryDto.FocalPoint
+		return s.renderSharedSet(fileEntryDto, options, missing)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, err)
+		return nil, err
 	}
 
-	fileEntryDto := dto.FromModel(*fileEntryModel)
+	for _, variant := range result.([]Variant) {
+		i := slices.Index(widths, variant.Width)
+		if i == -1 {
+			continue
+		}
+		variants[i] = variant
+		s.storeThumbnailInCache(cacheKeys[i], variant.Thumbnail, time.Hour*24*365)
+	}
+	return variants, nil
+}
 
-	if !s.processor.SupportsFile(fileEntryDto) {
-		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, fileEntryDto.MediaType)
+// renderSharedSet renders the widths of a set, reusing the widths already rendered for a file with the same contents
+func (s service) renderSharedSet(file dto.FileEntryDto, options Options, widths []int) ([]Variant, error) {
+	renderKeys := lo.Map(widths, func(width int, _ int) string {
+		return options.withWidth(width).renderKey(file.Checksum)
+	})
+
+	var variants []Variant
+	missing := widths
+	if file.Checksum != "" {
+		missing = nil
+		cached := s.getThumbnailsFromCache(renderKeys)
+		for i, width := range widths {
+			if i < len(cached) && cached[i] != nil {
+				variants = append(variants, Variant{Width: width, Thumbnail: cached[i]})
+				continue
+			}
+			missing = append(missing, width)
+		}
+		if len(missing) == 0 {
+			return variants, nil
+		}
 	}
 
-	thumbnail, err := s.processor.GenerateThumbnail(fileEntryDto, animate)
+	rendered, err := runInteractive(s.pool, s.processor.DecodeCost(file), func() ([]Variant, error) {
+		return s.processor.GenerateThumbnailSet(file, options, missing)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	s.storeThumbnailInCache(cacheKey, thumbnail, time.Hour*24*365)
-	return thumbnail, nil
+	renderedAt := time.Now()
+	for _, variant := range rendered {
+		variant.Thumbnail = stampRenderTime(variant.Thumbnail, renderedAt)
+		variants = append(variants, variant)
+		if i := slices.Index(widths, variant.Width); i != -1 && renderKeys[i] != "" {
+			s.storeThumbnailInCache(renderKeys[i], variant.Thumbnail, time.Hour*24*365)
+		}
+	}
+	return variants, nil
 }
 
-func (s service) GenerateThumbnailFromURL(url string, animate bool) ([]byte, error) {
-	cacheKey := fmt.Sprintf("url:%s:%s", url, s.getAnimateKey(animate))
+func (s service) GenerateThumbnailFromURL(url string, options Options) ([]byte, error) {
+	cacheKey := fmt.Sprintf("url:%s:%s", url, options.key())
 
 	if thumbnail := s.getThumbnailFromCache(cacheKey); thumbnail != nil {
 		return thumbnail, nil
 	}
 
-	thumbnail, err := s.processor.GenerateThumbnailFromURL(url, animate)
+	return s.generateOnce(cacheKey, func() ([]byte, error) {
+		// the size of the file is only known once it is downloaded
+		thumbnail, err := runInteractive(s.pool, fallbackDecodeCost, func() ([]byte, error) {
+			return s.processor.GenerateThumbnailFromURL(url, options)
+		})
+		if err != nil {
+			return nil, err
+		}
+		thumbnail = stampRenderTime(thumbnail, time.Now())
+
+		s.storeThumbnailInCache(cacheKey, thumbnail, time.Minute*10)
+		return thumbnail, nil
+	})
+}
+
+// generateOnce runs generate once for every concurrent request of the same cache key. with a locker, other instances
+// wait for the instance holding the lock and read its thumbnail from the cache
+func (s service) generateOnce(cacheKey string, generate func() ([]byte, error)) ([]byte, error) {
+	result, err, _ := s.inflight.Do(cacheKey, func() (any, error) {
+		if s.locker == nil {
+			return generate()
+		}
+		return s.generateLocked(cacheKey, generate)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result.([]byte), nil
+}
 
-	s.storeThumbnailInCache(cacheKey, thumbnail, time.Minute*10)
-	return thumbnail, nil
+func (s service) generateLocked(cacheKey string, generate func() ([]byte, error)) ([]byte, error) {
+	deadline := time.Now().Add(generationLockTTL)
+	for {
+		unlock, held, err := s.locker.TryLock(cacheKey, generationLockTTL)
+		if err != nil {
+			log.Error().Err(err).Str("key", cacheKey).Msg("failed to take generation lock")
+			return generate()
+		}
+		if held {
+			defer unlock()
+			// the holder before us may have finished between our cache miss and taking the lock
+			if thumbnail := s.getThumbnailFromCache(cacheKey); thumbnail != nil {
+				return thumbnail, nil
+			}
+			return generate()
+		}
+
+		// the lock expires after generationLockTTL, so a crashed holder only delays us until then
+		if time.Now().After(deadline) {
+			return generate()
+		}
+		time.Sleep(generationLockPollInterval)
+		if thumbnail := s.getThumbnailFromCache(cacheKey); thumbnail != nil {
+			return thumbnail, nil
+		}
+	}
+}
+
+// SetFocalPoint stores the focal point of a file and drops its cached cropped thumbnails so they are re-rendered
+func (s service) SetFocalPoint(fileToken uuid.UUID, focalPoint mod.FocalPoint) error {
+	if err := ValidateFocalPoint(focalPoint); err != nil {
+		return err
+	}
+
+	found, err := s.dao.SetFocalPoint(fileToken, focalPoint)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrFileNotFound, fileToken.String())
+	}
+
+	// cropped keys can have any width, so they are found by pattern rather than listed
+	for _, crop := range AllCropModes {
+		if crop == CropNone {
+			continue
+		}
+		pattern := fmt.Sprintf("%s:*:%s*", fileToken.String(), crop)
+		if err := s.cache.DeleteMatching(pattern); err != nil {
+			log.Error().Err(err).Str("token", fileToken.String()).Msg("failed to remove cropped thumbnails from cache")
+		}
+	}
+	return nil
 }
 
 func (s service) getThumbnailFromCache(key string) []byte {
-	result, err := s.redisClient.Get(context.Background(), key).Bytes()
+	result, err := s.cache.Get(key)
 	if err != nil {
-		if !errors.Is(err, redis.Nil) {
-			log.Error().Err(err).Str("key", key).Msg("failed to get thumbnail from Redis")
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			log.Error().Err(err).Str("key", key).Msg("failed to get thumbnail from cache")
 		}
 		return nil
 	}
 	return result
 }
 
-func (s service) storeThumbnailInCache(key string, thumbnail []byte, ttl time.Duration) {
-	_, err := s.redisClient.Set(context.Background(), key, thumbnail, ttl).Result()
+// getThumbnailsFromCache fetches several thumbnails at once, missing entries are nil
+func (s service) getThumbnailsFromCache(keys []string) [][]byte {
+	thumbnails, err := s.cache.GetMany(keys)
 	if err != nil {
-		log.Error().Err(err).Str("key", key).Msg("failed to store thumbnail in Redis")
+		log.Error().Err(err).Strs("keys", keys).Msg("failed to get thumbnails from cache")
 	}
+	return thumbnails
 }
 
-func (s service) getAnimateKey(animate bool) string {
-	if animate {
-		return "animated"
+func (s service) storeThumbnailInCache(key string, thumbnail []byte, ttl time.Duration) {
+	if err := s.cache.Set(key, thumbnail, ttl); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to store thumbnail in cache")
 	}
-	return "static"
 }
 
-func (s service) generateCacheKeyForMultipart(header *multipart.FileHeader, animate bool) (string, error) {
+func (s service) getTokenCacheKey(fileToken uuid.UUID, options Options) string {
+	return fmt.Sprintf("%s:%s", fileToken.String(), options.key())
+}
+
+func (s service) generateCacheKeyForMultipart(header *multipart.FileHeader, options Options) (string, error) {
 	file, err := header.Open()
 	if err != nil {
 		return "", err
@@ -178,7 +664,7 @@ func (s service) generateCacheKeyForMultipart(header *multipart.FileHeader, anim
 	defer file.Close()
 
 	fileHash := s.calculatePartialFileHash(file, header)
-	return fmt.Sprintf("hash:%s:%s", fileHash, s.getAnimateKey(animate)), nil
+	return fmt.Sprintf("hash:%s:%s", fileHash, options.key()), nil
 }
 
 func (s service) calculatePartialFileHash(file multipart.File, header *multipart.FileHeader) string {
@@ -195,12 +681,18 @@ func (s service) calculatePartialFileHash(file multipart.File, header *multipart
 	return fmt.Sprintf("%x", hasher.Sum64())
 }
 
-func (s service) processMultipartFile(header *multipart.FileHeader, animate bool) ([]byte, error) {
+func (s service) processMultipartFile(header *multipart.FileHeader, options Options) ([]byte, error) {
 	file, err := header.Open()
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	return s.processor.GenerateThumbnailFromMultipart(file, header, animate)
+	thumbnail, err := runInteractive(s.pool, multipartDecodeCost(header), func() ([]byte, error) {
+		return s.processor.GenerateThumbnailFromMultipart(file, header, options)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stampRenderTime(thumbnail, time.Now()), nil
 }
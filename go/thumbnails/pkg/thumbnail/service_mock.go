@@ -9,7 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	mock "github.com/stretchr/testify/mock"
-	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
 )
 
 // NewMockService creates a new instance of MockService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -39,9 +39,75 @@ func (_m *MockService) EXPECT() *MockService_Expecter {
 	return &MockService_Expecter{mock: &_m.Mock}
 }
 
+// CancelAlbumJobs provides a mock function for the type MockService
+func (_mock *MockService) CancelAlbumJobs(albumId int, discard bool) (int64, error) {
+	ret := _mock.Called(albumId, discard)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelAlbumJobs")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int, bool) (int64, error)); ok {
+		return returnFunc(albumId, discard)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int, bool) int64); ok {
+		r0 = returnFunc(albumId, discard)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int, bool) error); ok {
+		r1 = returnFunc(albumId, discard)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_CancelAlbumJobs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CancelAlbumJobs'
+type MockService_CancelAlbumJobs_Call struct {
+	*mock.Call
+}
+
+// CancelAlbumJobs is a helper method to define mock.On call
+//   - albumId int
+//   - discard bool
+func (_e *MockService_Expecter) CancelAlbumJobs(albumId interface{}, discard interface{}) *MockService_CancelAlbumJobs_Call {
+	return &MockService_CancelAlbumJobs_Call{Call: _e.mock.On("CancelAlbumJobs", albumId, discard)}
+}
+
+func (_c *MockService_CancelAlbumJobs_Call) Run(run func(albumId int, discard bool)) *MockService_CancelAlbumJobs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 bool
+		if args[1] != nil {
+			arg1 = args[1].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_CancelAlbumJobs_Call) Return(n int64, err error) *MockService_CancelAlbumJobs_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockService_CancelAlbumJobs_Call) RunAndReturn(run func(albumId int, discard bool) (int64, error)) *MockService_CancelAlbumJobs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GenerateThumbnail provides a mock function for the type MockService
-func (_mock *MockService) GenerateThumbnail(header *multipart.FileHeader, animate bool) ([]byte, error) {
-	ret := _mock.Called(header, animate)
+func (_mock *MockService) GenerateThumbnail(header *multipart.FileHeader, options Options) ([]byte, error) {
+	ret := _mock.Called(header, options)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateThumbnail")
@@ -49,18 +115,18 @@ func (_mock *MockService) GenerateThumbnail(header *multipart.FileHeader, animat
 
 	var r0 []byte
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(*multipart.FileHeader, bool) ([]byte, error)); ok {
-		return returnFunc(header, animate)
+	if returnFunc, ok := ret.Get(0).(func(*multipart.FileHeader, Options) ([]byte, error)); ok {
+		return returnFunc(header, options)
 	}
-	if returnFunc, ok := ret.Get(0).(func(*multipart.FileHeader, bool) []byte); ok {
-		r0 = returnFunc(header, animate)
+	if returnFunc, ok := ret.Get(0).(func(*multipart.FileHeader, Options) []byte); ok {
+		r0 = returnFunc(header, options)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(*multipart.FileHeader, bool) error); ok {
-		r1 = returnFunc(header, animate)
+	if returnFunc, ok := ret.Get(1).(func(*multipart.FileHeader, Options) error); ok {
+		r1 = returnFunc(header, options)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -74,20 +140,20 @@ type MockService_GenerateThumbnail_Call struct {
 
 // GenerateThumbnail is a helper method to define mock.On call
 //   - header *multipart.FileHeader
-//   - animate bool
-func (_e *MockService_Expecter) GenerateThumbnail(header interface{}, animate interface{}) *MockService_GenerateThumbnail_Call {
-	return &MockService_GenerateThumbnail_Call{Call: _e.mock.On("GenerateThumbnail", header, animate)}
+//   - options Options
+func (_e *MockService_Expecter) GenerateThumbnail(header interface{}, options interface{}) *MockService_GenerateThumbnail_Call {
+	return &MockService_GenerateThumbnail_Call{Call: _e.mock.On("GenerateThumbnail", header, options)}
 }
 
-func (_c *MockService_GenerateThumbnail_Call) Run(run func(header *multipart.FileHeader, animate bool)) *MockService_GenerateThumbnail_Call {
+func (_c *MockService_GenerateThumbnail_Call) Run(run func(header *multipart.FileHeader, options Options)) *MockService_GenerateThumbnail_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 *multipart.FileHeader
 		if args[0] != nil {
 			arg0 = args[0].(*multipart.FileHeader)
 		}
-		var arg1 bool
+		var arg1 Options
 		if args[1] != nil {
-			arg1 = args[1].(bool)
+			arg1 = args[1].(Options)
 		}
 		run(
 			arg0,
@@ -102,14 +168,14 @@ func (_c *MockService_GenerateThumbnail_Call) Return(bytes []byte, err error) *M
 	return _c
 }
 
-func (_c *MockService_GenerateThumbnail_Call) RunAndReturn(run func(header *multipart.FileHeader, animate bool) ([]byte, error)) *MockService_GenerateThumbnail_Call {
+func (_c *MockService_GenerateThumbnail_Call) RunAndReturn(run func(header *multipart.FileHeader, options Options) ([]byte, error)) *MockService_GenerateThumbnail_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
 // GenerateThumbnailByToken provides a mock function for the type MockService
-func (_mock *MockService) GenerateThumbnailByToken(fileToken uuid.UUID, animate bool) ([]byte, error) {
-	ret := _mock.Called(fileToken, animate)
+func (_mock *MockService) GenerateThumbnailByToken(fileToken uuid.UUID, options Options) ([]byte, error) {
+	ret := _mock.Called(fileToken, options)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateThumbnailByToken")
@@ -117,18 +183,18 @@ func (_mock *MockService) GenerateThumbnailByToken(fileToken uuid.UUID, animate
 
 	var r0 []byte
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, bool) ([]byte, error)); ok {
-		return returnFunc(fileToken, animate)
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, Options) ([]byte, error)); ok {
+		return returnFunc(fileToken, options)
 	}
-	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, bool) []byte); ok {
-		r0 = returnFunc(fileToken, animate)
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, Options) []byte); ok {
+		r0 = returnFunc(fileToken, options)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(uuid.UUID, bool) error); ok {
-		r1 = returnFunc(fileToken, animate)
+	if returnFunc, ok := ret.Get(1).(func(uuid.UUID, Options) error); ok {
+		r1 = returnFunc(fileToken, options)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -142,20 +208,20 @@ type MockService_GenerateThumbnailByToken_Call struct {
 
 // GenerateThumbnailByToken is a helper method to define mock.On call
 //   - fileToken uuid.UUID
-//   - animate bool
-func (_e *MockService_Expecter) GenerateThumbnailByToken(fileToken interface{}, animate interface{}) *MockService_GenerateThumbnailByToken_Call {
-	return &MockService_GenerateThumbnailByToken_Call{Call: _e.mock.On("GenerateThumbnailByToken", fileToken, animate)}
+//   - options Options
+func (_e *MockService_Expecter) GenerateThumbnailByToken(fileToken interface{}, options interface{}) *MockService_GenerateThumbnailByToken_Call {
+	return &MockService_GenerateThumbnailByToken_Call{Call: _e.mock.On("GenerateThumbnailByToken", fileToken, options)}
 }
 
-func (_c *MockService_GenerateThumbnailByToken_Call) Run(run func(fileToken uuid.UUID, animate bool)) *MockService_GenerateThumbnailByToken_Call {
+func (_c *MockService_GenerateThumbnailByToken_Call) Run(run func(fileToken uuid.UUID, options Options)) *MockService_GenerateThumbnailByToken_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 uuid.UUID
 		if args[0] != nil {
 			arg0 = args[0].(uuid.UUID)
 		}
-		var arg1 bool
+		var arg1 Options
 		if args[1] != nil {
-			arg1 = args[1].(bool)
+			arg1 = args[1].(Options)
 		}
 		run(
 			arg0,
@@ -170,14 +236,14 @@ func (_c *MockService_GenerateThumbnailByToken_Call) Return(bytes []byte, err er
 	return _c
 }
 
-func (_c *MockService_GenerateThumbnailByToken_Call) RunAndReturn(run func(fileToken uuid.UUID, animate bool) ([]byte, error)) *MockService_GenerateThumbnailByToken_Call {
+func (_c *MockService_GenerateThumbnailByToken_Call) RunAndReturn(run func(fileToken uuid.UUID, options Options) ([]byte, error)) *MockService_GenerateThumbnailByToken_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
 // GenerateThumbnailFromURL provides a mock function for the type MockService
-func (_mock *MockService) GenerateThumbnailFromURL(url string, animate bool) ([]byte, error) {
-	ret := _mock.Called(url, animate)
+func (_mock *MockService) GenerateThumbnailFromURL(url string, options Options) ([]byte, error) {
+	ret := _mock.Called(url, options)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateThumbnailFromURL")
@@ -185,18 +251,18 @@ func (_mock *MockService) GenerateThumbnailFromURL(url string, animate bool) ([]
 
 	var r0 []byte
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(string, bool) ([]byte, error)); ok {
-		return returnFunc(url, animate)
+	if returnFunc, ok := ret.Get(0).(func(string, Options) ([]byte, error)); ok {
+		return returnFunc(url, options)
 	}
-	if returnFunc, ok := ret.Get(0).(func(string, bool) []byte); ok {
-		r0 = returnFunc(url, animate)
+	if returnFunc, ok := ret.Get(0).(func(string, Options) []byte); ok {
+		r0 = returnFunc(url, options)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(string, bool) error); ok {
-		r1 = returnFunc(url, animate)
+	if returnFunc, ok := ret.Get(1).(func(string, Options) error); ok {
+		r1 = returnFunc(url, options)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -210,20 +276,20 @@ type MockService_GenerateThumbnailFromURL_Call struct {
 
 // GenerateThumbnailFromURL is a helper method to define mock.On call
 //   - url string
-//   - animate bool
-func (_e *MockService_Expecter) GenerateThumbnailFromURL(url interface{}, animate interface{}) *MockService_GenerateThumbnailFromURL_Call {
-	return &MockService_GenerateThumbnailFromURL_Call{Call: _e.mock.On("GenerateThumbnailFromURL", url, animate)}
+//   - options Options
+func (_e *MockService_Expecter) GenerateThumbnailFromURL(url interface{}, options interface{}) *MockService_GenerateThumbnailFromURL_Call {
+	return &MockService_GenerateThumbnailFromURL_Call{Call: _e.mock.On("GenerateThumbnailFromURL", url, options)}
 }
 
-func (_c *MockService_GenerateThumbnailFromURL_Call) Run(run func(url string, animate bool)) *MockService_GenerateThumbnailFromURL_Call {
+func (_c *MockService_GenerateThumbnailFromURL_Call) Run(run func(url string, options Options)) *MockService_GenerateThumbnailFromURL_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
 			arg0 = args[0].(string)
 		}
-		var arg1 bool
+		var arg1 Options
 		if args[1] != nil {
-			arg1 = args[1].(bool)
+			arg1 = args[1].(Options)
 		}
 		run(
 			arg0,
@@ -238,64 +304,141 @@ func (_c *MockService_GenerateThumbnailFromURL_Call) Return(bytes []byte, err er
 	return _c
 }
 
-func (_c *MockService_GenerateThumbnailFromURL_Call) RunAndReturn(run func(url string, animate bool) ([]byte, error)) *MockService_GenerateThumbnailFromURL_Call {
+func (_c *MockService_GenerateThumbnailFromURL_Call) RunAndReturn(run func(url string, options Options) ([]byte, error)) *MockService_GenerateThumbnailFromURL_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GenerateThumbnails provides a mock function for the type MockService
-func (_mock *MockService) GenerateThumbnails(files []dto.FileEntryDto, album int) error {
-	ret := _mock.Called(files, album)
+// GenerateThumbnailSetByToken provides a mock function for the type MockService
+func (_mock *MockService) GenerateThumbnailSetByToken(fileToken uuid.UUID, options Options, widths []int) ([]Variant, error) {
+	ret := _mock.Called(fileToken, options, widths)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GenerateThumbnails")
+		panic("no return value specified for GenerateThumbnailSetByToken")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func([]dto.FileEntryDto, int) error); ok {
-		r0 = returnFunc(files, album)
+	var r0 []Variant
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, Options, []int) ([]Variant, error)); ok {
+		return returnFunc(fileToken, options, widths)
+	}
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, Options, []int) []Variant); ok {
+		r0 = returnFunc(fileToken, options, widths)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Variant)
+		}
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func(uuid.UUID, Options, []int) error); ok {
+		r1 = returnFunc(fileToken, options, widths)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// MockService_GenerateThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenerateThumbnails'
-type MockService_GenerateThumbnails_Call struct {
+// MockService_GenerateThumbnailSetByToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GenerateThumbnailSetByToken'
+type MockService_GenerateThumbnailSetByToken_Call struct {
 	*mock.Call
 }
 
-// GenerateThumbnails is a helper method to define mock.On call
-//   - files []dto.FileEntryDto
-//   - album int
-func (_e *MockService_Expecter) GenerateThumbnails(files interface{}, album interface{}) *MockService_GenerateThumbnails_Call {
-	return &MockService_GenerateThumbnails_Call{Call: _e.mock.On("GenerateThumbnails", files, album)}
+// GenerateThumbnailSetByToken is a helper method to define mock.On call
+//   - fileToken uuid.UUID
+//   - options Options
+//   - widths []int
+func (_e *MockService_Expecter) GenerateThumbnailSetByToken(fileToken interface{}, options interface{}, widths interface{}) *MockService_GenerateThumbnailSetByToken_Call {
+	return &MockService_GenerateThumbnailSetByToken_Call{Call: _e.mock.On("GenerateThumbnailSetByToken", fileToken, options, widths)}
 }
 
-func (_c *MockService_GenerateThumbnails_Call) Run(run func(files []dto.FileEntryDto, album int)) *MockService_GenerateThumbnails_Call {
+func (_c *MockService_GenerateThumbnailSetByToken_Call) Run(run func(fileToken uuid.UUID, options Options, widths []int)) *MockService_GenerateThumbnailSetByToken_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 []dto.FileEntryDto
+		var arg0 uuid.UUID
 		if args[0] != nil {
-			arg0 = args[0].([]dto.FileEntryDto)
+			arg0 = args[0].(uuid.UUID)
 		}
-		var arg1 int
+		var arg1 Options
 		if args[1] != nil {
-			arg1 = args[1].(int)
+			arg1 = args[1].(Options)
+		}
+		var arg2 []int
+		if args[2] != nil {
+			arg2 = args[2].([]int)
 		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
 }
 
-func (_c *MockService_GenerateThumbnails_Call) Return(err error) *MockService_GenerateThumbnails_Call {
-	_c.Call.Return(err)
+func (_c *MockService_GenerateThumbnailSetByToken_Call) Return(variants []Variant, err error) *MockService_GenerateThumbnailSetByToken_Call {
+	_c.Call.Return(variants, err)
+	return _c
+}
+
+func (_c *MockService_GenerateThumbnailSetByToken_Call) RunAndReturn(run func(fileToken uuid.UUID, options Options, widths []int) ([]Variant, error)) *MockService_GenerateThumbnailSetByToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAlbumStatus provides a mock function for the type MockService
+func (_mock *MockService) GetAlbumStatus(albumId int) (AlbumProgress, error) {
+	ret := _mock.Called(albumId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAlbumStatus")
+	}
+
+	var r0 AlbumProgress
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int) (AlbumProgress, error)); ok {
+		return returnFunc(albumId)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int) AlbumProgress); ok {
+		r0 = returnFunc(albumId)
+	} else {
+		r0 = ret.Get(0).(AlbumProgress)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int) error); ok {
+		r1 = returnFunc(albumId)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_GetAlbumStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAlbumStatus'
+type MockService_GetAlbumStatus_Call struct {
+	*mock.Call
+}
+
+// GetAlbumStatus is a helper method to define mock.On call
+//   - albumId int
+func (_e *MockService_Expecter) GetAlbumStatus(albumId interface{}) *MockService_GetAlbumStatus_Call {
+	return &MockService_GetAlbumStatus_Call{Call: _e.mock.On("GetAlbumStatus", albumId)}
+}
+
+func (_c *MockService_GetAlbumStatus_Call) Run(run func(albumId int)) *MockService_GetAlbumStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_GetAlbumStatus_Call) Return(albumProgress AlbumProgress, err error) *MockService_GetAlbumStatus_Call {
+	_c.Call.Return(albumProgress, err)
 	return _c
 }
 
-func (_c *MockService_GenerateThumbnails_Call) RunAndReturn(run func(files []dto.FileEntryDto, album int) error) *MockService_GenerateThumbnails_Call {
+func (_c *MockService_GetAlbumStatus_Call) RunAndReturn(run func(albumId int) (AlbumProgress, error)) *MockService_GetAlbumStatus_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -346,35 +489,90 @@ func (_c *MockService_GetAllSupportedExtensions_Call) RunAndReturn(run func() []
 	return _c
 }
 
-// IsAlbumLoading provides a mock function for the type MockService
-func (_mock *MockService) IsAlbumLoading(album int) bool {
-	ret := _mock.Called(album)
+// GetBackfillStatus provides a mock function for the type MockService
+func (_mock *MockService) GetBackfillStatus() BackfillStatus {
+	ret := _mock.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for IsAlbumLoading")
+		panic("no return value specified for GetBackfillStatus")
 	}
 
-	var r0 bool
-	if returnFunc, ok := ret.Get(0).(func(int) bool); ok {
-		r0 = returnFunc(album)
+	var r0 BackfillStatus
+	if returnFunc, ok := ret.Get(0).(func() BackfillStatus); ok {
+		r0 = returnFunc()
 	} else {
-		r0 = ret.Get(0).(bool)
+		r0 = ret.Get(0).(BackfillStatus)
 	}
 	return r0
 }
 
-// MockService_IsAlbumLoading_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsAlbumLoading'
-type MockService_IsAlbumLoading_Call struct {
+// MockService_GetBackfillStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetBackfillStatus'
+type MockService_GetBackfillStatus_Call struct {
 	*mock.Call
 }
 
-// IsAlbumLoading is a helper method to define mock.On call
-//   - album int
-func (_e *MockService_Expecter) IsAlbumLoading(album interface{}) *MockService_IsAlbumLoading_Call {
-	return &MockService_IsAlbumLoading_Call{Call: _e.mock.On("IsAlbumLoading", album)}
+// GetBackfillStatus is a helper method to define mock.On call
+func (_e *MockService_Expecter) GetBackfillStatus() *MockService_GetBackfillStatus_Call {
+	return &MockService_GetBackfillStatus_Call{Call: _e.mock.On("GetBackfillStatus")}
 }
 
-func (_c *MockService_IsAlbumLoading_Call) Run(run func(album int)) *MockService_IsAlbumLoading_Call {
+func (_c *MockService_GetBackfillStatus_Call) Run(run func()) *MockService_GetBackfillStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockService_GetBackfillStatus_Call) Return(backfillStatus BackfillStatus) *MockService_GetBackfillStatus_Call {
+	_c.Call.Return(backfillStatus)
+	return _c
+}
+
+func (_c *MockService_GetBackfillStatus_Call) RunAndReturn(run func() BackfillStatus) *MockService_GetBackfillStatus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetCallbackDeliveries provides a mock function for the type MockService
+func (_mock *MockService) GetCallbackDeliveries(albumId int) ([]mod.CallbackDelivery, error) {
+	ret := _mock.Called(albumId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCallbackDeliveries")
+	}
+
+	var r0 []mod.CallbackDelivery
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int) ([]mod.CallbackDelivery, error)); ok {
+		return returnFunc(albumId)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int) []mod.CallbackDelivery); ok {
+		r0 = returnFunc(albumId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.CallbackDelivery)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int) error); ok {
+		r1 = returnFunc(albumId)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_GetCallbackDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCallbackDeliveries'
+type MockService_GetCallbackDeliveries_Call struct {
+	*mock.Call
+}
+
+// GetCallbackDeliveries is a helper method to define mock.On call
+//   - albumId int
+func (_e *MockService_Expecter) GetCallbackDeliveries(albumId interface{}) *MockService_GetCallbackDeliveries_Call {
+	return &MockService_GetCallbackDeliveries_Call{Call: _e.mock.On("GetCallbackDeliveries", albumId)}
+}
+
+func (_c *MockService_GetCallbackDeliveries_Call) Run(run func(albumId int)) *MockService_GetCallbackDeliveries_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 int
 		if args[0] != nil {
@@ -387,12 +585,824 @@ func (_c *MockService_IsAlbumLoading_Call) Run(run func(album int)) *MockService
 	return _c
 }
 
-func (_c *MockService_IsAlbumLoading_Call) Return(b bool) *MockService_IsAlbumLoading_Call {
-	_c.Call.Return(b)
+func (_c *MockService_GetCallbackDeliveries_Call) Return(callbackDeliverys []mod.CallbackDelivery, err error) *MockService_GetCallbackDeliveries_Call {
+	_c.Call.Return(callbackDeliverys, err)
 	return _c
 }
 
-func (_c *MockService_IsAlbumLoading_Call) RunAndReturn(run func(album int) bool) *MockService_IsAlbumLoading_Call {
+func (_c *MockService_GetCallbackDeliveries_Call) RunAndReturn(run func(albumId int) ([]mod.CallbackDelivery, error)) *MockService_GetCallbackDeliveries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetQuarantinedFiles provides a mock function for the type MockService
+func (_mock *MockService) GetQuarantinedFiles() ([]mod.FileFailure, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetQuarantinedFiles")
+	}
+
+	var r0 []mod.FileFailure
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() ([]mod.FileFailure, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() []mod.FileFailure); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mod.FileFailure)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_GetQuarantinedFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetQuarantinedFiles'
+type MockService_GetQuarantinedFiles_Call struct {
+	*mock.Call
+}
+
+// GetQuarantinedFiles is a helper method to define mock.On call
+func (_e *MockService_Expecter) GetQuarantinedFiles() *MockService_GetQuarantinedFiles_Call {
+	return &MockService_GetQuarantinedFiles_Call{Call: _e.mock.On("GetQuarantinedFiles")}
+}
+
+func (_c *MockService_GetQuarantinedFiles_Call) Run(run func()) *MockService_GetQuarantinedFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockService_GetQuarantinedFiles_Call) Return(fileFailures []mod.FileFailure, err error) *MockService_GetQuarantinedFiles_Call {
+	_c.Call.Return(fileFailures, err)
+	return _c
+}
+
+func (_c *MockService_GetQuarantinedFiles_Call) RunAndReturn(run func() ([]mod.FileFailure, error)) *MockService_GetQuarantinedFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetQueueStats provides a mock function for the type MockService
+func (_mock *MockService) GetQueueStats() PoolStats {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetQueueStats")
+	}
+
+	var r0 PoolStats
+	if returnFunc, ok := ret.Get(0).(func() PoolStats); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(PoolStats)
+	}
+	return r0
+}
+
+// MockService_GetQueueStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetQueueStats'
+type MockService_GetQueueStats_Call struct {
+	*mock.Call
+}
+
+// GetQueueStats is a helper method to define mock.On call
+func (_e *MockService_Expecter) GetQueueStats() *MockService_GetQueueStats_Call {
+	return &MockService_GetQueueStats_Call{Call: _e.mock.On("GetQueueStats")}
+}
+
+func (_c *MockService_GetQueueStats_Call) Run(run func()) *MockService_GetQueueStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockService_GetQueueStats_Call) Return(poolStats PoolStats) *MockService_GetQueueStats_Call {
+	_c.Call.Return(poolStats)
+	return _c
+}
+
+func (_c *MockService_GetQueueStats_Call) RunAndReturn(run func() PoolStats) *MockService_GetQueueStats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetStoredThumbnails provides a mock function for the type MockService
+func (_mock *MockService) GetStoredThumbnails(fileIds []int) (map[int][]byte, error) {
+	ret := _mock.Called(fileIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStoredThumbnails")
+	}
+
+	var r0 map[int][]byte
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]int) (map[int][]byte, error)); ok {
+		return returnFunc(fileIds)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]int) map[int][]byte); ok {
+		r0 = returnFunc(fileIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int][]byte)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func([]int) error); ok {
+		r1 = returnFunc(fileIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_GetStoredThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetStoredThumbnails'
+type MockService_GetStoredThumbnails_Call struct {
+	*mock.Call
+}
+
+// GetStoredThumbnails is a helper method to define mock.On call
+//   - fileIds []int
+func (_e *MockService_Expecter) GetStoredThumbnails(fileIds interface{}) *MockService_GetStoredThumbnails_Call {
+	return &MockService_GetStoredThumbnails_Call{Call: _e.mock.On("GetStoredThumbnails", fileIds)}
+}
+
+func (_c *MockService_GetStoredThumbnails_Call) Run(run func(fileIds []int)) *MockService_GetStoredThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []int
+		if args[0] != nil {
+			arg0 = args[0].([]int)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_GetStoredThumbnails_Call) Return(intToBytes map[int][]byte, err error) *MockService_GetStoredThumbnails_Call {
+	_c.Call.Return(intToBytes, err)
+	return _c
+}
+
+func (_c *MockService_GetStoredThumbnails_Call) RunAndReturn(run func(fileIds []int) (map[int][]byte, error)) *MockService_GetStoredThumbnails_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IsAlbumLoading provides a mock function for the type MockService
+func (_mock *MockService) IsAlbumLoading(album int) bool {
+	ret := _mock.Called(album)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsAlbumLoading")
+	}
+
+	var r0 bool
+	if returnFunc, ok := ret.Get(0).(func(int) bool); ok {
+		r0 = returnFunc(album)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	return r0
+}
+
+// MockService_IsAlbumLoading_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IsAlbumLoading'
+type MockService_IsAlbumLoading_Call struct {
+	*mock.Call
+}
+
+// IsAlbumLoading is a helper method to define mock.On call
+//   - album int
+func (_e *MockService_Expecter) IsAlbumLoading(album interface{}) *MockService_IsAlbumLoading_Call {
+	return &MockService_IsAlbumLoading_Call{Call: _e.mock.On("IsAlbumLoading", album)}
+}
+
+func (_c *MockService_IsAlbumLoading_Call) Run(run func(album int)) *MockService_IsAlbumLoading_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_IsAlbumLoading_Call) Return(b bool) *MockService_IsAlbumLoading_Call {
+	_c.Call.Return(b)
+	return _c
+}
+
+func (_c *MockService_IsAlbumLoading_Call) RunAndReturn(run func(album int) bool) *MockService_IsAlbumLoading_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Purge provides a mock function for the type MockService
+func (_mock *MockService) Purge(targets []PurgeTarget) (PurgeResult, error) {
+	ret := _mock.Called(targets)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Purge")
+	}
+
+	var r0 PurgeResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]PurgeTarget) (PurgeResult, error)); ok {
+		return returnFunc(targets)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]PurgeTarget) PurgeResult); ok {
+		r0 = returnFunc(targets)
+	} else {
+		r0 = ret.Get(0).(PurgeResult)
+	}
+	if returnFunc, ok := ret.Get(1).(func([]PurgeTarget) error); ok {
+		r1 = returnFunc(targets)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_Purge_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Purge'
+type MockService_Purge_Call struct {
+	*mock.Call
+}
+
+// Purge is a helper method to define mock.On call
+//   - targets []PurgeTarget
+func (_e *MockService_Expecter) Purge(targets interface{}) *MockService_Purge_Call {
+	return &MockService_Purge_Call{Call: _e.mock.On("Purge", targets)}
+}
+
+func (_c *MockService_Purge_Call) Run(run func(targets []PurgeTarget)) *MockService_Purge_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []PurgeTarget
+		if args[0] != nil {
+			arg0 = args[0].([]PurgeTarget)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_Purge_Call) Return(purgeResult PurgeResult, err error) *MockService_Purge_Call {
+	_c.Call.Return(purgeResult, err)
+	return _c
+}
+
+func (_c *MockService_Purge_Call) RunAndReturn(run func(targets []PurgeTarget) (PurgeResult, error)) *MockService_Purge_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeByAlbum provides a mock function for the type MockService
+func (_mock *MockService) PurgeByAlbum(albumId int) (PurgeResult, error) {
+	ret := _mock.Called(albumId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeByAlbum")
+	}
+
+	var r0 PurgeResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int) (PurgeResult, error)); ok {
+		return returnFunc(albumId)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int) PurgeResult); ok {
+		r0 = returnFunc(albumId)
+	} else {
+		r0 = ret.Get(0).(PurgeResult)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int) error); ok {
+		r1 = returnFunc(albumId)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_PurgeByAlbum_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeByAlbum'
+type MockService_PurgeByAlbum_Call struct {
+	*mock.Call
+}
+
+// PurgeByAlbum is a helper method to define mock.On call
+//   - albumId int
+func (_e *MockService_Expecter) PurgeByAlbum(albumId interface{}) *MockService_PurgeByAlbum_Call {
+	return &MockService_PurgeByAlbum_Call{Call: _e.mock.On("PurgeByAlbum", albumId)}
+}
+
+func (_c *MockService_PurgeByAlbum_Call) Run(run func(albumId int)) *MockService_PurgeByAlbum_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_PurgeByAlbum_Call) Return(purgeResult PurgeResult, err error) *MockService_PurgeByAlbum_Call {
+	_c.Call.Return(purgeResult, err)
+	return _c
+}
+
+func (_c *MockService_PurgeByAlbum_Call) RunAndReturn(run func(albumId int) (PurgeResult, error)) *MockService_PurgeByAlbum_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeByFileId provides a mock function for the type MockService
+func (_mock *MockService) PurgeByFileId(fileId int) (PurgeResult, error) {
+	ret := _mock.Called(fileId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeByFileId")
+	}
+
+	var r0 PurgeResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(int) (PurgeResult, error)); ok {
+		return returnFunc(fileId)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int) PurgeResult); ok {
+		r0 = returnFunc(fileId)
+	} else {
+		r0 = ret.Get(0).(PurgeResult)
+	}
+	if returnFunc, ok := ret.Get(1).(func(int) error); ok {
+		r1 = returnFunc(fileId)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_PurgeByFileId_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeByFileId'
+type MockService_PurgeByFileId_Call struct {
+	*mock.Call
+}
+
+// PurgeByFileId is a helper method to define mock.On call
+//   - fileId int
+func (_e *MockService_Expecter) PurgeByFileId(fileId interface{}) *MockService_PurgeByFileId_Call {
+	return &MockService_PurgeByFileId_Call{Call: _e.mock.On("PurgeByFileId", fileId)}
+}
+
+func (_c *MockService_PurgeByFileId_Call) Run(run func(fileId int)) *MockService_PurgeByFileId_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_PurgeByFileId_Call) Return(purgeResult PurgeResult, err error) *MockService_PurgeByFileId_Call {
+	_c.Call.Return(purgeResult, err)
+	return _c
+}
+
+func (_c *MockService_PurgeByFileId_Call) RunAndReturn(run func(fileId int) (PurgeResult, error)) *MockService_PurgeByFileId_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeByToken provides a mock function for the type MockService
+func (_mock *MockService) PurgeByToken(fileToken uuid.UUID) (PurgeResult, error) {
+	ret := _mock.Called(fileToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeByToken")
+	}
+
+	var r0 PurgeResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID) (PurgeResult, error)); ok {
+		return returnFunc(fileToken)
+	}
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID) PurgeResult); ok {
+		r0 = returnFunc(fileToken)
+	} else {
+		r0 = ret.Get(0).(PurgeResult)
+	}
+	if returnFunc, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = returnFunc(fileToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_PurgeByToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeByToken'
+type MockService_PurgeByToken_Call struct {
+	*mock.Call
+}
+
+// PurgeByToken is a helper method to define mock.On call
+//   - fileToken uuid.UUID
+func (_e *MockService_Expecter) PurgeByToken(fileToken interface{}) *MockService_PurgeByToken_Call {
+	return &MockService_PurgeByToken_Call{Call: _e.mock.On("PurgeByToken", fileToken)}
+}
+
+func (_c *MockService_PurgeByToken_Call) Run(run func(fileToken uuid.UUID)) *MockService_PurgeByToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 uuid.UUID
+		if args[0] != nil {
+			arg0 = args[0].(uuid.UUID)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_PurgeByToken_Call) Return(purgeResult PurgeResult, err error) *MockService_PurgeByToken_Call {
+	_c.Call.Return(purgeResult, err)
+	return _c
+}
+
+func (_c *MockService_PurgeByToken_Call) RunAndReturn(run func(fileToken uuid.UUID) (PurgeResult, error)) *MockService_PurgeByToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueueThumbnails provides a mock function for the type MockService
+func (_mock *MockService) QueueThumbnails(albumId int, fileIds []int, crop CropMode, force bool, callbackUrl string) error {
+	ret := _mock.Called(albumId, fileIds, crop, force, callbackUrl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueueThumbnails")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(int, []int, CropMode, bool, string) error); ok {
+		r0 = returnFunc(albumId, fileIds, crop, force, callbackUrl)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockService_QueueThumbnails_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueueThumbnails'
+type MockService_QueueThumbnails_Call struct {
+	*mock.Call
+}
+
+// QueueThumbnails is a helper method to define mock.On call
+//   - albumId int
+//   - fileIds []int
+//   - crop CropMode
+//   - force bool
+//   - callbackUrl string
+func (_e *MockService_Expecter) QueueThumbnails(albumId interface{}, fileIds interface{}, crop interface{}, force interface{}, callbackUrl interface{}) *MockService_QueueThumbnails_Call {
+	return &MockService_QueueThumbnails_Call{Call: _e.mock.On("QueueThumbnails", albumId, fileIds, crop, force, callbackUrl)}
+}
+
+func (_c *MockService_QueueThumbnails_Call) Run(run func(albumId int, fileIds []int, crop CropMode, force bool, callbackUrl string)) *MockService_QueueThumbnails_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		var arg1 []int
+		if args[1] != nil {
+			arg1 = args[1].([]int)
+		}
+		var arg2 CropMode
+		if args[2] != nil {
+			arg2 = args[2].(CropMode)
+		}
+		var arg3 bool
+		if args[3] != nil {
+			arg3 = args[3].(bool)
+		}
+		var arg4 string
+		if args[4] != nil {
+			arg4 = args[4].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_QueueThumbnails_Call) Return(err error) *MockService_QueueThumbnails_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockService_QueueThumbnails_Call) RunAndReturn(run func(albumId int, fileIds []int, crop CropMode, force bool, callbackUrl string) error) *MockService_QueueThumbnails_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetFileFailure provides a mock function for the type MockService
+func (_mock *MockService) ResetFileFailure(fileId int) error {
+	ret := _mock.Called(fileId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetFileFailure")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(int) error); ok {
+		r0 = returnFunc(fileId)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockService_ResetFileFailure_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetFileFailure'
+type MockService_ResetFileFailure_Call struct {
+	*mock.Call
+}
+
+// ResetFileFailure is a helper method to define mock.On call
+//   - fileId int
+func (_e *MockService_Expecter) ResetFileFailure(fileId interface{}) *MockService_ResetFileFailure_Call {
+	return &MockService_ResetFileFailure_Call{Call: _e.mock.On("ResetFileFailure", fileId)}
+}
+
+func (_c *MockService_ResetFileFailure_Call) Run(run func(fileId int)) *MockService_ResetFileFailure_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_ResetFileFailure_Call) Return(err error) *MockService_ResetFileFailure_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockService_ResetFileFailure_Call) RunAndReturn(run func(fileId int) error) *MockService_ResetFileFailure_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResetQuarantinedFiles provides a mock function for the type MockService
+func (_mock *MockService) ResetQuarantinedFiles() (int64, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetQuarantinedFiles")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() (int64, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() int64); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_ResetQuarantinedFiles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetQuarantinedFiles'
+type MockService_ResetQuarantinedFiles_Call struct {
+	*mock.Call
+}
+
+// ResetQuarantinedFiles is a helper method to define mock.On call
+func (_e *MockService_Expecter) ResetQuarantinedFiles() *MockService_ResetQuarantinedFiles_Call {
+	return &MockService_ResetQuarantinedFiles_Call{Call: _e.mock.On("ResetQuarantinedFiles")}
+}
+
+func (_c *MockService_ResetQuarantinedFiles_Call) Run(run func()) *MockService_ResetQuarantinedFiles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockService_ResetQuarantinedFiles_Call) Return(n int64, err error) *MockService_ResetQuarantinedFiles_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockService_ResetQuarantinedFiles_Call) RunAndReturn(run func() (int64, error)) *MockService_ResetQuarantinedFiles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetFocalPoint provides a mock function for the type MockService
+func (_mock *MockService) SetFocalPoint(fileToken uuid.UUID, focalPoint mod.FocalPoint) error {
+	ret := _mock.Called(fileToken, focalPoint)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFocalPoint")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(uuid.UUID, mod.FocalPoint) error); ok {
+		r0 = returnFunc(fileToken, focalPoint)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockService_SetFocalPoint_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFocalPoint'
+type MockService_SetFocalPoint_Call struct {
+	*mock.Call
+}
+
+// SetFocalPoint is a helper method to define mock.On call
+//   - fileToken uuid.UUID
+//   - focalPoint mod.FocalPoint
+func (_e *MockService_Expecter) SetFocalPoint(fileToken interface{}, focalPoint interface{}) *MockService_SetFocalPoint_Call {
+	return &MockService_SetFocalPoint_Call{Call: _e.mock.On("SetFocalPoint", fileToken, focalPoint)}
+}
+
+func (_c *MockService_SetFocalPoint_Call) Run(run func(fileToken uuid.UUID, focalPoint mod.FocalPoint)) *MockService_SetFocalPoint_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 uuid.UUID
+		if args[0] != nil {
+			arg0 = args[0].(uuid.UUID)
+		}
+		var arg1 mod.FocalPoint
+		if args[1] != nil {
+			arg1 = args[1].(mod.FocalPoint)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_SetFocalPoint_Call) Return(err error) *MockService_SetFocalPoint_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockService_SetFocalPoint_Call) RunAndReturn(run func(fileToken uuid.UUID, focalPoint mod.FocalPoint) error) *MockService_SetFocalPoint_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// StartBackfill provides a mock function for the type MockService
+func (_mock *MockService) StartBackfill(restart bool) (BackfillStatus, error) {
+	ret := _mock.Called(restart)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StartBackfill")
+	}
+
+	var r0 BackfillStatus
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(bool) (BackfillStatus, error)); ok {
+		return returnFunc(restart)
+	}
+	if returnFunc, ok := ret.Get(0).(func(bool) BackfillStatus); ok {
+		r0 = returnFunc(restart)
+	} else {
+		r0 = ret.Get(0).(BackfillStatus)
+	}
+	if returnFunc, ok := ret.Get(1).(func(bool) error); ok {
+		r1 = returnFunc(restart)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockService_StartBackfill_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StartBackfill'
+type MockService_StartBackfill_Call struct {
+	*mock.Call
+}
+
+// StartBackfill is a helper method to define mock.On call
+//   - restart bool
+func (_e *MockService_Expecter) StartBackfill(restart interface{}) *MockService_StartBackfill_Call {
+	return &MockService_StartBackfill_Call{Call: _e.mock.On("StartBackfill", restart)}
+}
+
+func (_c *MockService_StartBackfill_Call) Run(run func(restart bool)) *MockService_StartBackfill_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 bool
+		if args[0] != nil {
+			arg0 = args[0].(bool)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_StartBackfill_Call) Return(backfillStatus BackfillStatus, err error) *MockService_StartBackfill_Call {
+	_c.Call.Return(backfillStatus, err)
+	return _c
+}
+
+func (_c *MockService_StartBackfill_Call) RunAndReturn(run func(restart bool) (BackfillStatus, error)) *MockService_StartBackfill_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SubscribeAlbumProgress provides a mock function for the type MockService
+func (_mock *MockService) SubscribeAlbumProgress(albumId int) (<-chan AlbumProgress, func()) {
+	ret := _mock.Called(albumId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SubscribeAlbumProgress")
+	}
+
+	var r0 <-chan AlbumProgress
+	var r1 func()
+	if returnFunc, ok := ret.Get(0).(func(int) (<-chan AlbumProgress, func())); ok {
+		return returnFunc(albumId)
+	}
+	if returnFunc, ok := ret.Get(0).(func(int) <-chan AlbumProgress); ok {
+		r0 = returnFunc(albumId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan AlbumProgress)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(int) func()); ok {
+		r1 = returnFunc(albumId)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(func())
+		}
+	}
+	return r0, r1
+}
+
+// MockService_SubscribeAlbumProgress_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SubscribeAlbumProgress'
+type MockService_SubscribeAlbumProgress_Call struct {
+	*mock.Call
+}
+
+// SubscribeAlbumProgress is a helper method to define mock.On call
+//   - albumId int
+func (_e *MockService_Expecter) SubscribeAlbumProgress(albumId interface{}) *MockService_SubscribeAlbumProgress_Call {
+	return &MockService_SubscribeAlbumProgress_Call{Call: _e.mock.On("SubscribeAlbumProgress", albumId)}
+}
+
+func (_c *MockService_SubscribeAlbumProgress_Call) Run(run func(albumId int)) *MockService_SubscribeAlbumProgress_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 int
+		if args[0] != nil {
+			arg0 = args[0].(int)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockService_SubscribeAlbumProgress_Call) Return(ch <-chan AlbumProgress, fn func()) *MockService_SubscribeAlbumProgress_Call {
+	_c.Call.Return(ch, fn)
+	return _c
+}
+
+func (_c *MockService_SubscribeAlbumProgress_Call) RunAndReturn(run func(albumId int) (<-chan AlbumProgress, func())) *MockService_SubscribeAlbumProgress_Call {
 	_c.Call.Return(run)
 	return _c
 }
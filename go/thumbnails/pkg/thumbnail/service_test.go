@@ -1,17 +1,26 @@
 package thumbnail
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"mime/multipart"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/cache"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/callback"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dao"
+	"github.com/waifuvault/WaifuVault/thumbnails/pkg/dto"
 	"github.com/waifuvault/WaifuVault/thumbnails/pkg/mod"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
 )
 
 func setupTestRedis(t *testing.T) *redis.Client {
@@ -32,7 +41,10 @@ func newTestService(dao dao.Dao, processor Processor, rdb *redis.Client) Service
 		processor:     processor,
 		ffmpegFormats: []string{"mp4", "webm", "avi"},
 		supportedExts: []string{"jpg", "png", "gif", "webp"},
-		redisClient:   rdb,
+		cache:         cache.NewRedisCache(rdb),
+		inflight:      &singleflight.Group{},
+		pool:          NewWorkerPool(PoolConfig{Workers: DefaultPoolWorkers}),
+		progress:      newProgressHub(cache.NewRedisCache(rdb)),
 	}
 }
 
@@ -69,7 +81,7 @@ func TestService_GenerateThumbnail_UnsupportedFileType(t *testing.T) {
 	svc := newTestService(daoService, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnail(header, true)
+	result, err := svc.GenerateThumbnail(header, Options{Animate: true})
 
 	// then
 	assert.Error(t, err)
@@ -83,11 +95,11 @@ func TestService_GenerateThumbnailFromURL_Success(t *testing.T) {
 	mockRedis := setupTestRedis(t)
 	daoService := dao.NewMockDao(t)
 	url := "https://example.com/image.jpg"
-	mockProcessor.EXPECT().GenerateThumbnailFromURL(url, true).Return([]byte("thumbnail"), nil)
+	mockProcessor.EXPECT().GenerateThumbnailFromURL(url, Options{Animate: true}).Return([]byte("thumbnail"), nil)
 	svc := newTestService(daoService, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnailFromURL(url, true)
+	result, err := svc.GenerateThumbnailFromURL(url, Options{Animate: true})
 
 	// then
 	assert.NoError(t, err)
@@ -100,11 +112,11 @@ func TestService_GenerateThumbnailFromURL_InvalidURL(t *testing.T) {
 	mockRedis := setupTestRedis(t)
 	daoService := dao.NewMockDao(t)
 	url := "file:///etc/passwd"
-	mockProcessor.EXPECT().GenerateThumbnailFromURL(url, true).Return(nil, ErrInvalidURL)
+	mockProcessor.EXPECT().GenerateThumbnailFromURL(url, Options{Animate: true}).Return(nil, ErrInvalidURL)
 	svc := newTestService(daoService, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnailFromURL(url, true)
+	result, err := svc.GenerateThumbnailFromURL(url, Options{Animate: true})
 
 	// then
 	assert.Error(t, err)
@@ -118,11 +130,11 @@ func TestService_GenerateThumbnailFromURL_FileTooLarge(t *testing.T) {
 	mockRedis := setupTestRedis(t)
 	daoService := dao.NewMockDao(t)
 	url := "https://example.com/huge.jpg"
-	mockProcessor.EXPECT().GenerateThumbnailFromURL(url, true).Return(nil, ErrFileTooLarge)
+	mockProcessor.EXPECT().GenerateThumbnailFromURL(url, Options{Animate: true}).Return(nil, ErrFileTooLarge)
 	svc := newTestService(daoService, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnailFromURL(url, true)
+	result, err := svc.GenerateThumbnailFromURL(url, Options{Animate: true})
 
 	// then
 	assert.Error(t, err)
@@ -136,11 +148,11 @@ func TestService_GenerateThumbnailFromURL_UnsupportedFileType(t *testing.T) {
 	mockRedis := setupTestRedis(t)
 	daoService := dao.NewMockDao(t)
 	url := "https://example.com/file.exe"
-	mockProcessor.EXPECT().GenerateThumbnailFromURL(url, true).Return(nil, ErrUnsupportedFileType)
+	mockProcessor.EXPECT().GenerateThumbnailFromURL(url, Options{Animate: true}).Return(nil, ErrUnsupportedFileType)
 	svc := newTestService(daoService, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnailFromURL(url, true)
+	result, err := svc.GenerateThumbnailFromURL(url, Options{Animate: true})
 
 	// then
 	assert.Error(t, err)
@@ -162,11 +174,12 @@ func TestService_GenerateThumbnailByToken_Success(t *testing.T) {
 	}
 	mockDao.EXPECT().GetFileEntry(fileToken).Return(fileEntry, nil)
 	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
-	mockProcessor.EXPECT().GenerateThumbnail(mock.Anything, true).Return([]byte("thumbnail"), nil)
+	mockProcessor.EXPECT().DecodeCost(mock.Anything).Return(1)
+	mockProcessor.EXPECT().GenerateThumbnail(mock.Anything, mock.Anything, Options{Animate: true}).Return([]byte("thumbnail"), nil)
 	svc := newTestService(mockDao, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnailByToken(fileToken, true)
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{Animate: true})
 
 	// then
 	assert.NoError(t, err)
@@ -183,7 +196,7 @@ func TestService_GenerateThumbnailByToken_FileNotFound(t *testing.T) {
 	svc := newTestService(mockDao, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnailByToken(fileToken, true)
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{Animate: true})
 
 	// then
 	assert.Error(t, err)
@@ -208,7 +221,7 @@ func TestService_GenerateThumbnailByToken_UnsupportedFileType(t *testing.T) {
 	svc := newTestService(mockDao, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnailByToken(fileToken, true)
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{Animate: true})
 
 	// then
 	assert.Error(t, err)
@@ -231,11 +244,12 @@ func TestService_GenerateThumbnailByToken_ProcessorError(t *testing.T) {
 	expectedErr := errors.New("processing failed")
 	mockDao.EXPECT().GetFileEntry(fileToken).Return(fileEntry, nil)
 	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
-	mockProcessor.EXPECT().GenerateThumbnail(mock.Anything, true).Return(nil, expectedErr)
+	mockProcessor.EXPECT().DecodeCost(mock.Anything).Return(1)
+	mockProcessor.EXPECT().GenerateThumbnail(mock.Anything, mock.Anything, Options{Animate: true}).Return(nil, expectedErr)
 	svc := newTestService(mockDao, mockProcessor, mockRedis)
 
 	// when
-	result, err := svc.GenerateThumbnailByToken(fileToken, true)
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{Animate: true})
 
 	// then
 	assert.Error(t, err)
@@ -250,14 +264,350 @@ func TestService_IsAlbumLoading(t *testing.T) {
 	svc := newTestService(daoService, nil, mockRedis)
 	albumProcessing.Store(123, true)
 	defer albumProcessing.Delete(123)
+	daoService.EXPECT().HasUnfinishedJob(456).Return(false, nil)
+	daoService.EXPECT().HasUnfinishedJob(789).Return(true, nil)
 
 	// when
 	loading := svc.IsAlbumLoading(123)
 	notLoading := svc.IsAlbumLoading(456)
+	queued := svc.IsAlbumLoading(789)
 
 	// then
 	assert.True(t, loading)
 	assert.False(t, notLoading)
+	assert.True(t, queued)
+}
+
+func TestService_GetAlbumStatus(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	jobId, lastError := 8, "database error"
+	hub := newProgressHub(cache.NewRedisCache(mockRedis))
+	tracker := hub.track(mod.ThumbnailJob{Id: &jobId, AlbumId: 2, Attempts: 1}, 3)
+	tracker.processed(1, 2)
+	tracker.finish(mod.JobQueued, errors.New(lastError))
+	daoService.EXPECT().GetLatestJob(2).Return(&mod.ThumbnailJob{
+		Id:        &jobId,
+		AlbumId:   2,
+		Status:    mod.JobRunning,
+		Attempts:  2,
+		LastError: &lastError,
+	}, nil)
+
+	// when
+	progress, err := svc.GetAlbumStatus(2)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, mod.JobRunning, progress.State)
+	assert.Equal(t, 2, progress.Attempt)
+	assert.Equal(t, 3, progress.Total)
+	assert.Equal(t, 2, progress.Processed)
+	assert.Equal(t, lastError, progress.LastError)
+}
+
+func TestService_GetAlbumStatus_QueuedJob(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	jobId := 9
+	daoService.EXPECT().GetLatestJob(2).Return(&mod.ThumbnailJob{
+		Id:      &jobId,
+		AlbumId: 2,
+		Status:  mod.JobQueued,
+		Files:   `[{"id":1},{"id":2}]`,
+	}, nil)
+
+	// when
+	progress, err := svc.GetAlbumStatus(2)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, mod.JobQueued, progress.State)
+	assert.Equal(t, 9, progress.JobId)
+	assert.Equal(t, 2, progress.Total)
+	assert.Zero(t, progress.Processed)
+}
+
+func TestService_GetAlbumStatus_NoJob(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	daoService.EXPECT().GetLatestJob(2).Return(nil, nil)
+
+	// when
+	_, err := svc.GetAlbumStatus(2)
+
+	// then
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestService_CancelAlbumJobs(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	svc.(*service).jobs = newJobRunner(daoService, nil, nil, FailureConfig{}, nil, nil, JobConfig{})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	svc.(*service).jobs.running.Store(9, runningJob{albumId: 5, cancel: cancel})
+	daoService.EXPECT().CancelJobs(5, true).Return(int64(1), nil)
+
+	// when
+	cancelled, err := svc.CancelAlbumJobs(5, true)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), cancelled)
+	assert.True(t, discarding(ctx))
+}
+
+func TestService_CancelAlbumJobs_NothingToCancel(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	daoService.EXPECT().CancelJobs(5, false).Return(int64(0), nil)
+
+	// when
+	_, err := svc.CancelAlbumJobs(5, false)
+
+	// then
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestService_ResetFileFailure(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	daoService.EXPECT().DeleteFileFailures([]int{7}).Return(int64(1), nil)
+
+	// when
+	err := svc.ResetFileFailure(7)
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestService_ResetFileFailure_NotFailed(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	daoService.EXPECT().DeleteFileFailures([]int{7}).Return(int64(0), nil)
+
+	// when
+	err := svc.ResetFileFailure(7)
+
+	// then
+	assert.ErrorIs(t, err, ErrFileFailureNotFound)
+}
+
+// albumFiles stubs the lookup of the files of album 5 and supports every file
+func albumFiles(daoService *dao.MockDao, processor *MockProcessor, fileIds []int, fileEntries ...mod.FileEntry) {
+	daoService.EXPECT().GetAlbumThumbnailFiles(5, fileIds).Return(fileEntries, nil)
+	processor.On("SupportsFile", mock.Anything).Return(true).Maybe()
+}
+
+func TestService_QueueThumbnails(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	svc := newTestService(daoService, processor, mockRedis)
+	albumFiles(daoService, processor, []int{}, mod.FileEntry{Id: 1, FileName: "a", Extension: "png", MediaType: "image/png"})
+	daoService.EXPECT().GetUnfinishedJob(5).Return(nil, nil)
+	daoService.EXPECT().CreateJob(mock.Anything).RunAndReturn(func(job *mod.ThumbnailJob, _ ...*gorm.DB) error {
+		var queued []dto.FileEntryDto
+		assert.NoError(t, json.Unmarshal([]byte(job.Files), &queued))
+		assert.Equal(t, []dto.FileEntryDto{{Id: 1, MediaType: "image/png", Extension: "png", FullFileNameOnSystem: "a.png"}}, queued)
+		assert.Equal(t, 5, job.AlbumId)
+		assert.Equal(t, string(CropAttention), job.Crop)
+		return nil
+	})
+
+	// when
+	err := svc.QueueThumbnails(5, nil, CropAttention, false, "")
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestService_QueueThumbnails_UnsupportedFilesLeftOut(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	svc := newTestService(daoService, processor, mockRedis)
+	daoService.EXPECT().GetAlbumThumbnailFiles(5, []int{1}).Return([]mod.FileEntry{{Id: 1, FileName: "a", Extension: "zip"}}, nil)
+	processor.On("SupportsFile", mock.Anything).Return(false)
+
+	// when
+	err := svc.QueueThumbnails(5, []int{1}, CropNone, false, "")
+
+	// then no job is queued without files to render
+	assert.NoError(t, err)
+	daoService.AssertNotCalled(t, "CreateJob", mock.Anything)
+}
+
+func TestService_QueueThumbnails_AlbumNotFound(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	daoService.EXPECT().GetAlbumThumbnailFiles(5, []int{1}).Return(nil, gorm.ErrRecordNotFound)
+
+	// when
+	err := svc.QueueThumbnails(5, []int{1}, CropNone, false, "")
+
+	// then
+	assert.ErrorIs(t, err, ErrAlbumNotFound)
+}
+
+func TestService_QueueThumbnails_InvalidFileIds(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	svc := newTestService(daoService, nil, mockRedis)
+
+	// when
+	err := svc.QueueThumbnails(5, []int{1, -1}, CropNone, false, "")
+
+	// then
+	assert.ErrorIs(t, err, ErrInvalidFileIds)
+}
+
+func TestService_QueueThumbnails_WithCallback(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	notifier := callback.NewMockNotifier(t)
+	svc := newTestService(daoService, processor, mockRedis)
+	svc.(*service).notifier = notifier
+	callbackUrl := "https://waifuvault.moe/callback"
+	notifier.EXPECT().ValidateURL(callbackUrl).Return(nil)
+	albumFiles(daoService, processor, []int{1}, mod.FileEntry{Id: 1})
+	daoService.EXPECT().GetUnfinishedJob(5).Return(nil, nil)
+	daoService.EXPECT().CreateJob(mock.Anything).RunAndReturn(func(job *mod.ThumbnailJob, _ ...*gorm.DB) error {
+		assert.Equal(t, callbackUrl, *job.CallbackUrl)
+		return nil
+	})
+
+	// when
+	err := svc.QueueThumbnails(5, []int{1}, CropNone, false, callbackUrl)
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestService_QueueThumbnails_MergesIntoUnfinishedJob(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	svc := newTestService(daoService, processor, mockRedis)
+	jobId := 9
+	albumFiles(daoService, processor, []int{1, 2}, mod.FileEntry{Id: 1, FileName: "one", Extension: "png"}, mod.FileEntry{Id: 2, FileName: "two", Extension: "jpg"})
+	daoService.EXPECT().GetUnfinishedJob(5).Return(&mod.ThumbnailJob{
+		Id:       &jobId,
+		AlbumId:  5,
+		Crop:     string(CropNone),
+		Files:    `[{"id":1,"fileOnDisk":"one.png","extension":"png"}]`,
+		Revision: 2,
+	}, nil)
+	daoService.EXPECT().UpdateJobFiles(jobId, 2, mock.Anything).RunAndReturn(func(_ int, _ int, files string, _ ...*gorm.DB) (bool, error) {
+		var merged []dto.FileEntryDto
+		assert.NoError(t, json.Unmarshal([]byte(files), &merged))
+		assert.Equal(t, []dto.FileEntryDto{
+			{Id: 1, Extension: "png", FullFileNameOnSystem: "one.png"},
+			{Id: 2, Extension: "jpg", FullFileNameOnSystem: "two.jpg"},
+		}, merged)
+		return true, nil
+	})
+
+	// when
+	err := svc.QueueThumbnails(5, []int{1, 2, 2}, CropNone, false, "")
+
+	// then the job mock fails the test if another job is created
+	assert.NoError(t, err)
+}
+
+func TestService_QueueThumbnails_MergeRetriedWhenJobChanged(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	svc := newTestService(daoService, processor, mockRedis)
+	jobId := 9
+	albumFiles(daoService, processor, []int{2}, mod.FileEntry{Id: 2})
+	daoService.EXPECT().GetUnfinishedJob(5).Return(&mod.ThumbnailJob{Id: &jobId, Files: `[]`, Revision: 0}, nil).Once()
+	daoService.EXPECT().GetUnfinishedJob(5).Return(&mod.ThumbnailJob{Id: &jobId, Files: `[{"id":2}]`, Revision: 1}, nil).Once()
+	daoService.EXPECT().UpdateJobFiles(jobId, 0, mock.Anything).Return(false, nil)
+
+	// when
+	err := svc.QueueThumbnails(5, []int{2}, CropNone, false, "")
+
+	// then the file merged meanwhile isn't merged again
+	assert.NoError(t, err)
+}
+
+func TestService_QueueThumbnails_DifferentCropGetsItsOwnJob(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	svc := newTestService(daoService, processor, mockRedis)
+	jobId := 9
+	albumFiles(daoService, processor, []int{2}, mod.FileEntry{Id: 2})
+	daoService.EXPECT().GetUnfinishedJob(5).Return(&mod.ThumbnailJob{Id: &jobId, Crop: string(CropAttention), Files: `[]`}, nil)
+	daoService.EXPECT().CreateJob(mock.Anything).Return(nil)
+
+	// when
+	err := svc.QueueThumbnails(5, []int{2}, CropNone, false, "")
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestService_QueueThumbnails_ForcedFilesGetTheirOwnJob(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	processor := NewMockProcessor(t)
+	svc := newTestService(daoService, processor, mockRedis)
+	jobId := 9
+	albumFiles(daoService, processor, []int{2}, mod.FileEntry{Id: 2})
+	daoService.EXPECT().GetUnfinishedJob(5).Return(&mod.ThumbnailJob{Id: &jobId, Crop: string(CropNone), Files: `[]`}, nil)
+	daoService.EXPECT().CreateJob(mock.MatchedBy(func(job *mod.ThumbnailJob) bool {
+		return job.Force
+	})).Return(nil)
+
+	// when
+	err := svc.QueueThumbnails(5, []int{2}, CropNone, true, "")
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestService_QueueThumbnails_InvalidCallback(t *testing.T) {
+	// given
+	mockRedis := setupTestRedis(t)
+	daoService := dao.NewMockDao(t)
+	notifier := callback.NewMockNotifier(t)
+	svc := newTestService(daoService, nil, mockRedis)
+	svc.(*service).notifier = notifier
+	notifier.EXPECT().ValidateURL("ftp://waifuvault.moe").Return(callback.ErrInvalidURL)
+
+	// when
+	err := svc.QueueThumbnails(5, []int{1}, CropNone, false, "ftp://waifuvault.moe")
+
+	// then
+	assert.ErrorIs(t, err, callback.ErrInvalidURL)
 }
 
 func TestService_GenerateThumbnailByToken_CacheHit(t *testing.T) {
@@ -299,3 +649,404 @@ func TestService_GenerateThumbnailFromURL_CacheHit(t *testing.T) {
 	assert.NotNil(t, cacheValue)
 	assert.Equal(t, cachedThumbnail, cacheValue)
 }
+
+func TestService_GenerateThumbnailByToken_CropUsesFocalPoint(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	x, y := 0.2, 0.8
+	fileEntry := &mod.FileEntry{
+		Token:       fileToken,
+		MediaType:   "image/jpeg",
+		Extension:   "jpg",
+		FileName:    "test",
+		FocalPointX: &x,
+		FocalPointY: &y,
+	}
+	expectedOptions := Options{
+		Crop:       CropAttention,
+		FocalPoint: &mod.FocalPoint{X: x, Y: y},
+	}
+	mockDao.EXPECT().GetFileEntry(fileToken).Return(fileEntry, nil)
+	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
+	mockProcessor.EXPECT().DecodeCost(mock.Anything).Return(1)
+	mockProcessor.EXPECT().GenerateThumbnail(mock.Anything, mock.Anything, expectedOptions).Return([]byte("cropped"), nil)
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{Crop: CropAttention})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("cropped"), result)
+	cached, err := mockRedis.Get(context.Background(), fileToken.String()+":static:attention").Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("cropped"), cached)
+}
+
+func TestService_GenerateThumbnailByToken_KeepProfileUsesSeparateCacheKey(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	fileEntry := &mod.FileEntry{
+		Token:     fileToken,
+		MediaType: "image/jpeg",
+		Extension: "jpg",
+		FileName:  "test",
+	}
+	mockRedis.Set(context.Background(), fileToken.String()+":static", []byte("srgb"), 0)
+	mockDao.EXPECT().GetFileEntry(fileToken).Return(fileEntry, nil)
+	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
+	mockProcessor.EXPECT().DecodeCost(mock.Anything).Return(1)
+	mockProcessor.EXPECT().GenerateThumbnail(mock.Anything, mock.Anything, Options{KeepProfile: true}).Return([]byte("display-p3"), nil)
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{KeepProfile: true})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("display-p3"), result)
+	cached, err := mockRedis.Get(context.Background(), fileToken.String()+":static:icc").Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("display-p3"), cached)
+}
+
+func TestService_GenerateThumbnailSetByToken_RendersOnlyMissingWidths(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	fileEntry := &mod.FileEntry{
+		Token:     fileToken,
+		MediaType: "image/jpeg",
+		Extension: "jpg",
+		FileName:  "test",
+	}
+	mockRedis.Set(context.Background(), fileToken.String()+":static:w400", []byte("cached-400"), 0)
+	mockDao.EXPECT().GetFileEntry(fileToken).Return(fileEntry, nil)
+	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
+	mockProcessor.EXPECT().DecodeCost(mock.Anything).Return(1)
+	mockProcessor.EXPECT().GenerateThumbnailSet(mock.Anything, Options{}, []int{200, 800}).Return([]Variant{
+		{Width: 200, Thumbnail: []byte("rendered-200")},
+		{Width: 800, Thumbnail: []byte("rendered-800")},
+	}, nil)
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	result, err := svc.GenerateThumbnailSetByToken(fileToken, Options{}, []int{200, 400, 800})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []Variant{
+		{Width: 200, Thumbnail: []byte("rendered-200")},
+		{Width: 400, Thumbnail: []byte("cached-400")},
+		{Width: 800, Thumbnail: []byte("rendered-800")},
+	}, result)
+	cached, err := mockRedis.Get(context.Background(), fileToken.String()+":static:w800").Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("rendered-800"), cached)
+}
+
+func TestService_GenerateThumbnailSetByToken_AllCached(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	mockRedis.Set(context.Background(), fileToken.String()+":animated:w200", []byte("a"), 0)
+	mockRedis.Set(context.Background(), fileToken.String()+":animated:w400", []byte("b"), 0)
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	result, err := svc.GenerateThumbnailSetByToken(fileToken, Options{Animate: true}, []int{200, 400})
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, []byte("b"), result[1].Thumbnail)
+}
+
+func TestService_SetFocalPoint_Success(t *testing.T) {
+	// given
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	focalPoint := mod.FocalPoint{X: 0.3, Y: 0.6}
+	mockDao.EXPECT().SetFocalPoint(fileToken, focalPoint).Return(true, nil)
+	svc := newTestService(mockDao, nil, mockRedis)
+	serviceImpl := svc.(*service)
+	croppedKey := fileToken.String() + ":animated:entropy"
+	croppedSizedKey := fileToken.String() + ":static:attention:w800:icc"
+	uncroppedKey := fileToken.String() + ":animated:w800"
+	serviceImpl.storeThumbnailInCache(croppedKey, []byte("old"), 0)
+	serviceImpl.storeThumbnailInCache(croppedSizedKey, []byte("old"), 0)
+	serviceImpl.storeThumbnailInCache(uncroppedKey, []byte("full"), 0)
+
+	// when
+	err := svc.SetFocalPoint(fileToken, focalPoint)
+
+	// then
+	assert.NoError(t, err)
+	assert.Nil(t, serviceImpl.getThumbnailFromCache(croppedKey))
+	assert.Nil(t, serviceImpl.getThumbnailFromCache(croppedSizedKey))
+	assert.Equal(t, []byte("full"), serviceImpl.getThumbnailFromCache(uncroppedKey))
+}
+
+func TestService_SetFocalPoint_Invalid(t *testing.T) {
+	// given
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	svc := newTestService(mockDao, nil, mockRedis)
+
+	// when
+	err := svc.SetFocalPoint(uuid.New(), mod.FocalPoint{X: 2, Y: 0.5})
+
+	// then
+	assert.True(t, errors.Is(err, ErrInvalidFocalPoint))
+}
+
+func TestService_SetFocalPoint_FileNotFound(t *testing.T) {
+	// given
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	focalPoint := mod.FocalPoint{X: 0.5, Y: 0.5}
+	mockDao.EXPECT().SetFocalPoint(fileToken, focalPoint).Return(false, nil)
+	svc := newTestService(mockDao, nil, mockRedis)
+
+	// when
+	err := svc.SetFocalPoint(fileToken, focalPoint)
+
+	// then
+	assert.True(t, errors.Is(err, ErrFileNotFound))
+}
+
+func TestService_GenerateThumbnailByToken_CoalescesConcurrentRequests(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	fileEntry := &mod.FileEntry{
+		Token:     fileToken,
+		MediaType: "image/jpeg",
+		Extension: "jpg",
+		FileName:  "test",
+	}
+	release := make(chan struct{})
+	mockDao.EXPECT().GetFileEntry(fileToken).Return(fileEntry, nil).Once()
+	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true).Once()
+	mockProcessor.EXPECT().DecodeCost(mock.Anything).Return(1)
+	mockProcessor.EXPECT().GenerateThumbnail(mock.Anything, mock.Anything, Options{}).RunAndReturn(func(context.Context, dto.FileEntryDto, Options) ([]byte, error) {
+		<-release
+		return []byte("thumbnail"), nil
+	}).Once()
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	var wg sync.WaitGroup
+	results := make([][]byte, 5)
+	for i := range results {
+		wg.Go(func() {
+			results[i], _ = svc.GenerateThumbnailByToken(fileToken, Options{})
+		})
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// then
+	for _, result := range results {
+		assert.Equal(t, []byte("thumbnail"), result)
+	}
+}
+
+func TestService_GenerateThumbnailByToken_WaitsForLockHolder(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	cacheKey := fileToken.String() + ":static"
+	locker := cache.NewRedisLocker(mockRedis)
+	unlock, held, err := locker.TryLock(cacheKey, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, held)
+	svc := newTestService(mockDao, mockProcessor, mockRedis).(*service)
+	svc.locker = locker
+
+	// when
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		mockRedis.Set(context.Background(), cacheKey, []byte("from another instance"), 0)
+		unlock()
+	}()
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("from another instance"), result)
+}
+
+func TestService_GenerateThumbnailByToken_ReusesThumbnailOfIdenticalFile(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	firstToken, secondToken := uuid.New(), uuid.New()
+	for _, token := range []uuid.UUID{firstToken, secondToken} {
+		mockDao.EXPECT().GetFileEntry(token).Return(&mod.FileEntry{
+			Token:     token,
+			Checksum:  "abc",
+			MediaType: "image/jpeg",
+			Extension: "jpg",
+			FileName:  token.String(),
+		}, nil)
+	}
+	mockDao.EXPECT().GetThumbnailRowsByRenderKey([]string{"checksum:abc:static"}).Return(map[string]mod.Thumbnail{}, nil).Once()
+	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
+	mockProcessor.EXPECT().DecodeCost(mock.Anything).Return(1)
+	mockProcessor.EXPECT().GenerateThumbnail(mock.Anything, mock.Anything, Options{}).Return([]byte("thumbnail"), nil).Once()
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	first, firstErr := svc.GenerateThumbnailByToken(firstToken, Options{})
+	second, secondErr := svc.GenerateThumbnailByToken(secondToken, Options{})
+
+	// then
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, first, second)
+	for _, key := range []string{"checksum:abc:static", firstToken.String() + ":static", secondToken.String() + ":static"} {
+		cached, err := mockRedis.Get(context.Background(), key).Bytes()
+		assert.NoError(t, err)
+		assert.Equal(t, first, cached)
+	}
+}
+
+func TestService_GenerateThumbnailByToken_ReusesStoredAlbumThumbnail(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	mockDao.EXPECT().GetFileEntry(fileToken).Return(&mod.FileEntry{
+		Token:     fileToken,
+		Checksum:  "abc",
+		MediaType: "image/jpeg",
+		Extension: "jpg",
+		FileName:  "test",
+	}, nil)
+	mockDao.EXPECT().GetThumbnailRowsByRenderKey([]string{"checksum:abc:static"}).Return(map[string]mod.Thumbnail{
+		"checksum:abc:static": {Content: []byte("stored")},
+	}, nil)
+	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("stored"), result)
+}
+
+func TestService_GenerateThumbnailByToken_StoredThumbnailGetsTimeOfRow(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	updatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockDao.EXPECT().GetFileEntry(fileToken).Return(&mod.FileEntry{
+		Token:     fileToken,
+		Checksum:  "abc",
+		MediaType: "image/jpeg",
+		Extension: "jpg",
+		FileName:  "test",
+	}, nil)
+	mockDao.EXPECT().GetThumbnailRowsByRenderKey([]string{"checksum:abc:static"}).Return(map[string]mod.Thumbnail{
+		"checksum:abc:static": {Content: buildWebp(vp8lChunk(400, 300, false)), UpdatedAt: updatedAt},
+	}, nil)
+	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	result, err := svc.GenerateThumbnailByToken(fileToken, Options{})
+
+	// then the unstamped thumbnail gets a Last-Modified
+	assert.NoError(t, err)
+	renderedAt, stamped := ReadRenderTime(result)
+	assert.True(t, stamped)
+	assert.True(t, updatedAt.Equal(renderedAt))
+}
+
+func TestService_GenerateThumbnailSetByToken_ReusesWidthsOfIdenticalFile(t *testing.T) {
+	// given
+	mockProcessor := NewMockProcessor(t)
+	mockDao := dao.NewMockDao(t)
+	mockRedis := setupTestRedis(t)
+	fileToken := uuid.New()
+	mockRedis.Set(context.Background(), "checksum:abc:static:w200", []byte("shared-200"), 0)
+	mockDao.EXPECT().GetFileEntry(fileToken).Return(&mod.FileEntry{
+		Token:     fileToken,
+		Checksum:  "abc",
+		MediaType: "image/jpeg",
+		Extension: "jpg",
+		FileName:  "test",
+	}, nil)
+	mockProcessor.EXPECT().SupportsFile(mock.Anything).Return(true)
+	mockProcessor.EXPECT().DecodeCost(mock.Anything).Return(1)
+	mockProcessor.EXPECT().GenerateThumbnailSet(mock.Anything, Options{}, []int{400}).Return([]Variant{
+		{Width: 400, Thumbnail: []byte("rendered-400")},
+	}, nil)
+	svc := newTestService(mockDao, mockProcessor, mockRedis)
+
+	// when
+	result, err := svc.GenerateThumbnailSetByToken(fileToken, Options{}, []int{200, 400})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("shared-200"), result[0].Thumbnail)
+	assert.Equal(t, 400, result[1].Width)
+	shared, err := mockRedis.Get(context.Background(), "checksum:abc:static:w400").Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, result[1].Thumbnail, shared)
+}
+
+func TestService_GetStoredThumbnails_DeduplicatesFileIds(t *testing.T) {
+	// given
+	mockDao := dao.NewMockDao(t)
+	mockDao.EXPECT().GetThumbnails([]int{3, 1}).Return(map[int][]byte{3: []byte("three")}, nil)
+	svc := newTestService(mockDao, nil, setupTestRedis(t))
+
+	// when
+	result, err := svc.GetStoredThumbnails([]int{3, 1, 3})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, map[int][]byte{3: []byte("three")}, result)
+}
+
+func TestService_GetStoredThumbnails_InvalidFileIds(t *testing.T) {
+	// given
+	svc := newTestService(dao.NewMockDao(t), nil, setupTestRedis(t))
+	tooMany := make([]int, MaxStoredBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+
+	for _, fileIds := range [][]int{nil, {1, 0}, tooMany} {
+		// when
+		_, err := svc.GetStoredThumbnails(fileIds)
+
+		// then
+		assert.ErrorIs(t, err, ErrInvalidFileIds)
+	}
+}
@@ -0,0 +1,27 @@
+package thumbnail
+
+import "fmt"
+
+// ValidateFileIds checks a request for stored thumbnails names between 1 and MaxStoredBatchSize valid file ids
+func ValidateFileIds(fileIds []int) error {
+	if len(fileIds) == 0 {
+		return fmt.Errorf("%w: at least one file id is required", ErrInvalidFileIds)
+	}
+	if len(fileIds) > MaxStoredBatchSize {
+		return fmt.Errorf("%w: at most %d file ids can be requested at once", ErrInvalidFileIds, MaxStoredBatchSize)
+	}
+	for _, fileId := range fileIds {
+		if fileId <= 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidFileIds, fileId)
+		}
+	}
+	return nil
+}
+
+// ContentType returns the media type of a thumbnail, video frames are stored as the JPEG ffmpeg produced
+func ContentType(thumbnail []byte) string {
+	if isJPEG(thumbnail) {
+		return "image/jpeg"
+	}
+	return "image/webp"
+}
@@ -0,0 +1,21 @@
+package thumbnail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFileIds(t *testing.T) {
+	// then
+	assert.NoError(t, ValidateFileIds([]int{1, 2, 3}))
+	assert.ErrorIs(t, ValidateFileIds(nil), ErrInvalidFileIds)
+	assert.ErrorIs(t, ValidateFileIds([]int{1, -4}), ErrInvalidFileIds)
+	assert.ErrorIs(t, ValidateFileIds(make([]int, MaxStoredBatchSize+1)), ErrInvalidFileIds)
+}
+
+func TestContentType(t *testing.T) {
+	// then
+	assert.Equal(t, "image/jpeg", ContentType([]byte{0xff, 0xd8, 0xff, 0xe0}))
+	assert.Equal(t, "image/webp", ContentType(buildWebp(vp8lChunk(400, 300, false))))
+}
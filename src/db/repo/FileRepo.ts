@@ -76,7 +76,9 @@ export class FileRepo {
     public async deleteEntries(tokens: string[]): Promise<boolean> {
         const entries = await this.getEntries(tokens);
         await this.thumbnailCacheRepo.deleteThumbsIfExist(entries);
-        return this.fileDao.deleteEntries(tokens);
+        const deleted = await this.fileDao.deleteEntries(tokens);
+        await this.thumbnailCacheRepo.publishLifecycleEvents("deleted", entries);
+        return deleted;
     }
 
     public getRecordCount(bucket?: string): Promise<number> {
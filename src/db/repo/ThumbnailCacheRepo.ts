@@ -1,4 +1,4 @@
-import { Inject, Injectable } from "@tsed/di";
+import { Constant, Inject, Injectable } from "@tsed/di";
 import { ThumbnailCacheDao } from "../dao/ThumbnailCacheDao.js";
 import { ThumbnailCacheModel } from "../../model/db/ThumbnailCache.model.js";
 import { REDIS_CONNECTION } from "../../model/di/tokens.js";
@@ -6,11 +6,18 @@ import type { RedisConnection } from "../../redis/Connection.js";
 import { FileUploadModel } from "../../model/db/FileUpload.model.js";
 import { FileUtils } from "../../utils/Utils.js";
 import { EntityManager } from "typeorm";
+import { GlobalEnv } from "../../model/constants/GlobalEnv.js";
 
 @Injectable()
 export class ThumbnailCacheRepo {
     public static readonly redisCachePrefix = "thumbnail:";
     public static readonly redisCacheTTL = 31536000;
+    // the thumbnail service purges the thumbnails of the files added to this stream
+    public static readonly lifecycleStream = "file-lifecycle";
+    public static readonly lifecycleStreamMaxLength = 10000;
+
+    @Constant(GlobalEnv.THUMBNAIL_SERVICE_BASE_URL, "http://127.0.0.1:5006")
+    private readonly thumbnailServiceUrl: string;
 
     public constructor(
         @Inject() private thumbnailCacheDao: ThumbnailCacheDao,
@@ -65,23 +72,64 @@ export class ThumbnailCacheRepo {
         await this.deleteThumbnailCaches(thumbnailsToDelete, transaction);
     }
 
-    private async cacheRedis(thumbnailCache: ThumbnailCacheModel): Promise<void> {
+    public async publishLifecycleEvents(type: "deleted" | "protected", entries: FileUploadModel[]): Promise<void> {
+        if (entries.length === 0) {
+            return;
+        }
+        const pipeline = this.redis.pipeline();
+        for (const entry of entries) {
+            pipeline.xadd(
+                ThumbnailCacheRepo.lifecycleStream,
+                "MAXLEN",
+                "~",
+                ThumbnailCacheRepo.lifecycleStreamMaxLength,
+                "*",
+                "type",
+                type,
+                "fileId",
+                String(entry.id),
+                "token",
+                entry.token,
+            );
+        }
+        await pipeline.exec();
+    }
+
+    private async cacheRedis(fileId: number, thumbnail: Buffer): Promise<void> {
         await this.redis.setex(
-            `${ThumbnailCacheRepo.redisCachePrefix}${thumbnailCache.fileId}`,
+            `${ThumbnailCacheRepo.redisCachePrefix}${fileId}`,
             ThumbnailCacheRepo.redisCacheTTL,
-            Buffer.from(thumbnailCache.data, "base64"),
+            thumbnail,
         );
     }
 
+    private async readStored(thumbnailCache: ThumbnailCacheModel): Promise<Buffer | null> {
+        if (thumbnailCache.data) {
+            return Buffer.from(thumbnailCache.data, "base64");
+        }
+        if (thumbnailCache.blob) {
+            return thumbnailCache.blob;
+        }
+        // stored in a directory or object store only the thumbnail service can read
+        const response = await fetch(`${this.thumbnailServiceUrl}/api/v1/thumbnails/${thumbnailCache.fileId}`);
+        if (!response.ok) {
+            return null;
+        }
+        return Buffer.from(await response.arrayBuffer());
+    }
+
     public async getThumbnailBuffer(fileId: number): Promise<Buffer | null> {
         const thumbnailFromRedis = await this.redis.getBuffer(`${ThumbnailCacheRepo.redisCachePrefix}${fileId}`);
         if (!thumbnailFromRedis || thumbnailFromRedis.length === 0) {
             const fromDb = await this.thumbnailCacheDao.getThumbnailCache(fileId);
-            if (fromDb) {
-                await this.cacheRedis(fromDb);
-                return Buffer.from(fromDb.data, "base64");
+            if (!fromDb) {
+                return null;
             }
-            return null;
+            const thumbnail = await this.readStored(fromDb);
+            if (thumbnail) {
+                await this.cacheRedis(fileId, thumbnail);
+            }
+            return thumbnail;
         }
         return thumbnailFromRedis;
     }
@@ -0,0 +1,15 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddFocalPoint1792371234567 implements MigrationInterface {
+    name = 'AddFocalPoint1792371234567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "file_upload_model" ADD "focalPointX" real`);
+        await queryRunner.query(`ALTER TABLE "file_upload_model" ADD "focalPointY" real`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "file_upload_model" DROP COLUMN "focalPointY"`);
+        await queryRunner.query(`ALTER TABLE "file_upload_model" DROP COLUMN "focalPointX"`);
+    }
+}
@@ -0,0 +1,15 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailStore1792457634567 implements MigrationInterface {
+    name = 'AddThumbnailStore1792457634567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" ADD "blob" bytea`);
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" ADD "location" text`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" DROP COLUMN "location"`);
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" DROP COLUMN "blob"`);
+    }
+}
@@ -0,0 +1,15 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailPipelineVersion1792630434567 implements MigrationInterface {
+    name = 'AddThumbnailPipelineVersion1792630434567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" ADD "pipelineVersion" integer NOT NULL DEFAULT 1`);
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" ADD "crop" text NOT NULL DEFAULT ''`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" DROP COLUMN "crop"`);
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" DROP COLUMN "pipelineVersion"`);
+    }
+}
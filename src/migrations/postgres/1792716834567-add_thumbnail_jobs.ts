@@ -0,0 +1,19 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailJobs1792716834567 implements MigrationInterface {
+    name = 'AddThumbnailJobs1792716834567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`CREATE TABLE "thumbnail_job_model" ("id" SERIAL NOT NULL, "createdAt" TIMESTAMP NOT NULL DEFAULT now(), "updatedAt" TIMESTAMP NOT NULL DEFAULT now(), "crop" text NOT NULL DEFAULT '', "files" text NOT NULL, "status" text NOT NULL DEFAULT 'queued', "attempts" integer NOT NULL DEFAULT '0', "runAfter" TIMESTAMP NOT NULL, "leaseOwner" text, "leaseUntil" TIMESTAMP, "lastError" text, "albumId" integer NOT NULL, CONSTRAINT "PK_4b4b81454c4acf3a203d2f36f07" PRIMARY KEY ("id"))`);
+        await queryRunner.query(`CREATE INDEX "IDX_9816b532f29ed11936500541b1" ON "thumbnail_job_model" ("status", "runAfter") `);
+        await queryRunner.query(`CREATE INDEX "IDX_15bda019f4b47d198e17166c30" ON "thumbnail_job_model" ("albumId", "status") `);
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" ADD CONSTRAINT "FK_2a86e6c699af7da32aaff2a86f3" FOREIGN KEY ("albumId") REFERENCES "album_model"("id") ON DELETE CASCADE ON UPDATE CASCADE`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" DROP CONSTRAINT "FK_2a86e6c699af7da32aaff2a86f3"`);
+        await queryRunner.query(`DROP INDEX "public"."IDX_15bda019f4b47d198e17166c30"`);
+        await queryRunner.query(`DROP INDEX "public"."IDX_9816b532f29ed11936500541b1"`);
+        await queryRunner.query(`DROP TABLE "thumbnail_job_model"`);
+    }
+}
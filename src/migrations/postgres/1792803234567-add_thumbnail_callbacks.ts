@@ -0,0 +1,19 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailCallbacks1792803234567 implements MigrationInterface {
+    name = 'AddThumbnailCallbacks1792803234567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" ADD "callbackUrl" text`);
+        await queryRunner.query(`CREATE TABLE "thumbnail_callback_delivery_model" ("id" SERIAL NOT NULL, "createdAt" TIMESTAMP NOT NULL DEFAULT now(), "updatedAt" TIMESTAMP NOT NULL DEFAULT now(), "albumId" integer NOT NULL, "deliveryId" text NOT NULL, "target" text NOT NULL, "attempt" integer NOT NULL, "statusCode" integer, "error" text, "delivered" boolean NOT NULL DEFAULT false, "jobId" integer NOT NULL, CONSTRAINT "PK_a7fbedf777fe094d29c3b820ec2" PRIMARY KEY ("id"))`);
+        await queryRunner.query(`CREATE INDEX "IDX_e24590ccc3733fb98e628b76b2" ON "thumbnail_callback_delivery_model" ("albumId") `);
+        await queryRunner.query(`ALTER TABLE "thumbnail_callback_delivery_model" ADD CONSTRAINT "FK_6f54f7162c4cacf1d1ea334ca5f" FOREIGN KEY ("jobId") REFERENCES "thumbnail_job_model"("id") ON DELETE CASCADE ON UPDATE CASCADE`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_callback_delivery_model" DROP CONSTRAINT "FK_6f54f7162c4cacf1d1ea334ca5f"`);
+        await queryRunner.query(`DROP INDEX "public"."IDX_e24590ccc3733fb98e628b76b2"`);
+        await queryRunner.query(`DROP TABLE "thumbnail_callback_delivery_model"`);
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" DROP COLUMN "callbackUrl"`);
+    }
+}
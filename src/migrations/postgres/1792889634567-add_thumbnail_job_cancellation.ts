@@ -0,0 +1,13 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailJobCancellation1792889634567 implements MigrationInterface {
+    name = 'AddThumbnailJobCancellation1792889634567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" ADD "discardPartial" boolean NOT NULL DEFAULT false`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" DROP COLUMN "discardPartial"`);
+    }
+}
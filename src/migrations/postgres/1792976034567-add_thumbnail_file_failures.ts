@@ -0,0 +1,17 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailFileFailures1792976034567 implements MigrationInterface {
+    name = 'AddThumbnailFileFailures1792976034567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`CREATE TABLE "thumbnail_file_failure_model" ("id" SERIAL NOT NULL, "createdAt" TIMESTAMP NOT NULL DEFAULT now(), "updatedAt" TIMESTAMP NOT NULL DEFAULT now(), "errorClass" text NOT NULL, "attempts" integer NOT NULL, "lastError" text NOT NULL, "nextAttemptAt" TIMESTAMP, "quarantined" boolean NOT NULL DEFAULT false, "fileId" integer NOT NULL, CONSTRAINT "PK_fed0a9e0ccabaf9dac1421d769a" PRIMARY KEY ("id"))`);
+        await queryRunner.query(`CREATE UNIQUE INDEX "IDX_0c0501843e231f0184cb93a718" ON "thumbnail_file_failure_model" ("fileId") `);
+        await queryRunner.query(`ALTER TABLE "thumbnail_file_failure_model" ADD CONSTRAINT "FK_0c0501843e231f0184cb93a718a" FOREIGN KEY ("fileId") REFERENCES "file_upload_model"("id") ON DELETE CASCADE ON UPDATE CASCADE`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_file_failure_model" DROP CONSTRAINT "FK_0c0501843e231f0184cb93a718a"`);
+        await queryRunner.query(`DROP INDEX "public"."IDX_0c0501843e231f0184cb93a718"`);
+        await queryRunner.query(`DROP TABLE "thumbnail_file_failure_model"`);
+    }
+}
@@ -0,0 +1,13 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailJobRevision1793062434567 implements MigrationInterface {
+    name = 'AddThumbnailJobRevision1793062434567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" ADD "revision" integer NOT NULL DEFAULT '0'`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" DROP COLUMN "revision"`);
+    }
+}
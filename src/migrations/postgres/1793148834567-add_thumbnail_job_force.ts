@@ -0,0 +1,13 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailJobForce1793148834567 implements MigrationInterface {
+    name = 'AddThumbnailJobForce1793148834567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" ADD "force" boolean NOT NULL DEFAULT false`);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" DROP COLUMN "force"`);
+    }
+}
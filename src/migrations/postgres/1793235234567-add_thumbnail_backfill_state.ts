@@ -0,0 +1,15 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailBackfillState1793235234567 implements MigrationInterface {
+    name = 'AddThumbnailBackfillState1793235234567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`CREATE TABLE "thumbnail_backfill_state_model" ("id" SERIAL NOT NULL, "createdAt" TIMESTAMP NOT NULL DEFAULT now(), "updatedAt" TIMESTAMP NOT NULL DEFAULT now(), "name" text NOT NULL, "cursor" integer NOT NULL, CONSTRAINT "PK_cb053cb99208444373fa97d57b5" PRIMARY KEY ("id"))`);
+        await queryRunner.query(`CREATE UNIQUE INDEX "IDX_79fa516601e9257ed1e6085cbd" ON "thumbnail_backfill_state_model" ("name") `);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`DROP INDEX "public"."IDX_79fa516601e9257ed1e6085cbd"`);
+        await queryRunner.query(`DROP TABLE "thumbnail_backfill_state_model"`);
+    }
+}
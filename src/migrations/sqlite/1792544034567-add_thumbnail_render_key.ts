@@ -0,0 +1,15 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailRenderKey1792544034567 implements MigrationInterface {
+    name = 'AddThumbnailRenderKey1792544034567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" ADD COLUMN "renderKey" text`);
+        await queryRunner.query(`CREATE INDEX "IDX_df43d146ce73cee08e760864af" ON "thumbnail_cache_model" ("renderKey") `);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`DROP INDEX "IDX_df43d146ce73cee08e760864af"`);
+        await queryRunner.query(`ALTER TABLE "thumbnail_cache_model" DROP COLUMN "renderKey"`);
+    }
+}
@@ -0,0 +1,17 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailJobs1792716834567 implements MigrationInterface {
+    name = 'AddThumbnailJobs1792716834567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`CREATE TABLE "thumbnail_job_model" ("id" integer PRIMARY KEY AUTOINCREMENT NOT NULL, "createdAt" datetime NOT NULL DEFAULT (datetime('now')), "updatedAt" datetime NOT NULL DEFAULT (datetime('now')), "crop" text NOT NULL DEFAULT (''), "files" text NOT NULL, "status" text NOT NULL DEFAULT ('queued'), "attempts" integer NOT NULL DEFAULT (0), "runAfter" datetime NOT NULL, "leaseOwner" text, "leaseUntil" datetime, "lastError" text, "albumId" integer NOT NULL, CONSTRAINT "FK_2a86e6c699af7da32aaff2a86f3" FOREIGN KEY ("albumId") REFERENCES "album_model" ("id") ON DELETE CASCADE ON UPDATE CASCADE)`);
+        await queryRunner.query(`CREATE INDEX "IDX_9816b532f29ed11936500541b1" ON "thumbnail_job_model" ("status", "runAfter") `);
+        await queryRunner.query(`CREATE INDEX "IDX_15bda019f4b47d198e17166c30" ON "thumbnail_job_model" ("albumId", "status") `);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`DROP INDEX "IDX_15bda019f4b47d198e17166c30"`);
+        await queryRunner.query(`DROP INDEX "IDX_9816b532f29ed11936500541b1"`);
+        await queryRunner.query(`DROP TABLE "thumbnail_job_model"`);
+    }
+}
@@ -0,0 +1,17 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailCallbacks1792803234567 implements MigrationInterface {
+    name = 'AddThumbnailCallbacks1792803234567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" ADD COLUMN "callbackUrl" text`);
+        await queryRunner.query(`CREATE TABLE "thumbnail_callback_delivery_model" ("id" integer PRIMARY KEY AUTOINCREMENT NOT NULL, "createdAt" datetime NOT NULL DEFAULT (datetime('now')), "updatedAt" datetime NOT NULL DEFAULT (datetime('now')), "albumId" integer NOT NULL, "deliveryId" text NOT NULL, "target" text NOT NULL, "attempt" integer NOT NULL, "statusCode" integer, "error" text, "delivered" boolean NOT NULL DEFAULT (0), "jobId" integer NOT NULL, CONSTRAINT "FK_6f54f7162c4cacf1d1ea334ca5f" FOREIGN KEY ("jobId") REFERENCES "thumbnail_job_model" ("id") ON DELETE CASCADE ON UPDATE CASCADE)`);
+        await queryRunner.query(`CREATE INDEX "IDX_e24590ccc3733fb98e628b76b2" ON "thumbnail_callback_delivery_model" ("albumId") `);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`DROP INDEX "IDX_e24590ccc3733fb98e628b76b2"`);
+        await queryRunner.query(`DROP TABLE "thumbnail_callback_delivery_model"`);
+        await queryRunner.query(`ALTER TABLE "thumbnail_job_model" DROP COLUMN "callbackUrl"`);
+    }
+}
@@ -0,0 +1,15 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailFileFailures1792976034567 implements MigrationInterface {
+    name = 'AddThumbnailFileFailures1792976034567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`CREATE TABLE "thumbnail_file_failure_model" ("id" integer PRIMARY KEY AUTOINCREMENT NOT NULL, "createdAt" datetime NOT NULL DEFAULT (datetime('now')), "updatedAt" datetime NOT NULL DEFAULT (datetime('now')), "errorClass" text NOT NULL, "attempts" integer NOT NULL, "lastError" text NOT NULL, "nextAttemptAt" datetime, "quarantined" boolean NOT NULL DEFAULT (0), "fileId" integer NOT NULL, CONSTRAINT "FK_0c0501843e231f0184cb93a718a" FOREIGN KEY ("fileId") REFERENCES "file_upload_model" ("id") ON DELETE CASCADE ON UPDATE CASCADE)`);
+        await queryRunner.query(`CREATE UNIQUE INDEX "IDX_0c0501843e231f0184cb93a718" ON "thumbnail_file_failure_model" ("fileId") `);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`DROP INDEX "IDX_0c0501843e231f0184cb93a718"`);
+        await queryRunner.query(`DROP TABLE "thumbnail_file_failure_model"`);
+    }
+}
@@ -0,0 +1,15 @@
+import { MigrationInterface, QueryRunner } from "typeorm";
+
+export class AddThumbnailBackfillState1793235234567 implements MigrationInterface {
+    name = 'AddThumbnailBackfillState1793235234567'
+
+    public async up(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`CREATE TABLE "thumbnail_backfill_state_model" ("id" integer PRIMARY KEY AUTOINCREMENT NOT NULL, "createdAt" datetime NOT NULL DEFAULT (datetime('now')), "updatedAt" datetime NOT NULL DEFAULT (datetime('now')), "name" text NOT NULL, "cursor" integer NOT NULL)`);
+        await queryRunner.query(`CREATE UNIQUE INDEX "IDX_79fa516601e9257ed1e6085cbd" ON "thumbnail_backfill_state_model" ("name") `);
+    }
+
+    public async down(queryRunner: QueryRunner): Promise<void> {
+        await queryRunner.query(`DROP INDEX "IDX_79fa516601e9257ed1e6085cbd"`);
+        await queryRunner.query(`DROP TABLE "thumbnail_backfill_state_model"`);
+    }
+}
@@ -128,6 +128,18 @@ export class FileUploadModel extends AbstractModel {
     })
     public addedToAlbumOrder: number | null;
 
+    @Column({
+        nullable: true,
+        type: "real",
+    })
+    public focalPointX: number | null;
+
+    @Column({
+        nullable: true,
+        type: "real",
+    })
+    public focalPointY: number | null;
+
     @ManyToOne("BucketModel", "files", {
         ...AbstractModel.cascadeOps,
     })
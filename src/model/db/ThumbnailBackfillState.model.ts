@@ -0,0 +1,21 @@
+import { Column, Entity, Index } from "typeorm";
+import { AbstractModel } from "./AbstractModel.js";
+
+// progress of a background job of the thumbnail service, kept here so the job carries on from it after a restart
+@Entity()
+@Index(["name"], {
+    unique: true,
+})
+export class ThumbnailBackfillStateModel extends AbstractModel {
+    @Column({
+        nullable: false,
+        type: "text",
+    })
+    public name: string;
+
+    // id of the last file the job handled
+    @Column({
+        nullable: false,
+    })
+    public cursor: number;
+}
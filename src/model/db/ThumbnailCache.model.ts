@@ -6,6 +6,7 @@ import { FileUploadModel } from "./FileUpload.model.js";
 @Index(["fileId"], {
     unique: true,
 })
+@Index(["renderKey"])
 export class ThumbnailCacheModel extends AbstractModel {
     @Column({
         nullable: false,
@@ -13,6 +14,41 @@ export class ThumbnailCacheModel extends AbstractModel {
     })
     public data: string;
 
+    // set instead of data when the thumbnail service stores thumbnails in a binary column
+    @Column({
+        nullable: true,
+    })
+    public blob: Buffer | null;
+
+    // "<backend>:<key>" of thumbnails the thumbnail service keeps outside the database
+    @Column({
+        nullable: true,
+        type: "text",
+    })
+    public location: string | null;
+
+    // checksum and render options the thumbnail service rendered the thumbnail from, shared by identical uploads
+    @Column({
+        nullable: true,
+        type: "text",
+    })
+    public renderKey: string | null;
+
+    // version of the thumbnail service pipeline that rendered the thumbnail, older versions are re-rendered by it
+    @Column({
+        nullable: false,
+        default: 1,
+    })
+    public pipelineVersion: number;
+
+    // crop mode the thumbnail was rendered with, empty when it keeps the aspect ratio
+    @Column({
+        nullable: false,
+        type: "text",
+        default: "",
+    })
+    public crop: string;
+
     @Column({
         nullable: false,
     })
@@ -0,0 +1,64 @@
+import { Column, Entity, Index, JoinColumn, ManyToOne } from "typeorm";
+import { AbstractModel } from "./AbstractModel.js";
+import type { ThumbnailJobModel } from "./ThumbnailJob.model.js";
+
+// attempt of the thumbnail service at delivering the completion event of a job, kept for debugging callbacks
+@Entity()
+@Index(["albumId"])
+export class ThumbnailCallbackDeliveryModel extends AbstractModel {
+    @Column({
+        nullable: false,
+    })
+    public albumId: number;
+
+    // shared by every attempt of an event
+    @Column({
+        nullable: false,
+        type: "text",
+    })
+    public deliveryId: string;
+
+    // callback URL, or redis:<channel> for the configured channel
+    @Column({
+        nullable: false,
+        type: "text",
+    })
+    public target: string;
+
+    @Column({
+        nullable: false,
+    })
+    public attempt: number;
+
+    @Column({
+        nullable: true,
+        type: "integer",
+    })
+    public statusCode: number | null;
+
+    @Column({
+        nullable: true,
+        type: "text",
+    })
+    public error: string | null;
+
+    @Column({
+        nullable: false,
+        default: false,
+    })
+    public delivered: boolean;
+
+    @Column({
+        nullable: false,
+    })
+    public jobId: number;
+
+    @ManyToOne("ThumbnailJobModel", {
+        ...AbstractModel.cascadeOps,
+    })
+    @JoinColumn({
+        name: "jobId",
+        referencedColumnName: "id",
+    })
+    public job: Promise<ThumbnailJobModel>;
+}
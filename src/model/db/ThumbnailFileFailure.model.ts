@@ -0,0 +1,54 @@
+import { Column, Entity, Index, JoinColumn, ManyToOne } from "typeorm";
+import { AbstractModel } from "./AbstractModel.js";
+import type { FileUploadModel } from "./FileUpload.model.js";
+
+// failed renders of an album file, the thumbnail service skips the file until its retry is due or while it is quarantined
+@Entity()
+@Index(["fileId"], {
+    unique: true,
+})
+export class ThumbnailFileFailureModel extends AbstractModel {
+    // transient, unsupported or missing
+    @Column({
+        nullable: false,
+        type: "text",
+    })
+    public errorClass: string;
+
+    @Column({
+        nullable: false,
+    })
+    public attempts: number;
+
+    @Column({
+        nullable: false,
+        type: "text",
+    })
+    public lastError: string;
+
+    // when a transient failure is retried, null once the file is quarantined
+    @Column({
+        nullable: true,
+    })
+    public nextAttemptAt: Date | null;
+
+    @Column({
+        nullable: false,
+        default: false,
+    })
+    public quarantined: boolean;
+
+    @Column({
+        nullable: false,
+    })
+    public fileId: number;
+
+    @ManyToOne("FileUploadModel", {
+        ...AbstractModel.cascadeOps,
+    })
+    @JoinColumn({
+        name: "fileId",
+        referencedColumnName: "id",
+    })
+    public file: Promise<FileUploadModel>;
+}
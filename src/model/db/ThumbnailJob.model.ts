@@ -0,0 +1,104 @@
+import { Column, Entity, Index, JoinColumn, ManyToOne } from "typeorm";
+import { AbstractModel } from "./AbstractModel.js";
+import type { AlbumModel } from "./Album.model.js";
+
+// album thumbnail job queued and run by the thumbnail service, which owns every column but the schema
+@Entity()
+@Index(["status", "runAfter"])
+@Index(["albumId", "status"])
+export class ThumbnailJobModel extends AbstractModel {
+    // crop mode the thumbnails are rendered with, empty when they keep the aspect ratio
+    @Column({
+        nullable: false,
+        type: "text",
+        default: "",
+    })
+    public crop: string;
+
+    // JSON encoded files to generate thumbnails for
+    @Column({
+        nullable: false,
+        type: "text",
+    })
+    public files: string;
+
+    // queued, running, done, failed or cancelled
+    @Column({
+        nullable: false,
+        type: "text",
+        default: "queued",
+    })
+    public status: string;
+
+    @Column({
+        nullable: false,
+        default: 0,
+    })
+    public attempts: number;
+
+    // the job is not claimed before this time, set when it is queued and when a failed attempt is retried
+    @Column({
+        nullable: false,
+    })
+    public runAfter: Date;
+
+    // instance holding the job while leaseUntil is in the future
+    @Column({
+        nullable: true,
+        type: "text",
+    })
+    public leaseOwner: string | null;
+
+    @Column({
+        nullable: true,
+    })
+    public leaseUntil: Date | null;
+
+    @Column({
+        nullable: true,
+        type: "text",
+    })
+    public lastError: string | null;
+
+    // receives the signed completion event of the job
+    @Column({
+        nullable: true,
+        type: "text",
+    })
+    public callbackUrl: string | null;
+
+    // set by a cancellation that drops the thumbnails rendered but not stored yet
+    @Column({
+        nullable: false,
+        default: false,
+    })
+    public discardPartial: boolean;
+
+    // renders the files that already have a thumbnail again instead of skipping them
+    @Column({
+        nullable: false,
+        default: false,
+    })
+    public force: boolean;
+
+    // raised every time files are merged into the job, the worker running it picks up the files of a newer revision
+    @Column({
+        nullable: false,
+        default: 0,
+    })
+    public revision: number;
+
+    @Column({
+        nullable: false,
+    })
+    public albumId: number;
+
+    @ManyToOne("AlbumModel", {
+        ...AbstractModel.cascadeOps,
+    })
+    @JoinColumn({
+        name: "albumId",
+        referencedColumnName: "id",
+    })
+    public album: Promise<AlbumModel>;
+}
@@ -305,14 +305,14 @@ export class AlbumService implements AfterInit {
         return this.albumRepo.albumExists(publicToken);
     }
 
-    public async generateThumbnails(privateAlbumToken: string, filesIds: number[] = []): Promise<void> {
+    public async generateThumbnails(privateAlbumToken: string, filesIds: number[] = [], force = false): Promise<void> {
         const album = await this.albumRepo.getAlbum(privateAlbumToken, true);
         if (!album) {
             throw new NotFound("Album not found");
         }
         this.checkPrivateToken(privateAlbumToken, album);
 
-        const promise = this.thumbnailService.generateThumbnail(album, filesIds);
+        const promise = this.thumbnailService.generateThumbnail(album, filesIds, force);
 
         promise
             .then(() => this.logger.info(`Successfully generated thumbnails for album ${privateAlbumToken}`))
@@ -340,7 +340,7 @@ export class AlbumService implements AfterInit {
 
         // something went wrong, the entry is in the DB, but data is an empty string, re-generate thumbnail
         if (thumbnailFromCache && thumbnailFromCache.length === 0 && FileUtils.isValidForThumbnail(entry)) {
-            this.generateThumbnails(albumToken, [imageId]);
+            this.generateThumbnails(albumToken, [imageId], true);
         }
 
         if (FileUtils.isValidForThumbnail(entry)) {
@@ -28,6 +28,7 @@ import { uuid } from "../utils/uuidUtils.js";
 import { SettingsService } from "./SettingsService.js";
 import { GlobalEnv } from "../model/constants/GlobalEnv.js";
 import { FileReputationService } from "./FileReputationService.js";
+import { ThumbnailCacheRepo } from "../db/repo/ThumbnailCacheRepo.js";
 
 @Service()
 export class FileUploadService {
@@ -47,6 +48,7 @@ export class FileUploadService {
         @Inject() private bucketService: BucketService,
         @Inject() private fileFilterManager: FileFilterManager,
         @Inject() private fileReputationService: FileReputationService,
+        @Inject() private thumbnailCacheRepo: ThumbnailCacheRepo,
         @Inject() settingsService: SettingsService,
     ) {
         this.secret = settingsService.getSetting(GlobalEnv.UPLOAD_SECRET);
@@ -278,7 +280,12 @@ export class FileUploadService {
             const fileSize = await FileUtils.getFileSize(entryToModify);
             builder.expires(FileUtils.getExpiresBySize(fileSize, this.maxFileSize, entryToModify.createdAt.getTime()));
         }
-        return this.repo.saveEntry(builder.build());
+        const saved = await this.repo.saveEntry(builder.build());
+        if (typeof dto.password === "string") {
+            // the file was encrypted, decrypted or had its password changed, so thumbnails rendered from it are stale
+            await this.thumbnailCacheRepo.publishLifecycleEvents("protected", [saved]);
+        }
+        return saved;
     }
 
     private async calculateCustomExpires(
@@ -38,14 +38,15 @@ export class ThumbnailService implements AfterInit {
         this.logger.info(`loaded Supported image extensions`);
     }
 
-    public async generateThumbnail(album: AlbumModel, filesIds: number[] = []): Promise<void> {
+    public async generateThumbnail(album: AlbumModel, filesIds: number[] = [], force = false): Promise<void> {
         const addingFiles = filesIds.length > 0;
 
         const entries =
             album.files?.filter(
                 f => f.fileProtectionLevel === "None" && (filesIds.length === 0 || filesIds.includes(f.id)),
             ) ?? [];
-        const cacheResults = await this.thumbnailCacheReo.hasThumbnails(entries.map(e => e.id));
+        // a forced run regenerates the thumbnails that already exist, so none are filtered out
+        const cacheResults = force ? [] : await this.thumbnailCacheReo.hasThumbnails(entries.map(e => e.id));
         const toSend = entries
             .filter(
                 entry =>
@@ -54,21 +55,15 @@ export class ThumbnailService implements AfterInit {
                     entry.mediaType &&
                     entry.fileExtension,
             )
-            .map(entry => {
-                return {
-                    id: entry.id,
-                    fileOnDisk: entry.fullFileNameOnSystem,
-                    mediaType: entry.mediaType,
-                    extension: entry.fileExtension,
-                };
-            });
+            .map(entry => entry.id);
         if (toSend.length === 0) {
             return;
         }
+        // only the ids are sent, the thumbnail service looks the files up in the album itself
         const r = await fetch(
-            `${this.url}/generateThumbnails?albumId=${album.id}&addingAdditionalFiles=${addingFiles}`,
+            `${this.url}/generateThumbnails?albumId=${album.id}&addingAdditionalFiles=${addingFiles}&force=${force}`,
             {
-                body: JSON.stringify(toSend),
+                body: JSON.stringify({ fileIds: toSend }),
                 method: "POST",
                 headers: {
                     "Content-Type": "application/json",